@@ -0,0 +1,164 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+var (
+    ErrAddressNotFound = errors.New("address not found")
+    ErrInvalidAddress  = errors.New("invalid address")
+)
+
+// Address is an entry of an account's address book. Country is an ISO
+// 3166-1 alpha-2 code; which of Region and PostalCode are required depends
+// on it.
+type Address struct {
+    ID         string `json:"id"`
+    AccountID  string `json:"account_id"`
+    Name       string `json:"name"`
+    Company    string `json:"company"`
+    Line1      string `json:"line1"`
+    Line2      string `json:"line2"`
+    City       string `json:"city"`
+    Region     string `json:"region"`
+    PostalCode string `json:"postal_code"`
+    Country    string `json:"country"`
+    Phone      string `json:"phone"`
+    // IsDefault marks the address orders use when they don't name one.
+    // An account with addresses always has exactly one default.
+    IsDefault  bool      `json:"is_default"`
+    // CreatedAt orders an account's addresses; updates keep it.
+    CreatedAt  time.Time `json:"created_at"`
+}
+
+// addressRule lists what a country requires on top of a name, first line
+// and city.
+type addressRule struct {
+    region     bool
+    postalCode *regexp.Regexp
+}
+
+// addressRules covers the countries we ship to most; other countries only
+// need the common fields.
+var addressRules = map[string]addressRule{
+    "US": {region: true, postalCode: regexp.MustCompile(`^\d{5}(-\d{4})?$`)},
+    "CA": {region: true, postalCode: regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`)},
+    "AU": {region: true, postalCode: regexp.MustCompile(`^\d{4}$`)},
+    "CN": {region: true, postalCode: regexp.MustCompile(`^\d{6}$`)},
+    "JP": {region: true, postalCode: regexp.MustCompile(`^\d{3}-?\d{4}$`)},
+    "GB": {postalCode: regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`)},
+    "DE": {postalCode: regexp.MustCompile(`^\d{5}$`)},
+    "FR": {postalCode: regexp.MustCompile(`^\d{5}$`)},
+    "NL": {postalCode: regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`)},
+}
+
+var countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// Normalize trims the fields of a and upper-cases its country and postal
+// code.
+func (a Address) Normalize() Address {
+    for _, f := range []*string{
+        &a.Name, &a.Company, &a.Line1, &a.Line2, &a.City, &a.Region, &a.PostalCode, &a.Country, &a.Phone,
+    } {
+        *f = strings.TrimSpace(*f)
+    }
+    a.Country = strings.ToUpper(a.Country)
+    a.PostalCode = strings.ToUpper(a.PostalCode)
+    return a
+}
+
+// Validate checks a normalized address against the rules of its country.
+// Errors wrap ErrInvalidAddress and name the offending field.
+func (a Address) Validate() error {
+    if !countryCode.MatchString(a.Country) {
+        return fmt.Errorf("%w: country must be a two-letter code", ErrInvalidAddress)
+    }
+    for _, f := range []struct{ name, value string }{
+        {"name", a.Name}, {"line1", a.Line1}, {"city", a.City},
+    } {
+        if f.value == "" {
+            return fmt.Errorf("%w: %s is required", ErrInvalidAddress, f.name)
+        }
+    }
+
+    rule := addressRules[a.Country]
+    if rule.region && a.Region == "" {
+        return fmt.Errorf("%w: region is required in %s", ErrInvalidAddress, a.Country)
+    }
+    if rule.postalCode != nil && !rule.postalCode.MatchString(a.PostalCode) {
+        return fmt.Errorf("%w: postal code is invalid for %s", ErrInvalidAddress, a.Country)
+    }
+    return nil
+}
+
+// PostAddress adds an address to the account's book. The account's first
+// address becomes its default.
+func (s *accountService) PostAddress(ctx context.Context, accountID string, a Address) (*Address, error) {
+    if _, err := s.repository.GetAccountByID(ctx, accountID); err != nil {
+        log.Println("failed to get account from account service: ", err)
+        return nil, err
+    }
+
+    a = a.Normalize()
+    if err := a.Validate(); err != nil {
+        return nil, err
+    }
+    a.ID = ksuid.New().String()
+    a.AccountID = accountID
+    a.CreatedAt = time.Now().UTC()
+
+    if err := s.repository.PutAddress(ctx, a); err != nil {
+        log.Println("failed to put address from account service: ", err)
+        return nil, err
+    }
+    return s.repository.GetAddress(ctx, accountID, a.ID)
+}
+
+// UpdateAddress replaces the account's address with a.ID. Unsetting
+// IsDefault on the default address keeps it the default; set it on
+// another address instead.
+func (s *accountService) UpdateAddress(ctx context.Context, accountID string, a Address) (*Address, error) {
+    current, err := s.repository.GetAddress(ctx, accountID, a.ID)
+    if err != nil {
+        log.Println("failed to get address from account service: ", err)
+        return nil, err
+    }
+
+    a = a.Normalize()
+    if err := a.Validate(); err != nil {
+        return nil, err
+    }
+    a.AccountID = accountID
+    a.IsDefault = a.IsDefault || current.IsDefault
+    a.CreatedAt = current.CreatedAt
+
+    if err := s.repository.PutAddress(ctx, a); err != nil {
+        log.Println("failed to put address from account service: ", err)
+        return nil, err
+    }
+    return s.repository.GetAddress(ctx, accountID, a.ID)
+}
+
+func (s *accountService) DeleteAddress(ctx context.Context, accountID, id string) error {
+    if err := s.repository.DeleteAddress(ctx, accountID, id); err != nil {
+        log.Println("failed to delete address from account service: ", err)
+        return err
+    }
+    return nil
+}
+
+func (s *accountService) GetAddress(ctx context.Context, accountID, id string) (*Address, error) {
+    return s.repository.GetAddress(ctx, accountID, id)
+}
+
+func (s *accountService) GetAddresses(ctx context.Context, accountID string) ([]Address, error) {
+    return s.repository.GetAddresses(ctx, accountID)
+}
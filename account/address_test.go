@@ -0,0 +1,145 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAddressNormalize(t *testing.T) {
+    tests := []struct {
+        name string
+        in   Address
+        want Address
+    }{
+        {
+            name: "trims every field",
+            in: Address{
+                Name: " Ann ", Company: " Acme ", Line1: " 1 Main St ", Line2: " Apt 2 ",
+                City: " Springfield ", Region: " IL ", PostalCode: " 62701 ", Country: " US ", Phone: " 555 ",
+            },
+            want: Address{
+                Name: "Ann", Company: "Acme", Line1: "1 Main St", Line2: "Apt 2",
+                City: "Springfield", Region: "IL", PostalCode: "62701", Country: "US", Phone: "555",
+            },
+        },
+        {
+            name: "upper-cases country and postal code",
+            in: Address{Name: "Bo", Line1: "10 Downing St", City: "London", PostalCode: "sw1a 2aa", Country: "gb"},
+            want: Address{Name: "Bo", Line1: "10 Downing St", City: "London", PostalCode: "SW1A 2AA", Country: "GB"},
+        },
+        {
+            name: "keeps the case of other fields",
+            in: Address{Name: "de la Cruz", Line1: "rue x", City: "paris", Country: "fr"},
+            want: Address{Name: "de la Cruz", Line1: "rue x", City: "paris", Country: "FR"},
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := tt.in.Normalize(); got != tt.want {
+                t.Fatalf("got %+v, want %+v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestAddressValidate(t *testing.T) {
+    valid := Address{Name: "Ann", Line1: "1 Main St", City: "Springfield", Region: "IL", PostalCode: "62701", Country: "US"}
+    with := func(change func(a *Address)) Address {
+        a := valid
+        change(&a)
+        return a
+    }
+
+    tests := []struct {
+        name    string
+        address Address
+        // wantErr is part of the error message; "" means valid.
+        wantErr string
+    }{
+        {"valid US address", valid, ""},
+        {"US ZIP+4", with(func(a *Address) { a.PostalCode = "62701-1234" }), ""},
+        {"missing country", with(func(a *Address) { a.Country = "" }), "country must be a two-letter code"},
+        {"three-letter country", with(func(a *Address) { a.Country = "USA" }), "country must be a two-letter code"},
+        {"lower-case country", with(func(a *Address) { a.Country = "us" }), "country must be a two-letter code"},
+        {"missing name", with(func(a *Address) { a.Name = "" }), "name is required"},
+        {"missing line1", with(func(a *Address) { a.Line1 = "" }), "line1 is required"},
+        {"missing city", with(func(a *Address) { a.City = "" }), "city is required"},
+        {"US without region", with(func(a *Address) { a.Region = "" }), "region is required in US"},
+        {"invalid US postal code", with(func(a *Address) { a.PostalCode = "6270" }), "postal code is invalid for US"},
+        {"missing US postal code", with(func(a *Address) { a.PostalCode = "" }), "postal code is invalid for US"},
+        {"CA postal code", with(func(a *Address) { a.Country, a.Region, a.PostalCode = "CA", "ON", "K1A 0B1" }), ""},
+        {"GB without region", with(func(a *Address) { a.Country, a.Region, a.PostalCode = "GB", "", "SW1A 2AA" }), ""},
+        {"invalid GB postal code", with(func(a *Address) { a.Country, a.PostalCode = "GB", "12345" }), "postal code is invalid for GB"},
+        {"NL postal code", with(func(a *Address) { a.Country, a.Region, a.PostalCode = "NL", "", "1012 AB" }), ""},
+        {"country without rules", with(func(a *Address) { a.Country, a.Region, a.PostalCode = "BR", "", "" }), ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := tt.address.Validate()
+            if tt.wantErr == "" {
+                if err != nil {
+                    t.Fatalf("expected a valid address, got %v", err)
+                }
+                return
+            }
+            if !errors.Is(err, ErrInvalidAddress) || !strings.Contains(err.Error(), tt.wantErr) {
+                t.Fatalf("expected an ErrInvalidAddress mentioning %q, got %v", tt.wantErr, err)
+            }
+        })
+    }
+}
+
+func TestAddressesOrderedByCreation(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+
+    a, err := s.PostAccount(ctx, "ann")
+    if err != nil {
+        t.Fatal(err)
+    }
+    // Addresses added within the same second have ksuids in random order.
+    ids := []string{}
+    for _, city := range []string{"Aa", "Bb", "Cc", "Dd", "Ee"} {
+        address, err := s.PostAddress(ctx, a.ID, Address{Name: "Ann", Line1: "1 Main St", City: city, Country: "BR"})
+        if err != nil {
+            t.Fatal(err)
+        }
+        ids = append(ids, address.ID)
+    }
+    listed := func() []string {
+        addresses, err := s.GetAddresses(ctx, a.ID)
+        if err != nil {
+            t.Fatal(err)
+        }
+        ids := []string{}
+        for _, address := range addresses {
+            ids = append(ids, address.ID)
+        }
+        return ids
+    }
+    if got := listed(); strings.Join(got, ",") != strings.Join(ids, ",") {
+        t.Fatalf("got %v, want %v", got, ids)
+    }
+
+    // Updates keep an address's place.
+    if _, err := s.UpdateAddress(ctx, a.ID, Address{ID: ids[2], Name: "Ann", Line1: "2 Main St", City: "Cc", Country: "BR"}); err != nil {
+        t.Fatal(err)
+    }
+    if got := listed(); strings.Join(got, ",") != strings.Join(ids, ",") {
+        t.Fatalf("expected an update to keep the order, got %v", got)
+    }
+
+    // Deleting the default makes the oldest remaining address the default.
+    if err := s.DeleteAddress(ctx, a.ID, ids[0]); err != nil {
+        t.Fatal(err)
+    }
+    second, err := s.GetAddress(ctx, a.ID, ids[1])
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !second.IsDefault {
+        t.Fatalf("expected the oldest remaining address to become the default, got %+v", second)
+    }
+}
@@ -14,9 +14,14 @@ type Client struct {
     service pb.AccountServiceClient
 }
 
-func NewClient(url string) (*Client, error) {
-    opts := grpc.WithTransportCredentials(insecure.NewCredentials())
-    conn, err := grpc.NewClient(url, opts)
+// NewClient dials url with insecure credentials; opts are appended, e.g.
+// a custom dialer.
+func NewClient(url string, opts ...grpc.DialOption) (*Client, error) {
+    opts = append(
+        []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+        opts...,
+    )
+    conn, err := grpc.NewClient(url, opts...)
     if err != nil {
         log.Println("failed to create account grpc client: ", err)
         return nil, err
@@ -83,3 +88,92 @@ func (c *Client) GetAccounts(ctx context.Context, skip uint64, take uint64) ([]A
     }
     return accounts, nil
 }
+
+// SearchAccounts returns up to take accounts whose name contains query,
+// ignoring case; a zero take uses the server default.
+func (c *Client) SearchAccounts(ctx context.Context, query string, take uint64) ([]Account, error) {
+    r, err := c.service.SearchAccounts(
+        ctx,
+        &pb.SearchAccountsRequest{Query: query, Take: take},
+    )
+    if err != nil {
+        log.Println("failed to search accounts from account client: ", err)
+        return nil, err
+    }
+
+    accounts := []Account{}
+    for _, a := range r.Accounts {
+        accounts = append(accounts, Account{ID: a.Id, Name: a.Name})
+    }
+    return accounts, nil
+}
+
+// PostAddress adds a to the account's address book; the account's first
+// address becomes its default.
+func (c *Client) PostAddress(ctx context.Context, accountID string, a Address) (*Address, error) {
+    r, err := c.service.PostAddress(
+        ctx,
+        &pb.PostAddressRequest{AccountId: accountID, Address: addressToProto(a)},
+    )
+    if err != nil {
+        log.Println("failed to post address from account client: ", err)
+        return nil, err
+    }
+    address := addressFromProto(r.Address)
+    return &address, nil
+}
+
+func (c *Client) UpdateAddress(ctx context.Context, accountID string, a Address) (*Address, error) {
+    r, err := c.service.UpdateAddress(
+        ctx,
+        &pb.UpdateAddressRequest{AccountId: accountID, Address: addressToProto(a)},
+    )
+    if err != nil {
+        log.Println("failed to update address from account client: ", err)
+        return nil, err
+    }
+    address := addressFromProto(r.Address)
+    return &address, nil
+}
+
+func (c *Client) DeleteAddress(ctx context.Context, accountID, id string) error {
+    _, err := c.service.DeleteAddress(
+        ctx,
+        &pb.DeleteAddressRequest{AccountId: accountID, Id: id},
+    )
+    if err != nil {
+        log.Println("failed to delete address from account client: ", err)
+    }
+    return err
+}
+
+func (c *Client) GetAddress(ctx context.Context, accountID, id string) (*Address, error) {
+    r, err := c.service.GetAddress(
+        ctx,
+        &pb.GetAddressRequest{AccountId: accountID, Id: id},
+    )
+    if err != nil {
+        log.Println("failed to get address from account client: ", err)
+        return nil, err
+    }
+    address := addressFromProto(r.Address)
+    return &address, nil
+}
+
+// GetAddresses returns the account's addresses, oldest first.
+func (c *Client) GetAddresses(ctx context.Context, accountID string) ([]Address, error) {
+    r, err := c.service.GetAddresses(
+        ctx,
+        &pb.GetAddressesRequest{AccountId: accountID},
+    )
+    if err != nil {
+        log.Println("failed to get addresses from account client: ", err)
+        return nil, err
+    }
+
+    addresses := []Address{}
+    for _, a := range r.Addresses {
+        addresses = append(addresses, addressFromProto(a))
+    }
+    return addresses, nil
+}
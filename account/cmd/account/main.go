@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
@@ -24,7 +25,10 @@ func main() {
     var r account.Repository
 
     ctx := context.Background()
-    if err := retry.Constant(ctx, time.Second * 1 , func(ctx context.Context) error {
+    if strings.HasPrefix(cfg.DatabaseURL, "memory://") {
+        log.Println("using in-memory account repository")
+        r = account.NewMemoryRepository()
+    } else if err := retry.Constant(ctx, time.Second * 1 , func(ctx context.Context) error {
         r, err = account.NewPostgresRepository((cfg.DatabaseURL))
         if err != nil {
             log.Println("failed to create account postgres repository: ", err)
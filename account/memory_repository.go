@@ -0,0 +1,163 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+    ErrDuplicateID = errors.New("duplicate id")
+)
+
+type memoryRepository struct {
+    mu       sync.RWMutex
+    accounts map[string]Account
+    // addresses holds every account's addresses by id.
+    addresses map[string]Address
+}
+
+func NewMemoryRepository() Repository {
+    return &memoryRepository{accounts: map[string]Account{}, addresses: map[string]Address{}}
+}
+
+func (r *memoryRepository) Close() {}
+
+func (r *memoryRepository) PutAccount(ctx context.Context, a Account) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if _, ok := r.accounts[a.ID]; ok {
+        log.Println("failed to put account from account memory repository: duplicate id ", a.ID)
+        return ErrDuplicateID
+    }
+    r.accounts[a.ID] = a
+    return nil
+}
+
+func (r *memoryRepository) GetAccountByID(
+    ctx context.Context, id string,
+) (*Account, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    a, ok := r.accounts[id]
+    if !ok {
+        log.Println("failed to get account by id from account memory repository: ", sql.ErrNoRows)
+        return nil, sql.ErrNoRows
+    }
+    return &a, nil
+}
+
+// ListAccounts mirrors the postgres ordering: newest ksuid first.
+func (r *memoryRepository) ListAccounts(ctx context.Context, skip uint64, take uint64) ([]Account, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    all := make([]Account, 0, len(r.accounts))
+    for _, a := range r.accounts {
+        all = append(all, a)
+    }
+    sort.Slice(all, func(i, j int) bool { return all[i].ID > all[j].ID })
+
+    accounts := []Account{}
+    for i := skip; i < uint64(len(all)) && i-skip < take; i++ {
+        accounts = append(accounts, all[i])
+    }
+    return accounts, nil
+}
+
+func (r *memoryRepository) SearchAccounts(ctx context.Context, query string, take uint64) ([]Account, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    query = strings.ToLower(query)
+    accounts := []Account{}
+    for _, a := range r.accounts {
+        if strings.Contains(strings.ToLower(a.Name), query) {
+            accounts = append(accounts, a)
+        }
+    }
+    sort.Slice(accounts, func(i, j int) bool { return accounts[i].ID > accounts[j].ID })
+    return accounts[:min(take, uint64(len(accounts)))], nil
+}
+
+func (r *memoryRepository) PutAddress(ctx context.Context, a Address) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if current, ok := r.addresses[a.ID]; ok && current.AccountID != a.AccountID {
+        return ErrAddressNotFound
+    }
+    hasDefault := false
+    for id, other := range r.addresses {
+        if other.AccountID != a.AccountID || id == a.ID || !other.IsDefault {
+            continue
+        }
+        if a.IsDefault {
+            other.IsDefault = false
+            r.addresses[id] = other
+        } else {
+            hasDefault = true
+        }
+    }
+    a.IsDefault = a.IsDefault || !hasDefault
+    r.addresses[a.ID] = a
+    return nil
+}
+
+func (r *memoryRepository) DeleteAddress(ctx context.Context, accountID, id string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    a, ok := r.addresses[id]
+    if !ok || a.AccountID != accountID {
+        return ErrAddressNotFound
+    }
+    delete(r.addresses, id)
+    if rest := r.accountAddresses(accountID); a.IsDefault && len(rest) != 0 {
+        rest[0].IsDefault = true
+        r.addresses[rest[0].ID] = rest[0]
+    }
+    return nil
+}
+
+func (r *memoryRepository) GetAddress(ctx context.Context, accountID, id string) (*Address, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    a, ok := r.addresses[id]
+    if !ok || a.AccountID != accountID {
+        return nil, ErrAddressNotFound
+    }
+    return &a, nil
+}
+
+func (r *memoryRepository) GetAddresses(ctx context.Context, accountID string) ([]Address, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    return r.accountAddresses(accountID), nil
+}
+
+// accountAddresses mirrors the postgres ordering: oldest first. The caller
+// holds r.mu.
+func (r *memoryRepository) accountAddresses(accountID string) []Address {
+    addresses := []Address{}
+    for _, a := range r.addresses {
+        if a.AccountID == accountID {
+            addresses = append(addresses, a)
+        }
+    }
+    sort.Slice(addresses, func(i, j int) bool {
+        if !addresses[i].CreatedAt.Equal(addresses[j].CreatedAt) {
+            return addresses[i].CreatedAt.Before(addresses[j].CreatedAt)
+        }
+        return addresses[i].ID < addresses[j].ID
+    })
+    return addresses
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"log"
+	"strings"
 
 	_ "github.com/lib/pq"
 )
@@ -13,6 +14,19 @@ type Repository interface {
     PutAccount(ctx context.Context, a Account) error
     GetAccountByID(ctx context.Context, id string) (*Account, error)
     ListAccounts(ctx context.Context, skip uint64, take uint64) ([]Account, error)
+    // SearchAccounts returns up to take accounts whose name contains query,
+    // ignoring case, newest first.
+    SearchAccounts(ctx context.Context, query string, take uint64) ([]Account, error)
+    // PutAddress inserts or replaces a. If a is the default, the account's
+    // other addresses stop being one; if the account has no default, a
+    // becomes it.
+    PutAddress(ctx context.Context, a Address) error
+    // DeleteAddress removes the account's address with id, handing the
+    // default over to the oldest remaining address if it was the default.
+    DeleteAddress(ctx context.Context, accountID, id string) error
+    GetAddress(ctx context.Context, accountID, id string) (*Address, error)
+    // GetAddresses returns the account's addresses, oldest first.
+    GetAddresses(ctx context.Context, accountID string) ([]Address, error)
 }
 
 type postgresRepository struct {
@@ -99,3 +113,169 @@ func (r *postgresRepository) ListAccounts(ctx context.Context, skip uint64, take
     }
     return accounts, nil
 }
+
+// likeEscaper escapes the LIKE wildcards, so they match themselves.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (r *postgresRepository) SearchAccounts(ctx context.Context, query string, take uint64) ([]Account, error) {
+    rows, err := r.db.QueryContext(
+        ctx,
+        "SELECT id, name FROM accounts WHERE name ILIKE '%' || $1 || '%' ORDER BY id DESC LIMIT $2",
+        likeEscaper.Replace(query),
+        take,
+    )
+    if err != nil {
+        log.Println("failed to search accounts from account repository: ", err)
+        return nil, err
+    }
+    defer rows.Close()
+
+    accounts := []Account{}
+    for rows.Next() {
+        a := Account{}
+        if err := rows.Scan(&a.ID, &a.Name); err != nil {
+            log.Println("failed to scan account from account repository: ", err)
+            return nil, err
+        }
+        accounts = append(accounts, a)
+    }
+    return accounts, rows.Err()
+}
+
+const addressColumns = "id, account_id, name, company, line1, line2, city, region, postal_code, country, phone, is_default, created_at"
+
+func scanAddress(row interface{ Scan(...any) error }) (*Address, error) {
+    a := &Address{}
+    err := row.Scan(
+        &a.ID, &a.AccountID, &a.Name, &a.Company, &a.Line1, &a.Line2,
+        &a.City, &a.Region, &a.PostalCode, &a.Country, &a.Phone, &a.IsDefault, &a.CreatedAt,
+    )
+    return a, err
+}
+
+func (r *postgresRepository) PutAddress(ctx context.Context, a Address) error {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        log.Println("failed to begin transaction from account repository: ", err)
+        return err
+    }
+    defer tx.Rollback()
+
+    // The old default goes first, so the partial unique index never sees
+    // two of them.
+    if a.IsDefault {
+        if _, err := tx.ExecContext(
+            ctx,
+            "UPDATE addresses SET is_default = false WHERE account_id = $1 AND id <> $2 AND is_default",
+            a.AccountID, a.ID,
+        ); err != nil {
+            log.Println("failed to clear default address from account repository: ", err)
+            return err
+        }
+    }
+    res, err := tx.ExecContext(
+        ctx,
+        `INSERT INTO addresses(`+addressColumns+`)
+        VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+        ON CONFLICT (id) DO UPDATE SET
+            name = EXCLUDED.name, company = EXCLUDED.company,
+            line1 = EXCLUDED.line1, line2 = EXCLUDED.line2, city = EXCLUDED.city,
+            region = EXCLUDED.region, postal_code = EXCLUDED.postal_code,
+            country = EXCLUDED.country, phone = EXCLUDED.phone, is_default = EXCLUDED.is_default
+        WHERE addresses.account_id = EXCLUDED.account_id`,
+        a.ID, a.AccountID, a.Name, a.Company, a.Line1, a.Line2,
+        a.City, a.Region, a.PostalCode, a.Country, a.Phone, a.IsDefault, a.CreatedAt,
+    )
+    if err != nil {
+        log.Println("failed to put address from account repository: ", err)
+        return err
+    }
+    if n, _ := res.RowsAffected(); n == 0 {
+        return ErrAddressNotFound
+    }
+    if _, err := tx.ExecContext(
+        ctx,
+        `UPDATE addresses SET is_default = true WHERE id = $1
+        AND NOT EXISTS (SELECT 1 FROM addresses WHERE account_id = $2 AND is_default)`,
+        a.ID, a.AccountID,
+    ); err != nil {
+        log.Println("failed to set default address from account repository: ", err)
+        return err
+    }
+    return tx.Commit()
+}
+
+func (r *postgresRepository) DeleteAddress(ctx context.Context, accountID, id string) error {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        log.Println("failed to begin transaction from account repository: ", err)
+        return err
+    }
+    defer tx.Rollback()
+
+    wasDefault := false
+    err = tx.QueryRowContext(
+        ctx,
+        "DELETE FROM addresses WHERE account_id = $1 AND id = $2 RETURNING is_default",
+        accountID, id,
+    ).Scan(&wasDefault)
+    if err == sql.ErrNoRows {
+        return ErrAddressNotFound
+    }
+    if err != nil {
+        log.Println("failed to delete address from account repository: ", err)
+        return err
+    }
+    if wasDefault {
+        if _, err := tx.ExecContext(
+            ctx,
+            `UPDATE addresses SET is_default = true
+            WHERE id = (SELECT id FROM addresses WHERE account_id = $1 ORDER BY created_at, id LIMIT 1)`,
+            accountID,
+        ); err != nil {
+            log.Println("failed to set default address from account repository: ", err)
+            return err
+        }
+    }
+    return tx.Commit()
+}
+
+func (r *postgresRepository) GetAddress(ctx context.Context, accountID, id string) (*Address, error) {
+    a, err := scanAddress(r.db.QueryRowContext(
+        ctx,
+        "SELECT "+addressColumns+" FROM addresses WHERE account_id = $1 AND id = $2",
+        accountID, id,
+    ))
+    if err == sql.ErrNoRows {
+        return nil, ErrAddressNotFound
+    }
+    if err != nil {
+        log.Println("failed to get address from account repository: ", err)
+        return nil, err
+    }
+    return a, nil
+}
+
+func (r *postgresRepository) GetAddresses(ctx context.Context, accountID string) ([]Address, error) {
+    rows, err := r.db.QueryContext(
+        ctx,
+        "SELECT "+addressColumns+" FROM addresses WHERE account_id = $1 ORDER BY created_at, id",
+        accountID,
+    )
+    if err != nil {
+        log.Println("failed to get addresses from account repository: ", err)
+        return nil, err
+    }
+    defer rows.Close()
+
+    addresses := []Address{}
+    for rows.Next() {
+        a, err := scanAddress(rows)
+        if err != nil {
+            log.Println("failed to scan address from account repository: ", err)
+            return nil, err
+        }
+        addresses = append(addresses, *a)
+    }
+    return addresses, rows.Err()
+}
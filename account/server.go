@@ -25,13 +25,19 @@ func ListenGRPC(s Service, port int) error {
         log.Println("failed to create grpc server from account server: ", err)
         return err
     }
+    return NewGRPCServer(s).Serve(lis)
+}
+
+// NewGRPCServer returns a grpc server with the account service registered,
+// ready to Serve on any listener.
+func NewGRPCServer(s Service) *grpc.Server {
     serv := grpc.NewServer()
     pb.RegisterAccountServiceServer(serv, &grpcServer{
         UnimplementedAccountServiceServer: pb.UnimplementedAccountServiceServer{},
         service: s,
     })
     reflection.Register(serv)
-    return serv.Serve(lis)
+    return serv
 }
 
 func (s *grpcServer) PostAccount(
@@ -89,3 +95,120 @@ func (s *grpcServer) GetAccounts(
         Accounts: accounts,
     }, nil
 }
+
+func (s *grpcServer) SearchAccounts(
+    ctx context.Context, r *pb.SearchAccountsRequest,
+) (*pb.SearchAccountsResponse, error) {
+    res, err := s.service.SearchAccounts(ctx, r.Query, r.Take)
+    if err != nil {
+        log.Println("failed to search accounts from account server: ", err)
+        return nil, err
+    }
+
+    accounts := []*pb.Account{}
+    for _, a := range res {
+        accounts = append(accounts, &pb.Account{
+            Id: a.ID,
+            Name: a.Name,
+        })
+    }
+    return &pb.SearchAccountsResponse{Accounts: accounts}, nil
+}
+
+func (s *grpcServer) PostAddress(
+    ctx context.Context, r *pb.PostAddressRequest,
+) (*pb.AddressResponse, error) {
+    a, err := s.service.PostAddress(ctx, r.AccountId, addressFromProto(r.Address))
+    if err != nil {
+        log.Println("failed to post address from account server: ", err)
+        return nil, err
+    }
+    return &pb.AddressResponse{Address: addressToProto(*a)}, nil
+}
+
+func (s *grpcServer) UpdateAddress(
+    ctx context.Context, r *pb.UpdateAddressRequest,
+) (*pb.AddressResponse, error) {
+    a, err := s.service.UpdateAddress(ctx, r.AccountId, addressFromProto(r.Address))
+    if err != nil {
+        log.Println("failed to update address from account server: ", err)
+        return nil, err
+    }
+    return &pb.AddressResponse{Address: addressToProto(*a)}, nil
+}
+
+func (s *grpcServer) DeleteAddress(
+    ctx context.Context, r *pb.DeleteAddressRequest,
+) (*pb.DeleteAddressResponse, error) {
+    if err := s.service.DeleteAddress(ctx, r.AccountId, r.Id); err != nil {
+        log.Println("failed to delete address from account server: ", err)
+        return nil, err
+    }
+    return &pb.DeleteAddressResponse{}, nil
+}
+
+func (s *grpcServer) GetAddress(
+    ctx context.Context, r *pb.GetAddressRequest,
+) (*pb.AddressResponse, error) {
+    a, err := s.service.GetAddress(ctx, r.AccountId, r.Id)
+    if err != nil {
+        log.Println("failed to get address from account server: ", err)
+        return nil, err
+    }
+    return &pb.AddressResponse{Address: addressToProto(*a)}, nil
+}
+
+func (s *grpcServer) GetAddresses(
+    ctx context.Context, r *pb.GetAddressesRequest,
+) (*pb.GetAddressesResponse, error) {
+    res, err := s.service.GetAddresses(ctx, r.AccountId)
+    if err != nil {
+        log.Println("failed to get addresses from account server: ", err)
+        return nil, err
+    }
+
+    addresses := []*pb.Address{}
+    for _, a := range res {
+        addresses = append(addresses, addressToProto(a))
+    }
+    return &pb.GetAddressesResponse{Addresses: addresses}, nil
+}
+
+func addressToProto(a Address) *pb.Address {
+    return &pb.Address{
+        Id: a.ID,
+        AccountId: a.AccountID,
+        Name: a.Name,
+        Company: a.Company,
+        Line1: a.Line1,
+        Line2: a.Line2,
+        City: a.City,
+        Region: a.Region,
+        PostalCode: a.PostalCode,
+        Country: a.Country,
+        Phone: a.Phone,
+        IsDefault: a.IsDefault,
+    }
+}
+
+// addressFromProto returns the zero Address for a nil a, which fails
+// validation.
+func addressFromProto(a *pb.Address) Address {
+    if a == nil {
+        return Address{}
+    }
+    return Address{
+        ID: a.Id,
+        AccountID: a.AccountId,
+        Name: a.Name,
+        Company: a.Company,
+        Line1: a.Line1,
+        Line2: a.Line2,
+        City: a.City,
+        Region: a.Region,
+        PostalCode: a.PostalCode,
+        Country: a.Country,
+        Phone: a.Phone,
+        IsDefault: a.IsDefault,
+    }
+}
@@ -3,6 +3,7 @@ package account
 import (
 	"context"
 	"log"
+	"strings"
 
 	"github.com/segmentio/ksuid"
 )
@@ -11,6 +12,12 @@ type Service interface {
     PostAccount(ctx context.Context, name string) (*Account, error)
     GetAccount(ctx context.Context, id string) (*Account, error)
     GetAccounts(ctx context.Context, skip uint64, take uint64) ([]Account, error)
+    SearchAccounts(ctx context.Context, query string, take uint64) ([]Account, error)
+    PostAddress(ctx context.Context, accountID string, a Address) (*Address, error)
+    UpdateAddress(ctx context.Context, accountID string, a Address) (*Address, error)
+    DeleteAddress(ctx context.Context, accountID, id string) error
+    GetAddress(ctx context.Context, accountID, id string) (*Address, error)
+    GetAddresses(ctx context.Context, accountID string) ([]Address, error)
 }
 
 type Account struct {
@@ -54,3 +61,21 @@ func (s *accountService) GetAccounts(
 
     return s.repository.ListAccounts(ctx, skip, take)
 }
+
+// SearchAccounts returns no accounts for a blank query rather than all of
+// them.
+func (s *accountService) SearchAccounts(
+    ctx context.Context, query string, take uint64,
+) ([]Account, error) {
+    query = strings.TrimSpace(query)
+    if query == "" {
+        return []Account{}, nil
+    }
+    if take == 0 {
+        take = 100
+    }
+    if take > 1000 {
+        take = 1000
+    }
+    return s.repository.SearchAccounts(ctx, query, take)
+}
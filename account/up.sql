@@ -2,3 +2,31 @@ CREATE TABLE IF NOT EXISTS accounts (
     id CHAR(27) PRIMARY KEY,
     name VARCHAR(24) NOT NULL
 );
+
+-- Account search matches substrings of names, which only a trigram index can serve.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS accounts_name_trgm_idx ON accounts USING gin (name gin_trgm_ops);
+
+CREATE TABLE IF NOT EXISTS addresses (
+    id CHAR(27) PRIMARY KEY,
+    account_id CHAR(27) NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    company TEXT NOT NULL DEFAULT '',
+    line1 TEXT NOT NULL,
+    line2 TEXT NOT NULL DEFAULT '',
+    city TEXT NOT NULL,
+    region TEXT NOT NULL DEFAULT '',
+    postal_code TEXT NOT NULL DEFAULT '',
+    country CHAR(2) NOT NULL,
+    phone TEXT NOT NULL DEFAULT '',
+    is_default BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- Addresses stored before created_at share the migration time; their ids
+-- keep them in the order they were added within it.
+ALTER TABLE addresses ADD COLUMN IF NOT EXISTS created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS addresses_account_id_idx ON addresses (account_id);
+-- An account has at most one default address.
+CREATE UNIQUE INDEX IF NOT EXISTS addresses_default_idx ON addresses (account_id) WHERE is_default;
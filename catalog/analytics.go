@@ -0,0 +1,170 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+var ErrInvalidFeedback = errors.New("search feedback needs a search id, a product id and a known kind")
+
+const (
+    defaultReportDays  = 7
+    maxReportDays      = 90
+    defaultReportLimit = 10
+    maxReportLimit     = 100
+)
+
+type FeedbackKind string
+
+const (
+    FeedbackClick     FeedbackKind = "click"
+    FeedbackAddToCart FeedbackKind = "add_to_cart"
+)
+
+// SearchEvent records one SearchProducts call with the request as the
+// caller sent it, before stopwords and subcategories are applied.
+type SearchEvent struct {
+    ID string
+    // Query is lowercased with its whitespace collapsed, so reports group
+    // "TV" and " tv" together.
+    Query       string
+    MinPrice    *float64
+    MaxPrice    *float64
+    Tags        []string
+    CategoryIDs []string
+    Sort        SearchSort
+    Results     uint64
+    Latency     time.Duration
+    CreatedAt   time.Time
+}
+
+// SearchFeedback links a product a customer acted on to the search that
+// listed it.
+type SearchFeedback struct {
+    SearchID  string
+    ProductID string
+    Kind      FeedbackKind
+    CreatedAt time.Time
+}
+
+type QueryCount struct {
+    Query    string
+    Searches uint64
+}
+
+// ClickThrough counts the searches of a period and how many of them led
+// to feedback. Feedback is counted by when it was given, so a click on a
+// search from just before the period still counts.
+type ClickThrough struct {
+    Searches            uint64
+    ClickedSearches     uint64
+    AddedToCartSearches uint64
+    // Rate is ClickedSearches over Searches, 0 without searches.
+    Rate float64
+}
+
+// recordSearch stores the event for req. Analytics must not break
+// searching, so failures are only logged.
+func (s *catalogService) recordSearch(
+    ctx context.Context, id string, req SearchRequest, res *SearchResult, latency time.Duration,
+) {
+    event := SearchEvent{
+        ID: id,
+        Query: normalizeQuery(req.Query),
+        MinPrice: req.MinPrice,
+        MaxPrice: req.MaxPrice,
+        Tags: req.Tags,
+        CategoryIDs: req.CategoryIDs,
+        Sort: req.Sort,
+        Results: res.Total,
+        Latency: latency,
+        CreatedAt: time.Now().UTC(),
+    }
+    if err := s.repository.PutSearchEvent(ctx, event); err != nil {
+        log.Println("failed to record search event from catalog service: ", err)
+    }
+}
+
+func (s *catalogService) RecordSearchFeedback(
+    ctx context.Context, searchID, productID string, kind FeedbackKind,
+) error {
+    if searchID == "" || productID == "" || (kind != FeedbackClick && kind != FeedbackAddToCart) {
+        return ErrInvalidFeedback
+    }
+
+    feedback := SearchFeedback{
+        SearchID: searchID,
+        ProductID: productID,
+        Kind: kind,
+        CreatedAt: time.Now().UTC(),
+    }
+    if err := s.repository.PutSearchFeedback(ctx, feedback); err != nil {
+        log.Println("failed to record search feedback from catalog service: ", err)
+        return err
+    }
+    return nil
+}
+
+// GetTopSearchQueries returns the most frequent non-empty queries of the
+// last days.
+func (s *catalogService) GetTopSearchQueries(
+    ctx context.Context, days, limit int,
+) ([]QueryCount, error) {
+    queries, err := s.repository.TopSearchQueries(ctx, reportSince(days), reportLimit(limit), false)
+    if err != nil {
+        log.Println("failed to get top search queries from catalog service: ", err)
+        return nil, err
+    }
+    return queries, nil
+}
+
+// GetZeroResultQueries returns the most frequent queries of the last days
+// that found nothing.
+func (s *catalogService) GetZeroResultQueries(
+    ctx context.Context, days, limit int,
+) ([]QueryCount, error) {
+    queries, err := s.repository.TopSearchQueries(ctx, reportSince(days), reportLimit(limit), true)
+    if err != nil {
+        log.Println("failed to get zero result queries from catalog service: ", err)
+        return nil, err
+    }
+    return queries, nil
+}
+
+func (s *catalogService) GetSearchClickThrough(
+    ctx context.Context, days int,
+) (*ClickThrough, error) {
+    ct, err := s.repository.SearchClickThrough(ctx, reportSince(days))
+    if err != nil {
+        log.Println("failed to get search click through from catalog service: ", err)
+        return nil, err
+    }
+    if ct.Searches != 0 {
+        ct.Rate = min(float64(ct.ClickedSearches)/float64(ct.Searches), 1)
+    }
+    return ct, nil
+}
+
+// reportSince is the start of a report covering the last days, defaulting
+// to defaultReportDays and capped at maxReportDays.
+func reportSince(days int) time.Time {
+    if days <= 0 {
+        days = defaultReportDays
+    }
+    days = min(days, maxReportDays)
+    return time.Now().UTC().AddDate(0, 0, -days)
+}
+
+func reportLimit(limit int) int {
+    if limit <= 0 {
+        return defaultReportLimit
+    }
+    return min(limit, maxReportLimit)
+}
+
+func normalizeQuery(query string) string {
+    return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
@@ -0,0 +1,138 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSearchAnalyticsMemory(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+
+    tv, err := s.PostProduct(ctx, "TV", "55 inch", 499, nil, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    searchIDs := []string{}
+    for _, query := range []string{"TV", " tv ", "radio", "radio", "tv", ""} {
+        res, err := s.SearchProducts(ctx, SearchRequest{Query: query})
+        if err != nil {
+            t.Fatal(err)
+        }
+        if res.SearchID == "" {
+            t.Fatalf("expected search %q to be recorded", query)
+        }
+        searchIDs = append(searchIDs, res.SearchID)
+    }
+
+    for _, kind := range []FeedbackKind{FeedbackClick, FeedbackClick, FeedbackAddToCart} {
+        if err := s.RecordSearchFeedback(ctx, searchIDs[0], tv.ID, kind); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := s.RecordSearchFeedback(ctx, searchIDs[1], tv.ID, FeedbackClick); err != nil {
+        t.Fatal(err)
+    }
+    if err := s.RecordSearchFeedback(ctx, searchIDs[1], tv.ID, "like"); !errors.Is(err, ErrInvalidFeedback) {
+        t.Fatalf("expected ErrInvalidFeedback, got %v", err)
+    }
+
+    top, err := s.GetTopSearchQueries(ctx, 0, 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := []QueryCount{{Query: "tv", Searches: 3}, {Query: "radio", Searches: 2}}
+    if !reflect.DeepEqual(top, want) {
+        t.Fatalf("got top queries %+v, want %+v", top, want)
+    }
+    if top, err = s.GetTopSearchQueries(ctx, 0, 1); err != nil || len(top) != 1 {
+        t.Fatalf("expected the limit to apply, got %+v, %v", top, err)
+    }
+
+    zero, err := s.GetZeroResultQueries(ctx, 0, 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(zero, []QueryCount{{Query: "radio", Searches: 2}}) {
+        t.Fatalf("unexpected zero result queries %+v", zero)
+    }
+
+    ct, err := s.GetSearchClickThrough(ctx, 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+    wantCT := ClickThrough{Searches: 6, ClickedSearches: 2, AddedToCartSearches: 1, Rate: 2.0 / 6}
+    if *ct != wantCT {
+        t.Fatalf("got %+v, want %+v", *ct, wantCT)
+    }
+}
+
+func TestReportSince(t *testing.T) {
+    for _, tt := range []struct{ days, want int }{{0, defaultReportDays}, {3, 3}, {365, maxReportDays}} {
+        since := reportSince(tt.days)
+        if got := int(time.Since(since).Hours()+1) / 24; got != tt.want {
+            t.Errorf("reportSince(%d) is %d days ago, want %d", tt.days, got, tt.want)
+        }
+    }
+}
+
+func TestSearchAnalyticsElastic(t *testing.T) {
+    ctx := context.Background()
+    f := newFakeElastic(t)
+    r := newTestElasticRepository(t, f)
+
+    event := SearchEvent{ID: "s1", Query: "tv", Results: 0, Latency: 1500 * time.Microsecond, CreatedAt: time.Now()}
+    if err := r.PutSearchEvent(ctx, event); err != nil {
+        t.Fatal(err)
+    }
+    if body := f.lastBody(http.MethodPut, "/catalog_search_events/_doc/s1"); body["latency_ms"] != 1.5 {
+        t.Fatalf("unexpected event document %v", body)
+    }
+    feedback := SearchFeedback{SearchID: "s1", ProductID: "p1", Kind: FeedbackClick, CreatedAt: time.Now()}
+    if err := r.PutSearchFeedback(ctx, feedback); err != nil {
+        t.Fatal(err)
+    }
+    if ids := f.documentIDs(searchFeedbackIndex); len(ids) != 1 {
+        t.Fatalf("expected one feedback document, got %v", ids)
+    }
+
+    f.cannedHits = []fakeHit{}
+    f.cannedAggregations = map[string]any{
+        "sterms#queries": map[string]any{"buckets": []any{
+            map[string]any{"key": "tv", "doc_count": 4},
+        }},
+    }
+    queries, err := r.TopSearchQueries(ctx, time.Now().Add(-time.Hour), 5, true)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(queries, []QueryCount{{Query: "tv", Searches: 4}}) {
+        t.Fatalf("unexpected queries %+v", queries)
+    }
+    body := f.lastBody(http.MethodPost, "/catalog_search_events/_search")
+    filter := body["query"].(map[string]any)["bool"].(map[string]any)["filter"].([]any)
+    if len(filter) != 2 {
+        t.Fatalf("expected a date and a zero results filter, got %v", filter)
+    }
+    terms := body["aggregations"].(map[string]any)["queries"].(map[string]any)["terms"].(map[string]any)
+    if terms["field"] != "query" || terms["size"] != 5.0 {
+        t.Fatalf("unexpected terms aggregation %v", terms)
+    }
+
+    f.cannedAggregations = map[string]any{
+        "filter#clicks": map[string]any{"doc_count": 3, "cardinality#searches": map[string]any{"value": 1}},
+        "filter#add_to_cart": map[string]any{"doc_count": 0, "cardinality#searches": map[string]any{"value": 0}},
+    }
+    ct, err := r.SearchClickThrough(ctx, time.Now().Add(-time.Hour))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if *ct != (ClickThrough{Searches: 1, ClickedSearches: 1}) {
+        t.Fatalf("unexpected click through %+v", *ct)
+    }
+}
@@ -0,0 +1,388 @@
+package catalog
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/segmentio/ksuid"
+)
+
+const (
+    FormatCSV   = "csv"
+    FormatJSONL = "jsonl"
+)
+
+// defaultImportBatch is the number of rows written per bulk request.
+const defaultImportBatch = 500
+
+// csvColumns is the header of exported CSV files. Imports accept the
+// columns in any order and ignore id; list columns are "|" separated.
+var csvColumns = []string{"id", "sku", "name", "description", "price", "tags", "category_ids"}
+
+// productRow is one product in an import or export file. Variants are not
+// part of the row format and are left untouched by an import.
+type productRow struct {
+    ID          string   `json:"id,omitempty"`
+    SKU         string   `json:"sku"`
+    Name        string   `json:"name"`
+    Description string   `json:"description"`
+    Price       *float64 `json:"price"`
+    Tags        []string `json:"tags,omitempty"`
+    CategoryIDs []string `json:"category_ids,omitempty"`
+}
+
+type ImportOptions struct {
+    Format    string
+    // DryRun validates every row and resolves creates and updates without
+    // writing anything.
+    DryRun    bool
+    BatchSize int
+}
+
+type ImportReport struct {
+    Rows    int
+    Created int
+    Updated int
+    Errors  []RowError
+}
+
+// RowError is a problem with a single row; Row is the line number in the
+// input, counting the CSV header.
+type RowError struct {
+    Row int
+    SKU string
+    Err error
+}
+
+func (e RowError) Error() string {
+    if e.SKU == "" {
+        return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+    }
+    return fmt.Sprintf("row %d (sku %s): %v", e.Row, e.SKU, e.Err)
+}
+
+var (
+    errMissingSKU   = errors.New("sku is required")
+    errMissingName  = errors.New("name is required")
+    errInvalidPrice = errors.New("price must be a non-negative number")
+    errRepeatedSKU  = errors.New("sku appears more than once in the input")
+)
+
+type numberedRow struct {
+    line int
+    row  productRow
+}
+
+// ImportProducts reads products from in and upserts them by external SKU:
+// rows whose sku matches an existing product update it in place, others
+// create a new product. Rows are written in batches through
+// Repository.PutProducts. Invalid rows are reported and skipped; the
+// returned error is only set when the import could not continue.
+func ImportProducts(
+    ctx context.Context, r Repository, in io.Reader, opts ImportOptions,
+) (*ImportReport, error) {
+    if opts.BatchSize <= 0 {
+        opts.BatchSize = defaultImportBatch
+    }
+
+    categories, err := r.ListCategories(ctx)
+    if err != nil {
+        log.Println("failed to list categories for import: ", err)
+        return nil, err
+    }
+    knownCategories := map[string]bool{}
+    for _, c := range categories {
+        knownCategories[c.ID] = true
+    }
+
+    report := &ImportReport{Errors: []RowError{}}
+    seen := map[string]bool{}
+    batch := []numberedRow{}
+
+    err = readRows(in, opts.Format, func(line int, row productRow, err error) error {
+        report.Rows++
+        if err == nil {
+            err = validateRow(row, knownCategories)
+        }
+        if err == nil && seen[row.SKU] {
+            err = errRepeatedSKU
+        }
+        if err != nil {
+            report.Errors = append(report.Errors, RowError{Row: line, SKU: row.SKU, Err: err})
+            return nil
+        }
+        seen[row.SKU] = true
+
+        batch = append(batch, numberedRow{line: line, row: row})
+        if len(batch) < opts.BatchSize {
+            return nil
+        }
+        err = importBatch(ctx, r, batch, opts.DryRun, report)
+        batch = batch[:0]
+        return err
+    })
+    if err == nil {
+        err = importBatch(ctx, r, batch, opts.DryRun, report)
+    }
+    return report, err
+}
+
+func validateRow(row productRow, knownCategories map[string]bool) error {
+    if row.SKU == "" {
+        return errMissingSKU
+    }
+    if strings.TrimSpace(row.Name) == "" {
+        return errMissingName
+    }
+    if row.Price == nil || *row.Price < 0 {
+        return errInvalidPrice
+    }
+    for _, id := range row.CategoryIDs {
+        if !knownCategories[id] {
+            return fmt.Errorf("%w: %s", ErrInvalidCategory, id)
+        }
+    }
+    return nil
+}
+
+func importBatch(
+    ctx context.Context, r Repository, batch []numberedRow, dryRun bool, report *ImportReport,
+) error {
+    if len(batch) == 0 {
+        return nil
+    }
+
+    skus := []string{}
+    for _, b := range batch {
+        skus = append(skus, b.row.SKU)
+    }
+    existing, err := r.GetProductsByExternalSKUs(ctx, skus)
+    if err != nil {
+        log.Println("failed to look up existing products for import: ", err)
+        return err
+    }
+    bySKU := map[string]Product{}
+    for _, p := range existing {
+        bySKU[p.ExternalSKU] = p
+    }
+
+    products := []Product{}
+    updates := []bool{}
+    for _, b := range batch {
+        p, ok := bySKU[b.row.SKU]
+        if !ok {
+            p = Product{ID: ksuid.New().String(), ExternalSKU: b.row.SKU}
+        }
+        p.Name = strings.TrimSpace(b.row.Name)
+        p.Description = b.row.Description
+        p.Price = *b.row.Price
+        p.Tags = b.row.Tags
+        p.CategoryIDs = b.row.CategoryIDs
+        products = append(products, p)
+        updates = append(updates, ok)
+    }
+
+    errs := make([]error, len(products))
+    if !dryRun {
+        if errs, err = r.PutProducts(ctx, products); err != nil {
+            log.Println("failed to write import batch: ", err)
+            return err
+        }
+    }
+
+    for i, err := range errs {
+        switch {
+        case err != nil:
+            report.Errors = append(report.Errors, RowError{
+                Row: batch[i].line,
+                SKU: batch[i].row.SKU,
+                Err: err,
+            })
+        case updates[i]:
+            report.Updated++
+        default:
+            report.Created++
+        }
+    }
+    return nil
+}
+
+// readRows calls fn for every row of in. A row that cannot be parsed is
+// passed with a non-nil error; fn returning an error stops reading.
+func readRows(
+    in io.Reader, format string, fn func(line int, row productRow, err error) error,
+) error {
+    switch format {
+    case FormatCSV:
+        return readCSV(in, fn)
+    case FormatJSONL:
+        return readJSONL(in, fn)
+    default:
+        return fmt.Errorf("unknown import format %q", format)
+    }
+}
+
+func readCSV(in io.Reader, fn func(int, productRow, error) error) error {
+    reader := csv.NewReader(in)
+    reader.TrimLeadingSpace = true
+
+    header, err := reader.Read()
+    if err == io.EOF {
+        return nil
+    }
+    if err != nil {
+        return fmt.Errorf("failed to read csv header: %w", err)
+    }
+    columns := map[string]int{}
+    for i, name := range header {
+        columns[strings.ToLower(strings.TrimSpace(name))] = i
+    }
+    for _, required := range []string{"sku", "name", "price"} {
+        if _, ok := columns[required]; !ok {
+            return fmt.Errorf("csv header is missing the %s column", required)
+        }
+    }
+
+    for {
+        record, err := reader.Read()
+        if err == io.EOF {
+            return nil
+        }
+        line := 0
+        var parseErr *csv.ParseError
+        if errors.As(err, &parseErr) {
+            line = parseErr.Line
+        } else if len(record) > 0 {
+            line, _ = reader.FieldPos(0)
+        }
+
+        row := productRow{}
+        if err == nil {
+            row, err = csvRow(record, columns)
+        }
+        if err := fn(line, row, err); err != nil {
+            return err
+        }
+    }
+}
+
+func csvRow(record []string, columns map[string]int) (productRow, error) {
+    field := func(name string) string {
+        if i, ok := columns[name]; ok && i < len(record) {
+            return strings.TrimSpace(record[i])
+        }
+        return ""
+    }
+    list := func(name string) []string {
+        values := []string{}
+        for _, v := range strings.Split(field(name), "|") {
+            if v = strings.TrimSpace(v); v != "" {
+                values = append(values, v)
+            }
+        }
+        return values
+    }
+
+    row := productRow{
+        SKU: field("sku"),
+        Name: field("name"),
+        Description: field("description"),
+        Tags: list("tags"),
+        CategoryIDs: list("category_ids"),
+    }
+    if price := field("price"); price != "" {
+        p, err := strconv.ParseFloat(price, 64)
+        if err != nil {
+            return row, errInvalidPrice
+        }
+        row.Price = &p
+    }
+    return row, nil
+}
+
+func readJSONL(in io.Reader, fn func(int, productRow, error) error) error {
+    scanner := bufio.NewScanner(in)
+    scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+    line := 0
+    for scanner.Scan() {
+        line++
+        text := strings.TrimSpace(scanner.Text())
+        if text == "" {
+            continue
+        }
+
+        row := productRow{}
+        err := json.Unmarshal([]byte(text), &row)
+        row.SKU = strings.TrimSpace(row.SKU)
+        if err := fn(line, row, err); err != nil {
+            return err
+        }
+    }
+    return scanner.Err()
+}
+
+// ExportProducts streams every product to out in the import format and
+// returns how many were written.
+func ExportProducts(
+    ctx context.Context, r Repository, out io.Writer, format string,
+) (int, error) {
+    var write func(productRow) error
+    var flush func() error
+    switch format {
+    case FormatCSV:
+        writer := csv.NewWriter(out)
+        if err := writer.Write(csvColumns); err != nil {
+            return 0, err
+        }
+        write = func(row productRow) error {
+            return writer.Write([]string{
+                row.ID,
+                row.SKU,
+                row.Name,
+                row.Description,
+                strconv.FormatFloat(*row.Price, 'f', -1, 64),
+                strings.Join(row.Tags, "|"),
+                strings.Join(row.CategoryIDs, "|"),
+            })
+        }
+        flush = func() error {
+            writer.Flush()
+            return writer.Error()
+        }
+    case FormatJSONL:
+        encoder := json.NewEncoder(out)
+        write = func(row productRow) error { return encoder.Encode(row) }
+        flush = func() error { return nil }
+    default:
+        return 0, fmt.Errorf("unknown export format %q", format)
+    }
+
+    count := 0
+    err := r.ScanProducts(ctx, func(p Product) error {
+        price := p.Price
+        count++
+        return write(productRow{
+            ID: p.ID,
+            SKU: p.ExternalSKU,
+            Name: p.Name,
+            Description: p.Description,
+            Price: &price,
+            Tags: p.Tags,
+            CategoryIDs: p.CategoryIDs,
+        })
+    })
+    if err != nil {
+        log.Println("failed to export products: ", err)
+        return count, err
+    }
+    return count, flush()
+}
@@ -0,0 +1,183 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImportProductsCSV(t *testing.T) {
+    ctx := context.Background()
+    r := NewMemoryRepository()
+    s := NewService(r)
+    category, err := s.PostCategory(ctx, "Audio", "", "")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    in := strings.NewReader("sku,name,price,tags,category_ids\n" +
+        "HP-1,Studio Headphones,99.5,audio|wired," + category.ID + "\n" +
+        "HP-2,,10,,\n" +
+        "HP-3,Earbuds,cheap,,\n" +
+        "HP-4,Speaker,20,,missing\n" +
+        "HP-1,Duplicate,1,,\n" +
+        "HP-5,Cable,4,,\n")
+    report, err := ImportProducts(ctx, r, in, ImportOptions{Format: FormatCSV, BatchSize: 1})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if report.Rows != 6 || report.Created != 2 || report.Updated != 0 {
+        t.Fatalf("unexpected report %+v", report)
+    }
+
+    wantErrs := map[int]error{3: errMissingName, 4: errInvalidPrice, 5: ErrInvalidCategory, 6: errRepeatedSKU}
+    if len(report.Errors) != len(wantErrs) {
+        t.Fatalf("unexpected row errors %v", report.Errors)
+    }
+    for _, rowErr := range report.Errors {
+        if !errors.Is(rowErr.Err, wantErrs[rowErr.Row]) {
+            t.Fatalf("row %d: got %v, want %v", rowErr.Row, rowErr.Err, wantErrs[rowErr.Row])
+        }
+    }
+
+    products, err := r.GetProductsByExternalSKUs(ctx, []string{"HP-1"})
+    if err != nil || len(products) != 1 {
+        t.Fatalf("expected HP-1 to be imported, got %+v, %v", products, err)
+    }
+    p := products[0]
+    if p.Name != "Studio Headphones" || p.Price != 99.5 ||
+        !reflect.DeepEqual(p.Tags, []string{"audio", "wired"}) ||
+        !reflect.DeepEqual(p.CategoryIDs, []string{category.ID}) {
+        t.Fatalf("unexpected product %+v", p)
+    }
+}
+
+func TestImportProductsUpdatesBySKU(t *testing.T) {
+    ctx := context.Background()
+    r := NewMemoryRepository()
+
+    first := `{"sku":"MUG","name":"Mug","price":5}` + "\n"
+    if _, err := ImportProducts(ctx, r, strings.NewReader(first), ImportOptions{Format: FormatJSONL}); err != nil {
+        t.Fatal(err)
+    }
+    before, _ := r.GetProductsByExternalSKUs(ctx, []string{"MUG"})
+
+    second := `{"sku":"MUG","name":"Coffee Mug","price":6}` + "\n\n" +
+        `{"sku":"CUP","name":"Cup","price":3}` + "\n" +
+        `{"sku":` + "\n"
+    report, err := ImportProducts(ctx, r, strings.NewReader(second), ImportOptions{Format: FormatJSONL})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if report.Created != 1 || report.Updated != 1 || len(report.Errors) != 1 || report.Errors[0].Row != 4 {
+        t.Fatalf("unexpected report %+v", report)
+    }
+
+    after, _ := r.GetProductsByExternalSKUs(ctx, []string{"MUG"})
+    if len(after) != 1 || after[0].ID != before[0].ID || after[0].Name != "Coffee Mug" || after[0].Price != 6 {
+        t.Fatalf("expected MUG to be updated in place, got %+v", after)
+    }
+}
+
+func TestImportProductsDryRun(t *testing.T) {
+    ctx := context.Background()
+    r := NewMemoryRepository()
+
+    in := strings.NewReader(`{"sku":"MUG","name":"Mug","price":5}` + "\n")
+    report, err := ImportProducts(ctx, r, in, ImportOptions{Format: FormatJSONL, DryRun: true})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if report.Created != 1 {
+        t.Fatalf("unexpected report %+v", report)
+    }
+    products, _ := r.ListProducts(ctx, 0, 10)
+    if len(products) != 0 {
+        t.Fatalf("dry run wrote products %+v", products)
+    }
+}
+
+func TestExportProductsRoundTrip(t *testing.T) {
+    ctx := context.Background()
+    source := NewMemoryRepository()
+
+    in := "sku,name,description,price,tags\n" +
+        "A-1,\"Lamp, brass\",Warm light,30,home|light\n" +
+        "A-2,Rug,,120.25,\n"
+    if _, err := ImportProducts(ctx, source, strings.NewReader(in), ImportOptions{Format: FormatCSV}); err != nil {
+        t.Fatal(err)
+    }
+
+    for _, format := range []string{FormatCSV, FormatJSONL} {
+        out := &bytes.Buffer{}
+        count, err := ExportProducts(ctx, source, out, format)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if count != 2 {
+            t.Fatalf("%s: exported %d products", format, count)
+        }
+
+        dest := NewMemoryRepository()
+        report, err := ImportProducts(ctx, dest, out, ImportOptions{Format: format})
+        if err != nil {
+            t.Fatal(err)
+        }
+        if report.Created != 2 || len(report.Errors) != 0 {
+            t.Fatalf("%s: unexpected report %+v", format, report)
+        }
+        got, _ := dest.GetProductsByExternalSKUs(ctx, []string{"A-1"})
+        if len(got) != 1 || got[0].Name != "Lamp, brass" || got[0].Description != "Warm light" ||
+            !reflect.DeepEqual(got[0].Tags, []string{"home", "light"}) {
+            t.Fatalf("%s: unexpected round trip %+v", format, got)
+        }
+    }
+}
+
+func TestElasticPutAndScanProducts(t *testing.T) {
+    ctx := context.Background()
+    f := newFakeElastic(t)
+    r := newTestElasticRepository(t, f)
+    f.rejectIDs = map[string]bool{"p2": true}
+
+    products := []Product{
+        {ID: "p1", Name: "Lamp", Price: 30, ExternalSKU: "A-1"},
+        {ID: "p2", Name: "Rug", Price: 120, ExternalSKU: "A-2"},
+        {ID: "p3", Name: "Chair", Price: 80, ExternalSKU: "A-3"},
+    }
+    errs, err := r.PutProducts(ctx, products)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if errs[0] != nil || errs[1] == nil || errs[2] != nil {
+        t.Fatalf("unexpected item errors %v", errs)
+    }
+    if !reflect.DeepEqual(f.documentIDs(catalogAlias), []string{"p1", "p3"}) {
+        t.Fatalf("unexpected documents %v", f.documentIDs(catalogAlias))
+    }
+
+    found, err := r.GetProductsByExternalSKUs(ctx, []string{"A-3", "A-9"})
+    if err != nil || len(found) != 1 || found[0].ID != "p3" {
+        t.Fatalf("unexpected lookup %+v, %v", found, err)
+    }
+
+    defer func(size int) { scanPageSize = size }(scanPageSize)
+    scanPageSize = 1
+    f.putDocument(catalogAlias, "p4", productDocument{Name: "Desk", Price: 200})
+    scanned := []string{}
+    if err := r.ScanProducts(ctx, func(p Product) error {
+        scanned = append(scanned, p.ID)
+        return nil
+    }); err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(scanned, []string{"p1", "p3", "p4"}) {
+        t.Fatalf("unexpected scan %v", scanned)
+    }
+    if f.openPointsInTime() != 0 {
+        t.Fatal("expected the point in time to be closed")
+    }
+}
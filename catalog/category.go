@@ -0,0 +1,254 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/segmentio/ksuid"
+)
+
+var (
+    ErrInvalidCategory     = errors.New("invalid category")
+    ErrDuplicateCategory   = errors.New("category path already exists")
+    ErrCategoryHasChildren = errors.New("category has child categories")
+)
+
+// Category is a node in the product taxonomy. Path joins the slugs from the
+// root down to the category, e.g. "electronics/audio/headphones", and is
+// unique across the tree.
+type Category struct {
+    ID       string `json:"id"`
+    Name     string `json:"name"`
+    Slug     string `json:"slug"`
+    ParentID string `json:"parent_id"`
+    Path     string `json:"path"`
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify lowercases s and collapses anything but letters and digits into
+// single dashes.
+func slugify(s string) string {
+    return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+func (s *catalogService) PostCategory(
+    ctx context.Context, name, slug, parentID string,
+) (*Category, error) {
+    categories, err := s.repository.ListCategories(ctx)
+    if err != nil {
+        log.Println("failed to list categories from catalog service: ", err)
+        return nil, err
+    }
+
+    c := Category{ID: ksuid.New().String()}
+    if err := placeCategory(&c, name, slug, parentID, categories); err != nil {
+        return nil, err
+    }
+
+    if err := s.repository.PutCategory(ctx, c); err != nil {
+        log.Println("failed to put category from catalog service: ", err)
+        return nil, err
+    }
+    return &c, nil
+}
+
+// UpdateCategory renames or moves a category. Paths of all descendants are
+// rewritten to follow it.
+func (s *catalogService) UpdateCategory(
+    ctx context.Context, id, name, slug, parentID string,
+) (*Category, error) {
+    categories, err := s.repository.ListCategories(ctx)
+    if err != nil {
+        log.Println("failed to list categories from catalog service: ", err)
+        return nil, err
+    }
+
+    i := slices.IndexFunc(categories, func(c Category) bool { return c.ID == id })
+    if i < 0 {
+        return nil, ErrNotFound
+    }
+    c := categories[i]
+    if parentID == id || slices.Contains(descendantIDs(categories, id), parentID) {
+        return nil, ErrInvalidCategory
+    }
+
+    others := slices.Delete(slices.Clone(categories), i, i+1)
+    oldPath := c.Path
+    if err := placeCategory(&c, name, slug, parentID, others); err != nil {
+        return nil, err
+    }
+    if err := s.repository.PutCategory(ctx, c); err != nil {
+        log.Println("failed to put category from catalog service: ", err)
+        return nil, err
+    }
+
+    if c.Path != oldPath {
+        for _, d := range categories {
+            if strings.HasPrefix(d.Path, oldPath+"/") {
+                d.Path = c.Path + strings.TrimPrefix(d.Path, oldPath)
+                if err := s.repository.PutCategory(ctx, d); err != nil {
+                    log.Println("failed to move child category from catalog service: ", err)
+                    return nil, err
+                }
+            }
+        }
+    }
+    return &c, nil
+}
+
+// DeleteCategory removes a leaf category. Products keep the id, which no
+// longer resolves to a category.
+func (s *catalogService) DeleteCategory(ctx context.Context, id string) error {
+    categories, err := s.repository.ListCategories(ctx)
+    if err != nil {
+        log.Println("failed to list categories from catalog service: ", err)
+        return err
+    }
+    if !slices.ContainsFunc(categories, func(c Category) bool { return c.ID == id }) {
+        return ErrNotFound
+    }
+    if slices.ContainsFunc(categories, func(c Category) bool { return c.ParentID == id }) {
+        return ErrCategoryHasChildren
+    }
+    return s.repository.DeleteCategory(ctx, id)
+}
+
+func (s *catalogService) GetCategory(ctx context.Context, id string) (*Category, error) {
+    return s.repository.GetCategoryByID(ctx, id)
+}
+
+func (s *catalogService) GetCategoryByPath(ctx context.Context, path string) (*Category, error) {
+    categories, err := s.repository.ListCategories(ctx)
+    if err != nil {
+        log.Println("failed to list categories from catalog service: ", err)
+        return nil, err
+    }
+    path = strings.Trim(path, "/")
+    for _, c := range categories {
+        if c.Path == path {
+            return &c, nil
+        }
+    }
+    return nil, ErrNotFound
+}
+
+// GetCategories returns categories ordered by path. With ids set only those
+// are returned; otherwise a non-nil parentID limits the result to its
+// children, "" meaning root categories.
+func (s *catalogService) GetCategories(
+    ctx context.Context, ids []string, parentID *string,
+) ([]Category, error) {
+    categories, err := s.repository.ListCategories(ctx)
+    if err != nil {
+        log.Println("failed to list categories from catalog service: ", err)
+        return nil, err
+    }
+
+    return slices.DeleteFunc(categories, func(c Category) bool {
+        if len(ids) != 0 {
+            return !slices.Contains(ids, c.ID)
+        }
+        return parentID != nil && c.ParentID != *parentID
+    }), nil
+}
+
+// SetProductTaxonomy replaces the categories and tags of a product,
+// leaving the rest of it as it is.
+func (s *catalogService) SetProductTaxonomy(
+    ctx context.Context, productID string, categoryIDs, tags []string,
+) (*Product, error) {
+    if err := s.checkCategories(ctx, categoryIDs); err != nil {
+        return nil, err
+    }
+
+    if err := s.repository.SetProductTaxonomy(ctx, productID, categoryIDs, tags); err != nil {
+        log.Println("failed to set product taxonomy from catalog service: ", err)
+        return nil, err
+    }
+    return s.repository.GetProductByID(ctx, productID)
+}
+
+// checkCategories fails with ErrInvalidCategory if any id is unknown.
+func (s *catalogService) checkCategories(ctx context.Context, ids []string) error {
+    if len(ids) == 0 {
+        return nil
+    }
+    categories, err := s.repository.ListCategories(ctx)
+    if err != nil {
+        log.Println("failed to list categories from catalog service: ", err)
+        return err
+    }
+    for _, id := range ids {
+        if !slices.ContainsFunc(categories, func(c Category) bool { return c.ID == id }) {
+            return ErrInvalidCategory
+        }
+    }
+    return nil
+}
+
+// expandCategories returns ids together with the ids of all their
+// descendants, so filtering by a category includes its subcategories.
+func (s *catalogService) expandCategories(ctx context.Context, ids []string) ([]string, error) {
+    if len(ids) == 0 {
+        return ids, nil
+    }
+    categories, err := s.repository.ListCategories(ctx)
+    if err != nil {
+        log.Println("failed to list categories from catalog service: ", err)
+        return nil, err
+    }
+
+    expanded := slices.Clone(ids)
+    for _, id := range ids {
+        for _, d := range descendantIDs(categories, id) {
+            if !slices.Contains(expanded, d) {
+                expanded = append(expanded, d)
+            }
+        }
+    }
+    return expanded, nil
+}
+
+// placeCategory fills in name, slug, parent and path of c, checking that the
+// parent exists among categories and the resulting path is free.
+func placeCategory(c *Category, name, slug, parentID string, categories []Category) error {
+    name = strings.TrimSpace(name)
+    if slug == "" {
+        slug = name
+    }
+    slug = slugify(slug)
+    if name == "" || slug == "" {
+        return ErrInvalidCategory
+    }
+
+    path := slug
+    if parentID != "" {
+        i := slices.IndexFunc(categories, func(p Category) bool { return p.ID == parentID })
+        if i < 0 {
+            return ErrInvalidCategory
+        }
+        path = categories[i].Path + "/" + slug
+    }
+    if slices.ContainsFunc(categories, func(o Category) bool { return o.Path == path }) {
+        return ErrDuplicateCategory
+    }
+
+    c.Name, c.Slug, c.ParentID, c.Path = name, slug, parentID, path
+    return nil
+}
+
+func descendantIDs(categories []Category, id string) []string {
+    ids := []string{}
+    for _, c := range categories {
+        if c.ParentID == id {
+            ids = append(ids, c.ID)
+            ids = append(ids, descendantIDs(categories, c.ID)...)
+        }
+    }
+    return ids
+}
@@ -0,0 +1,197 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestCategoryTree(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+
+    electronics, err := s.PostCategory(ctx, "Electronics", "", "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    audio, err := s.PostCategory(ctx, "Audio & Hi-Fi", "", electronics.ID)
+    if err != nil {
+        t.Fatal(err)
+    }
+    headphones, err := s.PostCategory(ctx, "Headphones", "", audio.ID)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if headphones.Path != "electronics/audio-hi-fi/headphones" {
+        t.Fatalf("unexpected path %q", headphones.Path)
+    }
+
+    if _, err := s.PostCategory(ctx, "headphones", "", audio.ID); !errors.Is(err, ErrDuplicateCategory) {
+        t.Fatalf("expected ErrDuplicateCategory, got %v", err)
+    }
+    if _, err := s.PostCategory(ctx, "Cables", "", "missing"); !errors.Is(err, ErrInvalidCategory) {
+        t.Fatalf("expected ErrInvalidCategory, got %v", err)
+    }
+    if _, err := s.UpdateCategory(ctx, audio.ID, audio.Name, "", headphones.ID); !errors.Is(err, ErrInvalidCategory) {
+        t.Fatalf("expected moving a category below its child to fail, got %v", err)
+    }
+
+    if _, err := s.UpdateCategory(ctx, audio.ID, "Audio", "sound", ""); err != nil {
+        t.Fatal(err)
+    }
+    moved, err := s.GetCategoryByPath(ctx, "/sound/headphones/")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if moved.ID != headphones.ID {
+        t.Fatalf("expected headphones to follow its parent, got %+v", moved)
+    }
+
+    roots := ""
+    top, err := s.GetCategories(ctx, nil, &roots)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(top) != 2 || top[0].Path != "electronics" || top[1].Path != "sound" {
+        t.Fatalf("unexpected root categories %+v", top)
+    }
+
+    if err := s.DeleteCategory(ctx, audio.ID); !errors.Is(err, ErrCategoryHasChildren) {
+        t.Fatalf("expected ErrCategoryHasChildren, got %v", err)
+    }
+    if err := s.DeleteCategory(ctx, headphones.ID); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.GetCategory(ctx, headphones.ID); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("expected ErrNotFound, got %v", err)
+    }
+}
+
+func TestSearchProductsByCategoryIncludesSubcategories(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+
+    audio, _ := s.PostCategory(ctx, "Audio", "", "")
+    headphones, _ := s.PostCategory(ctx, "Headphones", "", audio.ID)
+    kitchen, _ := s.PostCategory(ctx, "Kitchen", "", "")
+
+    speaker, err := s.PostProduct(ctx, "Speaker", "", 80, nil, []string{audio.ID}, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    earbuds, err := s.PostProduct(ctx, "Earbuds", "", 40, nil, []string{headphones.ID}, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    mug, err := s.PostProduct(ctx, "Mug", "", 9, nil, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.PostProduct(ctx, "Kettle", "", 30, nil, []string{"missing"}, nil); !errors.Is(err, ErrInvalidCategory) {
+        t.Fatalf("expected ErrInvalidCategory, got %v", err)
+    }
+
+    if _, err := s.SetProductTaxonomy(ctx, mug.ID, []string{kitchen.ID}, []string{"ceramic"}); err != nil {
+        t.Fatal(err)
+    }
+
+    res, err := s.SearchProducts(ctx, SearchRequest{CategoryIDs: []string{audio.ID}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    ids := []string{}
+    for _, p := range res.Products {
+        ids = append(ids, p.ID)
+    }
+    slices.Sort(ids)
+    want := []string{speaker.ID, earbuds.ID}
+    slices.Sort(want)
+    if !slices.Equal(ids, want) {
+        t.Fatalf("expected %v, got %v", want, ids)
+    }
+
+    res, err = s.SearchProducts(ctx, SearchRequest{CategoryIDs: []string{kitchen.ID}, Tags: []string{"ceramic"}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(res.Products) != 1 || res.Products[0].ID != mug.ID {
+        t.Fatalf("expected the retagged mug, got %+v", res.Products)
+    }
+}
+
+func TestElasticRepositoryCategories(t *testing.T) {
+    ctx := context.Background()
+    f := newFakeElastic(t)
+    r := newTestElasticRepository(t, f)
+
+    if !f.hasIndex(categoriesIndex) {
+        t.Fatalf("expected %s to be created", categoriesIndex)
+    }
+
+    audio := Category{ID: "c1", Name: "Audio", Slug: "audio", Path: "audio"}
+    headphones := Category{ID: "c2", Name: "Headphones", Slug: "headphones", ParentID: "c1", Path: "audio/headphones"}
+    for _, c := range []Category{audio, headphones} {
+        if err := r.PutCategory(ctx, c); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    got, err := r.GetCategoryByID(ctx, "c2")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if *got != headphones {
+        t.Fatalf("got %+v, want %+v", got, headphones)
+    }
+    if _, err := r.GetCategoryByID(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("expected ErrNotFound, got %v", err)
+    }
+
+    if err := r.DeleteCategory(ctx, "c2"); err != nil {
+        t.Fatal(err)
+    }
+    categories, err := r.ListCategories(ctx)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !slices.Equal(categories, []Category{audio}) {
+        t.Fatalf("unexpected categories %+v", categories)
+    }
+}
+
+func TestElasticRepositorySetProductTaxonomy(t *testing.T) {
+    ctx := context.Background()
+    f := newFakeElastic(t)
+    r := newTestElasticRepository(t, f)
+
+    mug := Product{ID: "p1", Name: "Mug", Price: 9, Tags: []string{"old"}, Variants: []Variant{{ID: "v1", SKU: "M", Stock: 5}}}
+    if err := r.PutProduct(ctx, mug); err != nil {
+        t.Fatal(err)
+    }
+    // Stock taken by an order after the product was read.
+    if err := r.AdjustVariantStock(ctx, "p1", "v1", -2); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := r.SetProductTaxonomy(ctx, "p1", []string{"c1"}, nil); err != nil {
+        t.Fatal(err)
+    }
+    body := f.lastBody(http.MethodPost, "/_update/p1")
+    doc, _ := body["doc"].(map[string]any)
+    if len(doc) != 2 || doc["tags"] != nil {
+        t.Fatalf("expected a partial update of the taxonomy only, got %v", body)
+    }
+    got, err := r.GetProductByID(ctx, "p1")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !slices.Equal(got.CategoryIDs, []string{"c1"}) || got.Tags != nil || got.Variants[0].Stock != 3 {
+        t.Fatalf("unexpected product %+v", got)
+    }
+
+    if err := r.SetProductTaxonomy(ctx, "p2", nil, nil); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("expected ErrNotFound, got %v", err)
+    }
+}
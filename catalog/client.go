@@ -14,9 +14,14 @@ type Client struct {
     service pb.CatalogServiceClient
 }
 
-func NewClient(url string) (*Client, error) {
-    opts := grpc.WithTransportCredentials(insecure.NewCredentials())
-    conn, err := grpc.NewClient(url, opts)
+// NewClient dials url with insecure credentials; opts are appended, e.g.
+// a custom dialer.
+func NewClient(url string, opts ...grpc.DialOption) (*Client, error) {
+    opts = append(
+        []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+        opts...,
+    )
+    conn, err := grpc.NewClient(url, opts...)
     if err != nil {
         log.Println("failed to create catalog grpc client: ", err)
         return nil, err
@@ -33,12 +38,16 @@ func (c *Client) Close() {
 
 func (c *Client) PostProduct(
     ctx context.Context, name, description string, price float64,
+    tags []string, categoryIDs []string, variants []Variant,
 ) (*Product, error) {
     r, err := c.service.PostProduct(
         ctx, &pb.PostProductRequest{
             Name: name,
             Description: description,
             Price: price,
+            Tags: tags,
+            CategoryIds: categoryIDs,
+            Variants: variantsToProto(variants),
         },
     )
     if err != nil {
@@ -46,12 +55,8 @@ func (c *Client) PostProduct(
         return nil, err
     }
 
-    return &Product{
-        ID: r.Product.Id,
-        Name: r.Product.Name,
-        Description: r.Product.Description,
-        Price: r.Product.Price,
-    }, nil
+    product := productFromProto(r.Product)
+    return &product, nil
 }
 
 func (c *Client) GetProduct(ctx context.Context, id string) (*Product, error) {
@@ -66,12 +71,8 @@ func (c *Client) GetProduct(ctx context.Context, id string) (*Product, error) {
         return nil, err
     }
 
-    return &Product{
-        ID: r.Product.Id,
-        Name: r.Product.Name,
-        Description: r.Product.Description,
-        Price: r.Product.Price,
-    }, nil
+    product := productFromProto(r.Product)
+    return &product, nil
 }
 
 func (c *Client) GetProducts(
@@ -95,14 +96,473 @@ func (c *Client) GetProducts(
 
     products := []Product{}
     for _, p := range r.Products{
-        products = append(products, Product{
-            ID: p.Id,
-            Name: p.Name,
-            Description: p.Description,
-            Price: p.Price,
-        })
+        products = append(products, productFromProto(p))
     }
     log.Println("catalog: client: products: ", products)
 
     return &products, err
 }
+
+// GetProductsByIDs looks products up by id, returning them in the order
+// of ids along with the ids that were not found.
+func (c *Client) GetProductsByIDs(
+    ctx context.Context, ids []string,
+) ([]Product, []string, error) {
+    if len(ids) == 0 {
+        return []Product{}, []string{}, nil
+    }
+
+    r, err := c.service.GetProducts(ctx, &pb.GetProductsRequest{Ids: ids})
+    if err != nil {
+        log.Println("failed to get products by ids from catalog client: ", err)
+        return nil, nil, err
+    }
+
+    products := []Product{}
+    for _, p := range r.Products {
+        products = append(products, productFromProto(p))
+    }
+    missing := r.MissingIds
+    if missing == nil {
+        missing = []string{}
+    }
+    return products, missing, nil
+}
+
+// SearchProducts runs a filtered, sorted search and returns the page along
+// with the total hit count and facets.
+func (c *Client) SearchProducts(
+    ctx context.Context, req SearchRequest,
+) (*SearchResult, error) {
+    // The proto field is unsigned, so negative values are rejected here;
+    // the service checks the upper bound.
+    if req.Fuzziness != nil && *req.Fuzziness < 0 {
+        return nil, ErrInvalidFuzziness
+    }
+
+    r, err := c.service.GetProducts(
+        ctx,
+        &pb.GetProductsRequest{
+            Skip: req.Skip,
+            Take: req.Take,
+            Query: req.Query,
+            MinPrice: req.MinPrice,
+            MaxPrice: req.MaxPrice,
+            MinRating: req.MinRating,
+            Tags: req.Tags,
+            CategoryIds: req.CategoryIDs,
+            Fuzziness: fuzzinessToProto(req.Fuzziness),
+            Sort: pb.ProductSort(req.Sort),
+            Facets: true,
+        },
+    )
+    if err != nil {
+        log.Println("failed to search products from catalog client: ", err)
+        return nil, err
+    }
+
+    result := &SearchResult{
+        Products: []Product{},
+        Total: r.Total,
+        Facets: Facets{Prices: []PriceBucket{}, Tags: []TagCount{}},
+        Highlights: map[string][]Highlight{},
+        DidYouMean: r.DidYouMean,
+        SearchID: r.SearchId,
+    }
+    for _, p := range r.Products {
+        result.Products = append(result.Products, productFromProto(p))
+    }
+    for _, h := range r.Highlights {
+        result.Highlights[h.ProductId] = append(result.Highlights[h.ProductId], Highlight{
+            Field: h.Field,
+            Fragments: h.Fragments,
+        })
+    }
+    for _, b := range r.Facets.GetPrices() {
+        result.Facets.Prices = append(result.Facets.Prices, PriceBucket{
+            From: b.From,
+            To: b.To,
+            Count: b.Count,
+        })
+    }
+    for _, t := range r.Facets.GetTags() {
+        result.Facets.Tags = append(result.Facets.Tags, TagCount{
+            Tag: t.Tag,
+            Count: t.Count,
+        })
+    }
+
+    return result, nil
+}
+
+func fuzzinessToProto(f *int) *uint32 {
+    if f == nil {
+        return nil
+    }
+    fuzziness := uint32(*f)
+    return &fuzziness
+}
+
+// SuggestProducts returns up to limit autocomplete suggestions for prefix;
+// a zero limit uses the server default.
+func (c *Client) SuggestProducts(
+    ctx context.Context, prefix string, limit int,
+) ([]Suggestion, error) {
+    r, err := c.service.SuggestProducts(ctx, &pb.SuggestProductsRequest{
+        Prefix: prefix,
+        Limit: uint32(limit),
+    })
+    if err != nil {
+        log.Println("failed to suggest products from catalog client: ", err)
+        return nil, err
+    }
+
+    suggestions := []Suggestion{}
+    for _, s := range r.Suggestions {
+        suggestions = append(suggestions, Suggestion{
+            ProductID: s.ProductId,
+            Name: s.Name,
+            Highlighted: s.Highlighted,
+        })
+    }
+    return suggestions, nil
+}
+
+// GetSimilarProducts returns up to take products resembling the one with
+// productID; a zero take uses the server default.
+func (c *Client) GetSimilarProducts(
+    ctx context.Context, productID string, take int,
+) ([]Product, error) {
+    r, err := c.service.GetSimilarProducts(ctx, &pb.GetSimilarProductsRequest{
+        ProductId: productID,
+        Take: uint32(take),
+    })
+    if err != nil {
+        log.Println("failed to get similar products from catalog client: ", err)
+        return nil, err
+    }
+
+    products := []Product{}
+    for _, p := range r.Products {
+        products = append(products, productFromProto(p))
+    }
+    return products, nil
+}
+
+func (c *Client) PostCategory(
+    ctx context.Context, name, slug, parentID string,
+) (*Category, error) {
+    r, err := c.service.PostCategory(ctx, &pb.PostCategoryRequest{
+        Name: name,
+        Slug: slug,
+        ParentId: parentID,
+    })
+    if err != nil {
+        log.Println("failed to post category from catalog client: ", err)
+        return nil, err
+    }
+
+    category := categoryFromProto(r.Category)
+    return &category, nil
+}
+
+func (c *Client) UpdateCategory(
+    ctx context.Context, id, name, slug, parentID string,
+) (*Category, error) {
+    r, err := c.service.UpdateCategory(ctx, &pb.UpdateCategoryRequest{
+        Id: id,
+        Name: name,
+        Slug: slug,
+        ParentId: parentID,
+    })
+    if err != nil {
+        log.Println("failed to update category from catalog client: ", err)
+        return nil, err
+    }
+
+    category := categoryFromProto(r.Category)
+    return &category, nil
+}
+
+func (c *Client) DeleteCategory(ctx context.Context, id string) error {
+    _, err := c.service.DeleteCategory(ctx, &pb.DeleteCategoryRequest{Id: id})
+    if err != nil {
+        log.Println("failed to delete category from catalog client: ", err)
+    }
+    return err
+}
+
+// GetCategory looks a category up by id, or by path when id is empty.
+func (c *Client) GetCategory(ctx context.Context, id, path string) (*Category, error) {
+    r, err := c.service.GetCategory(ctx, &pb.GetCategoryRequest{
+        Id: id,
+        Path: path,
+    })
+    if err != nil {
+        log.Println("failed to get category from catalog client: ", err)
+        return nil, err
+    }
+
+    category := categoryFromProto(r.Category)
+    return &category, nil
+}
+
+// GetCategories returns the categories with ids, or the children of
+// parentID when it is non-nil, or the whole tree.
+func (c *Client) GetCategories(
+    ctx context.Context, ids []string, parentID *string,
+) ([]Category, error) {
+    r, err := c.service.GetCategories(ctx, &pb.GetCategoriesRequest{
+        Ids: ids,
+        ParentId: parentID,
+    })
+    if err != nil {
+        log.Println("failed to get categories from catalog client: ", err)
+        return nil, err
+    }
+
+    categories := []Category{}
+    for _, category := range r.Categories {
+        categories = append(categories, categoryFromProto(category))
+    }
+    return categories, nil
+}
+
+func (c *Client) SetProductTaxonomy(
+    ctx context.Context, productID string, categoryIDs, tags []string,
+) (*Product, error) {
+    r, err := c.service.SetProductTaxonomy(ctx, &pb.SetProductTaxonomyRequest{
+        ProductId: productID,
+        CategoryIds: categoryIDs,
+        Tags: tags,
+    })
+    if err != nil {
+        log.Println("failed to set product taxonomy from catalog client: ", err)
+        return nil, err
+    }
+
+    product := productFromProto(r.Product)
+    return &product, nil
+}
+
+func (c *Client) SetProductVariants(
+    ctx context.Context, productID string, variants []Variant,
+) (*Product, error) {
+    r, err := c.service.SetProductVariants(ctx, &pb.SetProductVariantsRequest{
+        ProductId: productID,
+        Variants: variantsToProto(variants),
+    })
+    if err != nil {
+        log.Println("failed to set product variants from catalog client: ", err)
+        return nil, err
+    }
+
+    product := productFromProto(r.Product)
+    return &product, nil
+}
+
+// RestockVariant adds quantity units to the stock of a product variant.
+func (c *Client) RestockVariant(
+    ctx context.Context, productID, variantID string, quantity uint32,
+) (*Product, error) {
+    r, err := c.service.RestockVariant(ctx, &pb.RestockVariantRequest{
+        ProductId: productID,
+        VariantId: variantID,
+        Quantity: quantity,
+    })
+    if err != nil {
+        log.Println("failed to restock variant from catalog client: ", err)
+        return nil, err
+    }
+
+    product := productFromProto(r.Product)
+    return &product, nil
+}
+
+// ReserveVariantStock takes quantity units out of the stock of a product
+// variant. It fails, changing nothing, if there are fewer left.
+func (c *Client) ReserveVariantStock(
+    ctx context.Context, productID, variantID string, quantity uint32,
+) (*Product, error) {
+    r, err := c.service.ReserveVariantStock(ctx, &pb.ReserveVariantStockRequest{
+        ProductId: productID,
+        VariantId: variantID,
+        Quantity: quantity,
+    })
+    if err != nil {
+        log.Println("failed to reserve variant stock from catalog client: ", err)
+        return nil, err
+    }
+
+    product := productFromProto(r.Product)
+    return &product, nil
+}
+
+func (c *Client) GetSynonyms(ctx context.Context) ([]SynonymRule, error) {
+    r, err := c.service.GetSynonyms(ctx, &pb.GetSynonymsRequest{})
+    if err != nil {
+        log.Println("failed to get synonyms from catalog client: ", err)
+        return nil, err
+    }
+
+    rules := []SynonymRule{}
+    for _, rule := range r.Rules {
+        rules = append(rules, SynonymRule{ID: rule.Id, Terms: rule.Terms})
+    }
+    return rules, nil
+}
+
+// PutSynonymRule creates a rule when id is empty and replaces the rule with
+// id otherwise.
+func (c *Client) PutSynonymRule(
+    ctx context.Context, id string, terms []string,
+) (*SynonymRule, error) {
+    r, err := c.service.PutSynonymRule(ctx, &pb.PutSynonymRuleRequest{
+        Id: id,
+        Terms: terms,
+    })
+    if err != nil {
+        log.Println("failed to put synonym rule from catalog client: ", err)
+        return nil, err
+    }
+
+    return &SynonymRule{ID: r.Rule.Id, Terms: r.Rule.Terms}, nil
+}
+
+func (c *Client) DeleteSynonymRule(ctx context.Context, id string) error {
+    _, err := c.service.DeleteSynonymRule(ctx, &pb.DeleteSynonymRuleRequest{Id: id})
+    if err != nil {
+        log.Println("failed to delete synonym rule from catalog client: ", err)
+    }
+    return err
+}
+
+func (c *Client) GetStopwords(ctx context.Context) ([]string, error) {
+    r, err := c.service.GetStopwords(ctx, &pb.GetStopwordsRequest{})
+    if err != nil {
+        log.Println("failed to get stopwords from catalog client: ", err)
+        return nil, err
+    }
+    return r.Words, nil
+}
+
+func (c *Client) SetStopwords(ctx context.Context, words []string) ([]string, error) {
+    r, err := c.service.SetStopwords(ctx, &pb.SetStopwordsRequest{Words: words})
+    if err != nil {
+        log.Println("failed to set stopwords from catalog client: ", err)
+        return nil, err
+    }
+    return r.Words, nil
+}
+
+// RecordSearchFeedback links a click or add to cart on productID to the
+// search that listed it.
+func (c *Client) RecordSearchFeedback(
+    ctx context.Context, searchID, productID string, kind FeedbackKind,
+) error {
+    req := &pb.RecordSearchFeedbackRequest{SearchId: searchID, ProductId: productID}
+    switch kind {
+    case FeedbackClick:
+        req.Kind = pb.SearchFeedbackKind_SEARCH_FEEDBACK_CLICK
+    case FeedbackAddToCart:
+        req.Kind = pb.SearchFeedbackKind_SEARCH_FEEDBACK_ADD_TO_CART
+    default:
+        return ErrInvalidFeedback
+    }
+
+    _, err := c.service.RecordSearchFeedback(ctx, req)
+    if err != nil {
+        log.Println("failed to record search feedback from catalog client: ", err)
+    }
+    return err
+}
+
+func (c *Client) GetTopSearchQueries(ctx context.Context, days, limit int) ([]QueryCount, error) {
+    r, err := c.service.GetTopSearchQueries(ctx, &pb.SearchQueriesRequest{
+        Days: uint32(max(days, 0)),
+        Limit: uint32(max(limit, 0)),
+    })
+    if err != nil {
+        log.Println("failed to get top search queries from catalog client: ", err)
+        return nil, err
+    }
+    return queryCountsFromProto(r.Queries), nil
+}
+
+func (c *Client) GetZeroResultQueries(ctx context.Context, days, limit int) ([]QueryCount, error) {
+    r, err := c.service.GetZeroResultQueries(ctx, &pb.SearchQueriesRequest{
+        Days: uint32(max(days, 0)),
+        Limit: uint32(max(limit, 0)),
+    })
+    if err != nil {
+        log.Println("failed to get zero result queries from catalog client: ", err)
+        return nil, err
+    }
+    return queryCountsFromProto(r.Queries), nil
+}
+
+func (c *Client) GetSearchClickThrough(ctx context.Context, days int) (*ClickThrough, error) {
+    r, err := c.service.GetSearchClickThrough(ctx, &pb.SearchClickThroughRequest{
+        Days: uint32(max(days, 0)),
+    })
+    if err != nil {
+        log.Println("failed to get search click through from catalog client: ", err)
+        return nil, err
+    }
+
+    return &ClickThrough{
+        Searches: r.Searches,
+        ClickedSearches: r.ClickedSearches,
+        AddedToCartSearches: r.AddedToCartSearches,
+        Rate: r.Rate,
+    }, nil
+}
+
+// SetProductRating stores the review aggregate of productID unless one of
+// a version at least as high is stored already.
+func (c *Client) SetProductRating(
+    ctx context.Context, productID string, rating float64, count, version uint64,
+) error {
+    _, err := c.service.SetProductRating(ctx, &pb.SetProductRatingRequest{
+        ProductId: productID,
+        Rating: rating,
+        ReviewCount: count,
+        Version: version,
+    })
+    if err != nil {
+        log.Println("failed to set product rating from catalog client: ", err)
+    }
+    return err
+}
+
+func queryCountsFromProto(queries []*pb.SearchQueryCount) []QueryCount {
+    res := []QueryCount{}
+    for _, q := range queries {
+        res = append(res, QueryCount{Query: q.Query, Searches: q.Searches})
+    }
+    return res
+}
+
+func productFromProto(p *pb.Product) Product {
+    return Product{
+        ID: p.Id,
+        Name: p.Name,
+        Description: p.Description,
+        Price: p.Price,
+        Tags: p.Tags,
+        CategoryIDs: p.CategoryIds,
+        Variants: variantsFromProto(p.Variants),
+        Rating: p.Rating,
+        ReviewCount: p.ReviewCount,
+    }
+}
+
+func categoryFromProto(c *pb.Category) Category {
+    return Category{
+        ID: c.Id,
+        Name: c.Name,
+        Slug: c.Slug,
+        ParentID: c.ParentId,
+        Path: c.Path,
+    }
+}
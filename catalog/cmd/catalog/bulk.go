@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/catalog"
+)
+
+// runImport upserts products from a CSV or JSON Lines file, "-" reading
+// standard input. Row errors are logged and make the command exit non-zero
+// once every valid row has been written:
+//
+//	catalog import [-format csv|jsonl] [-dry-run] [-batch-size n] file
+func runImport(cfg Config, args []string) {
+    flags := flag.NewFlagSet("import", flag.ExitOnError)
+    format := flags.String("format", "", "csv or jsonl, by default taken from the file extension")
+    dryRun := flags.Bool("dry-run", false, "validate rows without writing them")
+    batchSize := flags.Int("batch-size", 0, "rows per bulk request")
+    flags.Parse(args)
+    if flags.NArg() != 1 {
+        log.Fatal("usage: catalog import [-format csv|jsonl] [-dry-run] [-batch-size n] file")
+    }
+
+    path := flags.Arg(0)
+    in := io.Reader(os.Stdin)
+    if path != "-" {
+        f, err := os.Open(path)
+        if err != nil {
+            log.Fatal("failed to open import file: ", err)
+        }
+        defer f.Close()
+        in = f
+    }
+
+    r, err := openRepository(cfg)
+    if err != nil {
+        log.Fatal("failed to open catalog repository: ", err)
+    }
+    defer r.Close()
+
+    report, err := catalog.ImportProducts(context.Background(), r, in, catalog.ImportOptions{
+        Format: formatOf(*format, path),
+        DryRun: *dryRun,
+        BatchSize: *batchSize,
+    })
+    if report != nil {
+        for _, rowErr := range report.Errors {
+            log.Println(rowErr)
+        }
+        log.Printf(
+            "%d rows: %d created, %d updated, %d failed (dry run: %t)",
+            report.Rows, report.Created, report.Updated, len(report.Errors), *dryRun,
+        )
+    }
+    if err != nil {
+        log.Fatal("failed to import products: ", err)
+    }
+    if len(report.Errors) > 0 {
+        os.Exit(1)
+    }
+}
+
+// runExport writes every product to a file or standard output in the
+// format read by import:
+//
+//	catalog export [-format csv|jsonl] [-o file]
+func runExport(cfg Config, args []string) {
+    flags := flag.NewFlagSet("export", flag.ExitOnError)
+    format := flags.String("format", "", "csv or jsonl, by default taken from the file extension")
+    path := flags.String("o", "-", "output file, - for standard output")
+    flags.Parse(args)
+
+    out := io.Writer(os.Stdout)
+    if *path != "-" {
+        f, err := os.Create(*path)
+        if err != nil {
+            log.Fatal("failed to create export file: ", err)
+        }
+        defer f.Close()
+        out = f
+    }
+
+    r, err := openRepository(cfg)
+    if err != nil {
+        log.Fatal("failed to open catalog repository: ", err)
+    }
+    defer r.Close()
+
+    count, err := catalog.ExportProducts(context.Background(), r, out, formatOf(*format, *path))
+    if err != nil {
+        log.Fatal("failed to export products: ", err)
+    }
+    log.Printf("exported %d products", count)
+}
+
+// formatOf returns format if set, otherwise guesses it from the file
+// extension, defaulting to JSON Lines.
+func formatOf(format, path string) string {
+    if format != "" {
+        return format
+    }
+    if strings.EqualFold(filepath.Ext(path), ".csv") {
+        return catalog.FormatCSV
+    }
+    return catalog.FormatJSONL
+}
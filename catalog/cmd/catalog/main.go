@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"log"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
@@ -21,18 +23,35 @@ func main() {
         log.Fatal("failed to get catalog config with envconfig: ", err)
     }
 
+    if len(os.Args) > 1 {
+        switch os.Args[1] {
+        case "reindex":
+            runReindex(cfg, os.Args[2:])
+        case "import":
+            runImport(cfg, os.Args[2:])
+        case "export":
+            runExport(cfg, os.Args[2:])
+        default:
+            log.Fatal("unknown catalog command: ", os.Args[1])
+        }
+        return
+    }
+
     var r catalog.Repository
 
     ctx := context.Background()
-    if err := retry.Constant(ctx, time.Second * 1 , func(ctx context.Context) error {
-        r, err = catalog.NewElasticRepository(cfg.DatabaseURL)
+    if strings.HasPrefix(cfg.DatabaseURL, "memory://") {
+        log.Println("using in-memory catalog repository")
+        r = catalog.NewMemoryRepository()
+    } else if err := retry.Constant(ctx, time.Second * 1 , func(ctx context.Context) error {
+        r, err = openRepository(cfg)
         if err != nil {
-            log.Println("failed to create elasticsearch repository: ", err)
+            log.Println("failed to create catalog repository: ", err)
             return retry.RetryableError(err)
         }
         return nil
     }); err != nil {
-        log.Fatal("failed to retry create elasticsearch repository: ", err)
+        log.Fatal("failed to retry create catalog repository: ", err)
     }
 
     defer r.Close()
@@ -41,3 +60,22 @@ func main() {
     s := catalog.NewService(r)
     log.Fatal(catalog.ListenGRPC(s, 8080))
 }
+
+// openRepository connects to the repository selected by the DATABASE_URL
+// scheme without retrying: memory:// keeps the catalog in process,
+// postgres:// and postgresql:// use Postgres, anything else is an
+// Elasticsearch URL.
+func openRepository(cfg Config) (catalog.Repository, error) {
+    switch {
+    case strings.HasPrefix(cfg.DatabaseURL, "memory://"):
+        return catalog.NewMemoryRepository(), nil
+    case isPostgresURL(cfg.DatabaseURL):
+        return catalog.NewPostgresRepository(cfg.DatabaseURL)
+    default:
+        return catalog.NewElasticRepository(cfg.DatabaseURL)
+    }
+}
+
+func isPostgresURL(url string) bool {
+    return strings.HasPrefix(url, "postgres://") || strings.HasPrefix(url, "postgresql://")
+}
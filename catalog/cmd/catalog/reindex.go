@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"strings"
+
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/catalog"
+)
+
+// runReindex rebuilds the catalog index from the checked-in mapping and
+// swaps the catalog alias over to it:
+//
+//	catalog reindex [-delete-old]
+func runReindex(cfg Config, args []string) {
+    flags := flag.NewFlagSet("reindex", flag.ExitOnError)
+    deleteOld := flags.Bool(
+        "delete-old", false, "delete the previous index once the alias has moved",
+    )
+    flags.Parse(args)
+    if strings.HasPrefix(cfg.DatabaseURL, "memory://") || isPostgresURL(cfg.DatabaseURL) {
+        log.Fatal("reindex only applies to the elasticsearch catalog repository")
+    }
+
+    name, err := catalog.ReindexElastic(context.Background(), cfg.DatabaseURL, *deleteOld)
+    if err != nil {
+        log.Fatal("failed to reindex catalog: ", err)
+    }
+    log.Println("catalog reindexed into ", name)
+}
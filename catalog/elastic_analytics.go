@@ -0,0 +1,225 @@
+package catalog
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/count"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+)
+
+// searchEventsIndex and searchFeedbackIndex only grow; old documents are
+// expected to be removed by an index lifecycle policy, not by the service.
+const (
+    searchEventsIndex   = "catalog_search_events"
+    searchFeedbackIndex = "catalog_search_feedback"
+)
+
+const searchEventsMapping = `{
+  "mappings": {
+    "properties": {
+      "query": {"type": "keyword"},
+      "min_price": {"type": "double"},
+      "max_price": {"type": "double"},
+      "tags": {"type": "keyword"},
+      "category_ids": {"type": "keyword"},
+      "sort": {"type": "integer"},
+      "results": {"type": "long"},
+      "latency_ms": {"type": "double"},
+      "created_at": {"type": "date"}
+    }
+  }
+}`
+
+const searchFeedbackMapping = `{
+  "mappings": {
+    "properties": {
+      "search_id": {"type": "keyword"},
+      "product_id": {"type": "keyword"},
+      "kind": {"type": "keyword"},
+      "created_at": {"type": "date"}
+    }
+  }
+}`
+
+type searchEventDocument struct {
+    Query       string     `json:"query"`
+    MinPrice    *float64   `json:"min_price,omitempty"`
+    MaxPrice    *float64   `json:"max_price,omitempty"`
+    Tags        []string   `json:"tags,omitempty"`
+    CategoryIDs []string   `json:"category_ids,omitempty"`
+    Sort        SearchSort `json:"sort"`
+    Results     uint64     `json:"results"`
+    LatencyMS   float64    `json:"latency_ms"`
+    CreatedAt   time.Time  `json:"created_at"`
+}
+
+type searchFeedbackDocument struct {
+    SearchID  string       `json:"search_id"`
+    ProductID string       `json:"product_id"`
+    Kind      FeedbackKind `json:"kind"`
+    CreatedAt time.Time    `json:"created_at"`
+}
+
+func ensureAnalyticsIndices(ctx context.Context, client *elasticsearch.TypedClient) error {
+    for _, index := range []struct{ name, mapping string }{
+        {searchEventsIndex, searchEventsMapping},
+        {searchFeedbackIndex, searchFeedbackMapping},
+    } {
+        exists, err := client.Indices.Exists(index.name).Do(ctx)
+        if err != nil {
+            log.Println("failed to check analytics index exists: ", err)
+            return err
+        }
+        if exists {
+            continue
+        }
+
+        _, err = client.Indices.Create(index.name).Raw(strings.NewReader(index.mapping)).Do(ctx)
+        if err != nil {
+            log.Println("failed to create elasticsearch analytics index: ", err)
+            return err
+        }
+    }
+    return nil
+}
+
+func (r *elasticRepository) PutSearchEvent(ctx context.Context, e SearchEvent) error {
+    _, err := r.client.Index(searchEventsIndex).
+        Id(e.ID).
+        Request(searchEventDocument{
+            Query: e.Query,
+            MinPrice: e.MinPrice,
+            MaxPrice: e.MaxPrice,
+            Tags: e.Tags,
+            CategoryIDs: e.CategoryIDs,
+            Sort: e.Sort,
+            Results: e.Results,
+            LatencyMS: float64(e.Latency) / float64(time.Millisecond),
+            CreatedAt: e.CreatedAt,
+        }).
+        Do(ctx)
+    if err != nil {
+        log.Println("failed to index search event: ", err)
+        return err
+    }
+    return nil
+}
+
+func (r *elasticRepository) PutSearchFeedback(ctx context.Context, f SearchFeedback) error {
+    _, err := r.client.Index(searchFeedbackIndex).
+        Request(searchFeedbackDocument(f)).
+        Do(ctx)
+    if err != nil {
+        log.Println("failed to index search feedback: ", err)
+        return err
+    }
+    return nil
+}
+
+func (r *elasticRepository) TopSearchQueries(
+    ctx context.Context, since time.Time, limit int, zeroResults bool,
+) ([]QueryCount, error) {
+    filter := []types.Query{createdSince(since)}
+    if zeroResults {
+        filter = append(filter, types.Query{Term: map[string]types.TermQuery{"results": {Value: 0}}})
+    }
+    size, queryField := 0, "query"
+    res, err := r.client.Search().
+        Index(searchEventsIndex).
+        Request(&search.Request{
+            Query: &types.Query{Bool: &types.BoolQuery{
+                Filter: filter,
+                MustNot: []types.Query{{Term: map[string]types.TermQuery{"query": {Value: ""}}}},
+            }},
+            Aggregations: map[string]types.Aggregations{
+                "queries": {Terms: &types.TermsAggregation{Field: &queryField, Size: &limit}},
+            },
+            Size: &size,
+        }).Do(ctx)
+    if err != nil {
+        log.Println("failed to aggregate search queries from catalog repository: ", err)
+        return nil, err
+    }
+
+    queries := []QueryCount{}
+    if terms, ok := res.Aggregations["queries"].(*types.StringTermsAggregate); ok {
+        if buckets, ok := terms.Buckets.([]types.StringTermsBucket); ok {
+            for _, b := range buckets {
+                query, _ := b.Key.(string)
+                queries = append(queries, QueryCount{Query: query, Searches: uint64(b.DocCount)})
+            }
+        }
+    }
+    return queries, nil
+}
+
+// SearchClickThrough counts clicked searches with a cardinality
+// aggregation, which is exact up to its default precision threshold of
+// 3000 searches and approximate beyond.
+func (r *elasticRepository) SearchClickThrough(
+    ctx context.Context, since time.Time,
+) (*ClickThrough, error) {
+    searches, err := r.client.Count().
+        Index(searchEventsIndex).
+        Request(&count.Request{Query: &types.Query{Bool: &types.BoolQuery{
+            Filter: []types.Query{createdSince(since)},
+        }}}).
+        Do(ctx)
+    if err != nil {
+        log.Println("failed to count search events from catalog repository: ", err)
+        return nil, err
+    }
+
+    size, searchIDField := 0, "search_id"
+    kindAggregation := func(kind FeedbackKind) types.Aggregations {
+        return types.Aggregations{
+            Filter: &types.Query{Term: map[string]types.TermQuery{"kind": {Value: string(kind)}}},
+            Aggregations: map[string]types.Aggregations{
+                "searches": {Cardinality: &types.CardinalityAggregation{Field: &searchIDField}},
+            },
+        }
+    }
+    res, err := r.client.Search().
+        Index(searchFeedbackIndex).
+        Request(&search.Request{
+            Query: &types.Query{Bool: &types.BoolQuery{Filter: []types.Query{createdSince(since)}}},
+            Aggregations: map[string]types.Aggregations{
+                "clicks": kindAggregation(FeedbackClick),
+                "add_to_cart": kindAggregation(FeedbackAddToCart),
+            },
+            Size: &size,
+        }).Do(ctx)
+    if err != nil {
+        log.Println("failed to aggregate search feedback from catalog repository: ", err)
+        return nil, err
+    }
+
+    return &ClickThrough{
+        Searches: uint64(searches.Count),
+        ClickedSearches: feedbackSearches(res.Aggregations["clicks"]),
+        AddedToCartSearches: feedbackSearches(res.Aggregations["add_to_cart"]),
+    }, nil
+}
+
+func createdSince(since time.Time) types.Query {
+    gte := since.Format(time.RFC3339)
+    return types.Query{Range: map[string]types.RangeQuery{
+        "created_at": types.DateRangeQuery{Gte: &gte},
+    }}
+}
+
+func feedbackSearches(aggregate types.Aggregate) uint64 {
+    filter, ok := aggregate.(*types.FilterAggregate)
+    if !ok {
+        return 0
+    }
+    if searches, ok := filter.Aggregations["searches"].(*types.CardinalityAggregate); ok {
+        return uint64(searches.Value)
+    }
+    return 0
+}
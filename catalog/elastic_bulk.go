@@ -0,0 +1,157 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/bulk"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/closepointintime"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/operationtype"
+)
+
+const scanKeepAlive = "1m"
+
+// scanPageSize is the number of products fetched per page by ScanProducts.
+var scanPageSize = 1000
+
+// PutProducts indexes products with a single bulk request. The returned
+// slice holds one entry per product, nil where it was indexed.
+func (r *elasticRepository) PutProducts(
+    ctx context.Context, products []Product,
+) ([]error, error) {
+    errs := make([]error, len(products))
+    if len(products) == 0 {
+        return errs, nil
+    }
+
+    req := bulk.Request{}
+    for _, p := range products {
+        id := p.ID
+        req = append(req,
+            types.OperationContainer{Index: &types.IndexOperation{Id_: &id}},
+            newProductDocument(p),
+        )
+    }
+
+    res, err := r.client.Bulk().Index(catalogAlias).Request(&req).Do(ctx)
+    if err != nil {
+        log.Println("failed to bulk index products from catalog repository: ", err)
+        return nil, err
+    }
+    if !res.Errors {
+        return errs, nil
+    }
+
+    for i, item := range res.Items {
+        if i >= len(errs) {
+            break
+        }
+        result, ok := item[operationtype.Index]
+        if !ok || result.Error == nil {
+            continue
+        }
+        reason := "bulk index failed"
+        if result.Error.Reason != nil {
+            reason = *result.Error.Reason
+        }
+        errs[i] = errors.New(reason)
+    }
+    return errs, nil
+}
+
+func (r *elasticRepository) GetProductsByExternalSKUs(
+    ctx context.Context, skus []string,
+) ([]Product, error) {
+    products := []Product{}
+    if len(skus) == 0 {
+        return products, nil
+    }
+
+    values := []types.FieldValue{}
+    for _, sku := range skus {
+        values = append(values, sku)
+    }
+    size := len(skus)
+    res, err := r.client.Search().
+        Index(catalogAlias).
+        Request(&search.Request{
+            Query: &types.Query{
+                Terms: &types.TermsQuery{
+                    TermsQuery: map[string]types.TermsQueryField{"external_sku": values},
+                },
+            },
+            Size: &size,
+        }).Do(ctx)
+    if err != nil {
+        log.Println("failed to get products by external sku from catalog repository: ", err)
+        return nil, err
+    }
+
+    for _, hit := range res.Hits.Hits {
+        p := productDocument{}
+        if err = json.Unmarshal(hit.Source_, &p); err == nil {
+            products = append(products, p.product(*hit.Id_))
+        }
+    }
+    return products, nil
+}
+
+// ScanProducts walks every product in a consistent snapshot, using a point
+// in time and search_after so concurrent writes don't shift pages.
+func (r *elasticRepository) ScanProducts(
+    ctx context.Context, fn func(Product) error,
+) error {
+    pit, err := r.client.OpenPointInTime(catalogAlias).KeepAlive(scanKeepAlive).Do(ctx)
+    if err != nil {
+        log.Println("failed to open point in time from catalog repository: ", err)
+        return err
+    }
+    // Every search may hand back a refreshed id; the latest one is closed.
+    pitID := pit.Id
+    defer func() {
+        _, err := r.client.ClosePointInTime().
+            Request(&closepointintime.Request{Id: pitID}).
+            Do(context.Background())
+        if err != nil {
+            log.Println("failed to close point in time from catalog repository: ", err)
+        }
+    }()
+
+    size := scanPageSize
+    var after []types.FieldValue
+    for {
+        res, err := r.client.Search().Request(&search.Request{
+            Query: &types.Query{MatchAll: &types.MatchAllQuery{}},
+            Pit: &types.PointInTimeReference{Id: pitID, KeepAlive: scanKeepAlive},
+            Sort: []types.SortCombinations{"_shard_doc"},
+            SearchAfter: after,
+            Size: &size,
+        }).Do(ctx)
+        if err != nil {
+            log.Println("failed to scan products from catalog repository: ", err)
+            return err
+        }
+        if res.PitId != nil {
+            pitID = *res.PitId
+        }
+
+        for _, hit := range res.Hits.Hits {
+            p := productDocument{}
+            if err := json.Unmarshal(hit.Source_, &p); err != nil {
+                log.Println("failed to unmarshal productDocument: ", err)
+                return err
+            }
+            if err := fn(p.product(*hit.Id_)); err != nil {
+                return err
+            }
+            after = hit.Sort
+        }
+        if len(res.Hits.Hits) < size {
+            return nil
+        }
+    }
+}
@@ -0,0 +1,119 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/refresh"
+)
+
+// categoriesIndex holds the category tree. It is small and read as a whole,
+// so it is not versioned behind an alias like the product index.
+const categoriesIndex = "catalog_categories"
+
+// maxCategories bounds how many categories ListCategories reads back.
+const maxCategories = 10000
+
+const categoriesMapping = `{
+  "mappings": {
+    "properties": {
+      "name": {"type": "text"},
+      "slug": {"type": "keyword"},
+      "parent_id": {"type": "keyword"},
+      "path": {"type": "keyword"}
+    }
+  }
+}`
+
+func ensureCategoriesIndex(ctx context.Context, client *elasticsearch.TypedClient) error {
+    exists, err := client.Indices.Exists(categoriesIndex).Do(ctx)
+    if err != nil {
+        log.Println("failed to check categories index exists: ", err)
+        return err
+    }
+    if exists {
+        return nil
+    }
+
+    _, err = client.Indices.Create(categoriesIndex).
+        Raw(strings.NewReader(categoriesMapping)).
+        Do(ctx)
+    if err != nil {
+        log.Println("failed to create elasticsearch categories index: ", err)
+        return err
+    }
+    return nil
+}
+
+// PutCategory waits for the write to become searchable, since the service
+// re-reads the whole tree on the next category change.
+func (r *elasticRepository) PutCategory(ctx context.Context, c Category) error {
+    _, err := r.client.Index(categoriesIndex).
+        Id(c.ID).
+        Request(c).
+        Refresh(refresh.Waitfor).
+        Do(ctx)
+    if err != nil {
+        log.Println("failed to index category: ", err)
+        return err
+    }
+    return nil
+}
+
+func (r *elasticRepository) GetCategoryByID(
+    ctx context.Context, id string,
+) (*Category, error) {
+    res, err := r.client.Get(categoriesIndex, id).Do(ctx)
+    if err != nil {
+        log.Println("failed to get category by id from catalog repository: ", err)
+        return nil, err
+    }
+    if !res.Found {
+        return nil, ErrNotFound
+    }
+
+    c := &Category{}
+    if err := json.Unmarshal(res.Source_, c); err != nil {
+        log.Println("failed to unmarshal category: ", err)
+        return nil, err
+    }
+    return c, nil
+}
+
+func (r *elasticRepository) ListCategories(ctx context.Context) ([]Category, error) {
+    size := maxCategories
+    res, err := r.client.Search().
+        Index(categoriesIndex).
+        Request(&search.Request{
+            Query: &types.Query{MatchAll: &types.MatchAllQuery{}},
+            Sort: []types.SortCombinations{"path"},
+            Size: &size,
+        }).Do(ctx)
+    if err != nil {
+        log.Println("failed to list categories from catalog repository: ", err)
+        return nil, err
+    }
+
+    categories := []Category{}
+    for _, hit := range res.Hits.Hits {
+        c := Category{}
+        if err = json.Unmarshal(hit.Source_, &c); err == nil {
+            categories = append(categories, c)
+        }
+    }
+    return categories, err
+}
+
+func (r *elasticRepository) DeleteCategory(ctx context.Context, id string) error {
+    _, err := r.client.Delete(categoriesIndex, id).Refresh(refresh.Waitfor).Do(ctx)
+    if err != nil {
+        log.Println("failed to delete category from catalog repository: ", err)
+        return err
+    }
+    return nil
+}
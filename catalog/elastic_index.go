@@ -0,0 +1,272 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/reindex"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/conflicts"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/versiontype"
+)
+
+// catalogAlias is the name every read and write goes through. It points at
+// one versioned physical index, e.g. catalog_v3.
+const catalogAlias = "catalog"
+
+//go:embed mapping.json
+var indexMapping []byte
+
+func newElasticClient(url string) (*elasticsearch.TypedClient, error) {
+    elasticCfg := elasticsearch.Config {
+        Addresses: []string{url},
+    }
+    client, err := elasticsearch.NewTypedClient(elasticCfg)
+    if err != nil {
+        log.Printf("failed to create elasticsearch client: %v", err)
+        return nil, err
+    }
+    return client, nil
+}
+
+// ensureIndex makes sure the catalog alias resolves to an index. An empty
+// cluster gets catalog_v1 created from mapping.json with the alias attached.
+// A legacy concrete "catalog" index is left alone until it is reindexed.
+func ensureIndex(ctx context.Context, client *elasticsearch.TypedClient) error {
+    exists, err := client.Indices.Exists(catalogAlias).Do(ctx)
+    if err != nil {
+        log.Println("failed to check index exists: ", err)
+        return err
+    }
+    if exists {
+        return nil
+    }
+
+    name := nextIndexName("")
+    if err := createIndex(ctx, client, name, true); err != nil {
+        return err
+    }
+    log.Println("create elasticsearch index success: ", name)
+    return nil
+}
+
+// currentIndex returns the physical index the catalog alias points at. The
+// second result is false when "catalog" is a legacy concrete index rather
+// than an alias.
+func currentIndex(
+    ctx context.Context, client *elasticsearch.TypedClient,
+) (string, bool, error) {
+    isAlias, err := client.Indices.ExistsAlias(catalogAlias).Do(ctx)
+    if err != nil {
+        log.Println("failed to check catalog alias exists: ", err)
+        return "", false, err
+    }
+    if !isAlias {
+        exists, err := client.Indices.Exists(catalogAlias).Do(ctx)
+        if err != nil {
+            log.Println("failed to check index exists: ", err)
+            return "", false, err
+        }
+        if !exists {
+            return "", false, ErrNotFound
+        }
+        return catalogAlias, false, nil
+    }
+
+    res, err := client.Indices.GetAlias().Name(catalogAlias).Do(ctx)
+    if err != nil {
+        log.Println("failed to get catalog alias: ", err)
+        return "", false, err
+    }
+    for index := range res {
+        return index, true, nil
+    }
+    return "", false, ErrNotFound
+}
+
+// nextIndexName returns the versioned index that follows current:
+// catalog_v1 for an empty cluster or the legacy index, catalog_v(N+1) after
+// catalog_vN.
+func nextIndexName(current string) string {
+    version := 0
+    if v, ok := strings.CutPrefix(current, catalogAlias+"_v"); ok {
+        version, _ = strconv.Atoi(v)
+    }
+    return fmt.Sprintf("%s_v%d", catalogAlias, version+1)
+}
+
+// createIndex creates name with the checked-in settings and mappings,
+// optionally attaching the catalog alias in the same request.
+func createIndex(
+    ctx context.Context, client *elasticsearch.TypedClient, name string, withAlias bool,
+) error {
+    // The search analyzer in mapping.json reads this synonyms set.
+    if err := ensureSynonymsSet(ctx, client); err != nil {
+        return err
+    }
+
+    body := map[string]json.RawMessage{}
+    if err := json.Unmarshal(indexMapping, &body); err != nil {
+        log.Println("failed to decode catalog index mapping: ", err)
+        return err
+    }
+    if withAlias {
+        body["aliases"] = json.RawMessage(fmt.Sprintf(`{%q:{}}`, catalogAlias))
+    }
+    raw, err := json.Marshal(body)
+    if err != nil {
+        return err
+    }
+
+    if _, err := client.Indices.Create(name).Raw(bytes.NewReader(raw)).Do(ctx); err != nil {
+        log.Println("failed to create elasticsearch catalog index: ", err)
+        return err
+    }
+    return nil
+}
+
+// ReindexElastic builds the next versioned index from mapping.json, copies
+// every product into it and atomically moves the catalog alias over, so
+// searches never see a missing or half-filled index. Copies keep the
+// products' versions, so a second pass picks up products created or updated
+// while the first one ran. Writes to the old index are blocked from that
+// pass until the alias moves and fail rather than get lost. It returns the
+// name of the new index.
+//
+// The previous index is kept for rollback unless deleteOld is set. A legacy
+// concrete "catalog" index is always removed, since the alias takes its name.
+func ReindexElastic(ctx context.Context, url string, deleteOld bool) (string, error) {
+    client, err := newElasticClient(url)
+    if err != nil {
+        return "", err
+    }
+
+    old, isAlias, err := currentIndex(ctx, client)
+    if err != nil {
+        log.Println("failed to resolve current catalog index: ", err)
+        return "", err
+    }
+
+    name := nextIndexName(old)
+    if err := createIndex(ctx, client, name, false); err != nil {
+        return "", err
+    }
+
+    copied, err := fillIndex(ctx, client, old, name, isAlias)
+    if err != nil {
+        // A leftover index would take the name of the next attempt.
+        if _, err := client.Indices.Delete(name).Do(context.WithoutCancel(ctx)); err != nil {
+            log.Println("failed to delete unused catalog index: ", err)
+        }
+        return "", err
+    }
+    log.Printf("catalog alias moved from %s to %s, %d products copied", old, name, copied)
+
+    if isAlias && deleteOld {
+        if _, err := client.Indices.Delete(old).Do(ctx); err != nil {
+            log.Println("failed to delete previous catalog index: ", err)
+            return name, err
+        }
+    } else if isAlias {
+        // Rolling back moves the alias back to a writable index.
+        if err := setWriteBlock(ctx, client, old, false); err != nil {
+            return name, err
+        }
+    }
+
+    return name, nil
+}
+
+// fillIndex copies the products of old into name in two passes and moves
+// the catalog alias over, returning the number copied by the first pass.
+// Writes to old stay blocked if it succeeds, for ReindexElastic to deal
+// with.
+func fillIndex(
+    ctx context.Context, client *elasticsearch.TypedClient, old, name string, isAlias bool,
+) (copied int64, err error) {
+    external := versiontype.External
+    res, err := client.Reindex().Request(&reindex.Request{
+        Source: types.ReindexSource{Index: []string{old}},
+        Dest:   types.ReindexDestination{Index: name, VersionType: &external},
+    }).WaitForCompletion(true).Do(ctx)
+    if err != nil {
+        log.Println("failed to copy products into new catalog index: ", err)
+        return 0, err
+    }
+    if len(res.Failures) > 0 {
+        return 0, fmt.Errorf("failed to copy %d products into %s", len(res.Failures), name)
+    }
+    if res.Total != nil {
+        copied = *res.Total
+    }
+
+    if err := setWriteBlock(ctx, client, old, true); err != nil {
+        return 0, err
+    }
+    defer func() {
+        if err != nil {
+            setWriteBlock(context.WithoutCancel(ctx), client, old, false)
+        }
+    }()
+
+    // Products the first pass already copied unchanged conflict and are
+    // skipped; newer versions overwrite theirs.
+    proceed := conflicts.Proceed
+    res, err = client.Reindex().Request(&reindex.Request{
+        Conflicts: &proceed,
+        Source:    types.ReindexSource{Index: []string{old}},
+        Dest:      types.ReindexDestination{Index: name, VersionType: &external},
+    }).WaitForCompletion(true).Do(ctx)
+    if err != nil {
+        log.Println("failed to catch up new catalog index: ", err)
+        return 0, err
+    }
+    if len(res.Failures) > 0 {
+        return 0, fmt.Errorf("failed to catch up %d products in %s", len(res.Failures), name)
+    }
+
+    if _, err := client.Indices.Refresh().Index(name).Do(ctx); err != nil {
+        log.Println("failed to refresh new catalog index: ", err)
+        return 0, err
+    }
+
+    alias := catalogAlias
+    actions := []types.IndicesAction{
+        {Add: &types.AddAction{Index: &name, Alias: &alias}},
+    }
+    if isAlias {
+        actions = append(actions, types.IndicesAction{
+            Remove: &types.RemoveAction{Index: &old, Alias: &alias},
+        })
+    } else {
+        actions = append(actions, types.IndicesAction{
+            RemoveIndex: &types.RemoveIndexAction{Index: &old},
+        })
+    }
+    if _, err := client.Indices.UpdateAliases().Actions(actions...).Do(ctx); err != nil {
+        log.Println("failed to swap catalog alias: ", err)
+        return 0, err
+    }
+    return copied, nil
+}
+
+// setWriteBlock blocks or unblocks writes to index.
+func setWriteBlock(
+    ctx context.Context, client *elasticsearch.TypedClient, index string, blocked bool,
+) error {
+    _, err := client.Indices.PutSettings().Indices(index).
+        Blocks(&types.IndexSettingBlocks{Write: blocked}).
+        Do(ctx)
+    if err != nil {
+        log.Println("failed to set write block of catalog index: ", err)
+        return err
+    }
+    return nil
+}
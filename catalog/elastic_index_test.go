@@ -0,0 +1,162 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNextIndexName(t *testing.T) {
+    for current, want := range map[string]string{
+        "":            "catalog_v1",
+        "catalog":     "catalog_v1",
+        "catalog_v1":  "catalog_v2",
+        "catalog_v41": "catalog_v42",
+    } {
+        if got := nextIndexName(current); got != want {
+            t.Errorf("nextIndexName(%q) = %q, want %q", current, got, want)
+        }
+    }
+}
+
+func TestReindexElastic(t *testing.T) {
+    f := newFakeElastic(t)
+    f.createAlias("catalog", "catalog_v1")
+    f.putDocument("catalog", "p1", productDocument{Name: "Mug", Price: 9.5})
+    f.putDocument("catalog", "p2", productDocument{Name: "Beans", Price: 20})
+
+    name, err := ReindexElastic(context.Background(), f.URL(), false)
+    if err != nil {
+        t.Fatal("failed to reindex: ", err)
+    }
+
+    if name != "catalog_v2" {
+        t.Fatalf("expected catalog_v2, got %q", name)
+    }
+    if _, ok := f.lastBody(http.MethodPut, "/catalog_v2")["mappings"]; !ok {
+        t.Fatal("expected catalog_v2 to be created with the checked-in mapping")
+    }
+    if got := f.aliasTarget("catalog"); got != "catalog_v2" {
+        t.Fatalf("expected catalog alias to point at catalog_v2, got %q", got)
+    }
+    if got := f.documentIDs("catalog_v2"); !reflect.DeepEqual(got, []string{"p1", "p2"}) {
+        t.Fatalf("unexpected documents in catalog_v2: %v", got)
+    }
+    if !f.hasIndex("catalog_v1") {
+        t.Fatal("expected catalog_v1 to be kept for rollback")
+    }
+}
+
+func TestReindexElasticCatchesUpWithWritesBlocked(t *testing.T) {
+    f := newFakeElastic(t)
+    f.createAlias("catalog", "catalog_v1")
+    f.putDocument("catalog", "p1", productDocument{Name: "Mug", Price: 9.5})
+
+    if _, err := ReindexElastic(context.Background(), f.URL(), false); err != nil {
+        t.Fatal("failed to reindex: ", err)
+    }
+
+    f.mu.Lock()
+    requests := f.requests
+    f.mu.Unlock()
+
+    steps := []string{}
+    for _, r := range requests {
+        switch {
+        case r.Path == "/_reindex":
+            body := map[string]any{}
+            json.Unmarshal(r.Body, &body)
+            dest := body["dest"].(map[string]any)
+            if dest["version_type"] != "external" || dest["op_type"] != nil {
+                t.Fatalf("expected copies to keep product versions, got %v", dest)
+            }
+            steps = append(steps, "reindex")
+        case r.Path == "/catalog_v1/_settings":
+            steps = append(steps, "settings "+string(r.Body))
+        case r.Path == "/_aliases":
+            steps = append(steps, "swap")
+        }
+    }
+    want := []string{
+        "reindex",
+        `settings {"blocks":{"write":true}}`,
+        "reindex",
+        "swap",
+        `settings {"blocks":{"write":false}}`,
+    }
+    if !reflect.DeepEqual(steps, want) {
+        t.Fatalf("expected the catch-up and swap to run with writes blocked, got %v", steps)
+    }
+    if f.isWriteBlocked("catalog_v1") {
+        t.Fatal("expected catalog_v1 to be writable again for rollback")
+    }
+}
+
+func TestReindexElasticCleansUpFailedAttempts(t *testing.T) {
+    for _, failing := range []int{1, 2} {
+        f := newFakeElastic(t)
+        f.createAlias("catalog", "catalog_v1")
+        f.putDocument("catalog", "p1", productDocument{Name: "Mug", Price: 9.5})
+        f.failReindex = failing
+
+        _, err := ReindexElastic(context.Background(), f.URL(), false)
+        if err == nil || !strings.Contains(err.Error(), "products") {
+            t.Fatalf("expected a failure of copy pass %d to fail the reindex, got %v", failing, err)
+        }
+        if f.hasIndex("catalog_v2") || f.aliasTarget("catalog") != "catalog_v1" || f.isWriteBlocked("catalog_v1") {
+            t.Fatalf("expected pass %d to leave catalog_v1 as it was", failing)
+        }
+
+        // The next attempt can take the same name.
+        f.mu.Lock()
+        f.failReindex = 0
+        f.mu.Unlock()
+        if name, err := ReindexElastic(context.Background(), f.URL(), false); err != nil || name != "catalog_v2" {
+            t.Fatalf("expected a retry to reindex into catalog_v2, got %q, %v", name, err)
+        }
+    }
+}
+
+func TestReindexElasticDeletesOldIndex(t *testing.T) {
+    f := newFakeElastic(t)
+    f.createAlias("catalog", "catalog_v4")
+
+    if _, err := ReindexElastic(context.Background(), f.URL(), true); err != nil {
+        t.Fatal("failed to reindex: ", err)
+    }
+
+    if f.hasIndex("catalog_v4") {
+        t.Fatal("expected catalog_v4 to be deleted")
+    }
+    if got := f.aliasTarget("catalog"); got != "catalog_v5" {
+        t.Fatalf("expected catalog alias to point at catalog_v5, got %q", got)
+    }
+}
+
+func TestReindexElasticMigratesLegacyIndex(t *testing.T) {
+    f := newFakeElastic(t)
+    f.putDocument("catalog", "p1", productDocument{Name: "Mug", Price: 9.5})
+
+    name, err := ReindexElastic(context.Background(), f.URL(), false)
+    if err != nil {
+        t.Fatal("failed to reindex: ", err)
+    }
+
+    if name != "catalog_v1" {
+        t.Fatalf("expected catalog_v1, got %q", name)
+    }
+    body := f.lastBody(http.MethodPost, "/_aliases")
+    actions := body["actions"].([]any)
+    if len(actions) != 2 {
+        t.Fatalf("expected add and remove_index in one request, got %v", actions)
+    }
+    if _, ok := actions[1].(map[string]any)["remove_index"]; !ok {
+        t.Fatalf("expected the legacy index to be removed atomically, got %v", actions)
+    }
+    if got := f.documentIDs("catalog"); !reflect.DeepEqual(got, []string{"p1"}) {
+        t.Fatalf("unexpected documents behind catalog alias: %v", got)
+    }
+}
@@ -0,0 +1,272 @@
+package catalog
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/fieldtype"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/sortorder"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/suggestmode"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/textquerytype"
+)
+
+// searchFields are the fields the free-text query is scored against; a
+// match in the name counts three times as much as one in the description.
+var searchFields = []string{"name^3", "description"}
+
+const (
+    // didYouMeanField holds word shingles of the name for the phrase
+    // suggester.
+    didYouMeanField = "name.shingle"
+    // fuzzyPrefixLength leading characters of a term must match exactly,
+    // which keeps fuzzy queries from expanding to most of the index.
+    fuzzyPrefixLength = 1
+)
+
+// searchQuery scores the free-text query against name and description and
+// applies price, category and tag constraints as non-scoring filters.
+func searchQuery(req SearchRequest) *types.Query {
+    must := []types.Query{{MatchAll: &types.MatchAllQuery{}}}
+    if req.Query != "" {
+        fuzziness := "AUTO"
+        if req.Fuzziness != nil {
+            fuzziness = strconv.Itoa(*req.Fuzziness)
+        }
+        prefixLength := fuzzyPrefixLength
+        must = []types.Query{{
+            MultiMatch: &types.MultiMatchQuery{
+                Fields: searchFields,
+                Query: req.Query,
+                Fuzziness: fuzziness,
+                PrefixLength: &prefixLength,
+            },
+        }}
+    }
+
+    filter := []types.Query{}
+    if req.MinPrice != nil || req.MaxPrice != nil {
+        filter = append(filter, types.Query{
+            Range: map[string]types.RangeQuery{
+                "price": types.NumberRangeQuery{
+                    Gte: (*types.Float64)(req.MinPrice),
+                    Lte: (*types.Float64)(req.MaxPrice),
+                },
+            },
+        })
+    }
+    if req.MinRating != nil {
+        filter = append(filter, types.Query{
+            Range: map[string]types.RangeQuery{
+                "rating": types.NumberRangeQuery{Gte: (*types.Float64)(req.MinRating)},
+            },
+        })
+    }
+    if len(req.CategoryIDs) != 0 {
+        values := []types.FieldValue{}
+        for _, id := range req.CategoryIDs {
+            values = append(values, id)
+        }
+        filter = append(filter, types.Query{
+            Terms: &types.TermsQuery{
+                TermsQuery: map[string]types.TermsQueryField{"category_ids": values},
+            },
+        })
+    }
+    for _, tag := range req.Tags {
+        filter = append(filter, types.Query{
+            Term: map[string]types.TermQuery{"tags": {Value: tag}},
+        })
+    }
+
+    return &types.Query{Bool: &types.BoolQuery{Must: must, Filter: filter}}
+}
+
+// searchSort returns nil for relevance, leaving elasticsearch to order by
+// score.
+func searchSort(sort SearchSort) []types.SortCombinations {
+    field, order := "", sortorder.Asc
+    unmapped := fieldtype.Keyword
+    switch sort {
+    case SortPriceAsc:
+        field, unmapped = "price", fieldtype.Double
+    case SortPriceDesc:
+        field, order, unmapped = "price", sortorder.Desc, fieldtype.Double
+    case SortNameAsc:
+        field = "name.keyword"
+    case SortNameDesc:
+        field, order = "name.keyword", sortorder.Desc
+    case SortNewest:
+        field, order, unmapped = "created_at", sortorder.Desc, fieldtype.Date
+    case SortRatingDesc:
+        desc, unmappedFloat, unmappedInteger := sortorder.Desc, fieldtype.Float, fieldtype.Integer
+        return []types.SortCombinations{
+            types.SortOptions{SortOptions: map[string]types.FieldSort{
+                "rating": {Order: &desc, UnmappedType: &unmappedFloat},
+            }},
+            types.SortOptions{SortOptions: map[string]types.FieldSort{
+                "review_count": {Order: &desc, UnmappedType: &unmappedInteger},
+            }},
+        }
+    default:
+        return nil
+    }
+
+    return []types.SortCombinations{
+        types.SortOptions{SortOptions: map[string]types.FieldSort{
+            field: {Order: &order, UnmappedType: &unmapped},
+        }},
+    }
+}
+
+// searchHighlight returns the whole name and up to three description
+// fragments with the matched terms wrapped in <em>.
+func searchHighlight() *types.Highlight {
+    whole, fragments, fragmentSize := 0, 3, 150
+    return &types.Highlight{
+        Fields: map[string]types.HighlightField{
+            "name": {NumberOfFragments: &whole},
+            "description": {NumberOfFragments: &fragments, FragmentSize: &fragmentSize},
+        },
+        PreTags: []string{"<em>"},
+        PostTags: []string{"</em>"},
+    }
+}
+
+// searchSuggester asks the phrase suggester for the single most likely
+// correction of query, built from terms that occur in product names.
+func searchSuggester(query string) *types.Suggester {
+    size, gramSize := 1, 3
+    return &types.Suggester{
+        Text: &query,
+        Suggesters: map[string]types.FieldSuggester{
+            "did_you_mean": {
+                Phrase: &types.PhraseSuggester{
+                    Field: didYouMeanField,
+                    Size: &size,
+                    GramSize: &gramSize,
+                    DirectGenerator: []types.DirectGenerator{{
+                        Field: didYouMeanField,
+                        SuggestMode: &suggestmode.Always,
+                    }},
+                },
+            },
+        },
+    }
+}
+
+// searchHighlights collects the highlighted fields of every hit, in the
+// order of searchFields.
+func searchHighlights(hits []types.Hit) map[string][]Highlight {
+    highlights := map[string][]Highlight{}
+    for _, hit := range hits {
+        for _, field := range []string{"name", "description"} {
+            if fragments := hit.Highlight[field]; len(fragments) != 0 {
+                highlights[*hit.Id_] = append(highlights[*hit.Id_], Highlight{
+                    Field: field,
+                    Fragments: fragments,
+                })
+            }
+        }
+    }
+    return highlights
+}
+
+// didYouMean returns the phrase suggestion for query, or "" if there is
+// none or it only differs in case.
+func didYouMean(suggest map[string][]types.Suggest, query string) string {
+    for _, s := range suggest["did_you_mean"] {
+        phrase, ok := s.(*types.PhraseSuggest)
+        if !ok || len(phrase.Options) == 0 {
+            continue
+        }
+        if text := phrase.Options[0].Text; !strings.EqualFold(text, strings.TrimSpace(query)) {
+            return text
+        }
+    }
+    return ""
+}
+
+func searchAggregations() map[string]types.Aggregations {
+    priceField, tagsField, tagsSize := "price", "tags", maxTagFacets
+
+    ranges := []types.AggregationRange{}
+    for _, r := range priceRanges {
+        ranges = append(ranges, types.AggregationRange{
+            From: (*types.Float64)(r.From),
+            To: (*types.Float64)(r.To),
+        })
+    }
+
+    return map[string]types.Aggregations{
+        "prices": {Range: &types.RangeAggregation{Field: &priceField, Ranges: ranges}},
+        "tags": {Terms: &types.TermsAggregation{Field: &tagsField, Size: &tagsSize}},
+    }
+}
+
+func searchFacets(aggregations map[string]types.Aggregate) Facets {
+    facets := Facets{Prices: []PriceBucket{}, Tags: []TagCount{}}
+
+    if prices, ok := aggregations["prices"].(*types.RangeAggregate); ok {
+        if buckets, ok := prices.Buckets.([]types.RangeBucket); ok {
+            for _, b := range buckets {
+                facets.Prices = append(facets.Prices, PriceBucket{
+                    From: (*float64)(b.From),
+                    To: (*float64)(b.To),
+                    Count: uint64(b.DocCount),
+                })
+            }
+        }
+    }
+
+    if tags, ok := aggregations["tags"].(*types.StringTermsAggregate); ok {
+        if buckets, ok := tags.Buckets.([]types.StringTermsBucket); ok {
+            for _, b := range buckets {
+                tag, _ := b.Key.(string)
+                facets.Tags = append(facets.Tags, TagCount{
+                    Tag: tag,
+                    Count: uint64(b.DocCount),
+                })
+            }
+        }
+    }
+
+    return facets
+}
+
+// suggestTimeout bounds how long each shard may spend on a suggestion
+// query; autocomplete prefers a partial answer over a late one.
+const suggestTimeout = "150ms"
+
+// suggestRequest matches prefix against the search_as_you_type subfield of
+// name, treating the last term as a prefix, and highlights the matched
+// parts. Only the name is fetched and hits are not counted.
+func suggestRequest(prefix string, limit int) *search.Request {
+    timeout := suggestTimeout
+    fragments := 0
+    return &search.Request{
+        Query: &types.Query{
+            MultiMatch: &types.MultiMatchQuery{
+                Query: prefix,
+                Type: &textquerytype.Boolprefix,
+                Fields: []string{
+                    "name.suggest",
+                    "name.suggest._2gram",
+                    "name.suggest._3gram",
+                },
+            },
+        },
+        Highlight: &types.Highlight{
+            Fields: map[string]types.HighlightField{
+                "name.suggest": {NumberOfFragments: &fragments},
+            },
+            PreTags: []string{"<em>"},
+            PostTags: []string{"</em>"},
+        },
+        Source_: types.SourceFilter{Includes: []string{"name"}},
+        Size: &limit,
+        Timeout: &timeout,
+        TrackTotalHits: false,
+    }
+}
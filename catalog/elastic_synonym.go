@@ -0,0 +1,151 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/synonyms/putsynonym"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/refresh"
+)
+
+// synonymsSet is read by the product_synonyms filter of the search analyzer.
+// Its rules are reloaded on every change, so they apply without a reindex.
+const synonymsSet = "catalog-synonyms"
+
+// maxSynonymRules bounds how many rules ListSynonymRules reads back.
+const maxSynonymRules = 10000
+
+// searchSettingsIndex holds search tuning that isn't part of the analyzers,
+// one document per setting.
+const searchSettingsIndex = "catalog_search_settings"
+
+const stopwordsDocument = "stopwords"
+
+const searchSettingsMapping = `{
+  "mappings": {
+    "properties": {
+      "words": {"type": "keyword"}
+    }
+  }
+}`
+
+type stopwordsSetting struct {
+    Words []string `json:"words"`
+}
+
+// ensureSynonymsSet creates an empty synonyms set, since an index whose
+// analyzer reads a missing set can't be created.
+func ensureSynonymsSet(ctx context.Context, client *elasticsearch.TypedClient) error {
+    exists, err := client.Synonyms.GetSynonym(synonymsSet).IsSuccess(ctx)
+    if err != nil {
+        log.Println("failed to check synonyms set exists: ", err)
+        return err
+    }
+    if exists {
+        return nil
+    }
+
+    _, err = client.Synonyms.PutSynonym(synonymsSet).
+        Request(&putsynonym.Request{SynonymsSet: []types.SynonymRule{}}).
+        Do(ctx)
+    if err != nil {
+        log.Println("failed to create elasticsearch synonyms set: ", err)
+        return err
+    }
+    return nil
+}
+
+func ensureSearchSettingsIndex(ctx context.Context, client *elasticsearch.TypedClient) error {
+    exists, err := client.Indices.Exists(searchSettingsIndex).Do(ctx)
+    if err != nil {
+        log.Println("failed to check search settings index exists: ", err)
+        return err
+    }
+    if exists {
+        return nil
+    }
+
+    _, err = client.Indices.Create(searchSettingsIndex).
+        Raw(strings.NewReader(searchSettingsMapping)).
+        Do(ctx)
+    if err != nil {
+        log.Println("failed to create elasticsearch search settings index: ", err)
+        return err
+    }
+    return nil
+}
+
+func (r *elasticRepository) ListSynonymRules(ctx context.Context) ([]SynonymRule, error) {
+    res, err := r.client.Synonyms.GetSynonym(synonymsSet).Size(maxSynonymRules).Do(ctx)
+    if err != nil {
+        log.Println("failed to list synonym rules from catalog repository: ", err)
+        return nil, err
+    }
+
+    rules := []SynonymRule{}
+    for _, rule := range res.SynonymsSet {
+        rules = append(rules, SynonymRule{ID: rule.Id, Terms: normalizeSynonyms([]string{rule.Synonyms})})
+    }
+    return rules, nil
+}
+
+func (r *elasticRepository) PutSynonymRule(ctx context.Context, rule SynonymRule) error {
+    _, err := r.client.Synonyms.PutSynonymRule(synonymsSet, rule.ID).
+        Synonyms(strings.Join(rule.Terms, ", ")).
+        Do(ctx)
+    if err != nil {
+        log.Println("failed to put synonym rule from catalog repository: ", err)
+        return err
+    }
+    return nil
+}
+
+func (r *elasticRepository) DeleteSynonymRule(ctx context.Context, id string) error {
+    _, err := r.client.Synonyms.DeleteSynonymRule(synonymsSet, id).Do(ctx)
+    var esErr *types.ElasticsearchError
+    if errors.As(err, &esErr) && esErr.Status == http.StatusNotFound {
+        return ErrNotFound
+    }
+    if err != nil {
+        log.Println("failed to delete synonym rule from catalog repository: ", err)
+        return err
+    }
+    return nil
+}
+
+func (r *elasticRepository) GetStopwords(ctx context.Context) ([]string, error) {
+    res, err := r.client.Get(searchSettingsIndex, stopwordsDocument).Do(ctx)
+    if err != nil {
+        log.Println("failed to get stopwords from catalog repository: ", err)
+        return nil, err
+    }
+    if !res.Found {
+        return []string{}, nil
+    }
+
+    setting := stopwordsSetting{}
+    if err := json.Unmarshal(res.Source_, &setting); err != nil {
+        log.Println("failed to unmarshal stopwords: ", err)
+        return nil, err
+    }
+    return setting.Words, nil
+}
+
+func (r *elasticRepository) PutStopwords(ctx context.Context, words []string) error {
+    _, err := r.client.Index(searchSettingsIndex).
+        Id(stopwordsDocument).
+        Request(stopwordsSetting{Words: words}).
+        Refresh(refresh.Waitfor).
+        Do(ctx)
+    if err != nil {
+        log.Println("failed to put stopwords from catalog repository: ", err)
+        return err
+    }
+    return nil
+}
@@ -0,0 +1,874 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeElastic is an in-process stand-in for the parts of the Elasticsearch
+// REST API the catalog repository uses. It stores documents per index,
+// resolves aliases, records every request, and answers searches either with
+// canned hits or with the stored documents matched by an ids query.
+type fakeElastic struct {
+    t      *testing.T
+    server *httptest.Server
+
+    mu       sync.Mutex
+    indices  map[string]map[string]json.RawMessage
+    aliases  map[string]string
+    pits     map[string]string
+    // synonyms maps synonyms set ids to their rules, by rule id.
+    synonyms map[string]map[string]string
+    requests []fakeRequest
+
+    // cannedHits, when set, is returned as is by every _search instead of
+    // the stored documents.
+    cannedHits []fakeHit
+    // cannedAggregations is returned as the aggregations of every _search,
+    // keyed like typed_keys responses, e.g. "sterms#tags".
+    cannedAggregations map[string]any
+    // cannedSuggest is returned as the suggest section of every _search,
+    // keyed like typed_keys responses, e.g. "phrase#did_you_mean".
+    cannedSuggest map[string]any
+    // rejectIDs lists document ids every bulk index action fails for.
+    rejectIDs map[string]bool
+    // writeBlocked lists indices whose documents can't be written.
+    writeBlocked map[string]bool
+    // failReindex is the 1-based number of the _reindex request that
+    // reports a failed document, 0 for none.
+    failReindex int
+    reindexes   int
+}
+
+type fakeRequest struct {
+    Method string
+    Path   string
+    Body   []byte
+}
+
+type fakeHit struct {
+    ID        string
+    Source    any
+    Highlight map[string][]string
+}
+
+// fakeNestedQuery is the subset of a nested query the fake evaluates: term
+// matches on fields of the nested objects.
+type fakeNestedQuery struct {
+    Path  string `json:"path"`
+    Query struct {
+        Term map[string]struct {
+            Value any `json:"value"`
+        } `json:"term"`
+    } `json:"query"`
+}
+
+func newFakeElastic(t *testing.T) *fakeElastic {
+    t.Helper()
+
+    f := &fakeElastic{
+        t:       t,
+        indices:  map[string]map[string]json.RawMessage{},
+        aliases:  map[string]string{},
+        pits:     map[string]string{},
+        synonyms: map[string]map[string]string{},
+        writeBlocked: map[string]bool{},
+    }
+    f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+    t.Cleanup(f.server.Close)
+    return f
+}
+
+func (f *fakeElastic) URL() string {
+    return f.server.URL
+}
+
+// createIndex makes the index exist before the repository is created.
+func (f *fakeElastic) createIndex(name string) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+
+    if f.indices[name] == nil {
+        f.indices[name] = map[string]json.RawMessage{}
+    }
+}
+
+// createAlias points alias at index, creating the index if needed.
+func (f *fakeElastic) createAlias(alias, index string) {
+    f.createIndex(index)
+
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.aliases[alias] = index
+}
+
+// aliasTarget returns the index alias points at, or "" if there is none.
+func (f *fakeElastic) aliasTarget(alias string) string {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return f.aliases[alias]
+}
+
+func (f *fakeElastic) hasIndex(name string) bool {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return f.indices[name] != nil
+}
+
+func (f *fakeElastic) documentIDs(index string) []string {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+
+    ids := []string{}
+    for id := range f.indices[f.resolve(index)] {
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+    return ids
+}
+
+func (f *fakeElastic) openPointsInTime() int {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return len(f.pits)
+}
+
+func (f *fakeElastic) putDocument(index, id string, source any) {
+    b, err := json.Marshal(source)
+    if err != nil {
+        f.t.Fatal("failed to marshal fake document: ", err)
+    }
+
+    f.mu.Lock()
+    defer f.mu.Unlock()
+
+    index = f.resolve(index)
+    if f.indices[index] == nil {
+        f.indices[index] = map[string]json.RawMessage{}
+    }
+    f.indices[index][id] = b
+}
+
+// resolve maps an alias to its index; other names are returned unchanged.
+// Callers must hold f.mu.
+func (f *fakeElastic) resolve(name string) string {
+    if index, ok := f.aliases[name]; ok {
+        return index
+    }
+    return name
+}
+
+// requestsTo returns the recorded requests with the given method whose path
+// ends with suffix.
+func (f *fakeElastic) requestsTo(method, suffix string) []fakeRequest {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+
+    requests := []fakeRequest{}
+    for _, r := range f.requests {
+        if r.Method == method && strings.HasSuffix(r.Path, suffix) {
+            requests = append(requests, r)
+        }
+    }
+    return requests
+}
+
+// lastBody decodes the body of the last request matching method and suffix.
+func (f *fakeElastic) lastBody(method, suffix string) map[string]any {
+    f.t.Helper()
+
+    requests := f.requestsTo(method, suffix)
+    if len(requests) == 0 {
+        f.t.Fatalf("no %s request to %s was recorded", method, suffix)
+    }
+
+    body := map[string]any{}
+    if err := json.Unmarshal(requests[len(requests)-1].Body, &body); err != nil {
+        f.t.Fatal("failed to decode recorded request body: ", err)
+    }
+    return body
+}
+
+func (f *fakeElastic) handle(w http.ResponseWriter, r *http.Request) {
+    body, _ := io.ReadAll(r.Body)
+
+    f.mu.Lock()
+    defer f.mu.Unlock()
+
+    f.requests = append(f.requests, fakeRequest{
+        Method: r.Method,
+        Path:   r.URL.Path,
+        Body:   body,
+    })
+
+    w.Header().Set("X-Elastic-Product", "Elasticsearch")
+    w.Header().Set("Content-Type", "application/json")
+
+    parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+    switch {
+    case len(parts) == 2 && parts[0] == "_synonyms" && r.Method == http.MethodGet:
+        f.getSynonyms(w, parts[1])
+    case len(parts) == 2 && parts[0] == "_synonyms" && r.Method == http.MethodPut:
+        f.synonyms[parts[1]] = map[string]string{}
+        f.write(w, http.StatusOK, map[string]any{"result": "created"})
+    case len(parts) == 3 && parts[0] == "_synonyms" && r.Method == http.MethodPut:
+        f.putSynonymRule(w, parts[1], parts[2], body)
+    case len(parts) == 3 && parts[0] == "_synonyms" && r.Method == http.MethodDelete:
+        f.deleteSynonymRule(w, parts[1], parts[2])
+    case len(parts) == 2 && parts[0] == "_alias" && r.Method == http.MethodHead:
+        if _, ok := f.aliases[parts[1]]; !ok {
+            w.WriteHeader(http.StatusNotFound)
+        }
+    case len(parts) == 2 && parts[0] == "_alias" && r.Method == http.MethodGet:
+        f.getAlias(w, parts[1])
+    case len(parts) == 1 && parts[0] == "_aliases" && r.Method == http.MethodPost:
+        f.updateAliases(w, body)
+    case len(parts) == 1 && parts[0] == "_reindex" && r.Method == http.MethodPost:
+        f.reindex(w, body)
+    case len(parts) == 1 && parts[0] == "_pit" && r.Method == http.MethodDelete:
+        f.closePointInTime(w, body)
+    case len(parts) == 1 && r.Method == http.MethodHead:
+        if f.indices[f.resolve(parts[0])] == nil {
+            w.WriteHeader(http.StatusNotFound)
+        }
+    case len(parts) == 1 && r.Method == http.MethodPut:
+        f.createIndexRequest(w, parts[0], body)
+    case len(parts) == 1 && r.Method == http.MethodDelete:
+        delete(f.indices, parts[0])
+        f.write(w, http.StatusOK, map[string]any{"acknowledged": true})
+    case len(parts) == 2 && parts[1] == "_settings" && r.Method == http.MethodPut:
+        f.putSettings(w, f.resolve(parts[0]), body)
+    case len(parts) == 2 && parts[1] == "_refresh":
+        f.write(w, http.StatusOK, map[string]any{
+            "_shards": map[string]any{"total": 1, "successful": 1, "failed": 0},
+        })
+    case len(parts) == 2 && parts[1] == "_doc" && r.Method == http.MethodPost:
+        f.index(w, f.resolve(parts[0]), fmt.Sprintf("auto-%d", len(f.requests)), body)
+    case len(parts) == 2 && parts[1] == "_count":
+        f.write(w, http.StatusOK, map[string]any{"count": len(f.indices[f.resolve(parts[0])])})
+    case len(parts) == 3 && parts[1] == "_doc" && r.Method == http.MethodPut:
+        f.index(w, f.resolve(parts[0]), parts[2], body)
+    case len(parts) == 3 && parts[1] == "_doc" && r.Method == http.MethodHead:
+        if _, ok := f.indices[f.resolve(parts[0])][parts[2]]; !ok {
+            w.WriteHeader(http.StatusNotFound)
+        }
+    case len(parts) == 3 && parts[1] == "_doc" && r.Method == http.MethodGet:
+        f.get(w, f.resolve(parts[0]), parts[2])
+    case len(parts) == 3 && parts[1] == "_update" && r.Method == http.MethodPost:
+        f.update(w, f.resolve(parts[0]), parts[2], body)
+    case len(parts) == 3 && parts[1] == "_doc" && r.Method == http.MethodDelete:
+        f.deleteDocument(w, f.resolve(parts[0]), parts[2])
+    case len(parts) == 2 && parts[1] == "_search":
+        f.search(w, f.resolve(parts[0]), body)
+    case len(parts) == 1 && parts[0] == "_search":
+        f.searchPointInTime(w, body)
+    case len(parts) == 2 && parts[1] == "_bulk":
+        f.bulk(w, f.resolve(parts[0]), body)
+    case len(parts) == 2 && parts[1] == "_pit" && r.Method == http.MethodPost:
+        f.openPointInTime(w, f.resolve(parts[0]))
+    case len(parts) == 2 && parts[1] == "_mget":
+        f.mget(w, f.resolve(parts[0]), body)
+    default:
+        f.write(w, http.StatusBadRequest, map[string]any{
+            "error":  fmt.Sprintf("fake elasticsearch: unsupported %s %s", r.Method, r.URL.Path),
+            "status": http.StatusBadRequest,
+        })
+    }
+}
+
+func (f *fakeElastic) createIndexRequest(w http.ResponseWriter, index string, body []byte) {
+    req := struct {
+        Aliases map[string]any `json:"aliases"`
+    }{}
+    json.Unmarshal(body, &req)
+
+    f.indices[index] = map[string]json.RawMessage{}
+    for alias := range req.Aliases {
+        f.aliases[alias] = index
+    }
+    f.write(w, http.StatusOK, map[string]any{
+        "acknowledged":        true,
+        "shards_acknowledged": true,
+        "index":               index,
+    })
+}
+
+func (f *fakeElastic) getAlias(w http.ResponseWriter, alias string) {
+    index, ok := f.aliases[alias]
+    if !ok {
+        f.write(w, http.StatusNotFound, map[string]any{
+            "error":  fmt.Sprintf("alias [%s] missing", alias),
+            "status": http.StatusNotFound,
+        })
+        return
+    }
+    f.write(w, http.StatusOK, map[string]any{
+        index: map[string]any{"aliases": map[string]any{alias: map[string]any{}}},
+    })
+}
+
+func (f *fakeElastic) updateAliases(w http.ResponseWriter, body []byte) {
+    type target struct {
+        Index string `json:"index"`
+        Alias string `json:"alias"`
+    }
+    req := struct {
+        Actions []struct {
+            Add         *target `json:"add"`
+            Remove      *target `json:"remove"`
+            RemoveIndex *target `json:"remove_index"`
+        } `json:"actions"`
+    }{}
+    json.Unmarshal(body, &req)
+
+    for _, a := range req.Actions {
+        switch {
+        case a.Add != nil:
+            f.aliases[a.Add.Alias] = a.Add.Index
+        case a.Remove != nil:
+            if f.aliases[a.Remove.Alias] == a.Remove.Index {
+                delete(f.aliases, a.Remove.Alias)
+            }
+        case a.RemoveIndex != nil:
+            delete(f.indices, a.RemoveIndex.Index)
+        }
+    }
+    f.write(w, http.StatusOK, map[string]any{"acknowledged": true})
+}
+
+func (f *fakeElastic) reindex(w http.ResponseWriter, body []byte) {
+    req := struct {
+        Source struct {
+            Index []string `json:"index"`
+        } `json:"source"`
+        Dest struct {
+            Index  string `json:"index"`
+            OpType string `json:"op_type"`
+        } `json:"dest"`
+    }{}
+    json.Unmarshal(body, &req)
+
+    dest := f.indices[f.resolve(req.Dest.Index)]
+    created, conflicts := 0, 0
+    for _, source := range req.Source.Index {
+        for id, doc := range f.indices[f.resolve(source)] {
+            if _, ok := dest[id]; ok && req.Dest.OpType == "create" {
+                conflicts++
+                continue
+            }
+            dest[id] = doc
+            created++
+        }
+    }
+    failures := []any{}
+    f.reindexes++
+    if f.reindexes == f.failReindex {
+        failures = append(failures, map[string]any{
+            "index":  req.Dest.Index,
+            "id":     "failed",
+            "status": http.StatusBadRequest,
+            "cause":  map[string]any{"type": "mapper_parsing_exception", "reason": "failed to parse"},
+        })
+    }
+    f.write(w, http.StatusOK, map[string]any{
+        "took":              1,
+        "timed_out":         false,
+        "total":             created + conflicts,
+        "created":           created,
+        "version_conflicts": conflicts,
+        "failures":          failures,
+    })
+}
+
+// putSettings applies the write block setting and ignores the others.
+func (f *fakeElastic) putSettings(w http.ResponseWriter, index string, body []byte) {
+    req := struct {
+        Blocks struct {
+            Write *bool `json:"write"`
+        } `json:"blocks"`
+    }{}
+    json.Unmarshal(body, &req)
+
+    if req.Blocks.Write != nil {
+        f.writeBlocked[index] = *req.Blocks.Write
+    }
+    f.write(w, http.StatusOK, map[string]any{"acknowledged": true})
+}
+
+func (f *fakeElastic) blocked(w http.ResponseWriter, index string) bool {
+    if !f.writeBlocked[index] {
+        return false
+    }
+    f.write(w, http.StatusForbidden, map[string]any{
+        "error": map[string]any{
+            "type":   "cluster_block_exception",
+            "reason": "index [" + index + "] blocked by: [FORBIDDEN/8/index write (api)];",
+        },
+        "status": http.StatusForbidden,
+    })
+    return true
+}
+
+func (f *fakeElastic) isWriteBlocked(index string) bool {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return f.writeBlocked[index]
+}
+
+func (f *fakeElastic) index(w http.ResponseWriter, index, id string, body []byte) {
+    if f.blocked(w, index) {
+        return
+    }
+    if f.indices[index] == nil {
+        f.indices[index] = map[string]json.RawMessage{}
+    }
+    result := "created"
+    if _, ok := f.indices[index][id]; ok {
+        result = "updated"
+    }
+    f.indices[index][id] = body
+
+    f.write(w, http.StatusOK, map[string]any{
+        "_index":        index,
+        "_id":           id,
+        "_version":      1,
+        "result":        result,
+        "_shards":       map[string]any{"total": 1, "successful": 1, "failed": 0},
+        "_seq_no":       0,
+        "_primary_term": 1,
+    })
+}
+
+func (f *fakeElastic) get(w http.ResponseWriter, index, id string) {
+    source, ok := f.indices[index][id]
+    if !ok {
+        f.write(w, http.StatusNotFound, map[string]any{
+            "_index": index,
+            "_id":    id,
+            "found":  false,
+        })
+        return
+    }
+
+    f.write(w, http.StatusOK, map[string]any{
+        "_index":  index,
+        "_id":     id,
+        "found":   true,
+        "_source": source,
+    })
+}
+
+// update merges a partial doc into a document, or runs the stock
+// adjustment script, the only script the repository sends, natively.
+func (f *fakeElastic) update(w http.ResponseWriter, index, id string, body []byte) {
+    if f.blocked(w, index) {
+        return
+    }
+    source, ok := f.indices[index][id]
+    if !ok {
+        f.notFound(w, "document ["+id+"]")
+        return
+    }
+    req := struct {
+        Doc    map[string]any `json:"doc"`
+        Script *struct {
+            Params struct {
+                Variant  string           `json:"variant"`
+                Delta    int64            `json:"delta"`
+                Variants []map[string]any `json:"variants"`
+                Rating   float64          `json:"rating"`
+                Count    uint64           `json:"review_count"`
+                Version  *uint64          `json:"version"`
+            } `json:"params"`
+        } `json:"script"`
+    }{}
+    json.Unmarshal(body, &req)
+
+    doc := map[string]any{}
+    json.Unmarshal(source, &doc)
+    result := "updated"
+    for k, v := range req.Doc {
+        doc[k] = v
+    }
+    if req.Script != nil && req.Script.Params.Version != nil {
+        // The set rating script.
+        stored, _ := doc["rating_version"].(float64)
+        if p := req.Script.Params; *p.Version > uint64(stored) {
+            doc["rating"], doc["review_count"], doc["rating_version"] = p.Rating, p.Count, *p.Version
+        } else {
+            result = "noop"
+        }
+    } else if req.Script != nil && req.Script.Params.Variants != nil {
+        // The set variants script.
+        stock := map[any]any{}
+        old, _ := doc["variants"].([]any)
+        for _, v := range old {
+            stock[v.(map[string]any)["id"]] = v.(map[string]any)["stock"]
+        }
+        variants := []any{}
+        for _, v := range req.Script.Params.Variants {
+            if s, ok := stock[v["id"]]; ok {
+                v["stock"] = s
+            }
+            variants = append(variants, v)
+        }
+        doc["variants"] = variants
+        if len(variants) == 0 {
+            delete(doc, "variants")
+        }
+    } else if req.Script != nil {
+        result = "noop"
+        variants, _ := doc["variants"].([]any)
+        for _, v := range variants {
+            variant := v.(map[string]any)
+            stock, _ := variant["stock"].(float64)
+            if variant["id"] == req.Script.Params.Variant && int64(stock) + req.Script.Params.Delta >= 0 {
+                variant["stock"] = int64(stock) + req.Script.Params.Delta
+                result = "updated"
+            }
+        }
+    }
+    f.indices[index][id], _ = json.Marshal(doc)
+
+    f.write(w, http.StatusOK, map[string]any{
+        "_index":        index,
+        "_id":           id,
+        "_version":      1,
+        "result":        result,
+        "_shards":       map[string]any{"total": 1, "successful": 1, "failed": 0},
+        "_seq_no":       0,
+        "_primary_term": 1,
+    })
+}
+
+func (f *fakeElastic) deleteDocument(w http.ResponseWriter, index, id string) {
+    result, status := "not_found", http.StatusNotFound
+    if _, ok := f.indices[index][id]; ok {
+        delete(f.indices[index], id)
+        result, status = "deleted", http.StatusOK
+    }
+
+    f.write(w, status, map[string]any{
+        "_index":        index,
+        "_id":           id,
+        "_version":      1,
+        "result":        result,
+        "_shards":       map[string]any{"total": 1, "successful": 1, "failed": 0},
+        "_seq_no":       0,
+        "_primary_term": 1,
+    })
+}
+
+func (f *fakeElastic) search(w http.ResponseWriter, index string, body []byte) {
+    req := struct {
+        From        *int  `json:"from"`
+        Size        *int  `json:"size"`
+        SearchAfter []any `json:"search_after"`
+        Query       struct {
+            Ids *struct {
+                Values []string `json:"values"`
+            } `json:"ids"`
+            Nested *fakeNestedQuery `json:"nested"`
+            Terms  map[string][]any `json:"terms"`
+        } `json:"query"`
+    }{}
+    json.Unmarshal(body, &req)
+
+    total := 0
+    hits := []map[string]any{}
+    if f.cannedHits != nil {
+        total = len(f.cannedHits)
+        for _, h := range f.cannedHits {
+            hit := map[string]any{
+                "_index":  index,
+                "_id":     h.ID,
+                "_score":  1.0,
+                "_source": h.Source,
+            }
+            if h.Highlight != nil {
+                hit["highlight"] = h.Highlight
+            }
+            hits = append(hits, hit)
+        }
+    } else {
+        ids := []string{}
+        if req.Query.Ids != nil {
+            ids = req.Query.Ids.Values
+        } else {
+            for id := range f.indices[index] {
+                ids = append(ids, id)
+            }
+            sort.Strings(ids)
+        }
+        for _, id := range ids {
+            // Stored documents are returned sorted by id, which doubles as
+            // the sort value search_after pages on.
+            if len(req.SearchAfter) > 0 && id <= fmt.Sprint(req.SearchAfter[0]) {
+                continue
+            }
+            source, ok := f.indices[index][id]
+            if ok && matchesNested(source, req.Query.Nested) && matchesTerms(source, req.Query.Terms) {
+                hits = append(hits, map[string]any{
+                    "_index":  index,
+                    "_id":     id,
+                    "_score":  1.0,
+                    "_source": source,
+                    "sort":    []any{id},
+                })
+            }
+        }
+
+        total = len(hits)
+        from, size := 0, 10
+        if req.From != nil {
+            from = *req.From
+        }
+        if req.Size != nil {
+            size = *req.Size
+        }
+        if from > len(hits) {
+            from = len(hits)
+        }
+        hits = hits[from:]
+        if size < len(hits) {
+            hits = hits[:size]
+        }
+    }
+
+    res := map[string]any{
+        "took":      1,
+        "timed_out": false,
+        "_shards":   map[string]any{"total": 1, "successful": 1, "skipped": 0, "failed": 0},
+        "hits": map[string]any{
+            "total":     map[string]any{"value": total, "relation": "eq"},
+            "max_score": 1.0,
+            "hits":      hits,
+        },
+    }
+    if f.cannedAggregations != nil {
+        res["aggregations"] = f.cannedAggregations
+    }
+    if f.cannedSuggest != nil {
+        res["suggest"] = f.cannedSuggest
+    }
+    f.write(w, http.StatusOK, res)
+}
+
+// matchesNested reports whether any object under the nested path of source
+// matches every term of the nested query. A nil query matches everything.
+func matchesNested(source json.RawMessage, nested *fakeNestedQuery) bool {
+    if nested == nil {
+        return true
+    }
+
+    doc := map[string][]map[string]any{}
+    json.Unmarshal(source, &doc)
+    for _, object := range doc[nested.Path] {
+        matched := true
+        for field, term := range nested.Query.Term {
+            if object[strings.TrimPrefix(field, nested.Path+".")] != term.Value {
+                matched = false
+            }
+        }
+        if matched {
+            return true
+        }
+    }
+    return false
+}
+
+// matchesTerms reports whether every field of terms holds one of its
+// values in source, either as a string or in a list of strings.
+func matchesTerms(source json.RawMessage, terms map[string][]any) bool {
+    doc := map[string]any{}
+    json.Unmarshal(source, &doc)
+    for field, values := range terms {
+        fieldValues := []any{doc[field]}
+        if list, ok := doc[field].([]any); ok {
+            fieldValues = list
+        }
+
+        matched := false
+        for _, v := range values {
+            for _, fv := range fieldValues {
+                if fv == v {
+                    matched = true
+                }
+            }
+        }
+        if !matched {
+            return false
+        }
+    }
+    return true
+}
+
+// searchPointInTime answers an index-less search against the index a point
+// in time was opened on.
+func (f *fakeElastic) searchPointInTime(w http.ResponseWriter, body []byte) {
+    req := struct {
+        Pit struct {
+            ID string `json:"id"`
+        } `json:"pit"`
+    }{}
+    json.Unmarshal(body, &req)
+
+    index, ok := f.pits[req.Pit.ID]
+    if !ok {
+        f.write(w, http.StatusNotFound, map[string]any{
+            "error":  fmt.Sprintf("point in time [%s] missing", req.Pit.ID),
+            "status": http.StatusNotFound,
+        })
+        return
+    }
+    f.search(w, index, body)
+}
+
+func (f *fakeElastic) openPointInTime(w http.ResponseWriter, index string) {
+    id := "pit-" + index
+    f.pits[id] = index
+    f.write(w, http.StatusOK, map[string]any{"id": id})
+}
+
+func (f *fakeElastic) closePointInTime(w http.ResponseWriter, body []byte) {
+    req := struct {
+        ID string `json:"id"`
+    }{}
+    json.Unmarshal(body, &req)
+
+    _, ok := f.pits[req.ID]
+    delete(f.pits, req.ID)
+    f.write(w, http.StatusOK, map[string]any{"succeeded": ok, "num_freed": 1})
+}
+
+// bulk applies index actions from an NDJSON body, failing the items listed
+// in rejectIDs.
+func (f *fakeElastic) bulk(w http.ResponseWriter, index string, body []byte) {
+    if f.indices[index] == nil {
+        f.indices[index] = map[string]json.RawMessage{}
+    }
+
+    lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+    items := []map[string]any{}
+    errors := false
+    for i := 0; i+1 < len(lines); i += 2 {
+        action := map[string]struct {
+            ID string `json:"_id"`
+        }{}
+        json.Unmarshal([]byte(lines[i]), &action)
+        id := action["index"].ID
+        source := json.RawMessage(lines[i+1])
+
+        item := map[string]any{"_index": index, "_id": id, "status": http.StatusCreated, "result": "created"}
+        if f.rejectIDs[id] {
+            errors = true
+            item["status"] = http.StatusBadRequest
+            item["error"] = map[string]any{"type": "mapper_parsing_exception", "reason": "failed to parse"}
+            delete(item, "result")
+        } else {
+            if _, ok := f.indices[index][id]; ok {
+                item["status"], item["result"] = http.StatusOK, "updated"
+            }
+            f.indices[index][id] = source
+        }
+        items = append(items, map[string]any{"index": item})
+    }
+
+    f.write(w, http.StatusOK, map[string]any{"took": 1, "errors": errors, "items": items})
+}
+
+func (f *fakeElastic) mget(w http.ResponseWriter, index string, body []byte) {
+    req := struct {
+        Ids  []string `json:"ids"`
+        Docs []struct {
+            ID string `json:"_id"`
+        } `json:"docs"`
+    }{}
+    json.Unmarshal(body, &req)
+
+    ids := req.Ids
+    for _, d := range req.Docs {
+        ids = append(ids, d.ID)
+    }
+
+    docs := []map[string]any{}
+    for _, id := range ids {
+        doc := map[string]any{"_index": index, "_id": id, "found": false}
+        if source, ok := f.indices[index][id]; ok {
+            doc["found"] = true
+            doc["_source"] = source
+        }
+        docs = append(docs, doc)
+    }
+
+    f.write(w, http.StatusOK, map[string]any{"docs": docs})
+}
+
+func (f *fakeElastic) getSynonyms(w http.ResponseWriter, set string) {
+    rules, ok := f.synonyms[set]
+    if !ok {
+        f.notFound(w, "synonyms set "+set)
+        return
+    }
+
+    ids := []string{}
+    for id := range rules {
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+    read := []any{}
+    for _, id := range ids {
+        read = append(read, map[string]any{"id": id, "synonyms": rules[id]})
+    }
+    f.write(w, http.StatusOK, map[string]any{"count": len(read), "synonyms_set": read})
+}
+
+func (f *fakeElastic) putSynonymRule(w http.ResponseWriter, set, id string, body []byte) {
+    rules, ok := f.synonyms[set]
+    if !ok {
+        f.notFound(w, "synonyms set "+set)
+        return
+    }
+    req := struct {
+        Synonyms string `json:"synonyms"`
+    }{}
+    json.Unmarshal(body, &req)
+
+    result := "created"
+    if _, ok := rules[id]; ok {
+        result = "updated"
+    }
+    rules[id] = req.Synonyms
+    f.write(w, http.StatusOK, map[string]any{"result": result})
+}
+
+func (f *fakeElastic) deleteSynonymRule(w http.ResponseWriter, set, id string) {
+    if _, ok := f.synonyms[set][id]; !ok {
+        f.notFound(w, "synonym rule "+id)
+        return
+    }
+    delete(f.synonyms[set], id)
+    f.write(w, http.StatusOK, map[string]any{"result": "deleted"})
+}
+
+func (f *fakeElastic) notFound(w http.ResponseWriter, what string) {
+    f.write(w, http.StatusNotFound, map[string]any{
+        "error": map[string]any{
+            "type":   "resource_not_found_exception",
+            "reason": what + " not found",
+        },
+        "status": http.StatusNotFound,
+    })
+}
+
+func (f *fakeElastic) write(w http.ResponseWriter, status int, v any) {
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,814 @@
+package catalog
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryRepository struct {
+    mu         sync.RWMutex
+    products   map[string]Product
+    categories map[string]Category
+    synonyms   map[string]SynonymRule
+    stopwords  []string
+    searches   []SearchEvent
+    feedback   []SearchFeedback
+    // ratingVersions holds the version of each product's stored rating.
+    ratingVersions map[string]uint64
+}
+
+func NewMemoryRepository() Repository {
+    return &memoryRepository{
+        products:   map[string]Product{},
+        categories: map[string]Category{},
+        synonyms:   map[string]SynonymRule{},
+        stopwords:  []string{},
+        ratingVersions: map[string]uint64{},
+    }
+}
+
+func (r *memoryRepository) Close() {}
+
+// PutProduct overwrites any existing product with the same id, like an
+// elasticsearch index request does.
+func (r *memoryRepository) PutProduct(ctx context.Context, p Product) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.products[p.ID] = p
+    return nil
+}
+
+func (r *memoryRepository) GetProductByID(
+    ctx context.Context, id string,
+) (*Product, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    p, ok := r.products[id]
+    if !ok {
+        log.Println("failed to get product by id from catalog memory repository: ", ErrNotFound)
+        return nil, ErrNotFound
+    }
+    return &p, nil
+}
+
+func (r *memoryRepository) ListProducts(
+    ctx context.Context, skip uint64, take uint64,
+) ([]Product, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    return paginate(r.sorted(nil), skip, take), nil
+}
+
+func (r *memoryRepository) ListProductsWithIDs(
+    ctx context.Context, ids []string,
+) ([]Product, []string, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    products := []Product{}
+    missing := []string{}
+    for _, id := range uniqueIDs(ids) {
+        if p, ok := r.products[id]; ok {
+            products = append(products, p)
+        } else {
+            missing = append(missing, id)
+        }
+    }
+    return products, missing, nil
+}
+
+// SearchProducts matches the query as a case-insensitive substring of the
+// name or description, or failing that when every query term is within the
+// allowed edit distance of a word in either. It then applies the same
+// filters, sort orders, facets, highlights and corrections as the
+// elasticsearch repository.
+func (r *memoryRepository) SearchProducts(
+    ctx context.Context, req SearchRequest,
+) (*SearchResult, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    q := strings.ToLower(strings.TrimSpace(req.Query))
+    terms := wordPattern.FindAllString(q, -1)
+    alternatives := synonymAlternatives(terms, r.listSynonymRules())
+    products := r.sorted(func(p Product) bool {
+        text := strings.ToLower(p.Name + " " + p.Description)
+        if !strings.Contains(strings.ToLower(p.Name), q) &&
+            !strings.Contains(strings.ToLower(p.Description), q) &&
+            !fuzzyMatch(text, alternatives, req.Fuzziness) {
+            return false
+        }
+        if req.MinPrice != nil && p.Price < *req.MinPrice {
+            return false
+        }
+        if req.MaxPrice != nil && p.Price > *req.MaxPrice {
+            return false
+        }
+        if req.MinRating != nil && p.Rating < *req.MinRating {
+            return false
+        }
+        for _, tag := range req.Tags {
+            if !slices.Contains(p.Tags, tag) {
+                return false
+            }
+        }
+        if len(req.CategoryIDs) != 0 && !slices.ContainsFunc(p.CategoryIDs, func(id string) bool {
+            return slices.Contains(req.CategoryIDs, id)
+        }) {
+            return false
+        }
+        return true
+    })
+    sortProducts(products, req.Sort)
+
+    result := &SearchResult{
+        Products: paginate(products, req.Skip, req.Take),
+        Total: uint64(len(products)),
+        Facets: facetsOf(products),
+        Highlights: map[string][]Highlight{},
+    }
+    if len(terms) == 0 {
+        return result, nil
+    }
+    highlightWords := slices.Concat(alternatives...)
+    for _, p := range result.Products {
+        for _, field := range []struct{ name, text string }{
+            {"name", p.Name},
+            {"description", p.Description},
+        } {
+            if highlighted, ok := highlightTerms(field.text, highlightWords, req.Fuzziness); ok {
+                result.Highlights[p.ID] = append(result.Highlights[p.ID], Highlight{
+                    Field: field.name,
+                    Fragments: []string{highlighted},
+                })
+            }
+        }
+    }
+    result.DidYouMean = r.correctQuery(terms)
+    return result, nil
+}
+
+// termMatches reports whether word contains term or is within the edit
+// distance fuzziness allows for it.
+func termMatches(word, term string, fuzziness *int) bool {
+    if strings.Contains(word, term) {
+        return true
+    }
+    allowed := autoFuzziness(term)
+    if fuzziness != nil {
+        allowed = *fuzziness
+    }
+    return allowed > 0 && editDistance(word, term) <= allowed
+}
+
+// fuzzyMatch reports whether, for every query term, text has a word
+// matching one of its alternatives. Multi-word synonyms must appear in text
+// as they are.
+func fuzzyMatch(text string, alternatives [][]string, fuzziness *int) bool {
+    if len(alternatives) == 0 {
+        return false
+    }
+    words := wordPattern.FindAllString(text, -1)
+    for _, alts := range alternatives {
+        if !slices.ContainsFunc(alts, func(alt string) bool {
+            if strings.Contains(alt, " ") {
+                return strings.Contains(text, alt)
+            }
+            return slices.ContainsFunc(words, func(w string) bool {
+                return termMatches(w, alt, fuzziness)
+            })
+        }) {
+            return false
+        }
+    }
+    return true
+}
+
+// highlightTerms wraps the words of text matched by any of terms in <em>
+// tags. It returns false if none matched.
+func highlightTerms(text string, terms []string, fuzziness *int) (string, bool) {
+    var b strings.Builder
+    end, matched := 0, false
+    for _, w := range wordPattern.FindAllStringIndex(text, -1) {
+        word := strings.ToLower(text[w[0]:w[1]])
+        if !slices.ContainsFunc(terms, func(term string) bool {
+            return termMatches(word, term, fuzziness)
+        }) {
+            continue
+        }
+        b.WriteString(text[end:w[0]])
+        b.WriteString("<em>" + text[w[0]:w[1]] + "</em>")
+        end, matched = w[1], true
+    }
+    b.WriteString(text[end:])
+    return b.String(), matched
+}
+
+// minCorrectionLength matches the phrase suggester's default
+// min_word_length: shorter terms are never corrected.
+const minCorrectionLength = 4
+
+// correctQuery replaces every term of at least minCorrectionLength
+// characters that is not a word of any product name with the closest one,
+// preferring more frequent words on ties. It returns "" when no term needed
+// correcting.
+func (r *memoryRepository) correctQuery(terms []string) string {
+    frequency := map[string]int{}
+    for _, p := range r.products {
+        for _, w := range wordPattern.FindAllString(strings.ToLower(p.Name), -1) {
+            frequency[w]++
+        }
+    }
+
+    corrected, changed := []string{}, false
+    for _, term := range terms {
+        best, bestDistance := term, maxFuzziness+1
+        if frequency[term] == 0 && len([]rune(term)) >= minCorrectionLength {
+            for w, n := range frequency {
+                d := editDistance(w, term)
+                if d < bestDistance || (d == bestDistance &&
+                    (n > frequency[best] || (n == frequency[best] && w < best))) {
+                    best, bestDistance = w, d
+                }
+            }
+        }
+        changed = changed || best != term
+        corrected = append(corrected, best)
+    }
+    if !changed {
+        return ""
+    }
+    return strings.Join(corrected, " ")
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions and swaps of adjacent characters
+// each count as one edit, as in elasticsearch fuzzy queries.
+func editDistance(a, b string) int {
+    ra, rb := []rune(a), []rune(b)
+    d := make([][]int, len(ra)+1)
+    for i := range d {
+        d[i] = make([]int, len(rb)+1)
+        d[i][0] = i
+    }
+    for j := range d[0] {
+        d[0][j] = j
+    }
+    for i := 1; i <= len(ra); i++ {
+        for j := 1; j <= len(rb); j++ {
+            cost := 1
+            if ra[i-1] == rb[j-1] {
+                cost = 0
+            }
+            d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+            if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+                d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+            }
+        }
+    }
+    return d[len(ra)][len(rb)]
+}
+
+func (r *memoryRepository) GetProductBySKU(
+    ctx context.Context, sku string,
+) (*Product, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    for _, p := range r.products {
+        if slices.ContainsFunc(p.Variants, func(v Variant) bool { return v.SKU == sku }) {
+            return &p, nil
+        }
+    }
+    return nil, ErrNotFound
+}
+
+func (r *memoryRepository) GetProductsByExternalSKUs(
+    ctx context.Context, skus []string,
+) ([]Product, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    return r.sorted(func(p Product) bool {
+        return p.ExternalSKU != "" && slices.Contains(skus, p.ExternalSKU)
+    }), nil
+}
+
+func (r *memoryRepository) PutProducts(
+    ctx context.Context, products []Product,
+) ([]error, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    for _, p := range products {
+        r.products[p.ID] = p
+    }
+    return make([]error, len(products)), nil
+}
+
+// ScanProducts calls fn on a snapshot of the products in id order.
+func (r *memoryRepository) ScanProducts(
+    ctx context.Context, fn func(Product) error,
+) error {
+    r.mu.RLock()
+    products := r.sorted(nil)
+    r.mu.RUnlock()
+
+    for _, p := range products {
+        if err := fn(p); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// SuggestProducts treats every term of prefix but the last as a whole word
+// and the last as a word prefix, like a bool_prefix query. Names matching
+// from their first word rank first.
+func (r *memoryRepository) SuggestProducts(
+    ctx context.Context, prefix string, limit int,
+) ([]Suggestion, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    terms := strings.Fields(strings.ToLower(prefix))
+    if len(terms) == 0 {
+        return []Suggestion{}, nil
+    }
+
+    type match struct {
+        suggestion Suggestion
+        leading    bool
+    }
+    matches := []match{}
+    for _, p := range r.sorted(nil) {
+        highlighted, leading, ok := highlightPrefix(p.Name, terms)
+        if ok {
+            matches = append(matches, match{
+                suggestion: Suggestion{ProductID: p.ID, Name: p.Name, Highlighted: highlighted},
+                leading: leading,
+            })
+        }
+    }
+    slices.SortStableFunc(matches, func(a, b match) int {
+        if a.leading != b.leading {
+            if a.leading {
+                return -1
+            }
+            return 1
+        }
+        return strings.Compare(strings.ToLower(a.suggestion.Name), strings.ToLower(b.suggestion.Name))
+    })
+
+    suggestions := []Suggestion{}
+    for _, m := range matches {
+        if len(suggestions) == limit {
+            break
+        }
+        suggestions = append(suggestions, m.suggestion)
+    }
+    return suggestions, nil
+}
+
+// SimilarProducts scores products by the distinct name and description
+// words and the tags they share with the product, a rough stand-in for
+// more_like_this. Products sharing nothing are left out.
+func (r *memoryRepository) SimilarProducts(
+    ctx context.Context, id string, take int,
+) ([]Product, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    source, ok := r.products[id]
+    if !ok {
+        return nil, ErrNotFound
+    }
+    terms := similarityTerms(source)
+
+    type match struct {
+        product Product
+        score   int
+    }
+    matches := []match{}
+    for _, p := range r.sorted(nil) {
+        if p.ID == id {
+            continue
+        }
+        score := 0
+        for term := range similarityTerms(p) {
+            if terms[term] {
+                score++
+            }
+        }
+        if score > 0 {
+            matches = append(matches, match{product: p, score: score})
+        }
+    }
+    slices.SortStableFunc(matches, func(a, b match) int {
+        return cmp.Compare(b.score, a.score)
+    })
+
+    products := []Product{}
+    for _, m := range matches[:min(take, len(matches))] {
+        products = append(products, m.product)
+    }
+    return products, nil
+}
+
+// similarityTerms returns the lowercased words of p's name and description
+// and its tags, the latter prefixed so they don't collide with words.
+func similarityTerms(p Product) map[string]bool {
+    terms := map[string]bool{}
+    for _, w := range wordPattern.FindAllString(strings.ToLower(p.Name+" "+p.Description), -1) {
+        terms[w] = true
+    }
+    for _, tag := range p.Tags {
+        terms["tag:"+tag] = true
+    }
+    return terms
+}
+
+var wordPattern = regexp.MustCompile(`[\pL\pN]+`)
+
+// highlightPrefix wraps the words of name matched by terms in <em> tags.
+// leading reports whether the first word matched.
+func highlightPrefix(name string, terms []string) (string, bool, bool) {
+    words := wordPattern.FindAllStringIndex(name, -1)
+    matched := make([]bool, len(words))
+    for i, term := range terms {
+        last := i == len(terms)-1
+        found := false
+        for j, w := range words {
+            word := strings.ToLower(name[w[0]:w[1]])
+            if word == term || (last && strings.HasPrefix(word, term)) {
+                matched[j], found = true, true
+            }
+        }
+        if !found {
+            return "", false, false
+        }
+    }
+
+    var b strings.Builder
+    end := 0
+    for j, w := range words {
+        if !matched[j] {
+            continue
+        }
+        b.WriteString(name[end:w[0]])
+        b.WriteString("<em>" + name[w[0]:w[1]] + "</em>")
+        end = w[1]
+    }
+    b.WriteString(name[end:])
+    return b.String(), matched[0], true
+}
+
+// sorted returns the products accepted by match ordered by id, which for
+// ksuids is creation order. A nil match accepts every product.
+func (r *memoryRepository) sorted(match func(Product) bool) []Product {
+    products := []Product{}
+    for _, p := range r.products {
+        if match == nil || match(p) {
+            products = append(products, p)
+        }
+    }
+    sort.Slice(products, func(i, j int) bool {
+        return products[i].ID < products[j].ID
+    })
+    return products
+}
+
+func (r *memoryRepository) PutCategory(ctx context.Context, c Category) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.categories[c.ID] = c
+    return nil
+}
+
+func (r *memoryRepository) GetCategoryByID(
+    ctx context.Context, id string,
+) (*Category, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    c, ok := r.categories[id]
+    if !ok {
+        return nil, ErrNotFound
+    }
+    return &c, nil
+}
+
+// ListCategories returns every category ordered by path.
+func (r *memoryRepository) ListCategories(ctx context.Context) ([]Category, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    categories := []Category{}
+    for _, c := range r.categories {
+        categories = append(categories, c)
+    }
+    slices.SortFunc(categories, func(a, b Category) int {
+        return strings.Compare(a.Path, b.Path)
+    })
+    return categories, nil
+}
+
+func (r *memoryRepository) DeleteCategory(ctx context.Context, id string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if _, ok := r.categories[id]; !ok {
+        return ErrNotFound
+    }
+    delete(r.categories, id)
+    return nil
+}
+
+func (r *memoryRepository) SetProductRating(
+    ctx context.Context, id string, rating float64, count, version uint64,
+) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    p, ok := r.products[id]
+    if !ok {
+        return ErrNotFound
+    }
+    if version <= r.ratingVersions[id] {
+        return nil
+    }
+    p.Rating, p.ReviewCount = rating, count
+    r.products[id] = p
+    r.ratingVersions[id] = version
+    return nil
+}
+
+func (r *memoryRepository) SetProductTaxonomy(
+    ctx context.Context, id string, categoryIDs, tags []string,
+) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    p, ok := r.products[id]
+    if !ok {
+        return ErrNotFound
+    }
+    p.CategoryIDs, p.Tags = categoryIDs, tags
+    r.products[id] = p
+    return nil
+}
+
+func (r *memoryRepository) SetProductVariants(
+    ctx context.Context, id string, variants []Variant,
+) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    p, ok := r.products[id]
+    if !ok {
+        return ErrNotFound
+    }
+    merged := slices.Clone(variants)
+    for i, v := range merged {
+        if old, ok := p.Variant(v.ID); ok {
+            merged[i].Stock = old.Stock
+        }
+    }
+    if len(merged) == 0 {
+        merged = nil
+    }
+    p.Variants = merged
+    r.products[id] = p
+    return nil
+}
+
+func (r *memoryRepository) AdjustVariantStock(
+    ctx context.Context, productID, variantID string, delta int64,
+) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    p, ok := r.products[productID]
+    if !ok {
+        return ErrNotFound
+    }
+    i := slices.IndexFunc(p.Variants, func(v Variant) bool { return v.ID == variantID })
+    if i < 0 {
+        return ErrInvalidVariant
+    }
+    if int64(p.Variants[i].Stock) + delta < 0 {
+        return ErrOutOfStock
+    }
+    p.Variants = slices.Clone(p.Variants)
+    p.Variants[i].Stock = uint32(int64(p.Variants[i].Stock) + delta)
+    r.products[productID] = p
+    return nil
+}
+
+// listSynonymRules returns the rules ordered by id. Callers hold r.mu.
+func (r *memoryRepository) listSynonymRules() []SynonymRule {
+    rules := []SynonymRule{}
+    for _, rule := range r.synonyms {
+        rules = append(rules, rule)
+    }
+    slices.SortFunc(rules, func(a, b SynonymRule) int {
+        return strings.Compare(a.ID, b.ID)
+    })
+    return rules
+}
+
+func (r *memoryRepository) ListSynonymRules(ctx context.Context) ([]SynonymRule, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    return r.listSynonymRules(), nil
+}
+
+func (r *memoryRepository) PutSynonymRule(ctx context.Context, rule SynonymRule) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.synonyms[rule.ID] = rule
+    return nil
+}
+
+func (r *memoryRepository) DeleteSynonymRule(ctx context.Context, id string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if _, ok := r.synonyms[id]; !ok {
+        return ErrNotFound
+    }
+    delete(r.synonyms, id)
+    return nil
+}
+
+func (r *memoryRepository) GetStopwords(ctx context.Context) ([]string, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    return slices.Clone(r.stopwords), nil
+}
+
+func (r *memoryRepository) PutStopwords(ctx context.Context, words []string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.stopwords = slices.Clone(words)
+    return nil
+}
+
+func (r *memoryRepository) PutSearchEvent(ctx context.Context, e SearchEvent) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.searches = append(r.searches, e)
+    return nil
+}
+
+func (r *memoryRepository) PutSearchFeedback(ctx context.Context, f SearchFeedback) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.feedback = append(r.feedback, f)
+    return nil
+}
+
+func (r *memoryRepository) TopSearchQueries(
+    ctx context.Context, since time.Time, limit int, zeroResults bool,
+) ([]QueryCount, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    counts := map[string]uint64{}
+    for _, e := range r.searches {
+        if e.Query == "" || e.CreatedAt.Before(since) || (zeroResults && e.Results != 0) {
+            continue
+        }
+        counts[e.Query]++
+    }
+
+    queries := []QueryCount{}
+    for q, n := range counts {
+        queries = append(queries, QueryCount{Query: q, Searches: n})
+    }
+    slices.SortFunc(queries, func(a, b QueryCount) int {
+        if a.Searches != b.Searches {
+            return cmp.Compare(b.Searches, a.Searches)
+        }
+        return strings.Compare(a.Query, b.Query)
+    })
+    return queries[:min(limit, len(queries))], nil
+}
+
+func (r *memoryRepository) SearchClickThrough(
+    ctx context.Context, since time.Time,
+) (*ClickThrough, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    ct := &ClickThrough{}
+    for _, e := range r.searches {
+        if !e.CreatedAt.Before(since) {
+            ct.Searches++
+        }
+    }
+    seen := map[FeedbackKind]map[string]bool{FeedbackClick: {}, FeedbackAddToCart: {}}
+    for _, f := range r.feedback {
+        if f.CreatedAt.Before(since) || seen[f.Kind][f.SearchID] {
+            continue
+        }
+        seen[f.Kind][f.SearchID] = true
+        if f.Kind == FeedbackClick {
+            ct.ClickedSearches++
+        } else {
+            ct.AddedToCartSearches++
+        }
+    }
+    return ct, nil
+}
+
+// sortProducts reorders id-sorted products in place. Relevance keeps id
+// order since there is no scoring.
+func sortProducts(products []Product, order SearchSort) {
+    var less func(a, b Product) int
+    switch order {
+    case SortPriceAsc:
+        less = func(a, b Product) int { return cmp.Compare(a.Price, b.Price) }
+    case SortPriceDesc:
+        less = func(a, b Product) int { return cmp.Compare(b.Price, a.Price) }
+    case SortNameAsc:
+        less = func(a, b Product) int {
+            return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+        }
+    case SortNameDesc:
+        less = func(a, b Product) int {
+            return strings.Compare(strings.ToLower(b.Name), strings.ToLower(a.Name))
+        }
+    case SortNewest:
+        less = func(a, b Product) int { return strings.Compare(b.ID, a.ID) }
+    case SortRatingDesc:
+        less = func(a, b Product) int {
+            return cmp.Or(cmp.Compare(b.Rating, a.Rating), cmp.Compare(b.ReviewCount, a.ReviewCount))
+        }
+    default:
+        return
+    }
+    slices.SortStableFunc(products, less)
+}
+
+func facetsOf(products []Product) Facets {
+    facets := Facets{Prices: []PriceBucket{}, Tags: []TagCount{}}
+    for _, r := range priceRanges {
+        bucket := PriceBucket{From: r.From, To: r.To}
+        for _, p := range products {
+            if r.contains(p.Price) {
+                bucket.Count++
+            }
+        }
+        facets.Prices = append(facets.Prices, bucket)
+    }
+
+    counts := map[string]uint64{}
+    for _, p := range products {
+        for _, tag := range p.Tags {
+            counts[tag]++
+        }
+    }
+    for tag, count := range counts {
+        facets.Tags = append(facets.Tags, TagCount{Tag: tag, Count: count})
+    }
+    // Same order as an elasticsearch terms aggregation: count, then term.
+    slices.SortFunc(facets.Tags, func(a, b TagCount) int {
+        if c := cmp.Compare(b.Count, a.Count); c != 0 {
+            return c
+        }
+        return strings.Compare(a.Tag, b.Tag)
+    })
+    if len(facets.Tags) > maxTagFacets {
+        facets.Tags = facets.Tags[:maxTagFacets]
+    }
+    return facets
+}
+
+func paginate(products []Product, skip uint64, take uint64) []Product {
+    if skip >= uint64(len(products)) {
+        return []Product{}
+    }
+    products = products[skip:]
+    if take < uint64(len(products)) {
+        products = products[:take]
+    }
+    return products
+}
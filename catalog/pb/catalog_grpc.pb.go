@@ -25,6 +25,27 @@ type CatalogServiceClient interface {
 	PostProduct(ctx context.Context, in *PostProductRequest, opts ...grpc.CallOption) (*PostProductResponse, error)
 	GetProduct(ctx context.Context, in *GetProductRequest, opts ...grpc.CallOption) (*GetProductResponse, error)
 	GetProducts(ctx context.Context, in *GetProductsRequest, opts ...grpc.CallOption) (*GetProductsResponse, error)
+	SuggestProducts(ctx context.Context, in *SuggestProductsRequest, opts ...grpc.CallOption) (*SuggestProductsResponse, error)
+	GetSimilarProducts(ctx context.Context, in *GetSimilarProductsRequest, opts ...grpc.CallOption) (*GetSimilarProductsResponse, error)
+	PostCategory(ctx context.Context, in *PostCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error)
+	UpdateCategory(ctx context.Context, in *UpdateCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error)
+	DeleteCategory(ctx context.Context, in *DeleteCategoryRequest, opts ...grpc.CallOption) (*DeleteCategoryResponse, error)
+	GetCategory(ctx context.Context, in *GetCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error)
+	GetCategories(ctx context.Context, in *GetCategoriesRequest, opts ...grpc.CallOption) (*GetCategoriesResponse, error)
+	SetProductTaxonomy(ctx context.Context, in *SetProductTaxonomyRequest, opts ...grpc.CallOption) (*SetProductTaxonomyResponse, error)
+	SetProductVariants(ctx context.Context, in *SetProductVariantsRequest, opts ...grpc.CallOption) (*SetProductVariantsResponse, error)
+	RestockVariant(ctx context.Context, in *RestockVariantRequest, opts ...grpc.CallOption) (*RestockVariantResponse, error)
+	ReserveVariantStock(ctx context.Context, in *ReserveVariantStockRequest, opts ...grpc.CallOption) (*ReserveVariantStockResponse, error)
+	GetSynonyms(ctx context.Context, in *GetSynonymsRequest, opts ...grpc.CallOption) (*GetSynonymsResponse, error)
+	PutSynonymRule(ctx context.Context, in *PutSynonymRuleRequest, opts ...grpc.CallOption) (*PutSynonymRuleResponse, error)
+	DeleteSynonymRule(ctx context.Context, in *DeleteSynonymRuleRequest, opts ...grpc.CallOption) (*DeleteSynonymRuleResponse, error)
+	GetStopwords(ctx context.Context, in *GetStopwordsRequest, opts ...grpc.CallOption) (*StopwordsResponse, error)
+	SetStopwords(ctx context.Context, in *SetStopwordsRequest, opts ...grpc.CallOption) (*StopwordsResponse, error)
+	RecordSearchFeedback(ctx context.Context, in *RecordSearchFeedbackRequest, opts ...grpc.CallOption) (*RecordSearchFeedbackResponse, error)
+	GetTopSearchQueries(ctx context.Context, in *SearchQueriesRequest, opts ...grpc.CallOption) (*SearchQueriesResponse, error)
+	GetZeroResultQueries(ctx context.Context, in *SearchQueriesRequest, opts ...grpc.CallOption) (*SearchQueriesResponse, error)
+	GetSearchClickThrough(ctx context.Context, in *SearchClickThroughRequest, opts ...grpc.CallOption) (*SearchClickThroughResponse, error)
+	SetProductRating(ctx context.Context, in *SetProductRatingRequest, opts ...grpc.CallOption) (*SetProductRatingResponse, error)
 }
 
 type catalogServiceClient struct {
@@ -62,6 +83,195 @@ func (c *catalogServiceClient) GetProducts(ctx context.Context, in *GetProductsR
 	return out, nil
 }
 
+func (c *catalogServiceClient) SuggestProducts(ctx context.Context, in *SuggestProductsRequest, opts ...grpc.CallOption) (*SuggestProductsResponse, error) {
+	out := new(SuggestProductsResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/SuggestProducts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) GetSimilarProducts(ctx context.Context, in *GetSimilarProductsRequest, opts ...grpc.CallOption) (*GetSimilarProductsResponse, error) {
+	out := new(GetSimilarProductsResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/GetSimilarProducts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) PostCategory(ctx context.Context, in *PostCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error) {
+	out := new(CategoryResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/PostCategory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) UpdateCategory(ctx context.Context, in *UpdateCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error) {
+	out := new(CategoryResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/UpdateCategory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) DeleteCategory(ctx context.Context, in *DeleteCategoryRequest, opts ...grpc.CallOption) (*DeleteCategoryResponse, error) {
+	out := new(DeleteCategoryResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/DeleteCategory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) GetCategory(ctx context.Context, in *GetCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error) {
+	out := new(CategoryResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/GetCategory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) GetCategories(ctx context.Context, in *GetCategoriesRequest, opts ...grpc.CallOption) (*GetCategoriesResponse, error) {
+	out := new(GetCategoriesResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/GetCategories", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) SetProductTaxonomy(ctx context.Context, in *SetProductTaxonomyRequest, opts ...grpc.CallOption) (*SetProductTaxonomyResponse, error) {
+	out := new(SetProductTaxonomyResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/SetProductTaxonomy", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) SetProductVariants(ctx context.Context, in *SetProductVariantsRequest, opts ...grpc.CallOption) (*SetProductVariantsResponse, error) {
+	out := new(SetProductVariantsResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/SetProductVariants", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) RestockVariant(ctx context.Context, in *RestockVariantRequest, opts ...grpc.CallOption) (*RestockVariantResponse, error) {
+	out := new(RestockVariantResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/RestockVariant", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) ReserveVariantStock(ctx context.Context, in *ReserveVariantStockRequest, opts ...grpc.CallOption) (*ReserveVariantStockResponse, error) {
+	out := new(ReserveVariantStockResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/ReserveVariantStock", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) GetSynonyms(ctx context.Context, in *GetSynonymsRequest, opts ...grpc.CallOption) (*GetSynonymsResponse, error) {
+	out := new(GetSynonymsResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/GetSynonyms", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) PutSynonymRule(ctx context.Context, in *PutSynonymRuleRequest, opts ...grpc.CallOption) (*PutSynonymRuleResponse, error) {
+	out := new(PutSynonymRuleResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/PutSynonymRule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) DeleteSynonymRule(ctx context.Context, in *DeleteSynonymRuleRequest, opts ...grpc.CallOption) (*DeleteSynonymRuleResponse, error) {
+	out := new(DeleteSynonymRuleResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/DeleteSynonymRule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) GetStopwords(ctx context.Context, in *GetStopwordsRequest, opts ...grpc.CallOption) (*StopwordsResponse, error) {
+	out := new(StopwordsResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/GetStopwords", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) SetStopwords(ctx context.Context, in *SetStopwordsRequest, opts ...grpc.CallOption) (*StopwordsResponse, error) {
+	out := new(StopwordsResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/SetStopwords", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) RecordSearchFeedback(ctx context.Context, in *RecordSearchFeedbackRequest, opts ...grpc.CallOption) (*RecordSearchFeedbackResponse, error) {
+	out := new(RecordSearchFeedbackResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/RecordSearchFeedback", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) GetTopSearchQueries(ctx context.Context, in *SearchQueriesRequest, opts ...grpc.CallOption) (*SearchQueriesResponse, error) {
+	out := new(SearchQueriesResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/GetTopSearchQueries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) GetZeroResultQueries(ctx context.Context, in *SearchQueriesRequest, opts ...grpc.CallOption) (*SearchQueriesResponse, error) {
+	out := new(SearchQueriesResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/GetZeroResultQueries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) GetSearchClickThrough(ctx context.Context, in *SearchClickThroughRequest, opts ...grpc.CallOption) (*SearchClickThroughResponse, error) {
+	out := new(SearchClickThroughResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/GetSearchClickThrough", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) SetProductRating(ctx context.Context, in *SetProductRatingRequest, opts ...grpc.CallOption) (*SetProductRatingResponse, error) {
+	out := new(SetProductRatingResponse)
+	err := c.cc.Invoke(ctx, "/pb.CatalogService/SetProductRating", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CatalogServiceServer is the server API for CatalogService service.
 // All implementations must embed UnimplementedCatalogServiceServer
 // for forward compatibility
@@ -69,6 +279,27 @@ type CatalogServiceServer interface {
 	PostProduct(context.Context, *PostProductRequest) (*PostProductResponse, error)
 	GetProduct(context.Context, *GetProductRequest) (*GetProductResponse, error)
 	GetProducts(context.Context, *GetProductsRequest) (*GetProductsResponse, error)
+	SuggestProducts(context.Context, *SuggestProductsRequest) (*SuggestProductsResponse, error)
+	GetSimilarProducts(context.Context, *GetSimilarProductsRequest) (*GetSimilarProductsResponse, error)
+	PostCategory(context.Context, *PostCategoryRequest) (*CategoryResponse, error)
+	UpdateCategory(context.Context, *UpdateCategoryRequest) (*CategoryResponse, error)
+	DeleteCategory(context.Context, *DeleteCategoryRequest) (*DeleteCategoryResponse, error)
+	GetCategory(context.Context, *GetCategoryRequest) (*CategoryResponse, error)
+	GetCategories(context.Context, *GetCategoriesRequest) (*GetCategoriesResponse, error)
+	SetProductTaxonomy(context.Context, *SetProductTaxonomyRequest) (*SetProductTaxonomyResponse, error)
+	SetProductVariants(context.Context, *SetProductVariantsRequest) (*SetProductVariantsResponse, error)
+	RestockVariant(context.Context, *RestockVariantRequest) (*RestockVariantResponse, error)
+	ReserveVariantStock(context.Context, *ReserveVariantStockRequest) (*ReserveVariantStockResponse, error)
+	GetSynonyms(context.Context, *GetSynonymsRequest) (*GetSynonymsResponse, error)
+	PutSynonymRule(context.Context, *PutSynonymRuleRequest) (*PutSynonymRuleResponse, error)
+	DeleteSynonymRule(context.Context, *DeleteSynonymRuleRequest) (*DeleteSynonymRuleResponse, error)
+	GetStopwords(context.Context, *GetStopwordsRequest) (*StopwordsResponse, error)
+	SetStopwords(context.Context, *SetStopwordsRequest) (*StopwordsResponse, error)
+	RecordSearchFeedback(context.Context, *RecordSearchFeedbackRequest) (*RecordSearchFeedbackResponse, error)
+	GetTopSearchQueries(context.Context, *SearchQueriesRequest) (*SearchQueriesResponse, error)
+	GetZeroResultQueries(context.Context, *SearchQueriesRequest) (*SearchQueriesResponse, error)
+	GetSearchClickThrough(context.Context, *SearchClickThroughRequest) (*SearchClickThroughResponse, error)
+	SetProductRating(context.Context, *SetProductRatingRequest) (*SetProductRatingResponse, error)
 	mustEmbedUnimplementedCatalogServiceServer()
 }
 
@@ -85,6 +316,69 @@ func (UnimplementedCatalogServiceServer) GetProduct(context.Context, *GetProduct
 func (UnimplementedCatalogServiceServer) GetProducts(context.Context, *GetProductsRequest) (*GetProductsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetProducts not implemented")
 }
+func (UnimplementedCatalogServiceServer) SuggestProducts(context.Context, *SuggestProductsRequest) (*SuggestProductsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SuggestProducts not implemented")
+}
+func (UnimplementedCatalogServiceServer) GetSimilarProducts(context.Context, *GetSimilarProductsRequest) (*GetSimilarProductsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSimilarProducts not implemented")
+}
+func (UnimplementedCatalogServiceServer) PostCategory(context.Context, *PostCategoryRequest) (*CategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PostCategory not implemented")
+}
+func (UnimplementedCatalogServiceServer) UpdateCategory(context.Context, *UpdateCategoryRequest) (*CategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateCategory not implemented")
+}
+func (UnimplementedCatalogServiceServer) DeleteCategory(context.Context, *DeleteCategoryRequest) (*DeleteCategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteCategory not implemented")
+}
+func (UnimplementedCatalogServiceServer) GetCategory(context.Context, *GetCategoryRequest) (*CategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCategory not implemented")
+}
+func (UnimplementedCatalogServiceServer) GetCategories(context.Context, *GetCategoriesRequest) (*GetCategoriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCategories not implemented")
+}
+func (UnimplementedCatalogServiceServer) SetProductTaxonomy(context.Context, *SetProductTaxonomyRequest) (*SetProductTaxonomyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetProductTaxonomy not implemented")
+}
+func (UnimplementedCatalogServiceServer) SetProductVariants(context.Context, *SetProductVariantsRequest) (*SetProductVariantsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetProductVariants not implemented")
+}
+func (UnimplementedCatalogServiceServer) RestockVariant(context.Context, *RestockVariantRequest) (*RestockVariantResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestockVariant not implemented")
+}
+func (UnimplementedCatalogServiceServer) ReserveVariantStock(context.Context, *ReserveVariantStockRequest) (*ReserveVariantStockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReserveVariantStock not implemented")
+}
+func (UnimplementedCatalogServiceServer) GetSynonyms(context.Context, *GetSynonymsRequest) (*GetSynonymsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSynonyms not implemented")
+}
+func (UnimplementedCatalogServiceServer) PutSynonymRule(context.Context, *PutSynonymRuleRequest) (*PutSynonymRuleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutSynonymRule not implemented")
+}
+func (UnimplementedCatalogServiceServer) DeleteSynonymRule(context.Context, *DeleteSynonymRuleRequest) (*DeleteSynonymRuleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSynonymRule not implemented")
+}
+func (UnimplementedCatalogServiceServer) GetStopwords(context.Context, *GetStopwordsRequest) (*StopwordsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStopwords not implemented")
+}
+func (UnimplementedCatalogServiceServer) SetStopwords(context.Context, *SetStopwordsRequest) (*StopwordsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetStopwords not implemented")
+}
+func (UnimplementedCatalogServiceServer) RecordSearchFeedback(context.Context, *RecordSearchFeedbackRequest) (*RecordSearchFeedbackResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RecordSearchFeedback not implemented")
+}
+func (UnimplementedCatalogServiceServer) GetTopSearchQueries(context.Context, *SearchQueriesRequest) (*SearchQueriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTopSearchQueries not implemented")
+}
+func (UnimplementedCatalogServiceServer) GetZeroResultQueries(context.Context, *SearchQueriesRequest) (*SearchQueriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetZeroResultQueries not implemented")
+}
+func (UnimplementedCatalogServiceServer) GetSearchClickThrough(context.Context, *SearchClickThroughRequest) (*SearchClickThroughResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSearchClickThrough not implemented")
+}
+func (UnimplementedCatalogServiceServer) SetProductRating(context.Context, *SetProductRatingRequest) (*SetProductRatingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetProductRating not implemented")
+}
 func (UnimplementedCatalogServiceServer) mustEmbedUnimplementedCatalogServiceServer() {}
 
 // UnsafeCatalogServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -152,6 +446,384 @@ func _CatalogService_GetProducts_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CatalogService_SuggestProducts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SuggestProductsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).SuggestProducts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/SuggestProducts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).SuggestProducts(ctx, req.(*SuggestProductsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_GetSimilarProducts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSimilarProductsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).GetSimilarProducts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/GetSimilarProducts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).GetSimilarProducts(ctx, req.(*GetSimilarProductsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_PostCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).PostCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/PostCategory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).PostCategory(ctx, req.(*PostCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_UpdateCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).UpdateCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/UpdateCategory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).UpdateCategory(ctx, req.(*UpdateCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_DeleteCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).DeleteCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/DeleteCategory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).DeleteCategory(ctx, req.(*DeleteCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_GetCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).GetCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/GetCategory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).GetCategory(ctx, req.(*GetCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_GetCategories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCategoriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).GetCategories(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/GetCategories",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).GetCategories(ctx, req.(*GetCategoriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_SetProductTaxonomy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetProductTaxonomyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).SetProductTaxonomy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/SetProductTaxonomy",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).SetProductTaxonomy(ctx, req.(*SetProductTaxonomyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_SetProductVariants_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetProductVariantsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).SetProductVariants(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/SetProductVariants",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).SetProductVariants(ctx, req.(*SetProductVariantsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_RestockVariant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestockVariantRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).RestockVariant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/RestockVariant",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).RestockVariant(ctx, req.(*RestockVariantRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_ReserveVariantStock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReserveVariantStockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).ReserveVariantStock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/ReserveVariantStock",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).ReserveVariantStock(ctx, req.(*ReserveVariantStockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_GetSynonyms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSynonymsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).GetSynonyms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/GetSynonyms",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).GetSynonyms(ctx, req.(*GetSynonymsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_PutSynonymRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutSynonymRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).PutSynonymRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/PutSynonymRule",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).PutSynonymRule(ctx, req.(*PutSynonymRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_DeleteSynonymRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSynonymRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).DeleteSynonymRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/DeleteSynonymRule",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).DeleteSynonymRule(ctx, req.(*DeleteSynonymRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_GetStopwords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStopwordsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).GetStopwords(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/GetStopwords",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).GetStopwords(ctx, req.(*GetStopwordsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_SetStopwords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetStopwordsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).SetStopwords(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/SetStopwords",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).SetStopwords(ctx, req.(*SetStopwordsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_RecordSearchFeedback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecordSearchFeedbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).RecordSearchFeedback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/RecordSearchFeedback",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).RecordSearchFeedback(ctx, req.(*RecordSearchFeedbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_GetTopSearchQueries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchQueriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).GetTopSearchQueries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/GetTopSearchQueries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).GetTopSearchQueries(ctx, req.(*SearchQueriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_GetZeroResultQueries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchQueriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).GetZeroResultQueries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/GetZeroResultQueries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).GetZeroResultQueries(ctx, req.(*SearchQueriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_GetSearchClickThrough_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchClickThroughRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).GetSearchClickThrough(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/GetSearchClickThrough",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).GetSearchClickThrough(ctx, req.(*SearchClickThroughRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_SetProductRating_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetProductRatingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).SetProductRating(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CatalogService/SetProductRating",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).SetProductRating(ctx, req.(*SetProductRatingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // CatalogService_ServiceDesc is the grpc.ServiceDesc for CatalogService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -171,6 +843,90 @@ var CatalogService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetProducts",
 			Handler:    _CatalogService_GetProducts_Handler,
 		},
+		{
+			MethodName: "SuggestProducts",
+			Handler:    _CatalogService_SuggestProducts_Handler,
+		},
+		{
+			MethodName: "GetSimilarProducts",
+			Handler:    _CatalogService_GetSimilarProducts_Handler,
+		},
+		{
+			MethodName: "PostCategory",
+			Handler:    _CatalogService_PostCategory_Handler,
+		},
+		{
+			MethodName: "UpdateCategory",
+			Handler:    _CatalogService_UpdateCategory_Handler,
+		},
+		{
+			MethodName: "DeleteCategory",
+			Handler:    _CatalogService_DeleteCategory_Handler,
+		},
+		{
+			MethodName: "GetCategory",
+			Handler:    _CatalogService_GetCategory_Handler,
+		},
+		{
+			MethodName: "GetCategories",
+			Handler:    _CatalogService_GetCategories_Handler,
+		},
+		{
+			MethodName: "SetProductTaxonomy",
+			Handler:    _CatalogService_SetProductTaxonomy_Handler,
+		},
+		{
+			MethodName: "SetProductVariants",
+			Handler:    _CatalogService_SetProductVariants_Handler,
+		},
+		{
+			MethodName: "RestockVariant",
+			Handler:    _CatalogService_RestockVariant_Handler,
+		},
+		{
+			MethodName: "ReserveVariantStock",
+			Handler:    _CatalogService_ReserveVariantStock_Handler,
+		},
+		{
+			MethodName: "GetSynonyms",
+			Handler:    _CatalogService_GetSynonyms_Handler,
+		},
+		{
+			MethodName: "PutSynonymRule",
+			Handler:    _CatalogService_PutSynonymRule_Handler,
+		},
+		{
+			MethodName: "DeleteSynonymRule",
+			Handler:    _CatalogService_DeleteSynonymRule_Handler,
+		},
+		{
+			MethodName: "GetStopwords",
+			Handler:    _CatalogService_GetStopwords_Handler,
+		},
+		{
+			MethodName: "SetStopwords",
+			Handler:    _CatalogService_SetStopwords_Handler,
+		},
+		{
+			MethodName: "RecordSearchFeedback",
+			Handler:    _CatalogService_RecordSearchFeedback_Handler,
+		},
+		{
+			MethodName: "GetTopSearchQueries",
+			Handler:    _CatalogService_GetTopSearchQueries_Handler,
+		},
+		{
+			MethodName: "GetZeroResultQueries",
+			Handler:    _CatalogService_GetZeroResultQueries_Handler,
+		},
+		{
+			MethodName: "GetSearchClickThrough",
+			Handler:    _CatalogService_GetSearchClickThrough_Handler,
+		},
+		{
+			MethodName: "SetProductRating",
+			Handler:    _CatalogService_SetProductRating_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "catalog.proto",
@@ -0,0 +1,1038 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type postgresRepository struct {
+    db *sql.DB
+}
+
+// NewPostgresRepository stores the catalog in the tables created by up.sql
+// and searches it with the weighted tsvector in products.search.
+func NewPostgresRepository(url string) (Repository, error) {
+    db, err := sql.Open("postgres", url)
+    if err != nil {
+        log.Println("failed to create postgres catalog repository from catalog repository: ", err)
+        return nil, err
+    }
+
+    err = db.Ping()
+    if err != nil {
+        log.Println("failed to connect to postgres catalog repository: ", err)
+        return nil, err
+    }
+
+    return &postgresRepository{db: db}, nil
+}
+
+func (r *postgresRepository) Close() {
+    r.db.Close()
+}
+
+const productColumns = `id, COALESCE(external_sku, ''), name, description, price::float8,
+    tags, category_ids, variants, rating, review_count`
+
+// upsertProduct leaves the variants and rating of existing products alone:
+// their stock is adjusted by orders and the rating set by the review
+// service, and a product read before those changes would undo them.
+// SetProductVariants and SetProductRating update them.
+const upsertProduct = `INSERT INTO products
+    (id, external_sku, name, description, price, tags, category_ids, variants, created_at,
+        rating, review_count)
+    VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    ON CONFLICT (id) DO UPDATE SET
+        external_sku = EXCLUDED.external_sku,
+        name = EXCLUDED.name,
+        description = EXCLUDED.description,
+        price = EXCLUDED.price,
+        tags = EXCLUDED.tags,
+        category_ids = EXCLUDED.category_ids`
+
+type rowScanner interface {
+    Scan(dest ...any) error
+}
+
+func scanProduct(row rowScanner) (Product, error) {
+    p := Product{}
+    variants := []byte{}
+    err := row.Scan(
+        &p.ID,
+        &p.ExternalSKU,
+        &p.Name,
+        &p.Description,
+        &p.Price,
+        pq.Array(&p.Tags),
+        pq.Array(&p.CategoryIDs),
+        &variants,
+        &p.Rating,
+        &p.ReviewCount,
+    )
+    if err != nil {
+        return p, err
+    }
+    if err := json.Unmarshal(variants, &p.Variants); err != nil {
+        return p, err
+    }
+    // Match the elasticsearch documents, which omit empty lists.
+    if len(p.Tags) == 0 {
+        p.Tags = nil
+    }
+    if len(p.CategoryIDs) == 0 {
+        p.CategoryIDs = nil
+    }
+    if len(p.Variants) == 0 {
+        p.Variants = nil
+    }
+    return p, nil
+}
+
+func (r *postgresRepository) queryProducts(
+    ctx context.Context, query string, args ...any,
+) ([]Product, error) {
+    rows, err := r.db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    products := []Product{}
+    for rows.Next() {
+        p, err := scanProduct(rows)
+        if err != nil {
+            return nil, err
+        }
+        products = append(products, p)
+    }
+    return products, rows.Err()
+}
+
+func (r *postgresRepository) putProduct(ctx context.Context, p Product) error {
+    variants, err := json.Marshal(p.Variants)
+    if err != nil {
+        return err
+    }
+    if p.Variants == nil {
+        variants = []byte("[]")
+    }
+    tags, categoryIDs := p.Tags, p.CategoryIDs
+    if tags == nil {
+        tags = []string{}
+    }
+    if categoryIDs == nil {
+        categoryIDs = []string{}
+    }
+
+    // Product ids are ksuids, so the creation time comes from the id.
+    d := newProductDocument(p)
+    _, err = r.db.ExecContext(
+        ctx,
+        upsertProduct,
+        p.ID,
+        p.ExternalSKU,
+        p.Name,
+        p.Description,
+        p.Price,
+        pq.Array(tags),
+        pq.Array(categoryIDs),
+        variants,
+        d.CreatedAt,
+        p.Rating,
+        p.ReviewCount,
+    )
+    return err
+}
+
+func (r *postgresRepository) PutProduct(ctx context.Context, p Product) error {
+    if err := r.putProduct(ctx, p); err != nil {
+        log.Println("failed to put product from catalog repository: ", err)
+        return fmt.Errorf("failed to put product from catalog repository: %w", err)
+    }
+    return nil
+}
+
+func (r *postgresRepository) GetProductByID(
+    ctx context.Context, id string,
+) (*Product, error) {
+    row := r.db.QueryRowContext(
+        ctx, "SELECT "+productColumns+" FROM products WHERE id=$1", id,
+    )
+    p, err := scanProduct(row)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, ErrNotFound
+    }
+    if err != nil {
+        log.Println("failed to get product by id from catalog repository: ", err)
+        return nil, err
+    }
+    return &p, nil
+}
+
+func (r *postgresRepository) ListProducts(
+    ctx context.Context, skip uint64, take uint64,
+) ([]Product, error) {
+    products, err := r.queryProducts(
+        ctx,
+        "SELECT "+productColumns+" FROM products ORDER BY id OFFSET $1 LIMIT $2",
+        skip,
+        take,
+    )
+    if err != nil {
+        log.Println("failed to list products from catalog repository: ", err)
+        return nil, err
+    }
+    return products, nil
+}
+
+func (r *postgresRepository) ListProductsWithIDs(
+    ctx context.Context, ids []string,
+) ([]Product, []string, error) {
+    ids = uniqueIDs(ids)
+    found, err := r.queryProducts(
+        ctx,
+        "SELECT "+productColumns+" FROM products WHERE id = ANY($1)",
+        pq.Array(ids),
+    )
+    if err != nil {
+        log.Println("failed to list products with ids from catalog repository: ", err)
+        return nil, nil, err
+    }
+
+    byID := map[string]Product{}
+    for _, p := range found {
+        byID[p.ID] = p
+    }
+    products := []Product{}
+    missing := []string{}
+    for _, id := range ids {
+        if p, ok := byID[id]; ok {
+            products = append(products, p)
+        } else {
+            missing = append(missing, id)
+        }
+    }
+    return products, missing, nil
+}
+
+// searchFilter builds the WHERE clause shared by the hits and facets of a
+// search. The query, if any, is always the first argument so the ranking
+// can refer to it.
+func searchFilter(req SearchRequest, tsquery string) (string, []any) {
+    conditions := []string{}
+    args := []any{}
+    arg := func(v any) string {
+        args = append(args, v)
+        return fmt.Sprintf("$%d", len(args))
+    }
+
+    if q := strings.TrimSpace(req.Query); q != "" {
+        conditions = append(conditions, "search @@ "+tsquery+"('english', "+arg(q)+")")
+    }
+    if req.MinPrice != nil {
+        conditions = append(conditions, "price >= "+arg(*req.MinPrice))
+    }
+    if req.MaxPrice != nil {
+        conditions = append(conditions, "price <= "+arg(*req.MaxPrice))
+    }
+    if req.MinRating != nil {
+        conditions = append(conditions, "rating >= "+arg(*req.MinRating))
+    }
+    if len(req.Tags) != 0 {
+        conditions = append(conditions, "tags @> "+arg(pq.Array(req.Tags)))
+    }
+    if len(req.CategoryIDs) != 0 {
+        conditions = append(conditions, "category_ids && "+arg(pq.Array(req.CategoryIDs)))
+    }
+
+    if len(conditions) == 0 {
+        return "TRUE", args
+    }
+    return strings.Join(conditions, " AND "), args
+}
+
+// searchOrder is the ORDER BY clause for sort. Ties, and relevance without
+// a query, fall back to id order like the memory repository. tsquery is the
+// function parsing the query at $1, or "" without one.
+func searchOrder(sort SearchSort, tsquery string) string {
+    switch sort {
+    case SortPriceAsc:
+        return "price ASC, id"
+    case SortPriceDesc:
+        return "price DESC, id"
+    case SortNameAsc:
+        return "lower(name) ASC, id"
+    case SortNameDesc:
+        return "lower(name) DESC, id"
+    case SortNewest:
+        return "created_at DESC, id DESC"
+    case SortRatingDesc:
+        return "rating DESC, review_count DESC, id"
+    }
+    if tsquery != "" {
+        return "ts_rank(search, " + tsquery + "('english', $1)) DESC, id"
+    }
+    return "id"
+}
+
+// priceFacetColumns counts the filtered products in each of priceRanges.
+func priceFacetColumns() string {
+    columns := []string{}
+    for _, r := range priceRanges {
+        bounds := []string{}
+        if r.From != nil {
+            bounds = append(bounds, fmt.Sprintf("price >= %g", *r.From))
+        }
+        if r.To != nil {
+            bounds = append(bounds, fmt.Sprintf("price < %g", *r.To))
+        }
+        columns = append(columns, "count(*) FILTER (WHERE "+strings.Join(bounds, " AND ")+")")
+    }
+    return strings.Join(columns, ", ")
+}
+
+// SearchProducts matches stemmed words only: Postgres full-text search has
+// no edit distance, so Fuzziness is ignored and DidYouMean stays empty.
+func (r *postgresRepository) SearchProducts(
+    ctx context.Context, req SearchRequest,
+) (*SearchResult, error) {
+    // Queries are parsed like web searches unless synonyms rewrite them
+    // into tsquery syntax.
+    tsquery := ""
+    if strings.TrimSpace(req.Query) != "" {
+        rules, err := r.ListSynonymRules(ctx)
+        if err != nil {
+            return nil, err
+        }
+        tsquery = "websearch_to_tsquery"
+        if expanded := synonymQuery(req.Query, rules); expanded != "" {
+            req.Query, tsquery = expanded, "to_tsquery"
+        }
+    }
+    where, args := searchFilter(req, tsquery)
+
+    products, err := r.queryProducts(
+        ctx,
+        fmt.Sprintf(
+            "SELECT %s FROM products WHERE %s ORDER BY %s OFFSET %d LIMIT %d",
+            productColumns, where, searchOrder(req.Sort, tsquery), req.Skip, req.Take,
+        ),
+        args...,
+    )
+    if err != nil {
+        log.Println("failed to search products with query from catalog repository: ", err)
+        return nil, err
+    }
+    result := &SearchResult{Products: products}
+
+    counts := make([]uint64, len(priceRanges))
+    dest := []any{&result.Total}
+    for i := range priceRanges {
+        dest = append(dest, &counts[i])
+    }
+    err = r.db.QueryRowContext(
+        ctx,
+        "SELECT count(*), "+priceFacetColumns()+" FROM products WHERE "+where,
+        args...,
+    ).Scan(dest...)
+    if err != nil {
+        log.Println("failed to count search facets from catalog repository: ", err)
+        return nil, err
+    }
+    result.Facets = Facets{Prices: []PriceBucket{}, Tags: []TagCount{}}
+    for i, pr := range priceRanges {
+        result.Facets.Prices = append(result.Facets.Prices, PriceBucket{
+            From: pr.From,
+            To: pr.To,
+            Count: counts[i],
+        })
+    }
+
+    rows, err := r.db.QueryContext(
+        ctx,
+        fmt.Sprintf(
+            `SELECT tag, count(*) FROM products, unnest(tags) AS tag WHERE %s
+            GROUP BY tag ORDER BY count(*) DESC, tag LIMIT %d`,
+            where, maxTagFacets,
+        ),
+        args...,
+    )
+    if err != nil {
+        log.Println("failed to count tag facets from catalog repository: ", err)
+        return nil, err
+    }
+    defer rows.Close()
+    for rows.Next() {
+        t := TagCount{}
+        if err := rows.Scan(&t.Tag, &t.Count); err != nil {
+            log.Println("failed to scan tag facet from catalog repository: ", err)
+            return nil, err
+        }
+        result.Facets.Tags = append(result.Facets.Tags, t)
+    }
+    if err := rows.Err(); err != nil {
+        log.Println("failed to read tag facets from catalog repository: ", err)
+        return nil, err
+    }
+
+    result.Highlights = map[string][]Highlight{}
+    if tsquery != "" && len(products) != 0 {
+        if result.Highlights, err = r.highlights(ctx, tsquery, req.Query, products); err != nil {
+            return nil, err
+        }
+    }
+    return result, nil
+}
+
+// headlineDelimiter separates the description fragments ts_headline
+// returns.
+const headlineDelimiter = " ... "
+
+// highlights runs ts_headline over the name and description of products,
+// keeping the fields in which the query, parsed with tsquery, matched.
+func (r *postgresRepository) highlights(
+    ctx context.Context, tsquery, query string, products []Product,
+) (map[string][]Highlight, error) {
+    ids := []string{}
+    for _, p := range products {
+        ids = append(ids, p.ID)
+    }
+
+    rows, err := r.db.QueryContext(
+        ctx,
+        `SELECT id,
+            ts_headline('english', name, q, 'HighlightAll=true, StartSel=<em>, StopSel=</em>'),
+            ts_headline('english', description, q,
+                'MaxFragments=3, MaxWords=25, MinWords=5, StartSel=<em>, StopSel=</em>, FragmentDelimiter="`+headlineDelimiter+`"')
+        FROM products, `+tsquery+`('english', $1) AS q
+        WHERE id = ANY($2)`,
+        query,
+        pq.Array(ids),
+    )
+    if err != nil {
+        log.Println("failed to highlight products from catalog repository: ", err)
+        return nil, err
+    }
+    defer rows.Close()
+
+    highlights := map[string][]Highlight{}
+    for rows.Next() {
+        var id, name, description string
+        if err := rows.Scan(&id, &name, &description); err != nil {
+            log.Println("failed to scan highlight from catalog repository: ", err)
+            return nil, err
+        }
+        if strings.Contains(name, "<em>") {
+            highlights[id] = append(highlights[id], Highlight{Field: "name", Fragments: []string{name}})
+        }
+        if strings.Contains(description, "<em>") {
+            highlights[id] = append(highlights[id], Highlight{
+                Field: "description",
+                Fragments: strings.Split(description, headlineDelimiter),
+            })
+        }
+    }
+    return highlights, rows.Err()
+}
+
+// synonymQuery rewrites query into to_tsquery syntax, matching each word or
+// any of its synonyms. It returns "" when no rule applies to query.
+func synonymQuery(query string, rules []SynonymRule) string {
+    alternatives := synonymAlternatives(wordPattern.FindAllString(strings.ToLower(query), -1), rules)
+    if !slices.ContainsFunc(alternatives, func(alts []string) bool { return len(alts) > 1 }) {
+        return ""
+    }
+
+    groups := []string{}
+    for _, alts := range alternatives {
+        phrases := []string{}
+        for _, alt := range alts {
+            if words := wordPattern.FindAllString(alt, -1); len(words) != 0 {
+                phrases = append(phrases, strings.Join(words, " <-> "))
+            }
+        }
+        groups = append(groups, "("+strings.Join(phrases, " | ")+")")
+    }
+    return strings.Join(groups, " & ")
+}
+
+// GetProductBySKU finds the product owning the variant with sku through
+// JSON containment on the variants column.
+func (r *postgresRepository) GetProductBySKU(
+    ctx context.Context, sku string,
+) (*Product, error) {
+    row := r.db.QueryRowContext(
+        ctx,
+        "SELECT "+productColumns+` FROM products
+        WHERE variants @> jsonb_build_array(jsonb_build_object('sku', $1::text))
+        LIMIT 1`,
+        sku,
+    )
+    p, err := scanProduct(row)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, ErrNotFound
+    }
+    if err != nil {
+        log.Println("failed to get product by sku from catalog repository: ", err)
+        return nil, err
+    }
+    return &p, nil
+}
+
+func (r *postgresRepository) GetProductsByExternalSKUs(
+    ctx context.Context, skus []string,
+) ([]Product, error) {
+    products, err := r.queryProducts(
+        ctx,
+        "SELECT "+productColumns+" FROM products WHERE external_sku = ANY($1) ORDER BY id",
+        pq.Array(skus),
+    )
+    if err != nil {
+        log.Println("failed to get products by external sku from catalog repository: ", err)
+        return nil, err
+    }
+    return products, nil
+}
+
+// PutProducts upserts each product on its own, so one bad row doesn't fail
+// the others, like items of an elasticsearch bulk request.
+func (r *postgresRepository) PutProducts(
+    ctx context.Context, products []Product,
+) ([]error, error) {
+    errs := make([]error, len(products))
+    for i, p := range products {
+        if err := ctx.Err(); err != nil {
+            return nil, err
+        }
+        errs[i] = r.putProduct(ctx, p)
+    }
+    return errs, nil
+}
+
+// ScanProducts pages through the products in id order with keyset
+// pagination.
+func (r *postgresRepository) ScanProducts(
+    ctx context.Context, fn func(Product) error,
+) error {
+    after := ""
+    for {
+        products, err := r.queryProducts(
+            ctx,
+            "SELECT "+productColumns+" FROM products WHERE id > $1 ORDER BY id LIMIT $2",
+            after,
+            scanPageSize,
+        )
+        if err != nil {
+            log.Println("failed to scan products from catalog repository: ", err)
+            return err
+        }
+
+        for _, p := range products {
+            if err := fn(p); err != nil {
+                return err
+            }
+            after = p.ID
+        }
+        if len(products) < scanPageSize {
+            return nil
+        }
+    }
+}
+
+// prefixQuery turns a typed prefix into a tsquery in which every word must
+// match and the last one may be incomplete, like a bool_prefix query.
+// Anything but letters and digits is dropped so the input can't inject
+// tsquery operators.
+func prefixQuery(prefix string) (string, []string) {
+    terms := wordPattern.FindAllString(strings.ToLower(prefix), -1)
+    if len(terms) == 0 {
+        return "", terms
+    }
+    return strings.Join(terms, " & ") + ":*", terms
+}
+
+// SuggestProducts matches the prefix against the unstemmed name and
+// highlights it the same way as the memory repository.
+func (r *postgresRepository) SuggestProducts(
+    ctx context.Context, prefix string, limit int,
+) ([]Suggestion, error) {
+    query, terms := prefixQuery(prefix)
+    if query == "" {
+        return []Suggestion{}, nil
+    }
+
+    rows, err := r.db.QueryContext(
+        ctx,
+        `SELECT id, name FROM products
+        WHERE to_tsvector('simple', name) @@ to_tsquery('simple', $1)
+        ORDER BY lower(name) LIKE $2 || '%' DESC, lower(name)
+        LIMIT $3`,
+        query,
+        terms[0],
+        limit,
+    )
+    if err != nil {
+        log.Println("failed to suggest products from catalog repository: ", err)
+        return nil, err
+    }
+    defer rows.Close()
+
+    suggestions := []Suggestion{}
+    for rows.Next() {
+        s := Suggestion{}
+        if err := rows.Scan(&s.ProductID, &s.Name); err != nil {
+            log.Println("failed to scan suggestion from catalog repository: ", err)
+            return nil, err
+        }
+        s.Highlighted = s.Name
+        if highlighted, _, ok := highlightPrefix(s.Name, terms); ok {
+            s.Highlighted = highlighted
+        }
+        suggestions = append(suggestions, s)
+    }
+    return suggestions, rows.Err()
+}
+
+// SimilarProducts ranks the products matching any lexeme of the product's
+// search vector, adding one per shared tag.
+func (r *postgresRepository) SimilarProducts(
+    ctx context.Context, id string, take int,
+) ([]Product, error) {
+    products, err := r.queryProducts(
+        ctx,
+        `WITH source AS (
+            SELECT id, tags, (
+                SELECT COALESCE(string_agg(quote_literal(lexeme), ' | '), '')
+                FROM unnest(tsvector_to_array(search)) lexeme
+            )::tsquery AS query
+            FROM products WHERE id=$1
+        )
+        SELECT `+productColumns+` FROM (
+            SELECT p.*,
+                ts_rank(p.search, source.query) + cardinality(ARRAY(
+                    SELECT unnest(p.tags) INTERSECT SELECT unnest(source.tags)
+                )) AS score
+            FROM products p, source
+            WHERE p.id <> source.id AND (p.search @@ source.query OR p.tags && source.tags)
+        ) ranked
+        ORDER BY score DESC, id
+        LIMIT $2`,
+        id,
+        take,
+    )
+    if err != nil {
+        log.Println("failed to get similar products from catalog repository: ", err)
+        return nil, err
+    }
+    if len(products) != 0 {
+        return products, nil
+    }
+
+    // An empty result doesn't tell an unknown product from one without
+    // similar products.
+    if _, err := r.GetProductByID(ctx, id); err != nil {
+        return nil, err
+    }
+    return products, nil
+}
+
+func (r *postgresRepository) PutCategory(ctx context.Context, c Category) error {
+    _, err := r.db.ExecContext(
+        ctx,
+        `INSERT INTO categories (id, name, slug, parent_id, path) VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (id) DO UPDATE SET
+            name = EXCLUDED.name,
+            slug = EXCLUDED.slug,
+            parent_id = EXCLUDED.parent_id,
+            path = EXCLUDED.path`,
+        c.ID,
+        c.Name,
+        c.Slug,
+        c.ParentID,
+        c.Path,
+    )
+    if err != nil {
+        log.Println("failed to put category from catalog repository: ", err)
+        return fmt.Errorf("failed to put category from catalog repository: %w", err)
+    }
+    return nil
+}
+
+func (r *postgresRepository) SetProductRating(
+    ctx context.Context, id string, rating float64, count, version uint64,
+) error {
+    var updated bool
+    err := r.db.QueryRowContext(
+        ctx,
+        `WITH updated AS (
+            UPDATE products SET rating=$2, review_count=$3, rating_version=$4
+            WHERE id=$1 AND rating_version < $4
+            RETURNING id
+        )
+        SELECT EXISTS (SELECT 1 FROM updated) FROM products WHERE id=$1`,
+        id, rating, count, version,
+    ).Scan(&updated)
+    if errors.Is(err, sql.ErrNoRows) {
+        return ErrNotFound
+    }
+    if err != nil {
+        log.Println("failed to update product rating from catalog repository: ", err)
+        return err
+    }
+    return nil
+}
+
+func (r *postgresRepository) SetProductTaxonomy(
+    ctx context.Context, id string, categoryIDs, tags []string,
+) error {
+    if categoryIDs == nil {
+        categoryIDs = []string{}
+    }
+    if tags == nil {
+        tags = []string{}
+    }
+    res, err := r.db.ExecContext(
+        ctx,
+        "UPDATE products SET category_ids=$2, tags=$3 WHERE id=$1",
+        id, pq.Array(categoryIDs), pq.Array(tags),
+    )
+    if err != nil {
+        log.Println("failed to update product taxonomy from catalog repository: ", err)
+        return err
+    }
+    if n, err := res.RowsAffected(); err == nil && n == 0 {
+        return ErrNotFound
+    }
+    return nil
+}
+
+// SetProductVariants carries the stock of existing variants over in the
+// same UPDATE, under the row lock AdjustVariantStock takes too.
+func (r *postgresRepository) SetProductVariants(
+    ctx context.Context, id string, variants []Variant,
+) error {
+    if variants == nil {
+        variants = []Variant{}
+    }
+    encoded, err := json.Marshal(variants)
+    if err != nil {
+        return err
+    }
+    res, err := r.db.ExecContext(
+        ctx,
+        `UPDATE products SET variants = (
+            SELECT COALESCE(jsonb_agg(
+                CASE WHEN old.v IS NULL THEN n.v
+                ELSE jsonb_set(n.v, '{stock}', COALESCE(old.v->'stock', '0')) END
+                ORDER BY n.i
+            ), '[]')
+            FROM jsonb_array_elements($2::jsonb) WITH ORDINALITY AS n(v, i)
+            LEFT JOIN jsonb_array_elements(products.variants) AS old(v)
+                ON old.v->>'id' = n.v->>'id'
+        )
+        WHERE id = $1`,
+        id, encoded,
+    )
+    if err != nil {
+        log.Println("failed to set product variants from catalog repository: ", err)
+        return err
+    }
+    if n, err := res.RowsAffected(); err == nil && n == 0 {
+        return ErrNotFound
+    }
+    return nil
+}
+
+// AdjustVariantStock rewrites the variants array in a single UPDATE; the
+// row lock makes concurrent adjustments of the same product wait, and the
+// stock check is evaluated again once they're done.
+func (r *postgresRepository) AdjustVariantStock(
+    ctx context.Context, productID, variantID string, delta int64,
+) error {
+    res, err := r.db.ExecContext(
+        ctx,
+        `UPDATE products SET variants = (
+            SELECT jsonb_agg(
+                CASE WHEN v->>'id' = $2
+                THEN jsonb_set(v, '{stock}', to_jsonb(COALESCE((v->>'stock')::bigint, 0) + $3))
+                ELSE v END
+                ORDER BY i
+            )
+            FROM jsonb_array_elements(variants) WITH ORDINALITY AS e(v, i)
+        )
+        WHERE id = $1 AND EXISTS (
+            SELECT 1 FROM jsonb_array_elements(variants) AS e(v)
+            WHERE v->>'id' = $2 AND COALESCE((v->>'stock')::bigint, 0) + $3 >= 0
+        )`,
+        productID, variantID, delta,
+    )
+    if err != nil {
+        log.Println("failed to adjust variant stock from catalog repository: ", err)
+        return err
+    }
+    if n, err := res.RowsAffected(); err != nil || n != 0 {
+        return err
+    }
+
+    // Nothing was updated; tell the reasons apart.
+    p, err := r.GetProductByID(ctx, productID)
+    if err != nil {
+        return err
+    }
+    if _, ok := p.Variant(variantID); !ok {
+        return ErrInvalidVariant
+    }
+    return ErrOutOfStock
+}
+
+func (r *postgresRepository) GetCategoryByID(
+    ctx context.Context, id string,
+) (*Category, error) {
+    c := &Category{}
+    err := r.db.QueryRowContext(
+        ctx,
+        "SELECT id, name, slug, parent_id, path FROM categories WHERE id=$1",
+        id,
+    ).Scan(&c.ID, &c.Name, &c.Slug, &c.ParentID, &c.Path)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, ErrNotFound
+    }
+    if err != nil {
+        log.Println("failed to get category by id from catalog repository: ", err)
+        return nil, err
+    }
+    return c, nil
+}
+
+func (r *postgresRepository) ListCategories(ctx context.Context) ([]Category, error) {
+    rows, err := r.db.QueryContext(
+        ctx, "SELECT id, name, slug, parent_id, path FROM categories",
+    )
+    if err != nil {
+        log.Println("failed to list categories from catalog repository: ", err)
+        return nil, err
+    }
+    defer rows.Close()
+
+    categories := []Category{}
+    for rows.Next() {
+        c := Category{}
+        if err := rows.Scan(&c.ID, &c.Name, &c.Slug, &c.ParentID, &c.Path); err != nil {
+            log.Println("failed to scan category from catalog repository: ", err)
+            return nil, err
+        }
+        categories = append(categories, c)
+    }
+    // Sorted here rather than in SQL so the order doesn't depend on the
+    // database collation.
+    slices.SortFunc(categories, func(a, b Category) int {
+        return strings.Compare(a.Path, b.Path)
+    })
+    return categories, rows.Err()
+}
+
+func (r *postgresRepository) DeleteCategory(ctx context.Context, id string) error {
+    res, err := r.db.ExecContext(ctx, "DELETE FROM categories WHERE id=$1", id)
+    if err != nil {
+        log.Println("failed to delete category from catalog repository: ", err)
+        return err
+    }
+    if n, err := res.RowsAffected(); err == nil && n == 0 {
+        return ErrNotFound
+    }
+    return nil
+}
+
+func (r *postgresRepository) ListSynonymRules(ctx context.Context) ([]SynonymRule, error) {
+    rows, err := r.db.QueryContext(ctx, "SELECT id, terms FROM synonym_rules ORDER BY id")
+    if err != nil {
+        log.Println("failed to list synonym rules from catalog repository: ", err)
+        return nil, err
+    }
+    defer rows.Close()
+
+    rules := []SynonymRule{}
+    for rows.Next() {
+        rule := SynonymRule{}
+        if err := rows.Scan(&rule.ID, pq.Array(&rule.Terms)); err != nil {
+            log.Println("failed to scan synonym rule from catalog repository: ", err)
+            return nil, err
+        }
+        rules = append(rules, rule)
+    }
+    return rules, rows.Err()
+}
+
+func (r *postgresRepository) PutSynonymRule(ctx context.Context, rule SynonymRule) error {
+    _, err := r.db.ExecContext(
+        ctx,
+        `INSERT INTO synonym_rules (id, terms) VALUES ($1, $2)
+        ON CONFLICT (id) DO UPDATE SET terms = EXCLUDED.terms`,
+        rule.ID,
+        pq.Array(rule.Terms),
+    )
+    if err != nil {
+        log.Println("failed to put synonym rule from catalog repository: ", err)
+        return err
+    }
+    return nil
+}
+
+func (r *postgresRepository) DeleteSynonymRule(ctx context.Context, id string) error {
+    res, err := r.db.ExecContext(ctx, "DELETE FROM synonym_rules WHERE id=$1", id)
+    if err != nil {
+        log.Println("failed to delete synonym rule from catalog repository: ", err)
+        return err
+    }
+    if n, err := res.RowsAffected(); err == nil && n == 0 {
+        return ErrNotFound
+    }
+    return nil
+}
+
+func (r *postgresRepository) GetStopwords(ctx context.Context) ([]string, error) {
+    words := []string{}
+    err := r.db.QueryRowContext(
+        ctx, "SELECT coalesce(array_agg(word ORDER BY word), '{}') FROM stopwords",
+    ).Scan(pq.Array(&words))
+    if err != nil {
+        log.Println("failed to get stopwords from catalog repository: ", err)
+        return nil, err
+    }
+    return words, nil
+}
+
+// PutStopwords replaces the stopwords in a transaction, so searches never
+// see a partial list.
+func (r *postgresRepository) PutStopwords(ctx context.Context, words []string) error {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        log.Println("failed to begin stopwords transaction from catalog repository: ", err)
+        return err
+    }
+    defer tx.Rollback()
+
+    if _, err := tx.ExecContext(ctx, "DELETE FROM stopwords"); err != nil {
+        log.Println("failed to clear stopwords from catalog repository: ", err)
+        return err
+    }
+    _, err = tx.ExecContext(
+        ctx,
+        "INSERT INTO stopwords (word) SELECT unnest($1::TEXT[]) ON CONFLICT DO NOTHING",
+        pq.Array(words),
+    )
+    if err != nil {
+        log.Println("failed to put stopwords from catalog repository: ", err)
+        return err
+    }
+    return tx.Commit()
+}
+
+func (r *postgresRepository) PutSearchEvent(ctx context.Context, e SearchEvent) error {
+    tags, categoryIDs := e.Tags, e.CategoryIDs
+    if tags == nil {
+        tags = []string{}
+    }
+    if categoryIDs == nil {
+        categoryIDs = []string{}
+    }
+
+    _, err := r.db.ExecContext(
+        ctx,
+        `INSERT INTO search_events
+            (id, query, min_price, max_price, tags, category_ids, sort, results, latency_ms, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+        e.ID,
+        e.Query,
+        e.MinPrice,
+        e.MaxPrice,
+        pq.Array(tags),
+        pq.Array(categoryIDs),
+        e.Sort,
+        e.Results,
+        float64(e.Latency) / float64(time.Millisecond),
+        e.CreatedAt,
+    )
+    if err != nil {
+        log.Println("failed to put search event from catalog repository: ", err)
+        return err
+    }
+    return nil
+}
+
+func (r *postgresRepository) PutSearchFeedback(ctx context.Context, f SearchFeedback) error {
+    _, err := r.db.ExecContext(
+        ctx,
+        "INSERT INTO search_feedback (search_id, product_id, kind, created_at) VALUES ($1, $2, $3, $4)",
+        f.SearchID,
+        f.ProductID,
+        f.Kind,
+        f.CreatedAt,
+    )
+    if err != nil {
+        log.Println("failed to put search feedback from catalog repository: ", err)
+        return err
+    }
+    return nil
+}
+
+func (r *postgresRepository) TopSearchQueries(
+    ctx context.Context, since time.Time, limit int, zeroResults bool,
+) ([]QueryCount, error) {
+    where := "created_at >= $1 AND query <> ''"
+    if zeroResults {
+        where += " AND results = 0"
+    }
+    rows, err := r.db.QueryContext(
+        ctx,
+        "SELECT query, count(*) FROM search_events WHERE "+where+
+            " GROUP BY query ORDER BY count(*) DESC, query LIMIT $2",
+        since,
+        limit,
+    )
+    if err != nil {
+        log.Println("failed to count search queries from catalog repository: ", err)
+        return nil, err
+    }
+    defer rows.Close()
+
+    queries := []QueryCount{}
+    for rows.Next() {
+        q := QueryCount{}
+        if err := rows.Scan(&q.Query, &q.Searches); err != nil {
+            log.Println("failed to scan search query count from catalog repository: ", err)
+            return nil, err
+        }
+        queries = append(queries, q)
+    }
+    return queries, rows.Err()
+}
+
+func (r *postgresRepository) SearchClickThrough(
+    ctx context.Context, since time.Time,
+) (*ClickThrough, error) {
+    ct := &ClickThrough{}
+    err := r.db.QueryRowContext(
+        ctx,
+        `SELECT
+            (SELECT count(*) FROM search_events WHERE created_at >= $1),
+            count(DISTINCT search_id) FILTER (WHERE kind = 'click'),
+            count(DISTINCT search_id) FILTER (WHERE kind = 'add_to_cart')
+        FROM search_feedback WHERE created_at >= $1`,
+        since,
+    ).Scan(&ct.Searches, &ct.ClickedSearches, &ct.AddedToCartSearches)
+    if err != nil {
+        log.Println("failed to count search click through from catalog repository: ", err)
+        return nil, err
+    }
+    return ct, nil
+}
@@ -0,0 +1,93 @@
+package catalog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestSearchFilter(t *testing.T) {
+    min, max := 10.0, 50.0
+    where, args := searchFilter(SearchRequest{
+        Query: " wireless headphones ",
+        MinPrice: &min,
+        MaxPrice: &max,
+        Tags: []string{"audio"},
+        CategoryIDs: []string{"c1", "c2"},
+    }, "websearch_to_tsquery")
+
+    want := "search @@ websearch_to_tsquery('english', $1) AND price >= $2 AND price <= $3" +
+        " AND tags @> $4 AND category_ids && $5"
+    if where != want {
+        t.Fatalf("got %q, want %q", where, want)
+    }
+    wantArgs := []any{
+        "wireless headphones",
+        10.0,
+        50.0,
+        pq.Array([]string{"audio"}),
+        pq.Array([]string{"c1", "c2"}),
+    }
+    if !reflect.DeepEqual(args, wantArgs) {
+        t.Fatalf("got args %#v, want %#v", args, wantArgs)
+    }
+
+    if where, args := searchFilter(SearchRequest{Query: "  "}, ""); where != "TRUE" || len(args) != 0 {
+        t.Fatalf("expected an empty request to match everything, got %q %v", where, args)
+    }
+}
+
+func TestSearchOrderRanksOnlyWithQuery(t *testing.T) {
+    if order := searchOrder(SortRelevance, "websearch_to_tsquery"); !strings.HasPrefix(order, "ts_rank(search") {
+        t.Fatalf("expected relevance to rank by ts_rank, got %q", order)
+    }
+    if order := searchOrder(SortRelevance, ""); order != "id" {
+        t.Fatalf("expected relevance without a query to use id order, got %q", order)
+    }
+    if order := searchOrder(SortNewest, "websearch_to_tsquery"); order != "created_at DESC, id DESC" {
+        t.Fatalf("unexpected newest order %q", order)
+    }
+}
+
+func TestPriceFacetColumns(t *testing.T) {
+    columns := priceFacetColumns()
+    want := "count(*) FILTER (WHERE price < 25), " +
+        "count(*) FILTER (WHERE price >= 25 AND price < 50), " +
+        "count(*) FILTER (WHERE price >= 50 AND price < 100), " +
+        "count(*) FILTER (WHERE price >= 100 AND price < 250), " +
+        "count(*) FILTER (WHERE price >= 250)"
+    if columns != want {
+        t.Fatalf("got %q, want %q", columns, want)
+    }
+}
+
+func TestPrefixQuery(t *testing.T) {
+    tests := []struct {
+        prefix string
+        query  string
+    }{
+        {"Wireless hea", "wireless & hea:*"},
+        {"head", "head:*"},
+        {"a' | !b:*", "a & b:*"},
+        {"  &  ", ""},
+    }
+    for _, tt := range tests {
+        if query, _ := prefixQuery(tt.prefix); query != tt.query {
+            t.Errorf("prefixQuery(%q) = %q, want %q", tt.prefix, query, tt.query)
+        }
+    }
+}
+
+func TestUpsertProductKeepsVariantsAndRating(t *testing.T) {
+    _, set, ok := strings.Cut(upsertProduct, "DO UPDATE SET")
+    if !ok {
+        t.Fatal("expected an upsert")
+    }
+    for _, column := range []string{"variants", "rating", "review_count"} {
+        if strings.Contains(set, column+" =") {
+            t.Fatalf("expected updates to leave %s alone, got %s", column, set)
+        }
+    }
+}
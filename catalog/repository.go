@@ -5,23 +5,81 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/mget"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/update"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/fieldtype"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/result"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/sortorder"
+	"github.com/segmentio/ksuid"
 )
 
 var (
     ErrNotFound = errors.New("entity not found")
 )
 
+// mgetChunkSize bounds the number of ids sent in a single _mget request.
+const mgetChunkSize = 500
+
 type Repository interface {
     Close()
     PutProduct(ctx context.Context, p Product) error
     GetProductByID(ctx context.Context, id string) (*Product, error)
     ListProducts(ctx context.Context, skip uint64, take uint64) ([]Product, error)
-    ListProductsWithIDs(ctx context.Context, ids []string)([]Product, error)
-    SearchProducts(ctx context.Context, query string, skip uint64, take uint64)([]Product, error)
+    // ListProductsWithIDs returns the products for ids in the order
+    // requested, without duplicates, along with the ids that have none.
+    ListProductsWithIDs(ctx context.Context, ids []string) ([]Product, []string, error)
+    SearchProducts(ctx context.Context, req SearchRequest) (*SearchResult, error)
+    GetProductBySKU(ctx context.Context, sku string) (*Product, error)
+    GetProductsByExternalSKUs(ctx context.Context, skus []string) ([]Product, error)
+    PutProducts(ctx context.Context, products []Product) ([]error, error)
+    ScanProducts(ctx context.Context, fn func(Product) error) error
+    SuggestProducts(ctx context.Context, prefix string, limit int) ([]Suggestion, error)
+    // SimilarProducts leaves out the product itself and returns
+    // ErrNotFound if it doesn't exist.
+    SimilarProducts(ctx context.Context, id string, take int) ([]Product, error)
+    PutCategory(ctx context.Context, c Category) error
+    // SetProductRating stores the rating unless one of a version at least
+    // as high is stored already. It returns ErrNotFound for unknown
+    // products.
+    SetProductRating(ctx context.Context, id string, rating float64, count, version uint64) error
+    // SetProductTaxonomy replaces only the categories and tags of a
+    // product, so it doesn't overwrite concurrent rating or stock updates.
+    // It returns ErrNotFound for unknown products.
+    SetProductTaxonomy(ctx context.Context, id string, categoryIDs, tags []string) error
+    // SetProductVariants replaces the variants of a product in a single
+    // update. Variants the product already has keep their stored stock, so
+    // concurrent reservations and restocks aren't overwritten; new ones
+    // start with their given stock. It returns ErrNotFound for unknown
+    // products.
+    SetProductVariants(ctx context.Context, id string, variants []Variant) error
+    // AdjustVariantStock adds delta, which may be negative, to the stock of
+    // a variant in a single update. It returns ErrNotFound for unknown
+    // products, ErrInvalidVariant for unknown variants and ErrOutOfStock,
+    // changing nothing, if the stock would drop below zero.
+    AdjustVariantStock(ctx context.Context, productID, variantID string, delta int64) error
+    GetCategoryByID(ctx context.Context, id string) (*Category, error)
+    ListCategories(ctx context.Context) ([]Category, error)
+    DeleteCategory(ctx context.Context, id string) error
+    ListSynonymRules(ctx context.Context) ([]SynonymRule, error)
+    PutSynonymRule(ctx context.Context, rule SynonymRule) error
+    DeleteSynonymRule(ctx context.Context, id string) error
+    GetStopwords(ctx context.Context) ([]string, error)
+    PutStopwords(ctx context.Context, words []string) error
+    PutSearchEvent(ctx context.Context, e SearchEvent) error
+    PutSearchFeedback(ctx context.Context, f SearchFeedback) error
+    // TopSearchQueries counts the non-empty queries searched since, most
+    // frequent first, only counting searches without results if
+    // zeroResults is set.
+    TopSearchQueries(ctx context.Context, since time.Time, limit int, zeroResults bool) ([]QueryCount, error)
+    // SearchClickThrough leaves computing the rate to the service.
+    SearchClickThrough(ctx context.Context, since time.Time) (*ClickThrough, error)
 }
 
 type elasticRepository struct {
@@ -29,35 +87,84 @@ type elasticRepository struct {
 }
 
 type productDocument struct {
-    Name        string  `json:"name"`
-    Description string  `json:"description"`
-    Price       float64 `json:"price"`
+    ExternalSKU string     `json:"external_sku,omitempty"`
+    Name        string     `json:"name"`
+    Description string     `json:"description"`
+    Price       float64    `json:"price"`
+    Tags        []string   `json:"tags,omitempty"`
+    CategoryIDs []string   `json:"category_ids,omitempty"`
+    Variants    []Variant  `json:"variants,omitempty"`
+    CreatedAt   *time.Time `json:"created_at,omitempty"`
+    Rating      float64    `json:"rating,omitempty"`
+    ReviewCount uint64     `json:"review_count,omitempty"`
 }
 
-func NewElasticRepository(url string) (Repository, error) {
-    elasticCfg := elasticsearch.Config {
-        Addresses: []string{url},
+func (d productDocument) product(id string) Product {
+    return Product{
+        ID: id,
+        ExternalSKU: d.ExternalSKU,
+        Name: d.Name,
+        Description: d.Description,
+        Price: d.Price,
+        Tags: d.Tags,
+        CategoryIDs: d.CategoryIDs,
+        Variants: d.Variants,
+        Rating: d.Rating,
+        ReviewCount: d.ReviewCount,
     }
-    client, err := elasticsearch.NewTypedClient(elasticCfg)
+}
+
+func newProductDocument(p Product) productDocument {
+    d := productDocument{
+        ExternalSKU: p.ExternalSKU,
+        Name: p.Name,
+        Description: p.Description,
+        Price: p.Price,
+        Tags: p.Tags,
+        CategoryIDs: p.CategoryIDs,
+        Variants: p.Variants,
+        Rating: p.Rating,
+        ReviewCount: p.ReviewCount,
+    }
+    // Product ids are ksuids, so the creation time used for sorting by
+    // newest comes from the id itself.
+    if id, err := ksuid.Parse(p.ID); err == nil {
+        createdAt := id.Time().UTC()
+        d.CreatedAt = &createdAt
+    }
+    return d
+}
+
+// uniqueIDs returns ids without duplicates, keeping the first occurrence.
+func uniqueIDs(ids []string) []string {
+    unique := []string{}
+    seen := map[string]bool{}
+    for _, id := range ids {
+        if !seen[id] {
+            seen[id] = true
+            unique = append(unique, id)
+        }
+    }
+    return unique
+}
+
+func NewElasticRepository(url string) (Repository, error) {
+    client, err := newElasticClient(url)
     if err != nil {
-        log.Printf("failed to create elasticsearch client: %v", err)
         return nil, err
     }
 
-    exists, err  := client.Indices.Exists("catalog").Do(context.TODO())
-    if err != nil {
-        log.Println("failed to check index exists: ", err)
+    if err := ensureIndex(context.TODO(), client); err != nil {
         return nil, err
     }
-    if !exists {
-        res, err := client.Indices.Create(
-            "catalog",
-        ).Do(context.TODO())
-        if err != nil {
-            log.Println("failed to create elasticsearch catalog index: ", err)
-            return nil, err
-        }
-        log.Println("create elasticsearch index catalog success: ", res.Index)
+    if err := ensureCategoriesIndex(context.TODO(), client); err != nil {
+        return nil, err
+    }
+    if err := ensureSearchSettingsIndex(context.TODO(), client); err != nil {
+        return nil, err
+    }
+    if err := ensureAnalyticsIndices(context.TODO(), client); err != nil {
+        return nil, err
     }
 
     return &elasticRepository{client}, nil
@@ -66,13 +173,9 @@ func NewElasticRepository(url string) (Repository, error) {
 func (r *elasticRepository) Close() {}
 
 func (r *elasticRepository) PutProduct(ctx context.Context, p Product) error {
-    product_docuemnt := productDocument{
-        Name: p.Name,
-        Description: p.Description,
-        Price: p.Price,
-    }
+    product_docuemnt := newProductDocument(p)
 
-    res, err := r.client.Index("catalog").
+    res, err := r.client.Index(catalogAlias).
         Id(p.ID).
         Request(product_docuemnt).
         Do(ctx)
@@ -89,7 +192,7 @@ func (r *elasticRepository) PutProduct(ctx context.Context, p Product) error {
 func (r *elasticRepository) GetProductByID(
     ctx context.Context, id string,
 ) (*Product, error) {
-    res, err := r.client.Get("catalog", id).Do(ctx)
+    res, err := r.client.Get(catalogAlias, id).Do(ctx)
     if err != nil {
         log.Println("failed to get product by id from catalog repository: ", err)
         return nil, err
@@ -102,24 +205,225 @@ func (r *elasticRepository) GetProductByID(
         return nil, err
     }
 
-    return &Product{
-        ID: id,
-        Name: p.Name,
-        Description: p.Description,
-        Price: p.Price,
-    }, err
+    product := p.product(id)
+    return &product, err
+}
+
+// setRatingScript stores the rating fields unless the document has a
+// rating of the same or a later version.
+const setRatingScript = `
+long stored = ctx._source.rating_version == null ? 0 : ctx._source.rating_version;
+if (params.version > stored) {
+    ctx._source.rating = params.rating;
+    ctx._source.review_count = params.review_count;
+    ctx._source.rating_version = params.version;
+} else {
+    ctx.op = 'noop';
+}`
+
+// SetProductRating updates only the rating fields, so it doesn't race with
+// edits to the rest of the product.
+func (r *elasticRepository) SetProductRating(
+    ctx context.Context, id string, rating float64, count, version uint64,
+) error {
+    params := map[string]json.RawMessage{}
+    for name, v := range map[string]any{"rating": rating, "review_count": count, "version": version} {
+        raw, err := json.Marshal(v)
+        if err != nil {
+            return err
+        }
+        params[name] = raw
+    }
+
+    source := setRatingScript
+    _, err := r.client.Update(catalogAlias, id).
+        Request(&update.Request{Script: &types.Script{Source: &source, Params: params}}).
+        RetryOnConflict(3).
+        Do(ctx)
+    var esErr *types.ElasticsearchError
+    if errors.As(err, &esErr) && esErr.Status == http.StatusNotFound {
+        return ErrNotFound
+    }
+    if err != nil {
+        log.Println("failed to update product rating from catalog repository: ", err)
+    }
+    return err
+}
+
+// SetProductTaxonomy updates only the category and tag fields, so it
+// doesn't race with edits to the rest of the product.
+func (r *elasticRepository) SetProductTaxonomy(
+    ctx context.Context, id string, categoryIDs, tags []string,
+) error {
+    // Documents omit empty lists, so clearing one stores null.
+    fields := map[string]any{"category_ids": nil, "tags": nil}
+    if len(categoryIDs) != 0 {
+        fields["category_ids"] = categoryIDs
+    }
+    if len(tags) != 0 {
+        fields["tags"] = tags
+    }
+    err := r.updateProduct(ctx, id, fields)
+    if err != nil && !errors.Is(err, ErrNotFound) {
+        log.Println("failed to update product taxonomy from catalog repository: ", err)
+    }
+    return err
+}
+
+// updateProduct merges fields into the product document, returning
+// ErrNotFound for unknown products.
+func (r *elasticRepository) updateProduct(ctx context.Context, id string, fields map[string]any) error {
+    doc, err := json.Marshal(fields)
+    if err != nil {
+        return err
+    }
+
+    _, err = r.client.Update(catalogAlias, id).Request(&update.Request{Doc: doc}).Do(ctx)
+    var esErr *types.ElasticsearchError
+    if errors.As(err, &esErr) && esErr.Status == http.StatusNotFound {
+        return ErrNotFound
+    }
+    return err
+}
+
+// setVariantsScript replaces the variants with params.variants, carrying
+// over the stock of the ones already there by id.
+const setVariantsScript = `
+Map stock = new HashMap();
+if (ctx._source.variants != null) {
+    for (def v : ctx._source.variants) {
+        stock.put(v.id, v.stock);
+    }
+}
+List variants = new ArrayList();
+for (def v : params.variants) {
+    Map variant = new HashMap(v);
+    if (stock.containsKey(v.id)) {
+        variant.stock = stock.get(v.id);
+    }
+    variants.add(variant);
+}
+if (variants.isEmpty()) {
+    ctx._source.remove('variants');
+} else {
+    ctx._source.variants = variants;
+}`
+
+// SetProductVariants runs a script on the document like AdjustVariantStock.
+func (r *elasticRepository) SetProductVariants(
+    ctx context.Context, id string, variants []Variant,
+) error {
+    if variants == nil {
+        variants = []Variant{}
+    }
+    params, err := json.Marshal(variants)
+    if err != nil {
+        return err
+    }
+    source := setVariantsScript
+    _, err = r.client.Update(catalogAlias, id).
+        Request(&update.Request{Script: &types.Script{
+            Source: &source,
+            Params: map[string]json.RawMessage{"variants": params},
+        }}).
+        RetryOnConflict(3).
+        Do(ctx)
+    var esErr *types.ElasticsearchError
+    if errors.As(err, &esErr) && esErr.Status == http.StatusNotFound {
+        return ErrNotFound
+    }
+    if err != nil {
+        log.Println("failed to set product variants from catalog repository: ", err)
+        return err
+    }
+    return nil
+}
+
+// adjustStockScript changes the stock of the variant params.variant by
+// params.delta, or does nothing if that would make it negative or there is
+// no such variant.
+const adjustStockScript = `
+boolean adjusted = false;
+if (ctx._source.variants != null) {
+    for (def v : ctx._source.variants) {
+        if (v.id == params.variant) {
+            long stock = v.stock == null ? 0 : v.stock;
+            if (stock + params.delta >= 0) {
+                v.stock = stock + params.delta;
+                adjusted = true;
+            }
+        }
+    }
+}
+if (!adjusted) {
+    ctx.op = 'noop';
+}`
+
+// AdjustVariantStock runs a script on the document, so concurrent
+// adjustments and edits don't overwrite each other; Elasticsearch retries
+// the script on version conflicts.
+func (r *elasticRepository) AdjustVariantStock(
+    ctx context.Context, productID, variantID string, delta int64,
+) error {
+    variant, err := json.Marshal(variantID)
+    if err != nil {
+        return err
+    }
+    source := adjustStockScript
+    res, err := r.client.Update(catalogAlias, productID).
+        Request(&update.Request{Script: &types.Script{
+            Source: &source,
+            Params: map[string]json.RawMessage{
+                "variant": variant,
+                "delta": json.RawMessage(strconv.FormatInt(delta, 10)),
+            },
+        }}).
+        RetryOnConflict(3).
+        Do(ctx)
+    var esErr *types.ElasticsearchError
+    if errors.As(err, &esErr) && esErr.Status == http.StatusNotFound {
+        return ErrNotFound
+    }
+    if err != nil {
+        log.Println("failed to adjust variant stock from catalog repository: ", err)
+        return err
+    }
+    if res.Result != result.Noop {
+        return nil
+    }
+
+    // The script did nothing; tell a missing variant from missing stock.
+    p, err := r.GetProductByID(ctx, productID)
+    if err != nil {
+        return err
+    }
+    if _, ok := p.Variant(variantID); !ok {
+        return ErrInvalidVariant
+    }
+    return ErrOutOfStock
 }
 
+// ListProducts pages through the products in creation order. Documents
+// indexed before created_at existed sort first, in index order.
 func (r *elasticRepository) ListProducts(
     ctx context.Context, skip uint64, take uint64,
 ) ([]Product, error) {
+    from, size := int(skip), int(take)
+    order, unmapped := sortorder.Asc, fieldtype.Date
     res, err := r.client.Search().
-        Index("catalog").
+        Index(catalogAlias).
         Request(&search.Request{
             Query: &types.Query{MatchAll: &types.MatchAllQuery{}},
+            Sort: []types.SortCombinations{
+                types.SortOptions{SortOptions: map[string]types.FieldSort{
+                    "created_at": {Order: &order, Missing: "_first", UnmappedType: &unmapped},
+                }},
+                "_doc",
+            },
+            From: &from,
+            Size: &size,
         }).
-        Do(context.TODO())
-    
+        Do(ctx)
     if err != nil {
         log.Println("failed to list all products from catalog repository: ", err)
         return nil, err
@@ -128,89 +432,208 @@ func (r *elasticRepository) ListProducts(
     products := []Product{}
     for _, hit := range res.Hits.Hits {
         p := productDocument{}
-        if err = json.Unmarshal(*&hit.Source_, &p); err == nil {
-            products = append(products, Product{
-                ID: *hit.Id_,
-                Name: p.Name,
-                Description: p.Description,
-                Price: p.Price,
-            })
+        if err = json.Unmarshal(hit.Source_, &p); err == nil {
+            products = append(products, p.product(*hit.Id_))
         }
     }
 
     return products, err
 }
 
+// ListProductsWithIDs fetches the products with _mget, at most
+// mgetChunkSize ids per request.
 func (r *elasticRepository) ListProductsWithIDs(
     ctx context.Context, ids []string,
-)([]Product, error) {
+) ([]Product, []string, error) {
+    ids = uniqueIDs(ids)
+    products := []Product{}
+    missing := []string{}
+    for start := 0; start < len(ids); start += mgetChunkSize {
+        chunk := ids[start:min(start+mgetChunkSize, len(ids))]
+        res, err := r.client.Mget().
+            Index(catalogAlias).
+            Request(&mget.Request{Ids: chunk}).
+            Do(ctx)
+        if err != nil {
+            log.Println("failed to list products with ids from catalog repository: ", err)
+            return nil, nil, err
+        }
 
-    res, err := r.client.Search().
-        Index("catalog").
-        Request(&search.Request{
-            Query: &types.Query{
-                Ids: &types.IdsQuery{
-                    Values: ids,
-                },
-            },
-        }).Do(ctx)
+        for _, doc := range res.Docs {
+            switch doc := doc.(type) {
+            case *types.GetResult:
+                if !doc.Found {
+                    missing = append(missing, doc.Id_)
+                    continue
+                }
+                p := productDocument{}
+                if err := json.Unmarshal(doc.Source_, &p); err != nil {
+                    log.Println("failed to unmarshal productDocument: ", err)
+                    return nil, nil, err
+                }
+                products = append(products, p.product(doc.Id_))
+            case *types.MultiGetError:
+                reason := "multi get failed"
+                if doc.Error.Reason != nil {
+                    reason = *doc.Error.Reason
+                }
+                log.Println("failed to get product ", doc.Id_, " from catalog repository: ", reason)
+                return nil, nil, errors.New(reason)
+            }
+        }
+    }
+    return products, missing, nil
+}
 
+func (r *elasticRepository) SearchProducts(
+    ctx context.Context, req SearchRequest,
+) (*SearchResult, error) {
+    skip_int := int(req.Skip)
+    take_int := int(req.Take)
+    request := &search.Request{
+        Query: searchQuery(req),
+        Sort: searchSort(req.Sort),
+        Aggregations: searchAggregations(),
+        From: &skip_int,
+        Size: &take_int,
+    }
+    // The did you mean suggester reads name.shingle; indices created before
+    // it was added to mapping.json have to be reindexed first.
+    if req.Query != "" {
+        request.Highlight = searchHighlight()
+        request.Suggest = searchSuggester(req.Query)
+    }
+    res, err := r.client.Search().
+        Index(catalogAlias).
+        Request(request).
+        Do(ctx)
     if err != nil {
-        log.Println("failed to list products with ids from catalog repository: ", err)
+        log.Println("failed to search products with query from catalog repository: ", err)
         return nil, err
     }
 
-    products := []Product{}
+    result := &SearchResult{Products: []Product{}}
+    if res.Hits.Total != nil {
+        result.Total = uint64(res.Hits.Total.Value)
+    }
     for _, hit := range res.Hits.Hits {
         p := productDocument{}
         if err = json.Unmarshal(*&hit.Source_, &p); err == nil {
-            products = append(products, Product{
-                ID: *hit.Id_,
-                Name: p.Name,
-                Description: p.Description,
-                Price: p.Price,
-            })
+            result.Products = append(result.Products, p.product(*hit.Id_))
         }
     }
-    log.Println("catalog: repository: products: ", products)
+    result.Facets = searchFacets(res.Aggregations)
+    result.Highlights = searchHighlights(res.Hits.Hits)
+    result.DidYouMean = didYouMean(res.Suggest, req.Query)
 
-    return products, err
+    return result, err
 }
 
-func (r *elasticRepository) SearchProducts(
-    ctx context.Context, query string, skip uint64, take uint64,
-)([]Product, error) {
-    skip_int := int(skip)
-    take_int := int(take)
+// SuggestProducts needs the name.suggest field from mapping.json; indices
+// created before it existed return no suggestions until reindexed.
+func (r *elasticRepository) SuggestProducts(
+    ctx context.Context, prefix string, limit int,
+) ([]Suggestion, error) {
+    res, err := r.client.Search().
+        Index(catalogAlias).
+        Request(suggestRequest(prefix, limit)).
+        Do(ctx)
+    if err != nil {
+        log.Println("failed to suggest products from catalog repository: ", err)
+        return nil, err
+    }
+
+    suggestions := []Suggestion{}
+    for _, hit := range res.Hits.Hits {
+        p := productDocument{}
+        if err = json.Unmarshal(hit.Source_, &p); err != nil {
+            continue
+        }
+        s := Suggestion{ProductID: *hit.Id_, Name: p.Name, Highlighted: p.Name}
+        if fragments := hit.Highlight["name.suggest"]; len(fragments) != 0 {
+            s.Highlighted = fragments[0]
+        }
+        suggestions = append(suggestions, s)
+    }
+    return suggestions, nil
+}
+
+// SimilarProducts runs a more_like_this query seeded with the stored
+// product, so terms are taken from its indexed name, description and tags.
+// The document frequency thresholds are lowered from their defaults to
+// work on small catalogs.
+func (r *elasticRepository) SimilarProducts(
+    ctx context.Context, id string, take int,
+) ([]Product, error) {
+    exists, err := r.client.Exists(catalogAlias, id).Do(ctx)
+    if err != nil {
+        log.Println("failed to check product exists from catalog repository: ", err)
+        return nil, err
+    }
+    if !exists {
+        return nil, ErrNotFound
+    }
+
+    index, minFreq := catalogAlias, 1
     res, err := r.client.Search().
-        Index("catalog").
+        Index(catalogAlias).
         Request(&search.Request{
-            Query: &types.Query{
-                MultiMatch: &types.MultiMatchQuery{
-                    Fields: []string{"name", "description"},
-                    Query: query,
-                },
-            },
-            From: &skip_int,
-            Size: &take_int,
+            Query: &types.Query{MoreLikeThis: &types.MoreLikeThisQuery{
+                Fields: []string{"name", "description", "tags"},
+                Like: []types.Like{types.LikeDocument{Index_: &index, Id_: &id}},
+                MinTermFreq: &minFreq,
+                MinDocFreq: &minFreq,
+            }},
+            Size: &take,
         }).Do(ctx)
     if err != nil {
-        log.Println("failed to search products with query from catalog repository: ", err)
+        log.Println("failed to get similar products from catalog repository: ", err)
         return nil, err
     }
 
     products := []Product{}
     for _, hit := range res.Hits.Hits {
         p := productDocument{}
-        if err = json.Unmarshal(*&hit.Source_, &p); err == nil {
-            products = append(products, Product{
-                ID: *hit.Id_,
-                Name: p.Name,
-                Description: p.Description,
-                Price: p.Price,
-            })
+        if err := json.Unmarshal(hit.Source_, &p); err == nil {
+            products = append(products, p.product(*hit.Id_))
         }
     }
+    return products, nil
+}
 
-    return products, err
+// GetProductBySKU finds the product owning the variant with sku through a
+// nested query on the variants.
+func (r *elasticRepository) GetProductBySKU(
+    ctx context.Context, sku string,
+) (*Product, error) {
+    size := 1
+    res, err := r.client.Search().
+        Index(catalogAlias).
+        Request(&search.Request{
+            Query: &types.Query{
+                Nested: &types.NestedQuery{
+                    Path: "variants",
+                    Query: &types.Query{
+                        Term: map[string]types.TermQuery{"variants.sku": {Value: sku}},
+                    },
+                },
+            },
+            Size: &size,
+        }).Do(ctx)
+    if err != nil {
+        log.Println("failed to get product by sku from catalog repository: ", err)
+        return nil, err
+    }
+    if len(res.Hits.Hits) == 0 {
+        return nil, ErrNotFound
+    }
+
+    hit := res.Hits.Hits[0]
+    p := productDocument{}
+    if err := json.Unmarshal(hit.Source_, &p); err != nil {
+        log.Println("failed to unmarshal productDocument: ", err)
+        return nil, err
+    }
+    product := p.product(*hit.Id_)
+    return &product, nil
 }
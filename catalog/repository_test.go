@@ -0,0 +1,305 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func newTestElasticRepository(t *testing.T, f *fakeElastic) Repository {
+    t.Helper()
+
+    r, err := NewElasticRepository(f.URL())
+    if err != nil {
+        t.Fatal("failed to create elastic repository: ", err)
+    }
+    t.Cleanup(r.Close)
+    return r
+}
+
+func TestNewElasticRepositoryCreatesIndex(t *testing.T) {
+    f := newFakeElastic(t)
+
+    newTestElasticRepository(t, f)
+
+    if n := len(f.requestsTo(http.MethodHead, "/catalog")); n != 1 {
+        t.Fatalf("expected 1 index exists check, got %d", n)
+    }
+    body := f.lastBody(http.MethodPut, "/catalog_v1")
+    if _, ok := body["aliases"].(map[string]any)["catalog"]; !ok {
+        t.Fatalf("expected catalog_v1 to be created with the catalog alias, got %v", body["aliases"])
+    }
+    price := body["mappings"].(map[string]any)["properties"].(map[string]any)["price"]
+    if price.(map[string]any)["type"] != "scaled_float" {
+        t.Fatalf("expected the checked-in mapping, got price %v", price)
+    }
+    if got := f.aliasTarget("catalog"); got != "catalog_v1" {
+        t.Fatalf("expected catalog alias to point at catalog_v1, got %q", got)
+    }
+}
+
+func TestNewElasticRepositoryKeepsExistingIndex(t *testing.T) {
+    for name, setup := range map[string]func(f *fakeElastic){
+        "alias":  func(f *fakeElastic) { f.createAlias("catalog", "catalog_v3") },
+        "legacy": func(f *fakeElastic) { f.createIndex("catalog") },
+    } {
+        t.Run(name, func(t *testing.T) {
+            f := newFakeElastic(t)
+            setup(f)
+
+            newTestElasticRepository(t, f)
+
+            if n := len(f.requestsTo(http.MethodPut, "_v1")); n != 0 {
+                t.Fatalf("expected existing index to be reused, got %d create requests", n)
+            }
+        })
+    }
+}
+
+func TestPutProduct(t *testing.T) {
+    f := newFakeElastic(t)
+    r := newTestElasticRepository(t, f)
+
+    p := Product{ID: "p1", Name: "Mug", Description: "ceramic", Price: 9.5}
+    if err := r.PutProduct(context.Background(), p); err != nil {
+        t.Fatal("failed to put product: ", err)
+    }
+
+    body := f.lastBody(http.MethodPut, "/catalog/_doc/p1")
+    want := map[string]any{"name": "Mug", "description": "ceramic", "price": 9.5}
+    if !reflect.DeepEqual(body, want) {
+        t.Fatalf("unexpected document %v, want %v", body, want)
+    }
+
+    got, err := r.GetProductByID(context.Background(), "p1")
+    if err != nil {
+        t.Fatal("failed to get product: ", err)
+    }
+    if !reflect.DeepEqual(*got, p) {
+        t.Fatalf("got %+v, want %+v", *got, p)
+    }
+}
+
+func TestSearchProductsQueryShape(t *testing.T) {
+    f := newFakeElastic(t)
+    r := newTestElasticRepository(t, f)
+    f.cannedHits = []fakeHit{
+        {ID: "p1", Source: productDocument{Name: "Headphones", Description: "wireless", Price: 99}},
+    }
+
+    res, err := r.SearchProducts(context.Background(), SearchRequest{
+        Query: "wireless",
+        Skip: 20,
+        Take: 10,
+    })
+    if err != nil {
+        t.Fatal("failed to search products: ", err)
+    }
+
+    body := f.lastBody(http.MethodPost, "/catalog/_search")
+    if body["from"] != float64(20) || body["size"] != float64(10) {
+        t.Fatalf("unexpected pagination in %v", body)
+    }
+    if _, ok := body["sort"]; ok {
+        t.Fatalf("expected relevance order to leave sort unset, got %v", body["sort"])
+    }
+    must := body["query"].(map[string]any)["bool"].(map[string]any)["must"].([]any)
+    multiMatch, ok := must[0].(map[string]any)["multi_match"].(map[string]any)
+    if !ok {
+        t.Fatalf("expected a multi_match query, got %v", must)
+    }
+    if multiMatch["query"] != "wireless" {
+        t.Fatalf("unexpected query text %v", multiMatch["query"])
+    }
+    if !reflect.DeepEqual(multiMatch["fields"], []any{"name^3", "description"}) {
+        t.Fatalf("unexpected fields %v", multiMatch["fields"])
+    }
+    if multiMatch["fuzziness"] != "AUTO" {
+        t.Fatalf("expected AUTO fuzziness by default, got %v", multiMatch["fuzziness"])
+    }
+    if _, ok := body["highlight"]; !ok {
+        t.Fatal("expected a query search to request highlights")
+    }
+
+    want := []Product{{ID: "p1", Name: "Headphones", Description: "wireless", Price: 99}}
+    if !reflect.DeepEqual(res.Products, want) {
+        t.Fatalf("got %+v, want %+v", res.Products, want)
+    }
+    if res.Total != 1 {
+        t.Fatalf("expected total 1, got %d", res.Total)
+    }
+}
+
+func TestSearchProductsFiltersSortAndFacets(t *testing.T) {
+    f := newFakeElastic(t)
+    r := newTestElasticRepository(t, f)
+    f.cannedHits = []fakeHit{}
+    f.cannedAggregations = map[string]any{
+        "range#prices": map[string]any{"buckets": []any{
+            map[string]any{"key": "*-25.0", "to": 25.0, "doc_count": 3},
+            map[string]any{"key": "25.0-*", "from": 25.0, "doc_count": 1},
+        }},
+        "sterms#tags": map[string]any{"buckets": []any{
+            map[string]any{"key": "audio", "doc_count": 4},
+        }},
+    }
+
+    minPrice, maxPrice := 10.0, 50.0
+    res, err := r.SearchProducts(context.Background(), SearchRequest{
+        MinPrice: &minPrice,
+        MaxPrice: &maxPrice,
+        Tags: []string{"audio", "sale"},
+        Sort: SortPriceDesc,
+        Take: 10,
+    })
+    if err != nil {
+        t.Fatal("failed to search products: ", err)
+    }
+
+    body := f.lastBody(http.MethodPost, "/catalog/_search")
+    boolQuery := body["query"].(map[string]any)["bool"].(map[string]any)
+    if _, ok := boolQuery["must"].([]any)[0].(map[string]any)["match_all"]; !ok {
+        t.Fatalf("expected match_all without a query, got %v", boolQuery["must"])
+    }
+    wantFilter := []any{
+        map[string]any{"range": map[string]any{"price": map[string]any{"gte": 10.0, "lte": 50.0}}},
+        map[string]any{"term": map[string]any{"tags": map[string]any{"value": "audio"}}},
+        map[string]any{"term": map[string]any{"tags": map[string]any{"value": "sale"}}},
+    }
+    if !reflect.DeepEqual(boolQuery["filter"], wantFilter) {
+        t.Fatalf("unexpected filters %v", boolQuery["filter"])
+    }
+    wantSort := []any{map[string]any{"price": map[string]any{"order": "desc", "unmapped_type": "double"}}}
+    if !reflect.DeepEqual(body["sort"], wantSort) {
+        t.Fatalf("unexpected sort %v", body["sort"])
+    }
+    aggs := body["aggregations"].(map[string]any)
+    if len(aggs["prices"].(map[string]any)["range"].(map[string]any)["ranges"].([]any)) != len(priceRanges) {
+        t.Fatalf("unexpected price aggregation %v", aggs["prices"])
+    }
+
+    wantPrices := []PriceBucket{
+        {To: floatPtr(25), Count: 3},
+        {From: floatPtr(25), Count: 1},
+    }
+    if !reflect.DeepEqual(res.Facets.Prices, wantPrices) {
+        t.Fatalf("unexpected price facets %+v", res.Facets.Prices)
+    }
+    if !reflect.DeepEqual(res.Facets.Tags, []TagCount{{Tag: "audio", Count: 4}}) {
+        t.Fatalf("unexpected tag facets %+v", res.Facets.Tags)
+    }
+}
+
+func TestListProductsWithIDs(t *testing.T) {
+    f := newFakeElastic(t)
+    f.putDocument("catalog", "p1", productDocument{Name: "Mug", Price: 9.5})
+    f.putDocument("catalog", "p2", productDocument{Name: "Beans", Price: 20})
+    r := newTestElasticRepository(t, f)
+
+    products, missing, err := r.ListProductsWithIDs(
+        context.Background(), []string{"p2", "missing", "p1", "p2"},
+    )
+    if err != nil {
+        t.Fatal("failed to list products with ids: ", err)
+    }
+
+    body := f.lastBody(http.MethodPost, "/catalog/_mget")
+    if !reflect.DeepEqual(body["ids"], []any{"p2", "missing", "p1"}) {
+        t.Fatalf("unexpected ids %v", body["ids"])
+    }
+    if len(products) != 2 || products[0].ID != "p2" || products[1].ID != "p1" {
+        t.Fatalf("expected p2 then p1, got %+v", products)
+    }
+    if !reflect.DeepEqual(missing, []string{"missing"}) {
+        t.Fatalf("unexpected missing ids %v", missing)
+    }
+}
+
+func TestListProductsWithIDsChunksRequests(t *testing.T) {
+    f := newFakeElastic(t)
+    r := newTestElasticRepository(t, f)
+
+    ids := []string{}
+    for i := 0; i < 2*mgetChunkSize+1; i++ {
+        id := fmt.Sprintf("p%04d", i)
+        ids = append(ids, id)
+        if i%2 == 0 {
+            f.putDocument(catalogAlias, id, productDocument{Name: id})
+        }
+    }
+
+    products, missing, err := r.ListProductsWithIDs(context.Background(), ids)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if n := len(f.requestsTo(http.MethodPost, "/_mget")); n != 3 {
+        t.Fatalf("expected 3 mget requests, got %d", n)
+    }
+    if len(products) != mgetChunkSize+1 || len(missing) != mgetChunkSize {
+        t.Fatalf("got %d products and %d missing", len(products), len(missing))
+    }
+    for i, p := range products {
+        if p.ID != ids[2*i] {
+            t.Fatalf("product %d is %s, want %s", i, p.ID, ids[2*i])
+        }
+    }
+}
+
+func TestListProductsPaginates(t *testing.T) {
+    f := newFakeElastic(t)
+    for _, id := range []string{"p1", "p2", "p3"} {
+        f.putDocument("catalog", id, productDocument{Name: id})
+    }
+    r := newTestElasticRepository(t, f)
+
+    products, err := r.ListProducts(context.Background(), 1, 1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    body := f.lastBody(http.MethodPost, "/catalog/_search")
+    if body["from"] != 1.0 || body["size"] != 1.0 {
+        t.Fatalf("expected from 1 and size 1, got %v and %v", body["from"], body["size"])
+    }
+    if len(products) != 1 || products[0].ID != "p2" {
+        t.Fatalf("unexpected page %+v", products)
+    }
+}
+
+func TestSimilarProductsQueryShape(t *testing.T) {
+    f := newFakeElastic(t)
+    f.putDocument("catalog", "p1", productDocument{Name: "French Press", Tags: []string{"coffee"}})
+    r := newTestElasticRepository(t, f)
+    f.cannedHits = []fakeHit{
+        {ID: "p2", Source: productDocument{Name: "Coffee Grinder", Tags: []string{"coffee"}}},
+    }
+
+    products, err := r.SimilarProducts(context.Background(), "p1", 5)
+    if err != nil {
+        t.Fatal("failed to get similar products: ", err)
+    }
+
+    body := f.lastBody(http.MethodPost, "/catalog/_search")
+    if body["size"] != float64(5) {
+        t.Fatalf("unexpected size in %v", body)
+    }
+    mlt, ok := body["query"].(map[string]any)["more_like_this"].(map[string]any)
+    if !ok {
+        t.Fatalf("expected a more_like_this query, got %v", body["query"])
+    }
+    if !reflect.DeepEqual(mlt["like"], []any{map[string]any{"_index": "catalog", "_id": "p1"}}) {
+        t.Fatalf("unexpected like %v", mlt["like"])
+    }
+    if !reflect.DeepEqual(mlt["fields"], []any{"name", "description", "tags"}) {
+        t.Fatalf("unexpected fields %v", mlt["fields"])
+    }
+    if len(products) != 1 || products[0].ID != "p2" {
+        t.Fatalf("unexpected products %+v", products)
+    }
+
+    if _, err := r.SimilarProducts(context.Background(), "missing", 5); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("expected ErrNotFound, got %v", err)
+    }
+}
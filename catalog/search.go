@@ -0,0 +1,127 @@
+package catalog
+
+import "errors"
+
+var (
+    ErrInvalidFuzziness = errors.New("fuzziness must be between 0 and 2")
+    ErrInvalidRating    = errors.New("rating must be between 0 and 5")
+)
+
+// maxRating is the highest star rating a review can give.
+const maxRating = 5
+
+// maxFuzziness is the largest edit distance a query term may be off by.
+const maxFuzziness = 2
+
+type SearchSort int
+
+const (
+    SortRelevance SearchSort = iota
+    SortPriceAsc
+    SortPriceDesc
+    SortNameAsc
+    SortNameDesc
+    SortNewest
+    // SortRatingDesc ranks the best rated products first, breaking ties by
+    // the number of reviews.
+    SortRatingDesc
+)
+
+// SearchRequest narrows a product search. A zero value matches every
+// product, ranked by relevance.
+type SearchRequest struct {
+    Query    string
+    MinPrice *float64
+    MaxPrice *float64
+    // MinRating excludes products rated lower, including unrated ones.
+    MinRating *float64
+    // Tags lists tags a product must all carry to match.
+    Tags []string
+    // CategoryIDs matches products in any of the categories. The service
+    // widens it to include every subcategory before it reaches the
+    // repository.
+    CategoryIDs []string
+    // Fuzziness is the number of edits a query term may be off by and
+    // still match, up to maxFuzziness. nil picks it from the term length:
+    // none up to 2 characters, one up to 5 and two beyond.
+    Fuzziness *int
+    Sort      SearchSort
+    Skip      uint64
+    Take      uint64
+}
+
+type SearchResult struct {
+    Products []Product
+    Total    uint64
+    Facets   Facets
+    // Highlights holds the fragments of each returned product that matched
+    // the query, keyed by product id.
+    Highlights map[string][]Highlight
+    // DidYouMean is a corrected query likely to match better, or empty.
+    DidYouMean string
+    // SearchID identifies the recorded search, for feedback on its results.
+    SearchID string
+}
+
+// Highlight is a product field with the matched terms wrapped in <em>.
+type Highlight struct {
+    Field     string
+    Fragments []string
+}
+
+// Facets are aggregated over every product matching the request, not just
+// the returned page.
+type Facets struct {
+    Prices []PriceBucket
+    Tags   []TagCount
+}
+
+// PriceBucket counts products priced in [From, To). A nil bound is open.
+type PriceBucket struct {
+    From  *float64
+    To    *float64
+    Count uint64
+}
+
+type TagCount struct {
+    Tag   string
+    Count uint64
+}
+
+type priceRange struct {
+    From *float64
+    To   *float64
+}
+
+// priceRanges are the buckets returned in Facets.Prices.
+var priceRanges = []priceRange{
+    {To: floatPtr(25)},
+    {From: floatPtr(25), To: floatPtr(50)},
+    {From: floatPtr(50), To: floatPtr(100)},
+    {From: floatPtr(100), To: floatPtr(250)},
+    {From: floatPtr(250)},
+}
+
+// autoFuzziness is the edit distance allowed for term when the request
+// leaves it unset, matching elasticsearch's AUTO.
+func autoFuzziness(term string) int {
+    switch n := len([]rune(term)); {
+    case n <= 2:
+        return 0
+    case n <= 5:
+        return 1
+    default:
+        return 2
+    }
+}
+
+// maxTagFacets caps the number of tags returned in Facets.Tags.
+const maxTagFacets = 20
+
+func (r priceRange) contains(price float64) bool {
+    return (r.From == nil || price >= *r.From) && (r.To == nil || price < *r.To)
+}
+
+func floatPtr(f float64) *float64 {
+    return &f
+}
@@ -0,0 +1,234 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+    tests := []struct {
+        a, b string
+        want int
+    }{
+        {"headphones", "headphones", 0},
+        {"headphnoes", "headphones", 1},
+        {"mug", "rug", 1},
+        {"mug", "mugs", 1},
+        {"kaffee", "coffee", 2},
+        {"", "tea", 3},
+    }
+    for _, tt := range tests {
+        if got := editDistance(tt.a, tt.b); got != tt.want {
+            t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+        }
+    }
+}
+
+func TestSearchProductsFuzzyMemory(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+
+    headphones, err := s.PostProduct(ctx, "Wireless Headphones", "over-ear headphones", 199, nil, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.PostProduct(ctx, "Coffee Mug", "ceramic", 9.5, nil, nil, nil); err != nil {
+        t.Fatal(err)
+    }
+
+    res, err := s.SearchProducts(ctx, SearchRequest{Query: "headphnoes"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(res.Products) != 1 || res.Products[0].ID != headphones.ID {
+        t.Fatalf("expected the typo to match the headphones, got %+v", res.Products)
+    }
+    want := []Highlight{
+        {Field: "name", Fragments: []string{"Wireless <em>Headphones</em>"}},
+        {Field: "description", Fragments: []string{"over-ear <em>headphones</em>"}},
+    }
+    if !reflect.DeepEqual(res.Highlights[headphones.ID], want) {
+        t.Fatalf("unexpected highlights %+v", res.Highlights[headphones.ID])
+    }
+    if res.DidYouMean != "headphones" {
+        t.Fatalf("expected did you mean headphones, got %q", res.DidYouMean)
+    }
+
+    exact := 0
+    res, err = s.SearchProducts(ctx, SearchRequest{Query: "headphnoes", Fuzziness: &exact})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(res.Products) != 0 || res.DidYouMean != "headphones" {
+        t.Fatalf("expected no exact matches but a correction, got %+v", res)
+    }
+
+    res, err = s.SearchProducts(ctx, SearchRequest{Query: "coffee"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if res.DidYouMean != "" {
+        t.Fatalf("expected no correction for a known word, got %q", res.DidYouMean)
+    }
+
+    tooFuzzy := 3
+    if _, err := s.SearchProducts(ctx, SearchRequest{Query: "mug", Fuzziness: &tooFuzzy}); !errors.Is(err, ErrInvalidFuzziness) {
+        t.Fatalf("expected ErrInvalidFuzziness, got %v", err)
+    }
+}
+
+func TestSearchProductsHighlightsAndDidYouMean(t *testing.T) {
+    f := newFakeElastic(t)
+    r := newTestElasticRepository(t, f)
+    f.cannedHits = []fakeHit{{
+        ID: "p1",
+        Source: map[string]any{"name": "Wireless Headphones", "price": 199},
+        Highlight: map[string][]string{
+            "name": {"Wireless <em>Headphones</em>"},
+            "description": {"over-ear <em>headphones</em>", "folding <em>headphones</em>"},
+        },
+    }}
+    f.cannedSuggest = map[string]any{
+        "phrase#did_you_mean": []any{map[string]any{
+            "text": "wireles headphnoes",
+            "offset": 0,
+            "length": 18,
+            "options": []any{map[string]any{"text": "wireless headphones", "score": 0.4}},
+        }},
+    }
+
+    one := 1
+    res, err := r.SearchProducts(context.Background(), SearchRequest{
+        Query: "wireles headphnoes",
+        Fuzziness: &one,
+        Take: 10,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    body := f.lastBody(http.MethodPost, "/catalog/_search")
+    must := body["query"].(map[string]any)["bool"].(map[string]any)["must"].([]any)
+    multiMatch := must[0].(map[string]any)["multi_match"].(map[string]any)
+    if multiMatch["fuzziness"] != "1" || multiMatch["prefix_length"] != 1.0 {
+        t.Fatalf("unexpected fuzziness in %v", multiMatch)
+    }
+    suggest := body["suggest"].(map[string]any)
+    phrase := suggest["did_you_mean"].(map[string]any)["phrase"].(map[string]any)
+    if suggest["text"] != "wireles headphnoes" || phrase["field"] != didYouMeanField {
+        t.Fatalf("unexpected suggester %v", suggest)
+    }
+
+    want := []Highlight{
+        {Field: "name", Fragments: []string{"Wireless <em>Headphones</em>"}},
+        {Field: "description", Fragments: []string{"over-ear <em>headphones</em>", "folding <em>headphones</em>"}},
+    }
+    if !reflect.DeepEqual(res.Highlights["p1"], want) {
+        t.Fatalf("unexpected highlights %+v", res.Highlights)
+    }
+    if res.DidYouMean != "wireless headphones" {
+        t.Fatalf("unexpected did you mean %q", res.DidYouMean)
+    }
+}
+
+func TestSearchProductsByRatingMemory(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+
+    ids := []string{}
+    for _, name := range []string{"Kettle", "Toaster", "Blender"} {
+        p, err := s.PostProduct(ctx, name, "", 20, nil, nil, nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        ids = append(ids, p.ID)
+    }
+    if err := s.SetProductRating(ctx, ids[0], 3.5, 2, 1); err != nil {
+        t.Fatal(err)
+    }
+    if err := s.SetProductRating(ctx, ids[1], 4.5, 4, 2); err != nil {
+        t.Fatal(err)
+    }
+    // A sync that aggregated before the last one and arrives after it.
+    if err := s.SetProductRating(ctx, ids[1], 1, 3, 1); err != nil {
+        t.Fatal(err)
+    }
+
+    minRating := 3.0
+    res, err := s.SearchProducts(ctx, SearchRequest{Sort: SortRatingDesc, MinRating: &minRating})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(res.Products) != 2 || res.Products[0].ID != ids[1] || res.Products[1].ID != ids[0] {
+        t.Fatalf("expected the rated products best first, got %+v", res.Products)
+    }
+
+    if err := s.SetProductRating(ctx, ids[2], 6, 1, 1); !errors.Is(err, ErrInvalidRating) {
+        t.Fatalf("expected ErrInvalidRating, got %v", err)
+    }
+    if err := s.SetProductRating(ctx, "missing", 4, 1, 1); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("expected ErrNotFound, got %v", err)
+    }
+}
+
+func TestElasticRepositorySetProductRatingIgnoresOlderVersions(t *testing.T) {
+    ctx := context.Background()
+    r := newTestElasticRepository(t, newFakeElastic(t))
+    if err := r.PutProduct(ctx, Product{ID: "p1", Name: "Mug", Price: 9}); err != nil {
+        t.Fatal(err)
+    }
+
+    for _, rating := range []struct {
+        average        float64
+        count, version uint64
+    }{{4, 2, 2}, {5, 1, 1}, {4, 2, 2}} {
+        if err := r.SetProductRating(ctx, "p1", rating.average, rating.count, rating.version); err != nil {
+            t.Fatal(err)
+        }
+    }
+    got, err := r.GetProductByID(ctx, "p1")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got.Rating != 4 || got.ReviewCount != 2 {
+        t.Fatalf("expected the rating of version 2 to stay, got %v from %d reviews", got.Rating, got.ReviewCount)
+    }
+    if err := r.SetProductRating(ctx, "p2", 4, 1, 1); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("expected ErrNotFound, got %v", err)
+    }
+}
+
+func TestGetSimilarProductsMemory(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+
+    press, err := s.PostProduct(ctx, "French Press", "brews coffee", 25, []string{"coffee"}, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    grinder, err := s.PostProduct(ctx, "Coffee Grinder", "burr grinder", 40, []string{"coffee"}, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    beans, err := s.PostProduct(ctx, "Beans", "coffee beans", 12, nil, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.PostProduct(ctx, "Toaster", "two slots", 30, nil, nil, nil); err != nil {
+        t.Fatal(err)
+    }
+
+    similar, err := s.GetSimilarProducts(ctx, press.ID, 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(similar) != 2 || similar[0].ID != grinder.ID || similar[1].ID != beans.ID {
+        t.Fatalf("expected the grinder then the beans, got %+v", similar)
+    }
+    if _, err := s.GetSimilarProducts(ctx, "missing", 5); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("expected ErrNotFound, got %v", err)
+    }
+}
@@ -2,13 +2,16 @@ package catalog
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 
 	"github.com/pirateunclejack/go-grpc-graphql-microservice/catalog/pb"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 )
 
 type grpcServer struct {
@@ -24,6 +27,12 @@ func ListenGRPC(s Service, port int) error {
         return err
     }
 
+    return NewGRPCServer(s).Serve(lis)
+}
+
+// NewGRPCServer returns a grpc server with the catalog service registered,
+// ready to Serve on any listener.
+func NewGRPCServer(s Service) *grpc.Server {
     serv := grpc.NewServer()
     pb.RegisterCatalogServiceServer(serv, &grpcServer{
         UnimplementedCatalogServiceServer: pb.UnimplementedCatalogServiceServer{},
@@ -31,26 +40,22 @@ func ListenGRPC(s Service, port int) error {
     })
 
     reflection.Register(serv)
-    return serv.Serve(lis)
+    return serv
 }
 
 func (s *grpcServer) PostProduct(
     ctx context.Context, r *pb.PostProductRequest,
 ) (*pb.PostProductResponse, error) {
     p, err := s.service.PostProduct(
-        ctx, r.Name, r.Description, r.Price)
+        ctx, r.Name, r.Description, r.Price, r.Tags, r.CategoryIds,
+        variantsFromProto(r.Variants))
     if err != nil {
         log.Println("failed to post product from catalog server: ", err)
         return nil, err
     }
 
     return &pb.PostProductResponse{
-        Product: &pb.Product{
-            Id: p.ID,
-            Name: p.Name,
-            Description: p.Description,
-            Price: p.Price,
-        },
+        Product: productToProto(*p),
     }, nil
 }
 
@@ -64,12 +69,7 @@ func (s *grpcServer) GetProduct(
     }
 
     return &pb.GetProductResponse{
-        Product: &pb.Product{
-            Id: p.ID,
-            Name: p.Name,
-            Description: p.Description,
-            Price: p.Price,
-        },
+        Product: productToProto(*p),
     }, nil
 }
 
@@ -77,12 +77,28 @@ func (s *grpcServer) GetProducts(
     ctx context.Context, r *pb.GetProductsRequest,
 ) (*pb.GetProductsResponse, error) {
     var res []Product
+    var missing []string
+    var search *SearchResult
     var err error
 
-    if r.Query != "" {
-        res, err = s.service.SearchProducts(ctx, r.Query, r.Skip, r.Take)
+    if len(r.Ids) == 0 && (r.Query != "" || r.Facets || isFiltered(r)) {
+        search, err = s.service.SearchProducts(ctx, SearchRequest{
+            Query: r.Query,
+            MinPrice: r.MinPrice,
+            MaxPrice: r.MaxPrice,
+            MinRating: r.MinRating,
+            Tags: r.Tags,
+            CategoryIDs: r.CategoryIds,
+            Fuzziness: fuzzinessFromProto(r.Fuzziness),
+            Sort: SearchSort(r.Sort),
+            Skip: r.Skip,
+            Take: r.Take,
+        })
+        if search != nil {
+            res = search.Products
+        }
     } else if len (r.Ids) != 0 {
-        res, err = s.service.GetProductByIDs(ctx, r.Ids)
+        res, missing, err = s.service.GetProductByIDs(ctx, r.Ids)
     } else {
         res, err = s.service.GetProducts(ctx, r.Skip, r.Take)
     }
@@ -93,15 +109,432 @@ func (s *grpcServer) GetProducts(
 
     products := []*pb.Product{}
     for _, p := range res {
-        products = append(products, &pb.Product{
-            Id: p.ID,
-            Name: p.Name,
-            Description: p.Description,
-            Price: p.Price,
-        })
+        products = append(products, productToProto(p))
     }
 
-    return &pb.GetProductsResponse{
+    resp := &pb.GetProductsResponse{
         Products: products,
+        Total: uint64(len(products)),
+        MissingIds: missing,
+    }
+    if search != nil {
+        resp.Total = search.Total
+        resp.Facets = facetsToProto(search.Facets)
+        resp.Highlights = highlightsToProto(search.Products, search.Highlights)
+        resp.DidYouMean = search.DidYouMean
+        resp.SearchId = search.SearchID
+    }
+    return resp, nil
+}
+
+func fuzzinessFromProto(f *uint32) *int {
+    if f == nil {
+        return nil
+    }
+    fuzziness := int(*f)
+    return &fuzziness
+}
+
+// highlightsToProto lists highlights in the order of products so the
+// response is deterministic.
+func highlightsToProto(products []Product, highlights map[string][]Highlight) []*pb.ProductHighlight {
+    res := []*pb.ProductHighlight{}
+    for _, p := range products {
+        for _, h := range highlights[p.ID] {
+            res = append(res, &pb.ProductHighlight{
+                ProductId: p.ID,
+                Field: h.Field,
+                Fragments: h.Fragments,
+            })
+        }
+    }
+    return res
+}
+
+func (s *grpcServer) SuggestProducts(
+    ctx context.Context, r *pb.SuggestProductsRequest,
+) (*pb.SuggestProductsResponse, error) {
+    res, err := s.service.SuggestProducts(ctx, r.Prefix, int(r.Limit))
+    if err != nil {
+        log.Println("failed to suggest products from catalog server: ", err)
+        return nil, err
+    }
+
+    suggestions := []*pb.Suggestion{}
+    for _, sg := range res {
+        suggestions = append(suggestions, &pb.Suggestion{
+            ProductId: sg.ProductID,
+            Name: sg.Name,
+            Highlighted: sg.Highlighted,
+        })
+    }
+    return &pb.SuggestProductsResponse{Suggestions: suggestions}, nil
+}
+
+func (s *grpcServer) GetSimilarProducts(
+    ctx context.Context, r *pb.GetSimilarProductsRequest,
+) (*pb.GetSimilarProductsResponse, error) {
+    res, err := s.service.GetSimilarProducts(ctx, r.ProductId, int(r.Take))
+    if err != nil {
+        log.Println("failed to get similar products from catalog server: ", err)
+        return nil, err
+    }
+
+    products := []*pb.Product{}
+    for _, p := range res {
+        products = append(products, productToProto(p))
+    }
+    return &pb.GetSimilarProductsResponse{Products: products}, nil
+}
+
+func isFiltered(r *pb.GetProductsRequest) bool {
+    return r.MinPrice != nil || r.MaxPrice != nil || r.MinRating != nil || len(r.Tags) != 0 ||
+        len(r.CategoryIds) != 0 || r.Sort != pb.ProductSort_PRODUCT_SORT_RELEVANCE
+}
+
+func facetsToProto(f Facets) *pb.Facets {
+    facets := &pb.Facets{}
+    for _, b := range f.Prices {
+        facets.Prices = append(facets.Prices, &pb.PriceBucket{
+            From: b.From,
+            To: b.To,
+            Count: b.Count,
+        })
+    }
+    for _, t := range f.Tags {
+        facets.Tags = append(facets.Tags, &pb.TagCount{
+            Tag: t.Tag,
+            Count: t.Count,
+        })
+    }
+    return facets
+}
+
+func (s *grpcServer) PostCategory(
+    ctx context.Context, r *pb.PostCategoryRequest,
+) (*pb.CategoryResponse, error) {
+    c, err := s.service.PostCategory(ctx, r.Name, r.Slug, r.ParentId)
+    if err != nil {
+        log.Println("failed to post category from catalog server: ", err)
+        return nil, err
+    }
+
+    return &pb.CategoryResponse{Category: categoryToProto(*c)}, nil
+}
+
+func (s *grpcServer) UpdateCategory(
+    ctx context.Context, r *pb.UpdateCategoryRequest,
+) (*pb.CategoryResponse, error) {
+    c, err := s.service.UpdateCategory(ctx, r.Id, r.Name, r.Slug, r.ParentId)
+    if err != nil {
+        log.Println("failed to update category from catalog server: ", err)
+        return nil, err
+    }
+
+    return &pb.CategoryResponse{Category: categoryToProto(*c)}, nil
+}
+
+func (s *grpcServer) DeleteCategory(
+    ctx context.Context, r *pb.DeleteCategoryRequest,
+) (*pb.DeleteCategoryResponse, error) {
+    if err := s.service.DeleteCategory(ctx, r.Id); err != nil {
+        log.Println("failed to delete category from catalog server: ", err)
+        return nil, err
+    }
+
+    return &pb.DeleteCategoryResponse{}, nil
+}
+
+func (s *grpcServer) GetCategory(
+    ctx context.Context, r *pb.GetCategoryRequest,
+) (*pb.CategoryResponse, error) {
+    var c *Category
+    var err error
+    if r.Id != "" {
+        c, err = s.service.GetCategory(ctx, r.Id)
+    } else {
+        c, err = s.service.GetCategoryByPath(ctx, r.Path)
+    }
+    if err != nil {
+        log.Println("failed to get category from catalog server: ", err)
+        return nil, err
+    }
+
+    return &pb.CategoryResponse{Category: categoryToProto(*c)}, nil
+}
+
+func (s *grpcServer) GetCategories(
+    ctx context.Context, r *pb.GetCategoriesRequest,
+) (*pb.GetCategoriesResponse, error) {
+    res, err := s.service.GetCategories(ctx, r.Ids, r.ParentId)
+    if err != nil {
+        log.Println("failed to get categories from catalog server: ", err)
+        return nil, err
+    }
+
+    categories := []*pb.Category{}
+    for _, c := range res {
+        categories = append(categories, categoryToProto(c))
+    }
+    return &pb.GetCategoriesResponse{Categories: categories}, nil
+}
+
+func (s *grpcServer) SetProductTaxonomy(
+    ctx context.Context, r *pb.SetProductTaxonomyRequest,
+) (*pb.SetProductTaxonomyResponse, error) {
+    p, err := s.service.SetProductTaxonomy(ctx, r.ProductId, r.CategoryIds, r.Tags)
+    if err != nil {
+        log.Println("failed to set product taxonomy from catalog server: ", err)
+        return nil, err
+    }
+
+    return &pb.SetProductTaxonomyResponse{
+        Product: productToProto(*p),
     }, nil
 }
+
+func (s *grpcServer) SetProductVariants(
+    ctx context.Context, r *pb.SetProductVariantsRequest,
+) (*pb.SetProductVariantsResponse, error) {
+    p, err := s.service.SetProductVariants(ctx, r.ProductId, variantsFromProto(r.Variants))
+    if err != nil {
+        log.Println("failed to set product variants from catalog server: ", err)
+        return nil, err
+    }
+
+    return &pb.SetProductVariantsResponse{Product: productToProto(*p)}, nil
+}
+
+func (s *grpcServer) RestockVariant(
+    ctx context.Context, r *pb.RestockVariantRequest,
+) (*pb.RestockVariantResponse, error) {
+    p, err := s.service.RestockVariant(ctx, r.ProductId, r.VariantId, r.Quantity)
+    if err != nil {
+        log.Println("failed to restock variant from catalog server: ", err)
+        return nil, err
+    }
+
+    return &pb.RestockVariantResponse{Product: productToProto(*p)}, nil
+}
+
+func (s *grpcServer) ReserveVariantStock(
+    ctx context.Context, r *pb.ReserveVariantStockRequest,
+) (*pb.ReserveVariantStockResponse, error) {
+    p, err := s.service.ReserveVariantStock(ctx, r.ProductId, r.VariantId, r.Quantity)
+    if err != nil {
+        log.Println("failed to reserve variant stock from catalog server: ", err)
+        // Callers tell running out of stock from other failures by code.
+        if errors.Is(err, ErrOutOfStock) {
+            return nil, status.Error(codes.FailedPrecondition, err.Error())
+        }
+        return nil, err
+    }
+
+    return &pb.ReserveVariantStockResponse{Product: productToProto(*p)}, nil
+}
+
+func (s *grpcServer) GetSynonyms(
+    ctx context.Context, r *pb.GetSynonymsRequest,
+) (*pb.GetSynonymsResponse, error) {
+    rules, err := s.service.GetSynonyms(ctx)
+    if err != nil {
+        log.Println("failed to get synonyms from catalog server: ", err)
+        return nil, err
+    }
+
+    res := &pb.GetSynonymsResponse{Rules: []*pb.SynonymRule{}}
+    for _, rule := range rules {
+        res.Rules = append(res.Rules, &pb.SynonymRule{Id: rule.ID, Terms: rule.Terms})
+    }
+    return res, nil
+}
+
+func (s *grpcServer) PutSynonymRule(
+    ctx context.Context, r *pb.PutSynonymRuleRequest,
+) (*pb.PutSynonymRuleResponse, error) {
+    rule, err := s.service.PutSynonymRule(ctx, r.Id, r.Terms)
+    if err != nil {
+        log.Println("failed to put synonym rule from catalog server: ", err)
+        return nil, err
+    }
+
+    return &pb.PutSynonymRuleResponse{
+        Rule: &pb.SynonymRule{Id: rule.ID, Terms: rule.Terms},
+    }, nil
+}
+
+func (s *grpcServer) DeleteSynonymRule(
+    ctx context.Context, r *pb.DeleteSynonymRuleRequest,
+) (*pb.DeleteSynonymRuleResponse, error) {
+    if err := s.service.DeleteSynonymRule(ctx, r.Id); err != nil {
+        log.Println("failed to delete synonym rule from catalog server: ", err)
+        return nil, err
+    }
+
+    return &pb.DeleteSynonymRuleResponse{}, nil
+}
+
+func (s *grpcServer) GetStopwords(
+    ctx context.Context, r *pb.GetStopwordsRequest,
+) (*pb.StopwordsResponse, error) {
+    words, err := s.service.GetStopwords(ctx)
+    if err != nil {
+        log.Println("failed to get stopwords from catalog server: ", err)
+        return nil, err
+    }
+
+    return &pb.StopwordsResponse{Words: words}, nil
+}
+
+func (s *grpcServer) SetStopwords(
+    ctx context.Context, r *pb.SetStopwordsRequest,
+) (*pb.StopwordsResponse, error) {
+    words, err := s.service.SetStopwords(ctx, r.Words)
+    if err != nil {
+        log.Println("failed to set stopwords from catalog server: ", err)
+        return nil, err
+    }
+
+    return &pb.StopwordsResponse{Words: words}, nil
+}
+
+func (s *grpcServer) RecordSearchFeedback(
+    ctx context.Context, r *pb.RecordSearchFeedbackRequest,
+) (*pb.RecordSearchFeedbackResponse, error) {
+    kind := FeedbackClick
+    if r.Kind == pb.SearchFeedbackKind_SEARCH_FEEDBACK_ADD_TO_CART {
+        kind = FeedbackAddToCart
+    }
+    if err := s.service.RecordSearchFeedback(ctx, r.SearchId, r.ProductId, kind); err != nil {
+        log.Println("failed to record search feedback from catalog server: ", err)
+        return nil, err
+    }
+
+    return &pb.RecordSearchFeedbackResponse{}, nil
+}
+
+func (s *grpcServer) GetTopSearchQueries(
+    ctx context.Context, r *pb.SearchQueriesRequest,
+) (*pb.SearchQueriesResponse, error) {
+    queries, err := s.service.GetTopSearchQueries(ctx, int(r.Days), int(r.Limit))
+    if err != nil {
+        log.Println("failed to get top search queries from catalog server: ", err)
+        return nil, err
+    }
+
+    return &pb.SearchQueriesResponse{Queries: queryCountsToProto(queries)}, nil
+}
+
+func (s *grpcServer) GetZeroResultQueries(
+    ctx context.Context, r *pb.SearchQueriesRequest,
+) (*pb.SearchQueriesResponse, error) {
+    queries, err := s.service.GetZeroResultQueries(ctx, int(r.Days), int(r.Limit))
+    if err != nil {
+        log.Println("failed to get zero result queries from catalog server: ", err)
+        return nil, err
+    }
+
+    return &pb.SearchQueriesResponse{Queries: queryCountsToProto(queries)}, nil
+}
+
+func (s *grpcServer) GetSearchClickThrough(
+    ctx context.Context, r *pb.SearchClickThroughRequest,
+) (*pb.SearchClickThroughResponse, error) {
+    ct, err := s.service.GetSearchClickThrough(ctx, int(r.Days))
+    if err != nil {
+        log.Println("failed to get search click through from catalog server: ", err)
+        return nil, err
+    }
+
+    return &pb.SearchClickThroughResponse{
+        Searches: ct.Searches,
+        ClickedSearches: ct.ClickedSearches,
+        AddedToCartSearches: ct.AddedToCartSearches,
+        Rate: ct.Rate,
+    }, nil
+}
+
+func (s *grpcServer) SetProductRating(
+    ctx context.Context, r *pb.SetProductRatingRequest,
+) (*pb.SetProductRatingResponse, error) {
+    err := s.service.SetProductRating(ctx, r.ProductId, r.Rating, r.ReviewCount, r.Version)
+    if err != nil {
+        log.Println("failed to set product rating from catalog server: ", err)
+        return nil, err
+    }
+
+    return &pb.SetProductRatingResponse{}, nil
+}
+
+func queryCountsToProto(queries []QueryCount) []*pb.SearchQueryCount {
+    res := []*pb.SearchQueryCount{}
+    for _, q := range queries {
+        res = append(res, &pb.SearchQueryCount{Query: q.Query, Searches: q.Searches})
+    }
+    return res
+}
+
+func productToProto(p Product) *pb.Product {
+    return &pb.Product{
+        Id: p.ID,
+        Name: p.Name,
+        Description: p.Description,
+        Price: p.Price,
+        Tags: p.Tags,
+        CategoryIds: p.CategoryIDs,
+        Variants: variantsToProto(p.Variants),
+        Rating: p.Rating,
+        ReviewCount: p.ReviewCount,
+    }
+}
+
+func variantsToProto(variants []Variant) []*pb.ProductVariant {
+    res := []*pb.ProductVariant{}
+    for _, v := range variants {
+        variant := &pb.ProductVariant{
+            Id: v.ID,
+            Sku: v.SKU,
+            Price: v.Price,
+            Stock: v.Stock,
+        }
+        for _, o := range v.Options {
+            variant.Options = append(variant.Options, &pb.VariantOption{
+                Name: o.Name,
+                Value: o.Value,
+            })
+        }
+        res = append(res, variant)
+    }
+    return res
+}
+
+func variantsFromProto(variants []*pb.ProductVariant) []Variant {
+    res := []Variant{}
+    for _, v := range variants {
+        variant := Variant{
+            ID: v.Id,
+            SKU: v.Sku,
+            Price: v.Price,
+            Options: []Option{},
+            Stock: v.Stock,
+        }
+        for _, o := range v.Options {
+            variant.Options = append(variant.Options, Option{
+                Name: o.Name,
+                Value: o.Value,
+            })
+        }
+        res = append(res, variant)
+    }
+    return res
+}
+
+func categoryToProto(c Category) *pb.Category {
+    return &pb.Category{
+        Id: c.ID,
+        Name: c.Name,
+        Slug: c.Slug,
+        ParentId: c.ParentID,
+        Path: c.Path,
+    }
+}
@@ -3,6 +3,7 @@ package catalog
 import (
 	"context"
 	"log"
+	"time"
 
 	"github.com/segmentio/ksuid"
 )
@@ -10,20 +11,59 @@ import (
 type Service interface {
     PostProduct(
         ctx context.Context, name, description string, price float64,
+        tags []string, categoryIDs []string, variants []Variant,
         ) (*Product, error)
     GetProduct(ctx context.Context, id string) (*Product, error)
     GetProducts(ctx context.Context, skip uint64, take uint64) ([]Product, error)
-    GetProductByIDs(ctx context.Context, ids []string) ([]Product, error)
-    SearchProducts(
-        ctx context.Context, query string, skip uint64, take uint64,
-        ) ([]Product, error)
+    GetProductByIDs(ctx context.Context, ids []string) ([]Product, []string, error)
+    SearchProducts(ctx context.Context, req SearchRequest) (*SearchResult, error)
+    SuggestProducts(ctx context.Context, prefix string, limit int) ([]Suggestion, error)
+    GetSimilarProducts(ctx context.Context, productID string, take int) ([]Product, error)
+    SetProductTaxonomy(
+        ctx context.Context, productID string, categoryIDs, tags []string,
+        ) (*Product, error)
+    PostCategory(ctx context.Context, name, slug, parentID string) (*Category, error)
+    UpdateCategory(ctx context.Context, id, name, slug, parentID string) (*Category, error)
+    DeleteCategory(ctx context.Context, id string) error
+    GetCategory(ctx context.Context, id string) (*Category, error)
+    GetCategoryByPath(ctx context.Context, path string) (*Category, error)
+    GetCategories(ctx context.Context, ids []string, parentID *string) ([]Category, error)
+    SetProductVariants(
+        ctx context.Context, productID string, variants []Variant,
+        ) (*Product, error)
+    RestockVariant(
+        ctx context.Context, productID, variantID string, quantity uint32,
+        ) (*Product, error)
+    ReserveVariantStock(
+        ctx context.Context, productID, variantID string, quantity uint32,
+        ) (*Product, error)
+    GetSynonyms(ctx context.Context) ([]SynonymRule, error)
+    PutSynonymRule(ctx context.Context, id string, terms []string) (*SynonymRule, error)
+    DeleteSynonymRule(ctx context.Context, id string) error
+    GetStopwords(ctx context.Context) ([]string, error)
+    SetStopwords(ctx context.Context, words []string) ([]string, error)
+    SetProductRating(ctx context.Context, productID string, rating float64, count, version uint64) error
+    RecordSearchFeedback(ctx context.Context, searchID, productID string, kind FeedbackKind) error
+    GetTopSearchQueries(ctx context.Context, days, limit int) ([]QueryCount, error)
+    GetZeroResultQueries(ctx context.Context, days, limit int) ([]QueryCount, error)
+    GetSearchClickThrough(ctx context.Context, days int) (*ClickThrough, error)
 }
 
 type Product struct {
-    ID          string  `json:"id"`
-    Name        string  `json:"name"`
-    Description string  `json:"description"`
-    Price       float64 `json:"price"`
+    ID          string    `json:"id"`
+    // ExternalSKU identifies the product in a supplier catalog; imports
+    // upsert by it.
+    ExternalSKU string    `json:"external_sku,omitempty"`
+    Name        string    `json:"name"`
+    Description string    `json:"description"`
+    Price       float64   `json:"price"`
+    Tags        []string  `json:"tags"`
+    CategoryIDs []string  `json:"category_ids"`
+    Variants    []Variant `json:"variants"`
+    // Rating is the average star rating of the product's published
+    // reviews, kept up to date by the review service.
+    Rating      float64   `json:"rating,omitempty"`
+    ReviewCount uint64    `json:"review_count,omitempty"`
 }
 
 type catalogService struct {
@@ -38,14 +78,26 @@ func NewService(r Repository) Service {
 
 func (s *catalogService) PostProduct(
     ctx context.Context, name, description string, price float64,
+    tags []string, categoryIDs []string, variants []Variant,
 ) (*Product, error){
+    if err := s.checkCategories(ctx, categoryIDs); err != nil {
+        return nil, err
+    }
+
     p := &Product{
         Name: name,
         Description: description,
         Price: price,
+        Tags: tags,
+        CategoryIDs: categoryIDs,
         ID: ksuid.New().String(),
     }
 
+    var err error
+    if p.Variants, err = s.prepareVariants(ctx, p.ID, variants); err != nil {
+        return nil, err
+    }
+
     if err := s.repository.PutProduct(ctx, *p); err != nil {
         log.Println("failed to put product from catalog service: ", err)
         return nil, err
@@ -67,18 +119,63 @@ func (s *catalogService) GetProducts(
     return s.repository.ListProducts(ctx, skip, take)
 }
 
+// GetProductByIDs returns the products in the order of ids and the ids
+// no product was found for.
 func (s *catalogService) GetProductByIDs(
     ctx context.Context, ids []string,
-) ([]Product, error){
+) ([]Product, []string, error){
     return s.repository.ListProductsWithIDs(ctx, ids)
 }
 
+// SearchProducts records every search for the analytics reports, under the
+// returned SearchID.
 func (s *catalogService) SearchProducts(
-    ctx context.Context, query string, skip uint64, take uint64,
-) ([]Product, error) {
-    if take > 100 || (skip == 0 && take == 0) {
-        take = 100
+    ctx context.Context, req SearchRequest,
+) (*SearchResult, error) {
+    start, requested := time.Now(), req
+    if req.Take > 100 || (req.Skip == 0 && req.Take == 0) {
+        req.Take = 100
+    }
+    if req.Fuzziness != nil && (*req.Fuzziness < 0 || *req.Fuzziness > maxFuzziness) {
+        return nil, ErrInvalidFuzziness
+    }
+
+    categoryIDs, err := s.expandCategories(ctx, req.CategoryIDs)
+    if err != nil {
+        return nil, err
+    }
+    req.CategoryIDs = categoryIDs
+
+    if req.Query != "" {
+        stopwords, err := s.repository.GetStopwords(ctx)
+        if err != nil {
+            log.Println("failed to get stopwords from catalog service: ", err)
+            return nil, err
+        }
+        req.Query = removeStopwords(req.Query, stopwords)
     }
 
-    return s.repository.SearchProducts(ctx, query, skip, take)
+    res, err := s.repository.SearchProducts(ctx, req)
+    if err != nil {
+        return nil, err
+    }
+    res.SearchID = ksuid.New().String()
+    s.recordSearch(ctx, res.SearchID, requested, res, time.Since(start))
+    return res, nil
+}
+
+// SetProductRating stores the review aggregate of a product, so searches
+// can filter and sort by it. Aggregates arriving after one of a higher
+// version are ignored.
+func (s *catalogService) SetProductRating(
+    ctx context.Context, productID string, rating float64, count, version uint64,
+) error {
+    if rating < 0 || rating > maxRating || (count == 0 && rating != 0) {
+        return ErrInvalidRating
+    }
+    if err := s.repository.SetProductRating(ctx, productID, rating, count, version); err != nil {
+        log.Println("failed to set product rating from catalog service: ", err)
+        return err
+    }
+    return nil
 }
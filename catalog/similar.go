@@ -0,0 +1,31 @@
+package catalog
+
+import (
+	"context"
+	"log"
+)
+
+const (
+    defaultSimilarProducts = 5
+    maxSimilarProducts     = 20
+)
+
+// GetSimilarProducts returns up to take products that look like the one
+// with productID, by shared name and description words and tags, most
+// similar first. It's the recommendation fallback for products nobody has
+// ordered with others yet.
+func (s *catalogService) GetSimilarProducts(
+    ctx context.Context, productID string, take int,
+) ([]Product, error) {
+    if take <= 0 {
+        take = defaultSimilarProducts
+    }
+    take = min(take, maxSimilarProducts)
+
+    products, err := s.repository.SimilarProducts(ctx, productID, take)
+    if err != nil {
+        log.Println("failed to get similar products from catalog service: ", err)
+        return nil, err
+    }
+    return products, nil
+}
@@ -0,0 +1,37 @@
+package catalog
+
+import (
+	"context"
+	"strings"
+)
+
+// Suggestion is an autocomplete match for a search prefix. Highlighted is
+// the product name with the matched parts wrapped in <em> tags.
+type Suggestion struct {
+    ProductID   string
+    Name        string
+    Highlighted string
+}
+
+const (
+    defaultSuggestions = 10
+    maxSuggestions     = 20
+)
+
+// SuggestProducts returns product names completing prefix, best match
+// first. An empty prefix has no suggestions.
+func (s *catalogService) SuggestProducts(
+    ctx context.Context, prefix string, limit int,
+) ([]Suggestion, error) {
+    prefix = strings.TrimSpace(prefix)
+    if prefix == "" {
+        return []Suggestion{}, nil
+    }
+    if limit <= 0 {
+        limit = defaultSuggestions
+    }
+    if limit > maxSuggestions {
+        limit = maxSuggestions
+    }
+    return s.repository.SuggestProducts(ctx, prefix, limit)
+}
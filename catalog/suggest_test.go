@@ -0,0 +1,86 @@
+package catalog
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestSuggestProductsMemory(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+
+    for _, name := range []string{"Studio Headphones", "Headphone Stand", "Wireless Headphones", "Coffee Mug"} {
+        if _, err := s.PostProduct(ctx, name, "", 10, nil, nil, nil); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    res, err := s.SuggestProducts(ctx, "head", 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+    got := []string{}
+    for _, sg := range res {
+        got = append(got, sg.Highlighted)
+    }
+    want := []string{
+        "<em>Headphone</em> Stand",
+        "Studio <em>Headphones</em>",
+        "Wireless <em>Headphones</em>",
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Fatalf("got %q, want %q", got, want)
+    }
+
+    res, err = s.SuggestProducts(ctx, "wireless hea", 1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(res) != 1 || res[0].Highlighted != "<em>Wireless</em> <em>Headphones</em>" {
+        t.Fatalf("unexpected suggestions %+v", res)
+    }
+
+    res, err = s.SuggestProducts(ctx, "  ", 5)
+    if err != nil || len(res) != 0 {
+        t.Fatalf("expected no suggestions for a blank prefix, got %+v, %v", res, err)
+    }
+}
+
+func TestSuggestProductsQueryShape(t *testing.T) {
+    f := newFakeElastic(t)
+    r := newTestElasticRepository(t, f)
+    f.cannedHits = []fakeHit{
+        {
+            ID: "p1",
+            Source: map[string]any{"name": "Wireless Headphones"},
+            Highlight: map[string][]string{"name.suggest": {"<em>Wireless</em> Headphones"}},
+        },
+        {ID: "p2", Source: map[string]any{"name": "Wire Rack"}},
+    }
+
+    res, err := r.SuggestProducts(context.Background(), "wire", 5)
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := []Suggestion{
+        {ProductID: "p1", Name: "Wireless Headphones", Highlighted: "<em>Wireless</em> Headphones"},
+        {ProductID: "p2", Name: "Wire Rack", Highlighted: "Wire Rack"},
+    }
+    if !reflect.DeepEqual(res, want) {
+        t.Fatalf("got %+v, want %+v", res, want)
+    }
+
+    body := f.lastBody(http.MethodPost, "/catalog/_search")
+    multiMatch := body["query"].(map[string]any)["multi_match"].(map[string]any)
+    if multiMatch["type"] != "bool_prefix" || multiMatch["query"] != "wire" {
+        t.Fatalf("unexpected suggest query %v", multiMatch)
+    }
+    if body["size"] != float64(5) || body["track_total_hits"] != false || body["timeout"] != suggestTimeout {
+        t.Fatalf("unexpected suggest request %v", body)
+    }
+    if _, ok := body["highlight"].(map[string]any)["fields"].(map[string]any)["name.suggest"]; !ok {
+        t.Fatalf("expected name.suggest to be highlighted, got %v", body["highlight"])
+    }
+}
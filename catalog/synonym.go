@@ -0,0 +1,156 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/segmentio/ksuid"
+)
+
+var (
+    ErrInvalidSynonyms  = errors.New("a synonym rule needs at least two distinct terms")
+    ErrInvalidStopwords = errors.New("stopwords must be single words")
+)
+
+// SynonymRule makes its terms interchangeable in searches, e.g. "tv" and
+// "television". A term may be several words, e.g. "flat screen".
+type SynonymRule struct {
+    ID    string   `json:"id"`
+    Terms []string `json:"terms"`
+}
+
+// GetSynonyms returns every synonym rule.
+func (s *catalogService) GetSynonyms(ctx context.Context) ([]SynonymRule, error) {
+    rules, err := s.repository.ListSynonymRules(ctx)
+    if err != nil {
+        log.Println("failed to list synonym rules from catalog service: ", err)
+        return nil, err
+    }
+    return rules, nil
+}
+
+// PutSynonymRule creates a rule when id is empty and replaces the rule with
+// id otherwise. Searches pick the change up without a reindex.
+func (s *catalogService) PutSynonymRule(
+    ctx context.Context, id string, terms []string,
+) (*SynonymRule, error) {
+    rule := SynonymRule{ID: id, Terms: normalizeSynonyms(terms)}
+    if len(rule.Terms) < 2 {
+        return nil, ErrInvalidSynonyms
+    }
+    if rule.ID == "" {
+        rule.ID = ksuid.New().String()
+    }
+
+    if err := s.repository.PutSynonymRule(ctx, rule); err != nil {
+        log.Println("failed to put synonym rule from catalog service: ", err)
+        return nil, err
+    }
+    return &rule, nil
+}
+
+func (s *catalogService) DeleteSynonymRule(ctx context.Context, id string) error {
+    if err := s.repository.DeleteSynonymRule(ctx, id); err != nil {
+        log.Println("failed to delete synonym rule from catalog service: ", err)
+        return err
+    }
+    return nil
+}
+
+func (s *catalogService) GetStopwords(ctx context.Context) ([]string, error) {
+    words, err := s.repository.GetStopwords(ctx)
+    if err != nil {
+        log.Println("failed to get stopwords from catalog service: ", err)
+        return nil, err
+    }
+    return words, nil
+}
+
+// SetStopwords replaces the words dropped from search queries.
+func (s *catalogService) SetStopwords(ctx context.Context, words []string) ([]string, error) {
+    normalized := []string{}
+    for _, w := range words {
+        w = strings.ToLower(strings.TrimSpace(w))
+        if w == "" {
+            continue
+        }
+        if wordPattern.FindString(w) != w {
+            return nil, ErrInvalidStopwords
+        }
+        if !slices.Contains(normalized, w) {
+            normalized = append(normalized, w)
+        }
+    }
+    slices.Sort(normalized)
+
+    if err := s.repository.PutStopwords(ctx, normalized); err != nil {
+        log.Println("failed to put stopwords from catalog service: ", err)
+        return nil, err
+    }
+    return normalized, nil
+}
+
+// normalizeSynonyms lowercases terms, collapses their whitespace and drops
+// empty and repeated ones. Commas and "=>" are synonym rule syntax in
+// elasticsearch, so terms are cut at them.
+func normalizeSynonyms(terms []string) []string {
+    normalized := []string{}
+    for _, t := range terms {
+        for _, part := range strings.FieldsFunc(strings.ReplaceAll(t, "=>", ","), func(r rune) bool {
+            return r == ','
+        }) {
+            part = strings.Join(strings.Fields(strings.ToLower(part)), " ")
+            if part != "" && !slices.Contains(normalized, part) {
+                normalized = append(normalized, part)
+            }
+        }
+    }
+    return normalized
+}
+
+// removeStopwords drops the stopwords from query. A query made only of
+// stopwords is kept as is, so it still finds something.
+func removeStopwords(query string, stopwords []string) string {
+    if len(stopwords) == 0 {
+        return query
+    }
+
+    kept := []string{}
+    for _, field := range strings.Fields(query) {
+        words := wordPattern.FindAllString(strings.ToLower(field), -1)
+        if len(words) != 1 || !slices.Contains(stopwords, words[0]) {
+            kept = append(kept, field)
+        }
+    }
+    if len(kept) == 0 {
+        return query
+    }
+    return strings.Join(kept, " ")
+}
+
+// synonymAlternatives returns, for each query word in terms, what it may be
+// replaced with: itself first, then the other terms of every rule holding
+// it. Multi-word rule terms are offered as replacements but never looked
+// up themselves. Repositories without analyzer support expand queries with
+// it.
+func synonymAlternatives(terms []string, rules []SynonymRule) [][]string {
+    alternatives := [][]string{}
+    for _, term := range terms {
+        alts := []string{term}
+        for _, rule := range rules {
+            if !slices.Contains(rule.Terms, term) {
+                continue
+            }
+            for _, synonym := range rule.Terms {
+                if !slices.Contains(alts, synonym) {
+                    alts = append(alts, synonym)
+                }
+            }
+        }
+        alternatives = append(alternatives, alts)
+    }
+    return alternatives
+}
@@ -0,0 +1,135 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeSynonyms(t *testing.T) {
+    got := normalizeSynonyms([]string{" TV ", "television, telly", "flat   screen=>tv", ""})
+    want := []string{"tv", "television", "telly", "flat screen"}
+    if !reflect.DeepEqual(got, want) {
+        t.Fatalf("got %q, want %q", got, want)
+    }
+}
+
+func TestRemoveStopwords(t *testing.T) {
+    stopwords := []string{"the", "for"}
+    if got := removeStopwords("The mug for tea", stopwords); got != "mug tea" {
+        t.Fatalf("got %q, want %q", got, "mug tea")
+    }
+    if got := removeStopwords("for the", stopwords); got != "for the" {
+        t.Fatalf("expected a stopword-only query to be kept, got %q", got)
+    }
+}
+
+func TestSynonymQuery(t *testing.T) {
+    rules := []SynonymRule{{ID: "r1", Terms: []string{"tv", "television", "flat screen"}}}
+    if got, want := synonymQuery("Cheap TV", rules), "(cheap) & (tv | television | flat <-> screen)"; got != want {
+        t.Fatalf("got %q, want %q", got, want)
+    }
+    if got := synonymQuery("cheap radio", rules); got != "" {
+        t.Fatalf("expected no rewrite without a matching rule, got %q", got)
+    }
+}
+
+func TestSynonymsAndStopwordsMemory(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+
+    tv, err := s.PostProduct(ctx, "Television", "55 inch", 499, nil, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    res, err := s.SearchProducts(ctx, SearchRequest{Query: "the tv"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(res.Products) != 0 {
+        t.Fatalf("expected no match before the rule, got %+v", res.Products)
+    }
+
+    if _, err := s.PutSynonymRule(ctx, "", []string{"tv"}); !errors.Is(err, ErrInvalidSynonyms) {
+        t.Fatalf("expected ErrInvalidSynonyms, got %v", err)
+    }
+    rule, err := s.PutSynonymRule(ctx, "", []string{"TV", "television"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.SetStopwords(ctx, []string{"The", "the", " "}); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.SetStopwords(ctx, []string{"a b"}); !errors.Is(err, ErrInvalidStopwords) {
+        t.Fatalf("expected ErrInvalidStopwords, got %v", err)
+    }
+
+    res, err = s.SearchProducts(ctx, SearchRequest{Query: "the tv"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(res.Products) != 1 || res.Products[0].ID != tv.ID {
+        t.Fatalf("expected the synonym to find the television, got %+v", res.Products)
+    }
+    words, err := s.GetStopwords(ctx)
+    if err != nil || !reflect.DeepEqual(words, []string{"the"}) {
+        t.Fatalf("unexpected stopwords %v, %v", words, err)
+    }
+
+    if err := s.DeleteSynonymRule(ctx, rule.ID); err != nil {
+        t.Fatal(err)
+    }
+    if err := s.DeleteSynonymRule(ctx, rule.ID); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("expected ErrNotFound, got %v", err)
+    }
+    rules, err := s.GetSynonyms(ctx)
+    if err != nil || len(rules) != 0 {
+        t.Fatalf("expected no rules left, got %+v, %v", rules, err)
+    }
+}
+
+func TestSynonymsElastic(t *testing.T) {
+    ctx := context.Background()
+    f := newFakeElastic(t)
+    r := newTestElasticRepository(t, f)
+
+    if _, ok := f.synonyms[synonymsSet]; !ok {
+        t.Fatal("expected the synonyms set to be created with the index")
+    }
+    body := f.lastBody(http.MethodPut, "/catalog_v1")
+    filters := body["settings"].(map[string]any)["analysis"].(map[string]any)["filter"].(map[string]any)
+    if filters["product_synonyms"].(map[string]any)["updateable"] != true {
+        t.Fatalf("expected an updateable synonym filter, got %v", filters["product_synonyms"])
+    }
+
+    rule := SynonymRule{ID: "tv", Terms: []string{"tv", "television"}}
+    if err := r.PutSynonymRule(ctx, rule); err != nil {
+        t.Fatal(err)
+    }
+    if got := f.lastBody(http.MethodPut, "/_synonyms/catalog-synonyms/tv"); got["synonyms"] != "tv, television" {
+        t.Fatalf("unexpected rule body %v", got)
+    }
+    rules, err := r.ListSynonymRules(ctx)
+    if err != nil || !reflect.DeepEqual(rules, []SynonymRule{rule}) {
+        t.Fatalf("unexpected rules %+v, %v", rules, err)
+    }
+    if err := r.DeleteSynonymRule(ctx, "tv"); err != nil {
+        t.Fatal(err)
+    }
+    if err := r.DeleteSynonymRule(ctx, "tv"); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("expected ErrNotFound, got %v", err)
+    }
+
+    if words, err := r.GetStopwords(ctx); err != nil || len(words) != 0 {
+        t.Fatalf("expected no stopwords, got %v, %v", words, err)
+    }
+    if err := r.PutStopwords(ctx, []string{"the"}); err != nil {
+        t.Fatal(err)
+    }
+    if words, err := r.GetStopwords(ctx); err != nil || !reflect.DeepEqual(words, []string{"the"}) {
+        t.Fatalf("unexpected stopwords %v, %v", words, err)
+    }
+}
@@ -0,0 +1,83 @@
+-- Create a table for products. Tags and category IDs are arrays and variants a JSON array, mirroring the elasticsearch document.
+-- search is the weighted full-text vector SearchProducts matches against: name ranks above description.
+CREATE TABLE IF NOT EXISTS products (
+    id CHAR(27) PRIMARY KEY,
+    external_sku VARCHAR(64),
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    price NUMERIC(12, 2) NOT NULL,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    category_ids TEXT[] NOT NULL DEFAULT '{}',
+    variants JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    rating DOUBLE PRECISION NOT NULL DEFAULT 0,
+    review_count INT NOT NULL DEFAULT 0,
+    search TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', name), 'A') ||
+        setweight(to_tsvector('english', description), 'B')
+    ) STORED
+);
+
+-- Bring products created before reviews up to date.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS rating DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS review_count INT NOT NULL DEFAULT 0;
+-- rating_version is the version of the review aggregate stored in rating and review_count; older ones are ignored.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS rating_version BIGINT NOT NULL DEFAULT 0;
+
+-- Products created through the API have no external SKU; it is stored as NULL so they don't collide.
+CREATE UNIQUE INDEX IF NOT EXISTS products_external_sku_idx ON products (external_sku);
+CREATE INDEX IF NOT EXISTS products_search_idx ON products USING GIN (search);
+-- Prefix matching for SuggestProducts uses the unstemmed name.
+CREATE INDEX IF NOT EXISTS products_name_simple_idx ON products USING GIN (to_tsvector('simple', name));
+CREATE INDEX IF NOT EXISTS products_tags_idx ON products USING GIN (tags);
+CREATE INDEX IF NOT EXISTS products_category_ids_idx ON products USING GIN (category_ids);
+CREATE INDEX IF NOT EXISTS products_rating_idx ON products (rating DESC, review_count DESC);
+CREATE INDEX IF NOT EXISTS products_variants_idx ON products USING GIN (variants jsonb_path_ops);
+
+-- Create a table for the category tree, with each category's slash separated path of slugs.
+CREATE TABLE IF NOT EXISTS categories (
+    id CHAR(27) PRIMARY KEY,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL,
+    parent_id VARCHAR(27) NOT NULL DEFAULT '',
+    path TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS categories_path_idx ON categories (path);
+
+-- Create a table for search synonym rules; each rule's terms are interchangeable in queries.
+CREATE TABLE IF NOT EXISTS synonym_rules (
+    id TEXT PRIMARY KEY,
+    terms TEXT[] NOT NULL
+);
+
+-- Create a table for the words dropped from search queries.
+CREATE TABLE IF NOT EXISTS stopwords (
+    word TEXT PRIMARY KEY
+);
+
+-- Create a table recording every product search, with its query lowercased for reports.
+CREATE TABLE IF NOT EXISTS search_events (
+    id CHAR(27) PRIMARY KEY,
+    query TEXT NOT NULL,
+    min_price NUMERIC(12, 2),
+    max_price NUMERIC(12, 2),
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    category_ids TEXT[] NOT NULL DEFAULT '{}',
+    sort SMALLINT NOT NULL,
+    results BIGINT NOT NULL,
+    latency_ms DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS search_events_created_at_idx ON search_events (created_at);
+
+-- Create a table for clicks and add-to-carts on search results.
+CREATE TABLE IF NOT EXISTS search_feedback (
+    search_id CHAR(27) NOT NULL,
+    product_id CHAR(27) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS search_feedback_created_at_idx ON search_feedback (created_at);
@@ -0,0 +1,134 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/segmentio/ksuid"
+)
+
+var (
+    ErrInvalidVariant = errors.New("invalid variant")
+    ErrDuplicateSKU   = errors.New("sku already exists")
+    ErrOutOfStock     = errors.New("not enough stock")
+)
+
+// Variant is one purchasable version of a product, e.g. a shirt in size M
+// and color blue. Orders reference variants by id.
+type Variant struct {
+    ID      string   `json:"id"`
+    SKU     string   `json:"sku"`
+    // Price overrides the product price when set.
+    Price   *float64 `json:"price,omitempty"`
+    Options []Option `json:"options"`
+    Stock   uint32   `json:"stock"`
+}
+
+// Option is a single attribute a variant differs by, e.g. size=M.
+type Option struct {
+    Name  string `json:"name"`
+    Value string `json:"value"`
+}
+
+// Variant returns the variant of p with id.
+func (p Product) Variant(id string) (*Variant, bool) {
+    for _, v := range p.Variants {
+        if v.ID == id {
+            return &v, true
+        }
+    }
+    return nil, false
+}
+
+// VariantPrice is the price a variant sells for.
+func (p Product) VariantPrice(v Variant) float64 {
+    if v.Price != nil {
+        return *v.Price
+    }
+    return p.Price
+}
+
+// SetProductVariants replaces the variants of a product. Variants without
+// an id are new and get one assigned, starting with their given stock;
+// existing ones keep theirs, which orders, returns and restocks adjust.
+// SKUs must be unique across the catalog.
+func (s *catalogService) SetProductVariants(
+    ctx context.Context, productID string, variants []Variant,
+) (*Product, error) {
+    variants, err := s.prepareVariants(ctx, productID, variants)
+    if err != nil {
+        return nil, err
+    }
+
+    if err := s.repository.SetProductVariants(ctx, productID, variants); err != nil {
+        log.Println("failed to set product variants from catalog service: ", err)
+        return nil, err
+    }
+    return s.repository.GetProductByID(ctx, productID)
+}
+
+// RestockVariant adds quantity units of a variant back to its stock, e.g.
+// for returned items.
+func (s *catalogService) RestockVariant(
+    ctx context.Context, productID, variantID string, quantity uint32,
+) (*Product, error) {
+    return s.adjustVariantStock(ctx, productID, variantID, int64(quantity))
+}
+
+// ReserveVariantStock takes quantity units of a variant out of its stock
+// for an order. It returns ErrOutOfStock, changing nothing, if there are
+// fewer left.
+func (s *catalogService) ReserveVariantStock(
+    ctx context.Context, productID, variantID string, quantity uint32,
+) (*Product, error) {
+    return s.adjustVariantStock(ctx, productID, variantID, -int64(quantity))
+}
+
+func (s *catalogService) adjustVariantStock(
+    ctx context.Context, productID, variantID string, delta int64,
+) (*Product, error) {
+    if err := s.repository.AdjustVariantStock(ctx, productID, variantID, delta); err != nil {
+        log.Println("failed to adjust variant stock from catalog service: ", err)
+        return nil, err
+    }
+    return s.repository.GetProductByID(ctx, productID)
+}
+
+// prepareVariants validates variants for the product with productID and
+// assigns ids to new ones.
+func (s *catalogService) prepareVariants(
+    ctx context.Context, productID string, variants []Variant,
+) ([]Variant, error) {
+    prepared := []Variant{}
+    skus := map[string]bool{}
+    for _, v := range variants {
+        v.SKU = strings.TrimSpace(v.SKU)
+        if v.SKU == "" || (v.Price != nil && *v.Price < 0) {
+            return nil, ErrInvalidVariant
+        }
+        if skus[v.SKU] {
+            return nil, ErrDuplicateSKU
+        }
+        skus[v.SKU] = true
+
+        owner, err := s.repository.GetProductBySKU(ctx, v.SKU)
+        if err != nil && !errors.Is(err, ErrNotFound) {
+            log.Println("failed to look up sku from catalog service: ", err)
+            return nil, err
+        }
+        if owner != nil && owner.ID != productID {
+            return nil, ErrDuplicateSKU
+        }
+
+        if v.ID == "" {
+            v.ID = ksuid.New().String()
+        }
+        if v.Options == nil {
+            v.Options = []Option{}
+        }
+        prepared = append(prepared, v)
+    }
+    return prepared, nil
+}
@@ -0,0 +1,238 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestProductVariants(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+
+    shirt, err := s.PostProduct(ctx, "T-Shirt", "cotton", 20, nil, nil, []Variant{
+        {SKU: "TS-S", Options: []Option{{Name: "size", Value: "S"}}, Stock: 3},
+        {SKU: "TS-XL", Price: floatPtr(24), Options: []Option{{Name: "size", Value: "XL"}}},
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(shirt.Variants) != 2 || shirt.Variants[0].ID == "" || shirt.Variants[0].ID == shirt.Variants[1].ID {
+        t.Fatalf("expected variants with distinct ids, got %+v", shirt.Variants)
+    }
+    if got := shirt.VariantPrice(shirt.Variants[0]); got != 20 {
+        t.Fatalf("expected the product price for a variant without override, got %v", got)
+    }
+    if got := shirt.VariantPrice(shirt.Variants[1]); got != 24 {
+        t.Fatalf("expected the override price, got %v", got)
+    }
+
+    if _, err := s.PostProduct(ctx, "Hoodie", "", 40, nil, nil, []Variant{{SKU: "TS-S"}}); !errors.Is(err, ErrDuplicateSKU) {
+        t.Fatalf("expected ErrDuplicateSKU across products, got %v", err)
+    }
+    if _, err := s.PostProduct(ctx, "Hoodie", "", 40, nil, nil, []Variant{{SKU: "H"}, {SKU: "H"}}); !errors.Is(err, ErrDuplicateSKU) {
+        t.Fatalf("expected ErrDuplicateSKU within a product, got %v", err)
+    }
+    if _, err := s.PostProduct(ctx, "Hoodie", "", 40, nil, nil, []Variant{{SKU: " "}}); !errors.Is(err, ErrInvalidVariant) {
+        t.Fatalf("expected ErrInvalidVariant, got %v", err)
+    }
+
+    // Keeping a variant's sku on the same product is not a conflict.
+    kept := shirt.Variants[0]
+    kept.Price = floatPtr(22)
+    kept.Stock = 10
+    updated, err := s.SetProductVariants(ctx, shirt.ID, []Variant{kept, {SKU: "TS-M", Stock: 4}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if v, ok := updated.Variant(kept.ID); !ok || v.Price == nil || *v.Price != 22 || v.Stock != 3 {
+        t.Fatalf("expected the kept variant to be updated and keep its stock, got %+v", updated.Variants)
+    }
+    if v := updated.Variants[1]; v.SKU != "TS-M" || v.Stock != 4 {
+        t.Fatalf("expected the new variant to start with its stock, got %+v", v)
+    }
+    if _, ok := updated.Variant(shirt.Variants[1].ID); ok {
+        t.Fatal("expected the omitted variant to be removed")
+    }
+
+    res, err := s.SearchProducts(ctx, SearchRequest{Query: "shirt"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(res.Products) != 1 || len(res.Products[0].Variants) != 2 {
+        t.Fatalf("expected the parent product with its variants, got %+v", res.Products)
+    }
+}
+
+func TestRestockVariant(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+
+    shirt, err := s.PostProduct(ctx, "T-Shirt", "cotton", 20, nil, nil, []Variant{{SKU: "TS-S", Stock: 3}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    restocked, err := s.RestockVariant(ctx, shirt.ID, shirt.Variants[0].ID, 2)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if restocked.Variants[0].Stock != 5 {
+        t.Fatalf("expected a stock of 5, got %+v", restocked.Variants)
+    }
+    if _, err := s.RestockVariant(ctx, shirt.ID, "unknown", 1); !errors.Is(err, ErrInvalidVariant) {
+        t.Fatalf("expected ErrInvalidVariant for an unknown variant, got %v", err)
+    }
+}
+
+func TestReserveVariantStock(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+
+    shirt, err := s.PostProduct(ctx, "T-Shirt", "cotton", 20, nil, nil, []Variant{{SKU: "TS-S", Stock: 50}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    variantID := shirt.Variants[0].ID
+
+    // Concurrent reservations and restocks must not lose each other's
+    // updates.
+    var wg sync.WaitGroup
+    for range 20 {
+        wg.Add(2)
+        go func() {
+            defer wg.Done()
+            if _, err := s.ReserveVariantStock(ctx, shirt.ID, variantID, 2); err != nil {
+                t.Error(err)
+            }
+        }()
+        go func() {
+            defer wg.Done()
+            if _, err := s.RestockVariant(ctx, shirt.ID, variantID, 1); err != nil {
+                t.Error(err)
+            }
+        }()
+    }
+    wg.Wait()
+
+    p, err := s.GetProduct(ctx, shirt.ID)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if p.Variants[0].Stock != 30 {
+        t.Fatalf("expected a stock of 30, got %+v", p.Variants)
+    }
+    if _, err := s.ReserveVariantStock(ctx, shirt.ID, variantID, 31); !errors.Is(err, ErrOutOfStock) {
+        t.Fatalf("expected ErrOutOfStock, got %v", err)
+    }
+    if p, _ := s.GetProduct(ctx, shirt.ID); p.Variants[0].Stock != 30 {
+        t.Fatalf("expected a failed reservation to leave the stock, got %+v", p.Variants)
+    }
+}
+
+func TestElasticRepositoryAdjustVariantStock(t *testing.T) {
+    ctx := context.Background()
+    f := newFakeElastic(t)
+    r := newTestElasticRepository(t, f)
+
+    shirt := Product{ID: "p1", Name: "T-Shirt", Price: 20, Variants: []Variant{{ID: "v1", SKU: "TS-S", Stock: 2}}}
+    if err := r.PutProduct(ctx, shirt); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := r.AdjustVariantStock(ctx, "p1", "v1", -2); err != nil {
+        t.Fatal(err)
+    }
+    body := f.lastBody(http.MethodPost, "/_update/p1")
+    if body["script"] == nil || body["doc"] != nil {
+        t.Fatalf("expected a scripted update, got %v", body)
+    }
+    got, err := r.GetProductByID(ctx, "p1")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got.Variants[0].Stock != 0 {
+        t.Fatalf("expected a stock of 0, got %+v", got.Variants)
+    }
+
+    if err := r.AdjustVariantStock(ctx, "p1", "v1", -1); !errors.Is(err, ErrOutOfStock) {
+        t.Fatalf("expected ErrOutOfStock, got %v", err)
+    }
+    if err := r.AdjustVariantStock(ctx, "p1", "v2", 1); !errors.Is(err, ErrInvalidVariant) {
+        t.Fatalf("expected ErrInvalidVariant, got %v", err)
+    }
+    if err := r.AdjustVariantStock(ctx, "p2", "v1", 1); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("expected ErrNotFound, got %v", err)
+    }
+}
+
+func TestElasticRepositorySetProductVariants(t *testing.T) {
+    ctx := context.Background()
+    f := newFakeElastic(t)
+    r := newTestElasticRepository(t, f)
+
+    shirt := Product{ID: "p1", Name: "T-Shirt", Price: 20, Variants: []Variant{{ID: "v1", SKU: "TS-S", Stock: 5}}}
+    if err := r.PutProduct(ctx, shirt); err != nil {
+        t.Fatal(err)
+    }
+    // Stock taken by an order after the variants were read.
+    if err := r.AdjustVariantStock(ctx, "p1", "v1", -2); err != nil {
+        t.Fatal(err)
+    }
+
+    variants := []Variant{
+        {ID: "v1", SKU: "TS-S2", Options: []Option{}, Stock: 5},
+        {ID: "v2", SKU: "TS-M", Options: []Option{}, Stock: 1},
+    }
+    if err := r.SetProductVariants(ctx, "p1", variants); err != nil {
+        t.Fatal(err)
+    }
+    body := f.lastBody(http.MethodPost, "/_update/p1")
+    if body["script"] == nil || body["doc"] != nil {
+        t.Fatalf("expected a scripted update, got %v", body)
+    }
+    got, err := r.GetProductByID(ctx, "p1")
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := []Variant{
+        {ID: "v1", SKU: "TS-S2", Options: []Option{}, Stock: 3},
+        {ID: "v2", SKU: "TS-M", Options: []Option{}, Stock: 1},
+    }
+    if !reflect.DeepEqual(got.Variants, want) {
+        t.Fatalf("expected %+v, got %+v", want, got.Variants)
+    }
+
+    if err := r.SetProductVariants(ctx, "p2", variants); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("expected ErrNotFound, got %v", err)
+    }
+}
+
+func TestElasticRepositoryGetProductBySKU(t *testing.T) {
+    ctx := context.Background()
+    f := newFakeElastic(t)
+    r := newTestElasticRepository(t, f)
+
+    mug := Product{ID: "p1", Name: "Mug", Price: 9}
+    shirt := Product{ID: "p2", Name: "T-Shirt", Price: 20, Variants: []Variant{
+        {ID: "v1", SKU: "TS-S", Options: []Option{{Name: "size", Value: "S"}}, Stock: 2},
+    }}
+    for _, p := range []Product{mug, shirt} {
+        if err := r.PutProduct(ctx, p); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    got, err := r.GetProductBySKU(ctx, "TS-S")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(*got, shirt) {
+        t.Fatalf("got %+v, want %+v", got, shirt)
+    }
+    if _, err := r.GetProductBySKU(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("expected ErrNotFound, got %v", err)
+    }
+}
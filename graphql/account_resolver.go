@@ -4,47 +4,312 @@ import (
 	"context"
 	"log"
 	"time"
+
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/account"
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/order"
 )
 
 type accountResolver struct {
     server *Server
 }
 
+// Orders returns a page of the account's orders, oldest first by default.
+// after is the id of the last order of the previous page.
 func (r *accountResolver) Orders(
     ctx context.Context, obj *Account,
-) ([]*Order, error){
+    filter *OrderFilterInput, sort *OrderSort, first *int, after *string,
+) (*OrderConnection, error){
     ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
     defer cancel()
 
-    orderList, err := r.server.orderClient.GetOrdersForAccount(
-        ctx, obj.ID,
-    )
+    q := order.OrderQuery{Filter: orderFilter(filter)}
+    if sort != nil {
+        q.Descending = *sort == OrderSortNewest
+    }
+    if first != nil {
+        if *first < 0 || *first > order.MaxOrdersPage {
+            return nil, ErrInvalidParameter
+        }
+        q.First = *first
+    }
+    if after != nil {
+        q.After = *after
+    }
+    page, err := r.server.orderClient.GetOrdersForAccount(ctx, obj.ID, q)
     if err != nil {
         log.Println(err)
         return nil, err
     }
 
-    var orders []*Order
+    return newOrderConnection(page), nil
+}
+
+func newOrderConnection(page *order.OrderPage) *OrderConnection {
+    c := &OrderConnection{
+        Orders: []*Order{},
+        PageInfo: &PageInfo{HasMore: page.HasMore},
+    }
+    for _, o := range page.Orders {
+        c.Orders = append(c.Orders, newOrder(o))
+    }
+    if len(page.Orders) != 0 {
+        c.PageInfo.EndCursor = &page.Orders[len(page.Orders) - 1].ID
+    }
+    return c
+}
+
+func (r *accountResolver) Addresses(ctx context.Context, obj *Account) ([]*Address, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    res, err := r.server.accountClient.GetAddresses(ctx, obj.ID)
+    if err != nil {
+        log.Println(err)
+        return nil, err
+    }
+
+    addresses := []*Address{}
+    for _, a := range res {
+        addresses = append(addresses, newAddress(a))
+    }
+    return addresses, nil
+}
+
+func newAddress(a account.Address) *Address {
+    return &Address{
+        ID: a.ID,
+        Name: a.Name,
+        Company: a.Company,
+        Line1: a.Line1,
+        Line2: a.Line2,
+        City: a.City,
+        Region: a.Region,
+        PostalCode: a.PostalCode,
+        Country: a.Country,
+        Phone: a.Phone,
+        IsDefault: a.IsDefault,
+    }
+}
+
+func newOrderAddress(a *order.Address) *OrderAddress {
+    if a == nil {
+        return nil
+    }
+    return &OrderAddress{
+        Name: a.Name,
+        Company: a.Company,
+        Line1: a.Line1,
+        Line2: a.Line2,
+        City: a.City,
+        Region: a.Region,
+        PostalCode: a.PostalCode,
+        Country: a.Country,
+        Phone: a.Phone,
+    }
+}
+
+func newOrder(o order.Order) *Order {
+    products := []*OrderedProduct{}
+    for _, p := range o.Products {
+        product := &OrderedProduct{
+            ID:             p.ID,
+            Name:           p.Name,
+            Price:          p.Price,
+            Quantity:       int(p.Quantity),
+            Description:    p.Description,
+            CancelledQuantity: int(p.CancelledQuantity),
+        }
+        if p.VariantID != "" {
+            product.VariantID = &p.VariantID
+            product.Sku = &p.SKU
+        }
+        products = append(products, product)
+    }
+
+    refunds := []*Refund{}
+    for _, rf := range o.Refunds {
+        refund := &Refund{
+            ID: rf.ID,
+            Reason: cancelReasons[rf.Reason],
+            Amount: rf.Amount,
+            CreatedAt: rf.CreatedAt,
+            Lines: []*RefundLine{},
+        }
+        for _, l := range rf.Lines {
+            line := &RefundLine{
+                ProductID: l.ProductID,
+                Quantity: int(l.Quantity),
+                Amount: l.Amount,
+            }
+            if l.VariantID != "" {
+                line.VariantID = &l.VariantID
+            }
+            refund.Lines = append(refund.Lines, line)
+        }
+        if rf.ReturnID != "" {
+            refund.ReturnID = &rf.ReturnID
+        }
+        refunds = append(refunds, refund)
+    }
+
+    returns := []*Return{}
+    for _, ret := range o.Returns {
+        returns = append(returns, newReturn(ret))
+    }
 
-    for _, o := range orderList {
-        var products []*OrderedProduct
-        for _, p := range o.Products {
-            products = append(products, &OrderedProduct{
-                ID:             p.ID,
-                Name:           p.Name,
-                Price:          p.Price,
-                Quantity:       int(p.Quantity),
-                Description:    p.Description,
-            })
+    shipments := []*Shipment{}
+    for _, s := range o.Shipments {
+        shipments = append(shipments, newShipment(s))
+    }
+
+    res := &Order{
+        ID: o.ID,
+        AccountID: o.AccountID,
+        CreatedAt: o.CreatedAt,
+        UpdatedAt: o.UpdatedAt,
+        TotalPrice: o.TotalPrice,
+        Status: orderStatuses[o.Status],
+        Products: products,
+        RefundedTotal: o.RefundedTotal,
+        Refunds: refunds,
+        Returns: returns,
+        Shipments: shipments,
+        ShippingAddress: newOrderAddress(o.ShippingAddress),
+        BillingAddress: newOrderAddress(o.BillingAddress),
+    }
+    if o.Invoice != nil {
+        number := o.Invoice.String()
+        res.InvoiceNumber = &number
+    }
+    return res
+}
 
+func newShipment(s order.Shipment) *Shipment {
+    shipment := &Shipment{
+        ID: s.ID,
+        OrderID: s.OrderID,
+        Carrier: s.Carrier,
+        TrackingNumber: s.TrackingNumber,
+        Status: shipmentStatuses[s.Status],
+        Lines: []*ShipmentLine{},
+        CreatedAt: s.CreatedAt,
+        UpdatedAt: s.UpdatedAt,
+        ShippedAt: s.ShippedAt,
+        DeliveredAt: s.DeliveredAt,
+    }
+    for _, l := range s.Lines {
+        line := &ShipmentLine{
+            ProductID: l.ProductID,
+            Quantity: int(l.Quantity),
+        }
+        if l.VariantID != "" {
+            line.VariantID = &l.VariantID
         }
-        orders = append(orders, &Order{
-            ID: o.ID,
-            CreatedAt: o.CreatedAt,
-            TotalPrice: float64(o.TotalPrice),
-            Products: products,
+        shipment.Lines = append(shipment.Lines, line)
+    }
+    return shipment
+}
+
+func newReturn(r order.Return) *Return {
+    ret := &Return{
+        ID: r.ID,
+        OrderID: r.OrderID,
+        ProductID: r.ProductID,
+        Quantity: int(r.Quantity),
+        Reason: r.Reason,
+        Status: returnStatuses[r.Status],
+        RestockedQuantity: int(r.RestockedQuantity),
+        RefundAmount: r.RefundAmount,
+        CreatedAt: r.CreatedAt,
+        UpdatedAt: r.UpdatedAt,
+        History: []*ReturnEvent{},
+    }
+    if r.VariantID != "" {
+        ret.VariantID = &r.VariantID
+    }
+    for _, e := range r.History {
+        ret.History = append(ret.History, &ReturnEvent{
+            Status: returnStatuses[e.Status],
+            Note: e.Note,
+            CreatedAt: e.CreatedAt,
         })
     }
+    return ret
+}
 
-    return orders, nil
+var orderStatuses = map[order.Status]OrderStatus{
+    order.StatusPlaced:           OrderStatusPlaced,
+    order.StatusCancelled:        OrderStatusCancelled,
+    order.StatusPartiallyShipped: OrderStatusPartiallyShipped,
+    order.StatusShipped:          OrderStatusShipped,
+    order.StatusDelivered:        OrderStatusDelivered,
+}
+
+var shipmentStatuses = map[order.ShipmentStatus]ShipmentStatus{
+    order.ShipmentPending:        ShipmentStatusPending,
+    order.ShipmentInTransit:      ShipmentStatusInTransit,
+    order.ShipmentOutForDelivery: ShipmentStatusOutForDelivery,
+    order.ShipmentDelivered:      ShipmentStatusDelivered,
+    order.ShipmentException:      ShipmentStatusException,
+}
+
+var cancelReasons = map[order.CancelReason]CancelReason{
+    order.ReasonCustomerRequest: CancelReasonCustomerRequest,
+    order.ReasonOutOfStock:      CancelReasonOutOfStock,
+    order.ReasonPaymentFailed:   CancelReasonPaymentFailed,
+    order.ReasonFraud:           CancelReasonFraud,
+    order.ReasonOther:           CancelReasonOther,
+    order.ReasonReturn:          CancelReasonReturn,
+}
+
+var returnStatuses = map[order.ReturnStatus]ReturnStatus{
+    order.ReturnRequested: ReturnStatusRequested,
+    order.ReturnApproved:  ReturnStatusApproved,
+    order.ReturnRejected:  ReturnStatusRejected,
+    order.ReturnReceived:  ReturnStatusReceived,
+    order.ReturnInspected: ReturnStatusInspected,
+}
+
+func orderFilter(in *OrderFilterInput) order.OrderFilter {
+    f := order.OrderFilter{}
+    if in == nil {
+        return f
+    }
+    f.CreatedFrom = in.CreatedFrom
+    f.CreatedTo = in.CreatedTo
+    f.MinTotal = in.MinTotal
+    f.MaxTotal = in.MaxTotal
+    if in.ProductID != nil {
+        f.ProductID = *in.ProductID
+    }
+    for _, status := range in.Statuses {
+        for s, gs := range orderStatuses {
+            if gs == status {
+                f.Statuses = append(f.Statuses, s)
+            }
+        }
+    }
+    return f
+}
+
+func orderSearch(in *OrderSearchInput) order.OrderSearch {
+    q := order.OrderSearch{}
+    if in == nil {
+        return q
+    }
+    q.Filter = orderFilter(&OrderFilterInput{
+        CreatedFrom: in.CreatedFrom,
+        CreatedTo: in.CreatedTo,
+        Statuses: in.Statuses,
+        MinTotal: in.MinTotal,
+        MaxTotal: in.MaxTotal,
+        ProductID: in.ProductID,
+    })
+    if in.AccountID != nil {
+        q.AccountID = *in.AccountID
+    }
+    if in.AccountName != nil {
+        q.AccountName = *in.AccountName
+    }
+    return q
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/catalog"
+)
+
+type categoryResolver struct {
+    server *Server
+}
+
+func (r *categoryResolver) Parent(
+    ctx context.Context, obj *Category,
+) (*Category, error) {
+    if obj.ParentID == nil {
+        return nil, nil
+    }
+
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    c, err := r.server.catalogClient.GetCategory(ctx, *obj.ParentID, "")
+    if err != nil {
+        log.Println(err)
+        return nil, err
+    }
+    return newCategory(*c), nil
+}
+
+func (r *categoryResolver) Children(
+    ctx context.Context, obj *Category,
+) ([]*Category, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    categoryList, err := r.server.catalogClient.GetCategories(ctx, nil, &obj.ID)
+    if err != nil {
+        log.Println(err)
+        return nil, err
+    }
+
+    categories := []*Category{}
+    for _, c := range categoryList {
+        categories = append(categories, newCategory(c))
+    }
+    return categories, nil
+}
+
+func newCategory(c catalog.Category) *Category {
+    category := &Category{
+        ID: c.ID,
+        Name: c.Name,
+        Slug: c.Slug,
+        Path: c.Path,
+    }
+    if c.ParentID != "" {
+        category.ParentID = &c.ParentID
+    }
+    return category
+}
@@ -0,0 +1,2083 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/testharness"
+)
+
+func newTestClient(t *testing.T) *client.Client {
+    t.Helper()
+
+    return client.New(withRoles(handler.NewDefaultServer(newTestServer(t).ToExecutableSchema()), testAdminToken))
+}
+
+// newTestServer returns a gateway talking to services started on the
+// test harness.
+func newTestServer(t *testing.T) *Server {
+    t.Helper()
+
+    h := testharness.Start(t)
+    return &Server{
+        accountClient: h.AccountClient,
+        catalogClient: h.CatalogClient,
+        orderClient:   h.OrderClient,
+        reviewClient:  h.ReviewClient,
+    }
+}
+
+const testAdminToken = "test-admin-token"
+
+// asAdmin authenticates a request with the admin token.
+func asAdmin(bd *client.Request) {
+    bd.HTTP.Header.Set("Authorization", "Bearer "+testAdminToken)
+}
+
+func createAccount(t *testing.T, c *client.Client, name string) string {
+    t.Helper()
+
+    var resp struct {
+        CreateAccount struct {
+            ID   string
+            Name string
+        }
+    }
+    c.MustPost(
+        `mutation($name: String!) { createAccount(account: {name: $name}) { id name } }`,
+        &resp,
+        client.Var("name", name),
+    )
+    if resp.CreateAccount.ID == "" || resp.CreateAccount.Name != name {
+        t.Fatalf("unexpected account: %+v", resp.CreateAccount)
+    }
+    return resp.CreateAccount.ID
+}
+
+func createProduct(
+    t *testing.T, c *client.Client, name, description string, price float64,
+) string {
+    t.Helper()
+
+    var resp struct {
+        CreateProduct struct {
+            ID string
+        }
+    }
+    c.MustPost(
+        `mutation($name: String!, $description: String!, $price: Float!) {
+            createProduct(product: {name: $name, description: $description, price: $price}) { id }
+        }`,
+        &resp,
+        client.Var("name", name),
+        client.Var("description", description),
+        client.Var("price", price),
+    )
+    if resp.CreateProduct.ID == "" {
+        t.Fatal("product was created without an id")
+    }
+    return resp.CreateProduct.ID
+}
+
+func TestCreateAccount(t *testing.T) {
+    c := newTestClient(t)
+
+    id := createAccount(t, c, "alice")
+
+    var resp struct {
+        Accounts []struct {
+            ID   string
+            Name string
+        }
+    }
+    c.MustPost(
+        `query($id: String) { accounts(id: $id) { id name } }`,
+        &resp,
+        client.Var("id", id),
+    )
+    if len(resp.Accounts) != 1 || resp.Accounts[0].Name != "alice" {
+        t.Fatalf("unexpected accounts: %+v", resp.Accounts)
+    }
+}
+
+func TestListAccountsPagination(t *testing.T) {
+    c := newTestClient(t)
+
+    for _, name := range []string{"a", "b", "c"} {
+        createAccount(t, c, name)
+    }
+
+    var resp struct {
+        Accounts []struct {
+            Name string
+        }
+    }
+    c.MustPost(
+        `query { accounts(pagination: {skip: 1, take: 1}) { name } }`,
+        &resp,
+    )
+    if len(resp.Accounts) != 1 {
+        t.Fatalf("expected 1 account, got %d", len(resp.Accounts))
+    }
+}
+
+func TestSearchProducts(t *testing.T) {
+    c := newTestClient(t)
+
+    createProduct(t, c, "Wireless Headphones", "over-ear, noise cancelling", 199.99)
+    createProduct(t, c, "Coffee Mug", "ceramic, 350ml", 9.5)
+    createProduct(t, c, "Earbuds", "wireless in-ear headphones", 59)
+
+    var resp struct {
+        Products []struct {
+            Name  string
+            Price float64
+        }
+    }
+    c.MustPost(
+        `query($q: String) { products(query: $q) { name price } }`,
+        &resp,
+        client.Var("q", "headphones"),
+    )
+    if len(resp.Products) != 2 {
+        t.Fatalf("expected 2 products, got %+v", resp.Products)
+    }
+    for _, p := range resp.Products {
+        if p.Name == "Coffee Mug" {
+            t.Fatalf("search returned unrelated product: %+v", p)
+        }
+    }
+}
+
+func TestSearchProductsTypos(t *testing.T) {
+    c := newTestClient(t)
+
+    headphones := createProduct(t, c, "Wireless Headphones", "over-ear", 199.99)
+    createProduct(t, c, "Coffee Mug", "ceramic", 9.5)
+
+    var resp struct {
+        SearchProducts struct {
+            Products []struct {
+                ID string
+            }
+            Highlights []struct {
+                ProductID string
+                Field     string
+                Fragments []string
+            }
+            DidYouMean *string
+        }
+    }
+    c.MustPost(
+        `query($q: String) {
+            searchProducts(search: {query: $q}) {
+                products { id }
+                highlights { productId field fragments }
+                didYouMean
+            }
+        }`,
+        &resp,
+        client.Var("q", "wireles headphnoes"),
+    )
+    res := resp.SearchProducts
+    if len(res.Products) != 1 || res.Products[0].ID != headphones {
+        t.Fatalf("expected the typo to find the headphones, got %+v", res.Products)
+    }
+    if len(res.Highlights) != 1 || res.Highlights[0].ProductID != headphones ||
+        res.Highlights[0].Fragments[0] != "<em>Wireless</em> <em>Headphones</em>" {
+        t.Fatalf("unexpected highlights %+v", res.Highlights)
+    }
+    if res.DidYouMean == nil || *res.DidYouMean != "wireless headphones" {
+        t.Fatalf("unexpected did you mean %v", res.DidYouMean)
+    }
+
+    err := c.Post(
+        `{ searchProducts(search: {query: "mug", fuzziness: 3}) { total } }`,
+        &resp,
+    )
+    if err == nil {
+        t.Fatal("expected fuzziness above 2 to be rejected")
+    }
+}
+
+func TestPlaceOrderAndHistory(t *testing.T) {
+    c := newTestClient(t)
+
+    accountID := createAccount(t, c, "bob")
+    mug := createProduct(t, c, "Coffee Mug", "ceramic", 9.5)
+    beans := createProduct(t, c, "Coffee Beans", "1kg", 20)
+
+    var order struct {
+        CreateOrder struct {
+            ID         string
+            TotalPrice float64
+        }
+    }
+    c.MustPost(
+        `mutation($accountId: String!, $products: [OrderProductInput!]!) {
+            createOrder(order: {accountId: $accountId, products: $products}) { id totalPrice }
+        }`,
+        &order,
+        client.Var("accountId", accountID),
+        client.Var("products", []map[string]any{
+            {"id": mug, "quantity": 2},
+            {"id": beans, "quantity": 1},
+        }),
+    )
+    if order.CreateOrder.ID == "" {
+        t.Fatal("order was created without an id")
+    }
+    if order.CreateOrder.TotalPrice != 39 {
+        t.Fatalf("expected total 39, got %v", order.CreateOrder.TotalPrice)
+    }
+
+    var history struct {
+        Accounts []struct {
+            Orders struct {
+                Orders []struct {
+                    ID         string
+                    CreatedAt  string
+                    UpdatedAt  string
+                    TotalPrice float64
+                    Products   []struct {
+                        ID       string
+                        Name     string
+                        Price    float64
+                        Quantity int
+                    }
+                }
+            }
+        }
+    }
+    c.MustPost(
+        `query($id: String) {
+            accounts(id: $id) {
+                orders { orders { id createdAt updatedAt totalPrice products { id name price quantity } } }
+            }
+        }`,
+        &history,
+        client.Var("id", accountID),
+    )
+    if len(history.Accounts) != 1 || len(history.Accounts[0].Orders.Orders) != 1 {
+        t.Fatalf("unexpected order history: %+v", history)
+    }
+    o := history.Accounts[0].Orders.Orders[0]
+    if o.ID != order.CreateOrder.ID || o.TotalPrice != 39 {
+        t.Fatalf("unexpected order: %+v", o)
+    }
+    if o.CreatedAt == "" || o.UpdatedAt != o.CreatedAt {
+        t.Fatalf("expected a new order to be updated when created, got %v %v", o.CreatedAt, o.UpdatedAt)
+    }
+    if len(o.Products) != 2 {
+        t.Fatalf("expected 2 order lines, got %+v", o.Products)
+    }
+    for _, p := range o.Products {
+        if p.Name == "" || p.Price == 0 {
+            t.Fatalf("order line was not enriched from the catalog: %+v", p)
+        }
+    }
+}
+
+func TestOrderHistoryEmpty(t *testing.T) {
+    c := newTestClient(t)
+
+    accountID := createAccount(t, c, "carol")
+
+    var resp struct {
+        Accounts []struct {
+            Orders struct {
+                Orders []struct {
+                    ID string
+                }
+                PageInfo struct {
+                    HasMore   bool
+                    EndCursor *string
+                }
+            }
+        }
+    }
+    c.MustPost(
+        `query($id: String) { accounts(id: $id) { orders { orders { id } pageInfo { hasMore endCursor } } } }`,
+        &resp,
+        client.Var("id", accountID),
+    )
+    if len(resp.Accounts) != 1 || len(resp.Accounts[0].Orders.Orders) != 0 {
+        t.Fatalf("expected no orders, got %+v", resp.Accounts)
+    }
+    if info := resp.Accounts[0].Orders.PageInfo; info.HasMore || info.EndCursor != nil {
+        t.Fatalf("expected an empty last page, got %+v", info)
+    }
+}
+
+func TestCreateOrderUnknownAccount(t *testing.T) {
+    c := newTestClient(t)
+
+    product := createProduct(t, c, "Coffee Mug", "ceramic", 9.5)
+
+    var resp struct {
+        CreateOrder *struct {
+            ID string
+        }
+    }
+    err := c.Post(
+        `mutation($products: [OrderProductInput!]!) {
+            createOrder(order: {accountId: "missing", products: $products}) { id }
+        }`,
+        &resp,
+        client.Var("products", []map[string]any{{"id": product, "quantity": 1}}),
+    )
+    if err == nil {
+        t.Fatal("expected an error for an unknown account")
+    }
+}
+
+func TestCreateOrderManyProducts(t *testing.T) {
+    c := newTestClient(t)
+
+    accountID := createAccount(t, c, "dave")
+    lines := []map[string]any{}
+    for i := 0; i < 15; i++ {
+        id := createProduct(t, c, fmt.Sprintf("Product %d", i), "", 1)
+        lines = append(lines, map[string]any{"id": id, "quantity": 1})
+    }
+
+    var resp struct {
+        CreateOrder struct {
+            TotalPrice float64
+        }
+    }
+    c.MustPost(
+        `mutation($accountId: String!, $products: [OrderProductInput!]!) {
+            createOrder(order: {accountId: $accountId, products: $products}) { totalPrice }
+        }`,
+        &resp,
+        client.Var("accountId", accountID),
+        client.Var("products", lines),
+    )
+    if resp.CreateOrder.TotalPrice != 15 {
+        t.Fatalf("expected all 15 lines to be priced, got %+v", resp.CreateOrder)
+    }
+
+    err := c.Post(
+        `mutation($accountId: String!, $products: [OrderProductInput!]!) {
+            createOrder(order: {accountId: $accountId, products: $products}) { id }
+        }`,
+        &resp,
+        client.Var("accountId", accountID),
+        client.Var("products", append(lines, map[string]any{"id": "missing", "quantity": 1})),
+    )
+    if err == nil || !strings.Contains(err.Error(), "missing") {
+        t.Fatalf("expected an unknown product to fail the order, got %v", err)
+    }
+}
+
+func TestSearchProductsFacets(t *testing.T) {
+    c := newTestClient(t)
+
+    for _, p := range []map[string]any{
+        {"name": "Studio Headphones", "description": "wired", "price": 120.0, "tags": []string{"audio"}},
+        {"name": "Earbuds", "description": "wireless", "price": 40.0, "tags": []string{"audio", "sale"}},
+        {"name": "Coffee Mug", "description": "ceramic", "price": 9.5, "tags": []string{"kitchen"}},
+    } {
+        var resp struct{ CreateProduct struct{ ID string } }
+        c.MustPost(
+            `mutation($product: ProductInput) { createProduct(product: $product) { id } }`,
+            &resp,
+            client.Var("product", p),
+        )
+    }
+
+    var resp struct {
+        SearchProducts struct {
+            Total    int
+            Products []struct {
+                Name string
+            }
+            Facets struct {
+                Prices []struct {
+                    From  *float64
+                    To    *float64
+                    Count int
+                }
+                Tags []struct {
+                    Tag   string
+                    Count int
+                }
+            }
+        }
+    }
+    c.MustPost(
+        `query {
+            searchProducts(search: {tags: ["audio"], sort: PRICE_ASC}) {
+                total
+                products { name }
+                facets { prices { from to count } tags { tag count } }
+            }
+        }`,
+        &resp,
+    )
+
+    res := resp.SearchProducts
+    if res.Total != 2 || len(res.Products) != 2 {
+        t.Fatalf("expected 2 audio products, got %+v", res)
+    }
+    if res.Products[0].Name != "Earbuds" || res.Products[1].Name != "Studio Headphones" {
+        t.Fatalf("expected products sorted by price, got %+v", res.Products)
+    }
+    if len(res.Facets.Tags) != 2 || res.Facets.Tags[0].Tag != "audio" || res.Facets.Tags[0].Count != 2 {
+        t.Fatalf("unexpected tag facets %+v", res.Facets.Tags)
+    }
+    counted := 0
+    for _, b := range res.Facets.Prices {
+        counted += b.Count
+    }
+    if counted != 2 {
+        t.Fatalf("expected price buckets to cover both products, got %+v", res.Facets.Prices)
+    }
+}
+
+func TestCategoriesAndTaxonomy(t *testing.T) {
+    c := newTestClient(t)
+
+    createCategory := func(name string, parentID *string) string {
+        var resp struct{ CreateCategory struct{ ID string } }
+        c.MustPost(
+            `mutation($name: String!, $parentId: String) {
+                createCategory(category: {name: $name, parentId: $parentId}) { id }
+            }`,
+            &resp,
+            client.Var("name", name),
+            client.Var("parentId", parentID),
+        )
+        return resp.CreateCategory.ID
+    }
+    audio := createCategory("Audio", nil)
+    headphones := createCategory("Headphones", &audio)
+    createCategory("Kitchen", nil)
+
+    earbuds := createProduct(t, c, "Earbuds", "in-ear", 40)
+    createProduct(t, c, "Coffee Mug", "ceramic", 9.5)
+
+    var taxonomy struct {
+        SetProductTaxonomy struct {
+            Categories []struct{ Path string }
+        }
+    }
+    c.MustPost(
+        `mutation($id: String!, $categoryIds: [String!]!) {
+            setProductTaxonomy(productId: $id, categoryIds: $categoryIds, tags: ["sale"]) {
+                categories { path }
+            }
+        }`,
+        &taxonomy,
+        client.Var("id", earbuds),
+        client.Var("categoryIds", []string{headphones}),
+    )
+    if cs := taxonomy.SetProductTaxonomy.Categories; len(cs) != 1 || cs[0].Path != "audio/headphones" {
+        t.Fatalf("unexpected product categories %+v", cs)
+    }
+
+    var tree struct {
+        Categories []struct {
+            Name     string
+            Children []struct {
+                Path   string
+                Parent struct{ Name string }
+            }
+        }
+    }
+    c.MustPost(`query { categories { name children { path parent { name } } } }`, &tree)
+    if len(tree.Categories) != 2 || tree.Categories[0].Name != "Audio" {
+        t.Fatalf("unexpected root categories %+v", tree.Categories)
+    }
+    if ch := tree.Categories[0].Children; len(ch) != 1 || ch[0].Parent.Name != "Audio" {
+        t.Fatalf("unexpected children %+v", ch)
+    }
+
+    var search struct {
+        SearchProducts struct {
+            Products []struct{ ID string }
+        }
+    }
+    c.MustPost(
+        `query($categoryIds: [String!]) {
+            searchProducts(search: {categoryIds: $categoryIds}) { products { id } }
+        }`,
+        &search,
+        client.Var("categoryIds", []string{audio}),
+    )
+    if ps := search.SearchProducts.Products; len(ps) != 1 || ps[0].ID != earbuds {
+        t.Fatalf("expected the earbuds under audio, got %+v", ps)
+    }
+
+    var deleted struct{ DeleteCategory bool }
+    if err := c.Post(`mutation($id: String!) { deleteCategory(id: $id) }`, &deleted, client.Var("id", audio)); err == nil {
+        t.Fatal("expected deleting a category with children to fail")
+    }
+}
+
+func TestOrderProductVariants(t *testing.T) {
+    c := newTestClient(t)
+
+    accountID := createAccount(t, c, "dave")
+
+    var created struct {
+        CreateProduct struct {
+            ID       string
+            Variants []struct {
+                ID    string
+                Sku   string
+                Price float64
+            }
+        }
+    }
+    c.MustPost(
+        `mutation {
+            createProduct(product: {
+                name: "T-Shirt", description: "cotton", price: 20,
+                variants: [
+                    {sku: "TS-M", options: [{name: "size", value: "M"}], stock: 5},
+                    {sku: "TS-XL", price: 24, options: [{name: "size", value: "XL"}], stock: 2}
+                ]
+            }) { id variants { id sku price } }
+        }`,
+        &created,
+    )
+    shirt := created.CreateProduct
+    if len(shirt.Variants) != 2 || shirt.Variants[1].Price != 24 {
+        t.Fatalf("unexpected variants %+v", shirt.Variants)
+    }
+    medium, large := shirt.Variants[0].ID, shirt.Variants[1].ID
+
+    var order struct {
+        CreateOrder struct{ TotalPrice float64 }
+    }
+    c.MustPost(
+        `mutation($accountId: String!, $products: [OrderProductInput!]!) {
+            createOrder(order: {accountId: $accountId, products: $products}) { totalPrice }
+        }`,
+        &order,
+        client.Var("accountId", accountID),
+        client.Var("products", []map[string]any{
+            {"id": shirt.ID, "variantId": medium, "quantity": 1},
+            {"id": shirt.ID, "variantId": large, "quantity": 2},
+        }),
+    )
+    if order.CreateOrder.TotalPrice != 68 {
+        t.Fatalf("expected total 68, got %v", order.CreateOrder.TotalPrice)
+    }
+
+    err := c.Post(
+        `mutation($accountId: String!, $id: String!) {
+            createOrder(order: {accountId: $accountId, products: [{id: $id, quantity: 1}]}) { id }
+        }`,
+        &struct{}{},
+        client.Var("accountId", accountID),
+        client.Var("id", shirt.ID),
+    )
+    if err == nil {
+        t.Fatal("expected ordering a product with variants without a variant to fail")
+    }
+
+    // The order took its units out of stock; one more XL isn't left, and
+    // the M ordered alongside it is put back.
+    err = c.Post(
+        `mutation($accountId: String!, $products: [OrderProductInput!]!) {
+            createOrder(order: {accountId: $accountId, products: $products}) { id }
+        }`,
+        &struct{}{},
+        client.Var("accountId", accountID),
+        client.Var("products", []map[string]any{
+            {"id": shirt.ID, "variantId": medium, "quantity": 1},
+            {"id": shirt.ID, "variantId": large, "quantity": 1},
+        }),
+    )
+    if err == nil || !strings.Contains(err.Error(), "out of stock: TS-XL") {
+        t.Fatalf("expected the XL to be out of stock, got %v", err)
+    }
+    var stock struct {
+        Products []struct{ Variants []struct{ Stock int } }
+    }
+    c.MustPost(
+        `query($id: String) { products(id: $id) { variants { stock } } }`,
+        &stock,
+        client.Var("id", shirt.ID),
+    )
+    if v := stock.Products[0].Variants; v[0].Stock != 4 || v[1].Stock != 0 {
+        t.Fatalf("expected stocks of 4 and 0, got %+v", v)
+    }
+
+    var history struct {
+        Accounts []struct {
+            Orders struct {
+                Orders []struct {
+                    Products []struct {
+                        VariantID *string
+                        Sku       *string
+                        Price     float64
+                        Quantity  int
+                    }
+                }
+            }
+        }
+    }
+    c.MustPost(
+        `query($id: String) {
+            accounts(id: $id) { orders { orders { products { variantId sku price quantity } } } }
+        }`,
+        &history,
+        client.Var("id", accountID),
+    )
+    lines := history.Accounts[0].Orders.Orders[0].Products
+    if len(lines) != 2 {
+        t.Fatalf("expected 2 order lines, got %+v", lines)
+    }
+    for _, l := range lines {
+        if l.VariantID == nil || l.Sku == nil {
+            t.Fatalf("expected variant lines, got %+v", l)
+        }
+        if *l.VariantID == large && (*l.Sku != "TS-XL" || l.Price != 24 || l.Quantity != 2) {
+            t.Fatalf("unexpected XL line %+v", l)
+        }
+    }
+}
+
+func TestProductSuggestions(t *testing.T) {
+    c := newTestClient(t)
+
+    createProduct(t, c, "Wireless Headphones", "over-ear", 199.99)
+    createProduct(t, c, "Wireless Charger", "qi", 29)
+    createProduct(t, c, "Coffee Mug", "ceramic", 9.5)
+
+    var resp struct {
+        ProductSuggestions []struct {
+            ProductID   string
+            Name        string
+            Highlighted string
+        }
+    }
+    c.MustPost(
+        `query($prefix: String!) { productSuggestions(prefix: $prefix, limit: 5) { productId name highlighted } }`,
+        &resp,
+        client.Var("prefix", "wireless ch"),
+    )
+    if len(resp.ProductSuggestions) != 1 {
+        t.Fatalf("expected 1 suggestion, got %+v", resp.ProductSuggestions)
+    }
+    s := resp.ProductSuggestions[0]
+    if s.Name != "Wireless Charger" || s.Highlighted != "<em>Wireless</em> <em>Charger</em>" || s.ProductID == "" {
+        t.Fatalf("unexpected suggestion %+v", s)
+    }
+}
+
+func TestSearchSynonymsAndStopwords(t *testing.T) {
+    c := newTestClient(t)
+
+    tv := createProduct(t, c, "Television", "55 inch", 499)
+
+    var rule struct {
+        PutSynonymRule struct {
+            ID    string
+            Terms []string
+        }
+    }
+    err := c.Post(`mutation { putSynonymRule(terms: ["TV", "television"]) { id terms } }`, &rule)
+    if err == nil || !strings.Contains(err.Error(), "forbidden") {
+        t.Fatalf("expected editing synonyms without the admin token to be forbidden, got %v", err)
+    }
+    c.MustPost(
+        `mutation { putSynonymRule(terms: ["TV", "television"]) { id terms } }`,
+        &rule,
+        asAdmin,
+    )
+    if rule.PutSynonymRule.ID == "" ||
+        strings.Join(rule.PutSynonymRule.Terms, ",") != "tv,television" {
+        t.Fatalf("unexpected rule %+v", rule.PutSynonymRule)
+    }
+    var stopwords struct {
+        SetStopwords []string
+    }
+    err = c.Post(`mutation { setStopwords(words: ["The"]) }`, &stopwords)
+    if err == nil || !strings.Contains(err.Error(), "forbidden") {
+        t.Fatalf("expected setting stopwords without the admin token to be forbidden, got %v", err)
+    }
+    c.MustPost(`mutation { setStopwords(words: ["The"]) }`, &stopwords, asAdmin)
+    if strings.Join(stopwords.SetStopwords, ",") != "the" {
+        t.Fatalf("unexpected stopwords %v", stopwords.SetStopwords)
+    }
+
+    var resp struct {
+        SearchProducts struct {
+            Products []struct {
+                ID string
+            }
+        }
+        SearchSynonyms []struct {
+            ID string
+        }
+        SearchStopwords []string
+    }
+    c.MustPost(
+        `{
+            searchProducts(search: {query: "the tv"}) { products { id } }
+            searchSynonyms { id }
+            searchStopwords
+        }`,
+        &resp,
+    )
+    if len(resp.SearchProducts.Products) != 1 || resp.SearchProducts.Products[0].ID != tv {
+        t.Fatalf("expected the synonym to find the television, got %+v", resp.SearchProducts.Products)
+    }
+    if len(resp.SearchSynonyms) != 1 || resp.SearchSynonyms[0].ID != rule.PutSynonymRule.ID {
+        t.Fatalf("unexpected synonyms %+v", resp.SearchSynonyms)
+    }
+    if strings.Join(resp.SearchStopwords, ",") != "the" {
+        t.Fatalf("unexpected stopwords %v", resp.SearchStopwords)
+    }
+
+    var deleted struct {
+        DeleteSynonymRule bool
+    }
+    c.MustPost(
+        `mutation($id: String!) { deleteSynonymRule(id: $id) }`,
+        &deleted,
+        client.Var("id", rule.PutSynonymRule.ID),
+        asAdmin,
+    )
+    if !deleted.DeleteSynonymRule {
+        t.Fatal("expected the rule to be deleted")
+    }
+    if err := c.Post(`mutation { putSynonymRule(terms: ["tv"]) { id } }`, &rule, asAdmin); err == nil {
+        t.Fatal("expected a single term rule to be rejected")
+    }
+}
+
+func TestSearchAnalytics(t *testing.T) {
+    c := newTestClient(t)
+
+    tv := createProduct(t, c, "TV", "55 inch", 499)
+
+    var search struct {
+        SearchProducts struct {
+            SearchID string
+        }
+    }
+    for _, q := range []string{"radio", "tv", "TV"} {
+        c.MustPost(
+            `query($q: String) { searchProducts(search: {query: $q}) { searchId } }`,
+            &search,
+            client.Var("q", q),
+        )
+    }
+    if search.SearchProducts.SearchID == "" {
+        t.Fatal("expected a search id")
+    }
+
+    var feedback struct {
+        RecordSearchFeedback bool
+    }
+    c.MustPost(
+        `mutation($s: String!, $p: String!) {
+            recordSearchFeedback(searchId: $s, productId: $p, kind: CLICK)
+        }`,
+        &feedback,
+        client.Var("s", search.SearchProducts.SearchID),
+        client.Var("p", tv),
+    )
+    if !feedback.RecordSearchFeedback {
+        t.Fatal("expected the feedback to be recorded")
+    }
+
+    var reports struct {
+        TopSearchQueries []struct {
+            Query    string
+            Searches int
+        }
+        ZeroResultSearchQueries []struct {
+            Query string
+        }
+        SearchClickThrough struct {
+            Searches        int
+            ClickedSearches int
+            Rate            float64
+        }
+    }
+    c.MustPost(
+        `{
+            topSearchQueries(days: 1) { query searches }
+            zeroResultSearchQueries { query }
+            searchClickThrough { searches clickedSearches rate }
+        }`,
+        &reports,
+        asAdmin,
+    )
+    top := reports.TopSearchQueries
+    if len(top) != 2 || top[0].Query != "tv" || top[0].Searches != 2 || top[1].Query != "radio" {
+        t.Fatalf("unexpected top queries %+v", top)
+    }
+    if len(reports.ZeroResultSearchQueries) != 1 || reports.ZeroResultSearchQueries[0].Query != "radio" {
+        t.Fatalf("unexpected zero result queries %+v", reports.ZeroResultSearchQueries)
+    }
+    ct := reports.SearchClickThrough
+    if ct.Searches != 3 || ct.ClickedSearches != 1 || ct.Rate < 0.33 || ct.Rate > 0.34 {
+        t.Fatalf("unexpected click through %+v", ct)
+    }
+
+    if err := c.Post(`{ topSearchQueries(limit: -1) { query } }`, &reports, asAdmin); err == nil {
+        t.Fatal("expected a negative limit to be rejected")
+    }
+    for _, q := range []string{
+        `{ topSearchQueries { query } }`,
+        `{ zeroResultSearchQueries { query } }`,
+        `{ searchClickThrough { rate } }`,
+    } {
+        if err := c.Post(q, &reports); err == nil || !strings.Contains(err.Error(), "forbidden") {
+            t.Fatalf("expected %s without the admin token to be forbidden, got %v", q, err)
+        }
+    }
+}
+
+func TestProductReviews(t *testing.T) {
+    c := newTestClient(t)
+
+    alice := createAccount(t, c, "alice")
+    bob := createAccount(t, c, "bob")
+    kettle := createProduct(t, c, "Kettle", "1.7 litre", 30)
+    createProduct(t, c, "Toaster", "2 slots", 25)
+
+    type review struct {
+        ID           string
+        Rating       int
+        Status       string
+        HelpfulVotes int
+    }
+    var created struct {
+        CreateReview review
+    }
+    post := func(accountID string, rating int) error {
+        return c.Post(
+            `mutation($p: String!, $a: String!, $r: Int!) {
+                createReview(review: {productId: $p, accountId: $a, rating: $r, text: "boils fast"}) {
+                    id rating status helpfulVotes
+                }
+            }`,
+            &created,
+            client.Var("p", kettle),
+            client.Var("a", accountID),
+            client.Var("r", rating),
+        )
+    }
+    if err := post(alice, 4); err == nil {
+        t.Fatal("expected a review without an order to be rejected")
+    }
+
+    for _, accountID := range []string{alice, bob} {
+        var order struct {
+            CreateOrder struct {
+                ID string
+            }
+        }
+        c.MustPost(
+            `mutation($a: String!, $p: String!) {
+                createOrder(order: {accountId: $a, products: [{id: $p, quantity: 1}]}) { id }
+            }`,
+            &order,
+            client.Var("a", accountID),
+            client.Var("p", kettle),
+        )
+    }
+    if err := post(alice, 4); err != nil {
+        t.Fatal(err)
+    }
+    aliceReview := created.CreateReview
+    if aliceReview.Status != "PUBLISHED" || aliceReview.Rating != 4 {
+        t.Fatalf("unexpected review %+v", aliceReview)
+    }
+    if err := post(alice, 5); err == nil {
+        t.Fatal("expected a second review of the same product to be rejected")
+    }
+    if err := post(bob, 2); err != nil {
+        t.Fatal(err)
+    }
+    bobReview := created.CreateReview
+
+    var vote struct {
+        VoteReviewHelpful review
+    }
+    voteQuery := `mutation($id: String!, $a: String!) {
+        voteReviewHelpful(id: $id, accountId: $a) { id helpfulVotes }
+    }`
+    if err := c.Post(voteQuery, &vote, client.Var("id", bobReview.ID), client.Var("a", bob)); err == nil {
+        t.Fatal("expected a vote for an own review to be rejected")
+    }
+    c.MustPost(voteQuery, &vote, client.Var("id", bobReview.ID), client.Var("a", alice))
+    if vote.VoteReviewHelpful.HelpfulVotes != 1 {
+        t.Fatalf("expected one helpful vote, got %+v", vote.VoteReviewHelpful)
+    }
+    if err := c.Post(voteQuery, &vote, client.Var("id", bobReview.ID), client.Var("a", alice)); err == nil {
+        t.Fatal("expected a second vote to be rejected")
+    }
+
+    var products struct {
+        Products []struct {
+            Rating      *float64
+            ReviewCount int
+            Reviews     []review
+        }
+    }
+    productQuery := `query($id: String) { products(id: $id) { rating reviewCount reviews { id status } } }`
+    c.MustPost(productQuery, &products, client.Var("id", kettle))
+    p := products.Products[0]
+    if p.Rating == nil || *p.Rating != 3 || p.ReviewCount != 2 {
+        t.Fatalf("unexpected rating %v from %d reviews", p.Rating, p.ReviewCount)
+    }
+    if len(p.Reviews) != 2 || p.Reviews[0].ID != bobReview.ID {
+        t.Fatalf("expected the most helpful review first, got %+v", p.Reviews)
+    }
+
+    var moderated struct {
+        ModerateReview review
+    }
+    moderateQuery := `mutation($id: String!) { moderateReview(id: $id, status: REJECTED) { status } }`
+    err := c.Post(moderateQuery, &moderated, client.Var("id", bobReview.ID))
+    if err == nil || !strings.Contains(err.Error(), "forbidden") {
+        t.Fatalf("expected moderating without the admin token to be forbidden, got %v", err)
+    }
+    c.MustPost(moderateQuery, &moderated, client.Var("id", bobReview.ID), asAdmin)
+    if moderated.ModerateReview.Status != "REJECTED" {
+        t.Fatalf("unexpected moderated review %+v", moderated.ModerateReview)
+    }
+    c.MustPost(productQuery, &products, client.Var("id", kettle))
+    p = products.Products[0]
+    if p.Rating == nil || *p.Rating != 4 || p.ReviewCount != 1 || len(p.Reviews) != 1 {
+        t.Fatalf("expected the rejected review to be left out, got %+v", p)
+    }
+
+    rejectedQuery := `query($id: String) { products(id: $id) { reviews(includeRejected: true) { id status } } }`
+    err = c.Post(rejectedQuery, &products, client.Var("id", kettle))
+    if err == nil || !strings.Contains(err.Error(), "forbidden") {
+        t.Fatalf("expected rejected reviews without the admin token to be forbidden, got %v", err)
+    }
+    c.MustPost(rejectedQuery, &products, client.Var("id", kettle), asAdmin)
+    if len(products.Products[0].Reviews) != 2 {
+        t.Fatalf("expected admins to see the rejected review, got %+v", products.Products[0].Reviews)
+    }
+
+    var search struct {
+        SearchProducts struct {
+            Products []struct {
+                ID     string
+                Rating *float64
+            }
+        }
+    }
+    c.MustPost(
+        `{ searchProducts(search: {minRating: 3.5, sort: RATING_DESC}) { products { id rating } } }`,
+        &search,
+    )
+    if len(search.SearchProducts.Products) != 1 || search.SearchProducts.Products[0].ID != kettle {
+        t.Fatalf("expected only the rated kettle, got %+v", search.SearchProducts.Products)
+    }
+}
+
+func TestRelatedProducts(t *testing.T) {
+    c := newTestClient(t)
+
+    alice := createAccount(t, c, "alice")
+    bob := createAccount(t, c, "bob")
+    mug := createProduct(t, c, "Mug", "ceramic mug", 10)
+    beans := createProduct(t, c, "Coffee Beans", "arabica", 20)
+    filter := createProduct(t, c, "Paper Filters", "for drip brewing", 5)
+    grinder := createProduct(t, c, "Coffee Grinder", "burr", 40)
+
+    for accountID, products := range map[string][]string{
+        alice: {mug, beans},
+        bob:   {mug, beans, filter},
+    } {
+        lines := []map[string]any{}
+        for _, id := range products {
+            lines = append(lines, map[string]any{"id": id, "quantity": 1})
+        }
+        var order struct {
+            CreateOrder struct {
+                ID string
+            }
+        }
+        c.MustPost(
+            `mutation($a: String!, $p: [OrderProductInput!]!) {
+                createOrder(order: {accountId: $a, products: $p}) { id }
+            }`,
+            &order,
+            client.Var("a", accountID),
+            client.Var("p", lines),
+        )
+    }
+
+    var resp struct {
+        Products []struct {
+            RelatedProducts []struct {
+                ID string
+            }
+        }
+    }
+    query := `query($id: String, $take: Int) { products(id: $id) { relatedProducts(take: $take) { id } } }`
+    c.MustPost(query, &resp, client.Var("id", mug), client.Var("take", 2))
+    related := resp.Products[0].RelatedProducts
+    if len(related) != 2 || related[0].ID != beans || related[1].ID != filter {
+        t.Fatalf("expected the beans then the filters, got %+v", related)
+    }
+
+    // Nobody ordered the grinder yet, so it falls back to similar products.
+    c.MustPost(query, &resp, client.Var("id", grinder), client.Var("take", 5))
+    related = resp.Products[0].RelatedProducts
+    if len(related) != 1 || related[0].ID != beans {
+        t.Fatalf("expected the similar beans, got %+v", related)
+    }
+
+    if err := c.Post(query, &resp, client.Var("id", mug), client.Var("take", -1)); err == nil {
+        t.Fatal("expected a negative take to be rejected")
+    }
+}
+
+func TestOrderHistoryFiltersAndPagination(t *testing.T) {
+    c := newTestClient(t)
+
+    accountID := createAccount(t, c, "erin")
+    mug := createProduct(t, c, "Mug", "ceramic", 10)
+    beans := createProduct(t, c, "Beans", "arabica", 20)
+    for _, line := range []map[string]any{
+        {"id": mug, "quantity": 1},
+        {"id": beans, "quantity": 1},
+        {"id": beans, "quantity": 3},
+    } {
+        var order struct {
+            CreateOrder struct {
+                Status string
+            }
+        }
+        c.MustPost(
+            `mutation($a: String!, $p: [OrderProductInput!]!) {
+                createOrder(order: {accountId: $a, products: $p}) { status }
+            }`,
+            &order,
+            client.Var("a", accountID),
+            client.Var("p", []map[string]any{line}),
+        )
+        if order.CreateOrder.Status != "PLACED" {
+            t.Fatalf("expected a placed order, got %+v", order.CreateOrder)
+        }
+    }
+
+    type history struct {
+        Accounts []struct {
+            Orders struct {
+                Orders []struct {
+                    ID         string
+                    TotalPrice float64
+                }
+            }
+        }
+    }
+    // orders runs the account's orders field with args; variables other
+    // than $id are declared in decls.
+    orders := func(decls, args string, vars ...client.Option) []string {
+        t.Helper()
+        var resp history
+        vars = append(vars, client.Var("id", accountID))
+        c.MustPost(
+            `query($id: String`+decls+`) {
+                accounts(id: $id) { orders`+args+` { orders { id totalPrice } } }
+            }`,
+            &resp,
+            vars...,
+        )
+        ids := []string{}
+        for _, o := range resp.Accounts[0].Orders.Orders {
+            ids = append(ids, o.ID)
+        }
+        return ids
+    }
+
+    all := orders("", "")
+    if len(all) != 3 {
+        t.Fatalf("expected 3 orders, got %v", all)
+    }
+    newest := orders("", "(sort: NEWEST)")
+    if !reflect.DeepEqual(newest, []string{all[2], all[1], all[0]}) {
+        t.Fatalf("expected %v reversed, got %v", all, newest)
+    }
+
+    first := orders("", "(first: 2)")
+    rest := orders(", $after: String", "(first: 2, after: $after)", client.Var("after", first[1]))
+    if !reflect.DeepEqual(append(first, rest...), all) {
+        t.Fatalf("expected pages %v and %v to make up %v", first, rest, all)
+    }
+
+    var info struct {
+        Accounts []struct {
+            Orders struct {
+                PageInfo struct {
+                    HasMore   bool
+                    EndCursor *string
+                }
+            }
+        }
+    }
+    pageInfo := func(args string) (bool, *string) {
+        t.Helper()
+        c.MustPost(
+            `query($id: String) { accounts(id: $id) { orders`+args+` { pageInfo { hasMore endCursor } } } }`,
+            &info,
+            client.Var("id", accountID),
+        )
+        p := info.Accounts[0].Orders.PageInfo
+        return p.HasMore, p.EndCursor
+    }
+    if hasMore, cursor := pageInfo("(first: 2)"); !hasMore || cursor == nil || *cursor != first[1] {
+        t.Fatalf("expected a next page after %v, got %v %v", first[1], hasMore, cursor)
+    }
+    // A full last page still tells there is nothing after it.
+    if hasMore, cursor := pageInfo("(first: 3)"); hasMore || cursor == nil || *cursor != all[2] {
+        t.Fatalf("expected a last page ending at %v, got %v %v", all[2], hasMore, cursor)
+    }
+
+    if ids := orders("", "(filter: {minTotal: 15, maxTotal: 50})"); len(ids) != 1 {
+        t.Fatalf("expected only the 20 order, got %v", ids)
+    }
+    if ids := orders(", $product: String", "(filter: {productId: $product})", client.Var("product", beans)); len(ids) != 2 {
+        t.Fatalf("expected the two bean orders, got %v", ids)
+    }
+    if ids := orders("", `(filter: {createdFrom: "2100-01-01T00:00:00Z"})`); len(ids) != 0 {
+        t.Fatalf("expected no future orders, got %v", ids)
+    }
+    if ids := orders("", "(filter: {statuses: [PLACED]})"); len(ids) != 3 {
+        t.Fatalf("expected every order to be placed, got %v", ids)
+    }
+
+    var resp history
+    err := c.Post(
+        `query($id: String) { accounts(id: $id) { orders(filter: {minTotal: 50, maxTotal: 10}) { orders { id } } } }`,
+        &resp,
+        client.Var("id", accountID),
+    )
+    if err == nil {
+        t.Fatal("expected an inverted total range to be rejected")
+    }
+    if ids := orders("", "(first: 200)"); len(ids) != 3 {
+        t.Fatalf("expected the largest page to hold every order, got %v", ids)
+    }
+    err = c.Post(
+        `query($id: String) { accounts(id: $id) { orders(first: 201) { orders { id } } } }`,
+        &resp,
+        client.Var("id", accountID),
+    )
+    if err == nil {
+        t.Fatal("expected a page size above 200 to be rejected")
+    }
+}
+
+func TestAdminSearchOrders(t *testing.T) {
+    c := newTestClient(t)
+
+    alice := createAccount(t, c, "Alice Smith")
+    smithy := createAccount(t, c, "Bob Smithson")
+    carol := createAccount(t, c, "Carol Jones")
+    mug := createProduct(t, c, "Mug", "ceramic", 10)
+    beans := createProduct(t, c, "Beans", "arabica", 20)
+    orderIDs := map[string]string{}
+    for _, o := range []struct {
+        name, account, product string
+    }{
+        {"alice mug", alice, mug},
+        {"smithy beans", smithy, beans},
+        {"carol beans", carol, beans},
+    } {
+        var resp struct {
+            CreateOrder struct {
+                ID string
+            }
+        }
+        c.MustPost(
+            `mutation($a: String!, $p: [OrderProductInput!]!) {
+                createOrder(order: {accountId: $a, products: $p}) { id }
+            }`,
+            &resp,
+            client.Var("a", o.account),
+            client.Var("p", []map[string]any{{"id": o.product, "quantity": 1}}),
+        )
+        orderIDs[resp.CreateOrder.ID] = o.name
+    }
+
+    type search struct {
+        Orders struct {
+            Orders []struct {
+                ID        string
+                AccountID string
+            }
+        }
+    }
+    // orders searches with the admin token; variables are declared in decls.
+    orders := func(decls, args string, vars ...client.Option) []string {
+        t.Helper()
+        var resp search
+        vars = append(vars, asAdmin)
+        query := `query { orders` + args + ` { orders { id accountId } } }`
+        if decls != "" {
+            query = `query(` + decls + `) { orders` + args + ` { orders { id accountId } } }`
+        }
+        c.MustPost(query, &resp, vars...)
+        names := []string{}
+        for _, o := range resp.Orders.Orders {
+            names = append(names, orderIDs[o.ID])
+        }
+        // Orders placed within the same second have ids in random order.
+        slices.Sort(names)
+        return names
+    }
+
+    if names := orders("", ""); len(names) != 3 {
+        t.Fatalf("expected the orders of every account, got %v", names)
+    }
+    if names := orders("", `(filter: {accountName: "SMITH"})`); !reflect.DeepEqual(names, []string{"alice mug", "smithy beans"}) {
+        t.Fatalf("expected the orders of both smiths, got %v", names)
+    }
+    names := orders(
+        "$a: String, $p: String",
+        `(filter: {accountName: "smith", accountId: $a, productId: $p})`,
+        client.Var("a", smithy),
+        client.Var("p", beans),
+    )
+    if !reflect.DeepEqual(names, []string{"smithy beans"}) {
+        t.Fatalf("expected only the smithson order, got %v", names)
+    }
+    if names := orders("", `(filter: {accountName: "nobody"})`); len(names) != 0 {
+        t.Fatalf("expected no orders for an unknown name, got %v", names)
+    }
+    if names := orders("", "(filter: {minTotal: 15}, first: 1)"); len(names) != 1 || !strings.HasSuffix(names[0], "beans") {
+        t.Fatalf("expected a page of one bean order, got %v", names)
+    }
+
+    var resp search
+    if err := c.Post(`query { orders { orders { id } } }`, &resp); err == nil || !strings.Contains(err.Error(), "forbidden") {
+        t.Fatalf("expected the search to require the admin role, got %v", err)
+    }
+    wrongToken := func(bd *client.Request) {
+        bd.HTTP.Header.Set("Authorization", "Bearer wrong")
+    }
+    if err := c.Post(`query { orders { orders { id } } }`, &resp, wrongToken); err == nil {
+        t.Fatal("expected a wrong token to be rejected")
+    }
+    if err := c.Post(`query { orders(first: 201) { orders { id } } }`, &resp, asAdmin); err == nil {
+        t.Fatal("expected a page size above 200 to be rejected")
+    }
+
+    // A name matching more accounts than are looked up is rejected rather
+    // than searched in part.
+    for i := range 501 {
+        createAccount(t, c, fmt.Sprintf("crowd %d", i))
+    }
+    err := c.Post(`query { orders(filter: {accountName: "crowd"}) { orders { id } } }`, &resp, asAdmin)
+    if err == nil || !strings.Contains(err.Error(), "too many accounts") {
+        t.Fatalf("expected a name matching too many accounts to be rejected, got %v", err)
+    }
+    if names := orders("", `(filter: {accountName: "crowd 500"})`); len(names) != 0 {
+        t.Fatalf("expected no orders for a narrower name, got %v", names)
+    }
+}
+
+func TestCancelOrder(t *testing.T) {
+    c := newTestClient(t)
+
+    accountID := createAccount(t, c, "gina")
+    mug := createProduct(t, c, "Mug", "ceramic", 9.5)
+    beans := createProduct(t, c, "Beans", "arabica", 20)
+
+    var placed struct {
+        CreateOrder struct {
+            ID string
+        }
+    }
+    c.MustPost(
+        `mutation($a: String!, $p: [OrderProductInput!]!) {
+            createOrder(order: {accountId: $a, products: $p}) { id }
+        }`,
+        &placed,
+        client.Var("a", accountID),
+        client.Var("p", []map[string]any{
+            {"id": mug, "quantity": 2},
+            {"id": beans, "quantity": 1},
+        }),
+    )
+    orderID := placed.CreateOrder.ID
+
+    type cancelled struct {
+        CancelOrder struct {
+            Status        string
+            TotalPrice    float64
+            RefundedTotal float64
+            Products      []struct {
+                ID                string
+                Quantity          int
+                CancelledQuantity int
+            }
+            Refunds []struct {
+                Reason string
+                Amount float64
+                Lines  []struct {
+                    ProductID string
+                    Quantity  int
+                }
+            }
+        }
+    }
+    const fields = `{ status totalPrice refundedTotal products { id quantity cancelledQuantity }
+        refunds { reason amount lines { productId quantity } } }`
+
+    err := c.Post(
+        `mutation($id: String!) { cancelOrder(id: $id, reason: OTHER) { status } }`,
+        &struct{}{},
+        client.Var("id", orderID),
+    )
+    if err == nil || !strings.Contains(err.Error(), "forbidden") {
+        t.Fatalf("expected cancelling without the admin token to be forbidden, got %v", err)
+    }
+
+    var partial cancelled
+    c.MustPost(
+        `mutation($id: String!, $lines: [CancelOrderLineInput!]) {
+            cancelOrder(id: $id, reason: OUT_OF_STOCK, lines: $lines) `+fields+`
+        }`,
+        &partial,
+        client.Var("id", orderID),
+        client.Var("lines", []map[string]any{{"productId": mug, "quantity": 1}}),
+        asAdmin,
+    )
+    o := partial.CancelOrder
+    if o.Status != "PLACED" || o.TotalPrice != 39 || o.RefundedTotal != 9.5 || len(o.Refunds) != 1 {
+        t.Fatalf("unexpected partially cancelled order %+v", o)
+    }
+    if r := o.Refunds[0]; r.Reason != "OUT_OF_STOCK" || r.Amount != 9.5 || len(r.Lines) != 1 || r.Lines[0].ProductID != mug {
+        t.Fatalf("unexpected refund %+v", r)
+    }
+    for _, p := range o.Products {
+        if p.ID == mug && p.CancelledQuantity != 1 {
+            t.Fatalf("expected one cancelled mug, got %+v", p)
+        }
+    }
+
+    var resp cancelled
+    err = c.Post(
+        `mutation($id: String!, $lines: [CancelOrderLineInput!]) {
+            cancelOrder(id: $id, reason: OTHER, lines: $lines) { status }
+        }`,
+        &resp,
+        client.Var("id", orderID),
+        client.Var("lines", []map[string]any{{"productId": mug, "quantity": 2}}),
+        asAdmin,
+    )
+    if err == nil {
+        t.Fatal("expected cancelling more mugs than are left to fail")
+    }
+
+    var full cancelled
+    c.MustPost(
+        `mutation($id: String!) { cancelOrder(id: $id, reason: CUSTOMER_REQUEST) `+fields+` }`,
+        &full,
+        client.Var("id", orderID),
+        asAdmin,
+    )
+    o = full.CancelOrder
+    if o.Status != "CANCELLED" || o.RefundedTotal != 39 || len(o.Refunds) != 2 || o.Refunds[1].Amount != 29.5 {
+        t.Fatalf("unexpected cancelled order %+v", o)
+    }
+
+    err = c.Post(
+        `mutation($id: String!) { cancelOrder(id: $id, reason: OTHER) { status } }`,
+        &resp,
+        client.Var("id", orderID),
+        asAdmin,
+    )
+    if err == nil || !strings.Contains(err.Error(), "before it is fulfilled") {
+        t.Fatalf("expected a cancelled order not to be cancellable again, got %v", err)
+    }
+
+    var history struct {
+        Accounts []struct {
+            Orders struct {
+                Orders []struct {
+                    Status        string
+                    RefundedTotal float64
+                }
+            }
+        }
+    }
+    c.MustPost(
+        `query($id: String) { accounts(id: $id) { orders(filter: {statuses: [CANCELLED]}) { orders { status refundedTotal } } } }`,
+        &history,
+        client.Var("id", accountID),
+    )
+    if len(history.Accounts[0].Orders.Orders) != 1 || history.Accounts[0].Orders.Orders[0].RefundedTotal != 39 {
+        t.Fatalf("expected the cancelled order in the history, got %+v", history)
+    }
+}
+
+func TestCancelOrderRestocksVariants(t *testing.T) {
+    c := newTestClient(t)
+
+    accountID := createAccount(t, c, "hank")
+    var created struct {
+        CreateProduct struct {
+            ID       string
+            Variants []struct{ ID string }
+        }
+    }
+    c.MustPost(
+        `mutation {
+            createProduct(product: {
+                name: "T-Shirt", description: "cotton", price: 20,
+                variants: [{sku: "CX-M", stock: 5}]
+            }) { id variants { id } }
+        }`,
+        &created,
+    )
+    shirt, medium := created.CreateProduct.ID, created.CreateProduct.Variants[0].ID
+
+    var placed struct {
+        CreateOrder struct{ ID string }
+    }
+    c.MustPost(
+        `mutation($a: String!, $p: [OrderProductInput!]!) {
+            createOrder(order: {accountId: $a, products: $p}) { id }
+        }`,
+        &placed,
+        client.Var("a", accountID),
+        client.Var("p", []map[string]any{{"id": shirt, "variantId": medium, "quantity": 3}}),
+    )
+
+    stock := func() int {
+        var products struct {
+            Products []struct{ Variants []struct{ Stock int } }
+        }
+        c.MustPost(
+            `query($id: String) { products(id: $id) { variants { stock } } }`,
+            &products,
+            client.Var("id", shirt),
+        )
+        return products.Products[0].Variants[0].Stock
+    }
+    if s := stock(); s != 2 {
+        t.Fatalf("expected the order to take 3 units, got a stock of %d", s)
+    }
+
+    c.MustPost(
+        `mutation($id: String!, $lines: [CancelOrderLineInput!]) {
+            cancelOrder(id: $id, reason: CUSTOMER_REQUEST, lines: $lines) { status }
+        }`,
+        &struct{ CancelOrder struct{ Status string } }{},
+        client.Var("id", placed.CreateOrder.ID),
+        client.Var("lines", []map[string]any{{"productId": shirt, "variantId": medium, "quantity": 1}}),
+        asAdmin,
+    )
+    if s := stock(); s != 3 {
+        t.Fatalf("expected the cancelled unit back in stock, got %d", s)
+    }
+    c.MustPost(
+        `mutation($id: String!) { cancelOrder(id: $id, reason: CUSTOMER_REQUEST) { status } }`,
+        &struct{ CancelOrder struct{ Status string } }{},
+        client.Var("id", placed.CreateOrder.ID),
+        asAdmin,
+    )
+    if s := stock(); s != 5 {
+        t.Fatalf("expected the rest of the order back in stock, got %d", s)
+    }
+}
+
+func TestReturns(t *testing.T) {
+    c := newTestClient(t)
+
+    accountID := createAccount(t, c, "hank")
+    var created struct {
+        CreateProduct struct {
+            ID       string
+            Variants []struct{ ID string }
+        }
+    }
+    c.MustPost(
+        `mutation {
+            createProduct(product: {
+                name: "T-Shirt", description: "cotton", price: 20,
+                variants: [{sku: "RT-M", stock: 5}]
+            }) { id variants { id } }
+        }`,
+        &created,
+    )
+    shirt, medium := created.CreateProduct.ID, created.CreateProduct.Variants[0].ID
+
+    var placed struct {
+        CreateOrder struct{ ID string }
+    }
+    c.MustPost(
+        `mutation($a: String!, $p: [OrderProductInput!]!) {
+            createOrder(order: {accountId: $a, products: $p}) { id }
+        }`,
+        &placed,
+        client.Var("a", accountID),
+        client.Var("p", []map[string]any{{"id": shirt, "variantId": medium, "quantity": 3}}),
+    )
+    orderID := placed.CreateOrder.ID
+
+    requestReturn := func(quantity int) (string, error) {
+        var resp struct {
+            RequestReturn struct {
+                ID     string
+                Status string
+            }
+        }
+        err := c.Post(
+            `mutation($r: ReturnInput!) { requestReturn(return: $r) { id status } }`,
+            &resp,
+            client.Var("r", map[string]any{
+                "accountId": accountID, "orderId": orderID, "productId": shirt,
+                "variantId": medium, "quantity": quantity, "reason": "too small",
+            }),
+        )
+        return resp.RequestReturn.ID, err
+    }
+    if _, err := requestReturn(1); err == nil {
+        t.Fatal("expected returning shirts that weren't shipped to fail")
+    }
+    var shipped struct {
+        CreateShipment struct{ ID string }
+    }
+    c.MustPost(
+        `mutation($o: String!) { createShipment(orderId: $o, carrier: "ups") { id } }`,
+        &shipped,
+        client.Var("o", orderID),
+        asAdmin,
+    )
+    c.MustPost(
+        `mutation($id: String!) { updateShipment(id: $id, status: IN_TRANSIT) { id } }`,
+        &struct{ UpdateShipment struct{ ID string } }{},
+        client.Var("id", shipped.CreateShipment.ID),
+        asAdmin,
+    )
+
+    if _, err := requestReturn(4); err == nil {
+        t.Fatal("expected returning more than was ordered to fail")
+    }
+    returnID, err := requestReturn(2)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    err = c.Post(
+        `mutation($id: String!) { reviewReturn(id: $id, approve: true) { status } }`,
+        &struct{}{},
+        client.Var("id", returnID),
+    )
+    if err == nil || !strings.Contains(err.Error(), "forbidden") {
+        t.Fatalf("expected reviewing a return without the admin token to be forbidden, got %v", err)
+    }
+
+    var reviewed struct {
+        ReviewReturn struct{ Status string }
+    }
+    c.MustPost(
+        `mutation($id: String!) { reviewReturn(id: $id, approve: true, note: "ok") { status } }`,
+        &reviewed,
+        client.Var("id", returnID),
+        asAdmin,
+    )
+    var received struct {
+        ReceiveReturn struct{ Status string }
+    }
+    c.MustPost(
+        `mutation($id: String!) { receiveReturn(id: $id) { status } }`,
+        &received,
+        client.Var("id", returnID),
+        asAdmin,
+    )
+    if reviewed.ReviewReturn.Status != "APPROVED" || received.ReceiveReturn.Status != "RECEIVED" {
+        t.Fatalf("unexpected statuses %+v %+v", reviewed, received)
+    }
+    var inspected struct {
+        InspectReturn struct {
+            Status            string
+            RestockedQuantity int
+            RefundAmount      float64
+            History           []struct {
+                Status string
+                Note   string
+            }
+        }
+    }
+    c.MustPost(
+        `mutation($id: String!) {
+            inspectReturn(id: $id, restockQuantity: 1, note: "one stained") {
+                status restockedQuantity refundAmount history { status note }
+            }
+        }`,
+        &inspected,
+        client.Var("id", returnID),
+        asAdmin,
+    )
+    r := inspected.InspectReturn
+    if r.Status != "INSPECTED" || r.RestockedQuantity != 1 || r.RefundAmount != 40 {
+        t.Fatalf("unexpected inspected return %+v", r)
+    }
+    statuses := []string{}
+    for _, e := range r.History {
+        statuses = append(statuses, e.Status)
+    }
+    if !reflect.DeepEqual(statuses, []string{"REQUESTED", "APPROVED", "RECEIVED", "INSPECTED"}) || r.History[3].Note != "one stained" {
+        t.Fatalf("unexpected history %+v", r.History)
+    }
+
+    var history struct {
+        Accounts []struct {
+            Orders struct {
+                Orders []struct {
+                    RefundedTotal float64
+                    Refunds       []struct {
+                        ReturnID *string
+                        Reason   string
+                    }
+                    Returns []struct{ ID string }
+                }
+            }
+        }
+    }
+    c.MustPost(
+        `query($id: String) {
+            accounts(id: $id) { orders { orders { refundedTotal refunds { returnId reason } returns { id } } } }
+        }`,
+        &history,
+        client.Var("id", accountID),
+    )
+    o := history.Accounts[0].Orders.Orders[0]
+    if o.RefundedTotal != 40 || len(o.Refunds) != 1 || o.Refunds[0].Reason != "RETURN" ||
+        o.Refunds[0].ReturnID == nil || *o.Refunds[0].ReturnID != returnID {
+        t.Fatalf("expected the return's refund on the order, got %+v", o)
+    }
+    if len(o.Returns) != 1 || o.Returns[0].ID != returnID {
+        t.Fatalf("expected the return on the order, got %+v", o.Returns)
+    }
+
+    var products struct {
+        Products []struct {
+            Variants []struct{ Stock int }
+        }
+    }
+    c.MustPost(
+        `query($id: String) { products(id: $id) { variants { stock } } }`,
+        &products,
+        client.Var("id", shirt),
+    )
+    if stock := products.Products[0].Variants[0].Stock; stock != 3 {
+        t.Fatalf("expected the order's units gone and the restocked one back, got %d", stock)
+    }
+}
+
+func TestShipments(t *testing.T) {
+    c := newTestClient(t)
+
+    accountID := createAccount(t, c, "iris")
+    mug := createProduct(t, c, "Mug", "ceramic", 9.5)
+    beans := createProduct(t, c, "Beans", "arabica", 20)
+
+    var placed struct {
+        CreateOrder struct{ ID string }
+    }
+    c.MustPost(
+        `mutation($a: String!, $p: [OrderProductInput!]!) {
+            createOrder(order: {accountId: $a, products: $p}) { id }
+        }`,
+        &placed,
+        client.Var("a", accountID),
+        client.Var("p", []map[string]any{
+            {"id": mug, "quantity": 2},
+            {"id": beans, "quantity": 1},
+        }),
+    )
+    orderID := placed.CreateOrder.ID
+
+    type shipment struct {
+        ID             string
+        Status         string
+        Carrier        string
+        TrackingNumber string
+        ShippedAt      *string
+        DeliveredAt    *string
+        Lines          []struct {
+            ProductID string
+            Quantity  int
+        }
+    }
+    const fields = `{ id status carrier trackingNumber shippedAt deliveredAt lines { productId quantity } }`
+
+    err := c.Post(
+        `mutation($o: String!) { createShipment(orderId: $o, carrier: "ups") { id } }`,
+        &struct{}{},
+        client.Var("o", orderID),
+    )
+    if err == nil || !strings.Contains(err.Error(), "forbidden") {
+        t.Fatalf("expected creating a shipment without the admin token to be forbidden, got %v", err)
+    }
+
+    var first struct{ CreateShipment shipment }
+    c.MustPost(
+        `mutation($o: String!, $lines: [ShipmentLineInput!]) {
+            createShipment(orderId: $o, carrier: "ups", trackingNumber: "1Z1", lines: $lines) `+fields+`
+        }`,
+        &first,
+        client.Var("o", orderID),
+        client.Var("lines", []map[string]any{{"productId": mug, "quantity": 1}}),
+        asAdmin,
+    )
+    var second struct{ CreateShipment shipment }
+    c.MustPost(
+        `mutation($o: String!) { createShipment(orderId: $o, carrier: "dhl") `+fields+` }`,
+        &second,
+        client.Var("o", orderID),
+        asAdmin,
+    )
+    if s := first.CreateShipment; s.Status != "PENDING" || s.ShippedAt != nil || len(s.Lines) != 1 {
+        t.Fatalf("unexpected first shipment %+v", s)
+    }
+    if s := second.CreateShipment; len(s.Lines) != 2 {
+        t.Fatalf("expected the rest of the order in the second shipment, got %+v", s)
+    }
+
+    updateShipment := func(id, status string) shipment {
+        var resp struct{ UpdateShipment shipment }
+        c.MustPost(
+            `mutation($id: String!, $status: ShipmentStatus!) {
+                updateShipment(id: $id, status: $status) `+fields+`
+            }`,
+            &resp,
+            client.Var("id", id),
+            client.Var("status", status),
+            asAdmin,
+        )
+        return resp.UpdateShipment
+    }
+    orderStatus := func() string {
+        var resp struct {
+            Accounts []struct {
+                Orders struct {
+                    Orders []struct {
+                        Status    string
+                        Shipments []struct{ ID string }
+                    }
+                }
+            }
+        }
+        c.MustPost(
+            `query($id: String) { accounts(id: $id) { orders { orders { status shipments { id } } } } }`,
+            &resp,
+            client.Var("id", accountID),
+        )
+        o := resp.Accounts[0].Orders.Orders[0]
+        if len(o.Shipments) != 2 {
+            t.Fatalf("expected the order's shipments, got %+v", o.Shipments)
+        }
+        return o.Status
+    }
+
+    if s := updateShipment(first.CreateShipment.ID, "IN_TRANSIT"); s.ShippedAt == nil || s.TrackingNumber != "1Z1" {
+        t.Fatalf("unexpected shipped shipment %+v", s)
+    }
+    if got := orderStatus(); got != "PARTIALLY_SHIPPED" {
+        t.Fatalf("expected a partially shipped order, got %s", got)
+    }
+    updateShipment(second.CreateShipment.ID, "IN_TRANSIT")
+    if got := orderStatus(); got != "SHIPPED" {
+        t.Fatalf("expected a shipped order, got %s", got)
+    }
+    updateShipment(first.CreateShipment.ID, "DELIVERED")
+    if s := updateShipment(second.CreateShipment.ID, "DELIVERED"); s.DeliveredAt == nil {
+        t.Fatalf("unexpected delivered shipment %+v", s)
+    }
+    if got := orderStatus(); got != "DELIVERED" {
+        t.Fatalf("expected a delivered order, got %s", got)
+    }
+}
+
+func TestAddresses(t *testing.T) {
+    c := newTestClient(t)
+
+    accountID := createAccount(t, c, "juno")
+    mug := createProduct(t, c, "Mug", "ceramic", 9.5)
+
+    type address struct {
+        ID         string
+        City       string
+        PostalCode string
+        IsDefault  bool
+    }
+    const fields = `{ id city postalCode isDefault }`
+    create := func(in map[string]any) (address, error) {
+        var res struct{ CreateAddress address }
+        err := c.Post(
+            `mutation($a: String!, $in: AddressInput!) { createAddress(accountId: $a, address: $in) `+fields+` }`,
+            &res,
+            client.Var("a", accountID),
+            client.Var("in", in),
+        )
+        return res.CreateAddress, err
+    }
+
+    if _, err := create(map[string]any{
+        "name": "Juno", "line1": "1 Main St", "city": "Springfield", "country": "US", "postalCode": "12345",
+    }); err == nil || !strings.Contains(err.Error(), "region is required") {
+        t.Fatalf("expected a US address without a region to be rejected, got %v", err)
+    }
+    if _, err := create(map[string]any{
+        "name": "Juno", "line1": "1 Main St", "city": "Springfield", "country": "US", "region": "IL", "postalCode": "1234",
+    }); err == nil || !strings.Contains(err.Error(), "postal code") {
+        t.Fatalf("expected a malformed ZIP code to be rejected, got %v", err)
+    }
+
+    home, err := create(map[string]any{
+        "name": "Juno", "line1": "1 Main St", "city": "Springfield", "country": "us", "region": "IL", "postalCode": "62701",
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !home.IsDefault {
+        t.Fatalf("expected the first address to become the default, got %+v", home)
+    }
+    work, err := create(map[string]any{
+        "name": "Juno", "company": "Acme", "line1": "10 Downing St", "city": "London", "country": "GB",
+        "postalCode": "sw1a 2aa", "isDefault": true,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !work.IsDefault || work.PostalCode != "SW1A 2AA" {
+        t.Fatalf("unexpected second address %+v", work)
+    }
+
+    // Addresses created within a second have no defined order, so they are
+    // looked up by id.
+    addresses := func() map[string]address {
+        var res struct {
+            Accounts []struct{ Addresses []address }
+        }
+        c.MustPost(
+            `query($id: String!) { accounts(id: $id) { addresses `+fields+` } }`,
+            &res,
+            client.Var("id", accountID),
+        )
+        byID := map[string]address{}
+        for _, a := range res.Accounts[0].Addresses {
+            byID[a.ID] = a
+        }
+        return byID
+    }
+    if got := addresses(); len(got) != 2 || got[home.ID].IsDefault || !got[work.ID].IsDefault {
+        t.Fatalf("expected the second address to take over the default, got %+v", got)
+    }
+
+    var updated struct{ UpdateAddress address }
+    c.MustPost(
+        `mutation($a: String!, $id: String!) {
+            updateAddress(accountId: $a, id: $id, address: {
+                name: "Juno", line1: "2 Main St", city: "Shelbyville", country: "US", region: "IL", postalCode: "62565"
+            }) `+fields+`
+        }`,
+        &updated,
+        client.Var("a", accountID),
+        client.Var("id", home.ID),
+    )
+    if updated.UpdateAddress.City != "Shelbyville" || updated.UpdateAddress.IsDefault {
+        t.Fatalf("unexpected updated address %+v", updated.UpdateAddress)
+    }
+
+    type orderAddress struct {
+        City    string
+        Country string
+    }
+    type placedOrder struct {
+        CreateOrder struct {
+            ShippingAddress *orderAddress
+            BillingAddress  *orderAddress
+        }
+    }
+    // Null address choices are the same as omitted ones.
+    placeOrder := func(shipping, billing map[string]any) placedOrder {
+        var placed placedOrder
+        c.MustPost(
+            `mutation($a: String!, $p: [OrderProductInput!]!, $s: OrderAddressInput, $b: OrderAddressInput) {
+                createOrder(order: {accountId: $a, products: $p, shippingAddress: $s, billingAddress: $b}) {
+                    shippingAddress { city country }
+                    billingAddress { city country }
+                }
+            }`,
+            &placed,
+            client.Var("a", accountID),
+            client.Var("p", []map[string]any{{"id": mug, "quantity": 1}}),
+            client.Var("s", shipping),
+            client.Var("b", billing),
+        )
+        return placed
+    }
+
+    // Without addresses the order ships to and bills the default address.
+    placed := placeOrder(nil, nil)
+    if s, b := placed.CreateOrder.ShippingAddress, placed.CreateOrder.BillingAddress; s == nil || s.City != "London" || b == nil || b.City != "London" {
+        t.Fatalf("expected the default address on the order, got %+v", placed.CreateOrder)
+    }
+
+    placed = placeOrder(
+        map[string]any{"addressId": home.ID},
+        map[string]any{"address": map[string]any{
+            "name": "Juno", "line1": "5 Rue de Rivoli", "city": "Paris", "country": "FR", "postalCode": "75001",
+        }},
+    )
+    if s, b := placed.CreateOrder.ShippingAddress, placed.CreateOrder.BillingAddress; s.City != "Shelbyville" || b.City != "Paris" || b.Country != "FR" {
+        t.Fatalf("expected the chosen addresses on the order, got %+v", placed.CreateOrder)
+    }
+
+    // Orders keep their copies when the address book changes.
+    var deleted struct{ DeleteAddress bool }
+    c.MustPost(
+        `mutation($a: String!, $id: String!) { deleteAddress(accountId: $a, id: $id) }`,
+        &deleted,
+        client.Var("a", accountID),
+        client.Var("id", work.ID),
+    )
+    if got := addresses(); len(got) != 1 || !got[home.ID].IsDefault {
+        t.Fatalf("expected the remaining address to become the default, got %+v", got)
+    }
+    var history struct {
+        Accounts []struct {
+            Orders struct{ Orders []struct{ ShippingAddress *orderAddress } }
+        }
+    }
+    c.MustPost(
+        `query($id: String!) { accounts(id: $id) { orders { orders { shippingAddress { city country } } } } }`,
+        &history,
+        client.Var("id", accountID),
+    )
+    cities := []string{}
+    for _, o := range history.Accounts[0].Orders.Orders {
+        cities = append(cities, o.ShippingAddress.City)
+    }
+    if !slices.Contains(cities, "London") || !slices.Contains(cities, "Shelbyville") {
+        t.Fatalf("expected the orders to keep their addresses, got %v", cities)
+    }
+
+    err = c.Post(
+        `mutation($a: String!, $p: [OrderProductInput!]!, $s: OrderAddressInput) {
+            createOrder(order: {accountId: $a, products: $p, shippingAddress: $s}) { id }
+        }`,
+        &struct{}{},
+        client.Var("a", accountID),
+        client.Var("p", []map[string]any{{"id": mug, "quantity": 1}}),
+        client.Var("s", map[string]any{"addressId": work.ID}),
+    )
+    if err == nil || !strings.Contains(err.Error(), "address not found") {
+        t.Fatalf("expected ordering to a deleted address to fail, got %v", err)
+    }
+}
+
+func TestInvoices(t *testing.T) {
+    s := newTestServer(t)
+    c := client.New(withRoles(handler.NewDefaultServer(s.ToExecutableSchema()), testAdminToken))
+    mux := http.NewServeMux()
+    mux.Handle("GET /invoices/{orderId}", withRoles(s.invoiceHandler(), testAdminToken))
+
+    accountID := createAccount(t, c, "kai")
+    mug := createProduct(t, c, "Mug", "ceramic", 9.5)
+
+    setTaxRate := func(rate float64) {
+        var set struct {
+            SetTaxRate struct{ Country string }
+        }
+        c.MustPost(
+            `mutation($rate: Float!) { setTaxRate(country: "de", rate: $rate) { country } }`,
+            &set,
+            client.Var("rate", rate),
+            asAdmin,
+        )
+        if set.SetTaxRate.Country != "DE" {
+            t.Fatalf("expected the rate to be set for DE, got %q", set.SetTaxRate.Country)
+        }
+    }
+    setTaxRate(0.19)
+
+    placeOrder := func() (id, invoiceNumber string) {
+        var placed struct {
+            CreateOrder struct {
+                ID            string
+                InvoiceNumber string
+            }
+        }
+        c.MustPost(
+            `mutation($a: String!, $p: [OrderProductInput!]!, $b: OrderAddressInput) {
+                createOrder(order: {accountId: $a, products: $p, billingAddress: $b}) { id invoiceNumber }
+            }`,
+            &placed,
+            client.Var("a", accountID),
+            client.Var("p", []map[string]any{{"id": mug, "quantity": 2}}),
+            client.Var("b", map[string]any{"address": map[string]any{
+                "name": "Kai", "line1": "1 Hauptstr.", "city": "Berlin", "country": "DE", "postalCode": "10115",
+            }}),
+        )
+        return placed.CreateOrder.ID, placed.CreateOrder.InvoiceNumber
+    }
+    firstID, first := placeOrder()
+    _, second := placeOrder()
+    year := first[:strings.Index(first, "-")]
+    if first != year+"-000001" || second != year+"-000002" {
+        t.Fatalf("expected sequential invoice numbers, got %q and %q", first, second)
+    }
+    // Issued invoices keep the rate their order was placed with.
+    setTaxRate(0.16)
+
+    download := func(path string, admin bool) *httptest.ResponseRecorder {
+        req := httptest.NewRequest(http.MethodGet, path, nil)
+        if admin {
+            req.Header.Set("Authorization", "Bearer "+testAdminToken)
+        }
+        rec := httptest.NewRecorder()
+        mux.ServeHTTP(rec, req)
+        return rec
+    }
+
+    if rec := download("/invoices/"+firstID, false); rec.Code != http.StatusForbidden {
+        t.Fatalf("expected downloading an invoice without the admin token to be forbidden, got %d", rec.Code)
+    }
+
+    rec := download("/invoices/"+firstID, true)
+    if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+        t.Fatalf("unexpected response %d %q", rec.Code, rec.Header().Get("Content-Type"))
+    }
+    if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="invoice-`+first+`.html"` {
+        t.Fatalf("unexpected content disposition %q", got)
+    }
+    for _, want := range []string{"Invoice " + first, "<td>Mug</td>", "Tax (19%)", "3.03", "19.00"} {
+        if !strings.Contains(rec.Body.String(), want) {
+            t.Fatalf("expected %q in the invoice:\n%s", want, rec.Body.String())
+        }
+    }
+
+    rec = download("/invoices/"+firstID+"?format=text", true)
+    if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "INVOICE "+first) {
+        t.Fatalf("unexpected text invoice %d:\n%s", rec.Code, rec.Body.String())
+    }
+    if rec := download("/invoices/"+firstID+"?format=pdf", true); rec.Code != http.StatusBadRequest {
+        t.Fatalf("expected an unknown format to be rejected, got %d", rec.Code)
+    }
+    if rec := download("/invoices/unknown", true); rec.Code != http.StatusNotFound {
+        t.Fatalf("expected an unknown order to have no invoice, got %d", rec.Code)
+    }
+}
@@ -5,15 +5,17 @@ import (
 	"github.com/pirateunclejack/go-grpc-graphql-microservice/account"
 	"github.com/pirateunclejack/go-grpc-graphql-microservice/catalog"
 	"github.com/pirateunclejack/go-grpc-graphql-microservice/order"
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/review"
 )
 
 type Server struct {
     accountClient *account.Client
     catalogClient *catalog.Client
     orderClient   *order.Client
+    reviewClient  *review.Client
 }
 
-func NewGraphQLServer(accountUrl, catalogUrl, orderUrl string) (*Server, error) {
+func NewGraphQLServer(accountUrl, catalogUrl, orderUrl, reviewUrl string) (*Server, error) {
     accountClient, err := account.NewClient(accountUrl)
     if err!= nil {
         return nil, err
@@ -32,10 +34,19 @@ func NewGraphQLServer(accountUrl, catalogUrl, orderUrl string) (*Server, error)
         return nil, err
     }
 
+    reviewClient, err := review.NewClient(reviewUrl)
+    if err!= nil {
+        accountClient.Close()
+        catalogClient.Close()
+        orderClient.Close()
+        return nil, err
+    }
+
     return &Server {
         accountClient: accountClient,
         catalogClient: catalogClient,
         orderClient: orderClient,
+        reviewClient: reviewClient,
     }, nil
 
 }
@@ -52,10 +63,19 @@ func (s *Server) Account() AccountResolver {
     return &accountResolver{server: s}
 }
 
+func (s *Server) Product() ProductResolver {
+    return &productResolver{server: s}
+}
+
+func (s *Server) Category() CategoryResolver {
+    return &categoryResolver{server: s}
+}
+
 func (s *Server) ToExecutableSchema() graphql.ExecutableSchema {
     return NewExecutableSchema(
         Config{
             Resolvers: s,
+            Directives: DirectiveRoot{HasRole: hasRole},
         },
     )
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/order"
+)
+
+// invoiceHandler serves GET /invoices/{orderId}: the order's invoice as an
+// HTML download, or as plain text with ?format=text. Only admins may
+// download invoices.
+func (s *Server) invoiceHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if role, _ := r.Context().Value(roleKey{}).(Role); role != RoleAdmin {
+            http.Error(w, ErrForbidden.Error(), http.StatusForbidden)
+            return
+        }
+
+        format, extension := order.InvoiceHTML, "html"
+        switch r.URL.Query().Get("format") {
+        case "", "html":
+        case "text":
+            format, extension = order.InvoiceText, "txt"
+        default:
+            http.Error(w, ErrInvalidParameter.Error(), http.StatusBadRequest)
+            return
+        }
+
+        ctx, cancel := context.WithTimeout(r.Context(), 3 * time.Second)
+        defer cancel()
+
+        invoice, err := s.orderClient.GetInvoice(ctx, r.PathValue("orderId"), format)
+        if err != nil {
+            log.Println("failed to get invoice from graphql: ", err)
+            // The order service's errors arrive as text.
+            if strings.Contains(err.Error(), order.ErrOrderNotFound.Error()) ||
+                strings.Contains(err.Error(), order.ErrInvoiceNotFound.Error()) {
+                http.Error(w, order.ErrInvoiceNotFound.Error(), http.StatusNotFound)
+                return
+            }
+            http.Error(w, "could not get invoice", http.StatusBadGateway)
+            return
+        }
+
+        w.Header().Set("Content-Type", invoice.ContentType)
+        w.Header().Set(
+            "Content-Disposition",
+            fmt.Sprintf(`attachment; filename="invoice-%s.%s"`, invoice.Number, extension),
+        )
+        w.Write(invoice.Document)
+    })
+}
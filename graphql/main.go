@@ -14,6 +14,10 @@ type AppConfig struct {
     AccountURL string `envconfig:"ACCOUNT_SERVICE_URL"`
     CatalogURL string `envconfig:"CATALOG_SERVICE_URL"`
     OrderURL   string `envconfig:"ORDER_SERVICE_URL"`
+    ReviewURL  string `envconfig:"REVIEW_SERVICE_URL"`
+    // AdminToken is the bearer token of admin requests; without it no
+    // request is an admin.
+    AdminToken string `envconfig:"ADMIN_TOKEN"`
 }
 
 func main() {
@@ -24,7 +28,7 @@ func main() {
     }
 
     s, err := NewGraphQLServer(
-        cfg.AccountURL, cfg.CatalogURL, cfg.OrderURL,
+        cfg.AccountURL, cfg.CatalogURL, cfg.OrderURL, cfg.ReviewURL,
     )
     if err != nil {
         log.Fatal("failed to create graphql server from graphql: ", err)
@@ -33,7 +37,12 @@ func main() {
     http.Handle(
         "/graphql",
         // handler.New(s.ToExecutableSchema()),
-        handler.GraphQL(s.ToExecutableSchema()),
+        withRoles(handler.GraphQL(s.ToExecutableSchema()), cfg.AdminToken),
+    )
+
+    http.Handle(
+        "GET /invoices/{orderId}",
+        withRoles(s.invoiceHandler(), cfg.AdminToken),
     )
 
     http.Handle(
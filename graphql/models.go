@@ -5,3 +5,24 @@ type Account struct {
     Name    string  `json:"name"`
     Orders  []Order `json:"orders"`
 }
+
+type Product struct {
+    ID          string            `json:"id"`
+    Name        string            `json:"name"`
+    Description string            `json:"description"`
+    Price       float64           `json:"price"`
+    Tags        []string          `json:"tags"`
+    CategoryIDs []string          `json:"categoryIds"`
+    Variants    []*ProductVariant `json:"variants"`
+    // Rating is nil until the product has a published review.
+    Rating      *float64          `json:"rating"`
+    ReviewCount int               `json:"reviewCount"`
+}
+
+type Category struct {
+    ID       string  `json:"id"`
+    Name     string  `json:"name"`
+    Slug     string  `json:"slug"`
+    Path     string  `json:"path"`
+    ParentID *string `json:"parentId"`
+}
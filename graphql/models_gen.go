@@ -3,6 +3,9 @@
 package main
 
 import (
+	"fmt"
+	"io"
+	"strconv"
 	"time"
 )
 
@@ -10,32 +13,134 @@ type AccountInput struct {
 	Name string `json:"name"`
 }
 
+type Address struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Company    string `json:"company"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	City       string `json:"city"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postalCode"`
+	Country    string `json:"country"`
+	Phone      string `json:"phone"`
+	IsDefault  bool   `json:"isDefault"`
+}
+
+type AddressInput struct {
+	Name       string  `json:"name"`
+	Company    *string `json:"company,omitempty"`
+	Line1      string  `json:"line1"`
+	Line2      *string `json:"line2,omitempty"`
+	City       string  `json:"city"`
+	Region     *string `json:"region,omitempty"`
+	PostalCode *string `json:"postalCode,omitempty"`
+	Country    string  `json:"country"`
+	Phone      *string `json:"phone,omitempty"`
+	IsDefault  *bool   `json:"isDefault,omitempty"`
+}
+
+type CancelOrderLineInput struct {
+	ProductID string  `json:"productId"`
+	VariantID *string `json:"variantId,omitempty"`
+	Quantity  int     `json:"quantity"`
+}
+
+type CategoryInput struct {
+	Name     string  `json:"name"`
+	Slug     *string `json:"slug,omitempty"`
+	ParentID *string `json:"parentId,omitempty"`
+}
+
 type Mutation struct {
 }
 
 type Order struct {
-	ID         string            `json:"id"`
-	CreatedAt  time.Time         `json:"createdAt"`
-	TotalPrice float64           `json:"totalPrice"`
-	Products   []*OrderedProduct `json:"products"`
+	ID              string            `json:"id"`
+	AccountID       string            `json:"accountId"`
+	CreatedAt       time.Time         `json:"createdAt"`
+	UpdatedAt       time.Time         `json:"updatedAt"`
+	TotalPrice      float64           `json:"totalPrice"`
+	Status          OrderStatus       `json:"status"`
+	Products        []*OrderedProduct `json:"products"`
+	RefundedTotal   float64           `json:"refundedTotal"`
+	Refunds         []*Refund         `json:"refunds"`
+	Returns         []*Return         `json:"returns"`
+	Shipments       []*Shipment       `json:"shipments"`
+	ShippingAddress *OrderAddress     `json:"shippingAddress,omitempty"`
+	BillingAddress  *OrderAddress     `json:"billingAddress,omitempty"`
+	InvoiceNumber   *string           `json:"invoiceNumber,omitempty"`
+}
+
+type OrderAddress struct {
+	Name       string `json:"name"`
+	Company    string `json:"company"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	City       string `json:"city"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postalCode"`
+	Country    string `json:"country"`
+	Phone      string `json:"phone"`
+}
+
+type OrderAddressInput struct {
+	AddressID *string       `json:"addressId,omitempty"`
+	Address   *AddressInput `json:"address,omitempty"`
+}
+
+type OrderConnection struct {
+	Orders   []*Order  `json:"orders"`
+	PageInfo *PageInfo `json:"pageInfo"`
+}
+
+type OrderFilterInput struct {
+	CreatedFrom *time.Time    `json:"createdFrom,omitempty"`
+	CreatedTo   *time.Time    `json:"createdTo,omitempty"`
+	Statuses    []OrderStatus `json:"statuses,omitempty"`
+	MinTotal    *float64      `json:"minTotal,omitempty"`
+	MaxTotal    *float64      `json:"maxTotal,omitempty"`
+	ProductID   *string       `json:"productId,omitempty"`
 }
 
 type OrderInput struct {
-	AccountID string               `json:"accountId"`
-	Products  []*OrderProductInput `json:"products"`
+	AccountID       string               `json:"accountId"`
+	Products        []*OrderProductInput `json:"products"`
+	ShippingAddress *OrderAddressInput   `json:"shippingAddress,omitempty"`
+	BillingAddress  *OrderAddressInput   `json:"billingAddress,omitempty"`
 }
 
 type OrderProductInput struct {
-	ID       string `json:"id"`
-	Quantity int    `json:"quantity"`
+	ID        string  `json:"id"`
+	VariantID *string `json:"variantId,omitempty"`
+	Quantity  int     `json:"quantity"`
+}
+
+type OrderSearchInput struct {
+	AccountID   *string       `json:"accountId,omitempty"`
+	AccountName *string       `json:"accountName,omitempty"`
+	CreatedFrom *time.Time    `json:"createdFrom,omitempty"`
+	CreatedTo   *time.Time    `json:"createdTo,omitempty"`
+	Statuses    []OrderStatus `json:"statuses,omitempty"`
+	MinTotal    *float64      `json:"minTotal,omitempty"`
+	MaxTotal    *float64      `json:"maxTotal,omitempty"`
+	ProductID   *string       `json:"productId,omitempty"`
 }
 
 type OrderedProduct struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Quantity    int     `json:"quantity"`
+	ID                string  `json:"id"`
+	VariantID         *string `json:"variantId,omitempty"`
+	Sku               *string `json:"sku,omitempty"`
+	Name              string  `json:"name"`
+	Description       string  `json:"description"`
+	Price             float64 `json:"price"`
+	Quantity          int     `json:"quantity"`
+	CancelledQuantity int     `json:"cancelledQuantity"`
+}
+
+type PageInfo struct {
+	HasMore   bool    `json:"hasMore"`
+	EndCursor *string `json:"endCursor,omitempty"`
 }
 
 type PaginationInput struct {
@@ -43,18 +148,605 @@ type PaginationInput struct {
 	Take *int `json:"take,omitempty"`
 }
 
-type Product struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
+type PriceBucket struct {
+	From  *float64 `json:"from,omitempty"`
+	To    *float64 `json:"to,omitempty"`
+	Count int      `json:"count"`
+}
+
+type ProductFacets struct {
+	Prices []*PriceBucket `json:"prices"`
+	Tags   []*TagFacet    `json:"tags"`
+}
+
+type ProductHighlight struct {
+	ProductID string   `json:"productId"`
+	Field     string   `json:"field"`
+	Fragments []string `json:"fragments"`
 }
 
 type ProductInput struct {
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Price       float64         `json:"price"`
+	Tags        []string        `json:"tags,omitempty"`
+	CategoryIds []string        `json:"categoryIds,omitempty"`
+	Variants    []*VariantInput `json:"variants,omitempty"`
+}
+
+type ProductSearchInput struct {
+	Query       *string      `json:"query,omitempty"`
+	MinPrice    *float64     `json:"minPrice,omitempty"`
+	MaxPrice    *float64     `json:"maxPrice,omitempty"`
+	Tags        []string     `json:"tags,omitempty"`
+	CategoryIds []string     `json:"categoryIds,omitempty"`
+	Fuzziness   *int         `json:"fuzziness,omitempty"`
+	MinRating   *float64     `json:"minRating,omitempty"`
+	Sort        *ProductSort `json:"sort,omitempty"`
+}
+
+type ProductSearchResult struct {
+	Products   []*Product          `json:"products"`
+	Total      int                 `json:"total"`
+	Facets     *ProductFacets      `json:"facets"`
+	Highlights []*ProductHighlight `json:"highlights"`
+	DidYouMean *string             `json:"didYouMean,omitempty"`
+	SearchID   string              `json:"searchId"`
+}
+
+type ProductSuggestion struct {
+	ProductID   string `json:"productId"`
+	Name        string `json:"name"`
+	Highlighted string `json:"highlighted"`
+}
+
+type ProductVariant struct {
+	ID      string           `json:"id"`
+	Sku     string           `json:"sku"`
+	Price   float64          `json:"price"`
+	Options []*VariantOption `json:"options"`
+	Stock   int              `json:"stock"`
 }
 
 type Query struct {
 }
+
+type Refund struct {
+	ID        string        `json:"id"`
+	ReturnID  *string       `json:"returnId,omitempty"`
+	Reason    CancelReason  `json:"reason"`
+	Amount    float64       `json:"amount"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Lines     []*RefundLine `json:"lines"`
+}
+
+type RefundLine struct {
+	ProductID string  `json:"productId"`
+	VariantID *string `json:"variantId,omitempty"`
+	Quantity  int     `json:"quantity"`
+	Amount    float64 `json:"amount"`
+}
+
+type Return struct {
+	ID                string         `json:"id"`
+	OrderID           string         `json:"orderId"`
+	ProductID         string         `json:"productId"`
+	VariantID         *string        `json:"variantId,omitempty"`
+	Quantity          int            `json:"quantity"`
+	Reason            string         `json:"reason"`
+	Status            ReturnStatus   `json:"status"`
+	RestockedQuantity int            `json:"restockedQuantity"`
+	RefundAmount      float64        `json:"refundAmount"`
+	CreatedAt         time.Time      `json:"createdAt"`
+	UpdatedAt         time.Time      `json:"updatedAt"`
+	History           []*ReturnEvent `json:"history"`
+}
+
+type ReturnEvent struct {
+	Status    ReturnStatus `json:"status"`
+	Note      string       `json:"note"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+type ReturnInput struct {
+	AccountID string  `json:"accountId"`
+	OrderID   string  `json:"orderId"`
+	ProductID string  `json:"productId"`
+	VariantID *string `json:"variantId,omitempty"`
+	Quantity  int     `json:"quantity"`
+	Reason    *string `json:"reason,omitempty"`
+}
+
+type Review struct {
+	ID           string       `json:"id"`
+	ProductID    string       `json:"productId"`
+	AccountID    string       `json:"accountId"`
+	Rating       int          `json:"rating"`
+	Title        string       `json:"title"`
+	Text         string       `json:"text"`
+	Status       ReviewStatus `json:"status"`
+	HelpfulVotes int          `json:"helpfulVotes"`
+	CreatedAt    time.Time    `json:"createdAt"`
+}
+
+type ReviewInput struct {
+	ProductID string  `json:"productId"`
+	AccountID string  `json:"accountId"`
+	Rating    int     `json:"rating"`
+	Title     *string `json:"title,omitempty"`
+	Text      string  `json:"text"`
+}
+
+type SearchClickThrough struct {
+	Searches            int     `json:"searches"`
+	ClickedSearches     int     `json:"clickedSearches"`
+	AddedToCartSearches int     `json:"addedToCartSearches"`
+	Rate                float64 `json:"rate"`
+}
+
+type SearchQueryCount struct {
+	Query    string `json:"query"`
+	Searches int    `json:"searches"`
+}
+
+type Shipment struct {
+	ID             string          `json:"id"`
+	OrderID        string          `json:"orderId"`
+	Carrier        string          `json:"carrier"`
+	TrackingNumber string          `json:"trackingNumber"`
+	Status         ShipmentStatus  `json:"status"`
+	Lines          []*ShipmentLine `json:"lines"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+	ShippedAt      *time.Time      `json:"shippedAt,omitempty"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+}
+
+type ShipmentLine struct {
+	ProductID string  `json:"productId"`
+	VariantID *string `json:"variantId,omitempty"`
+	Quantity  int     `json:"quantity"`
+}
+
+type ShipmentLineInput struct {
+	ProductID string  `json:"productId"`
+	VariantID *string `json:"variantId,omitempty"`
+	Quantity  int     `json:"quantity"`
+}
+
+type SynonymRule struct {
+	ID    string   `json:"id"`
+	Terms []string `json:"terms"`
+}
+
+type TagFacet struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+type TaxRate struct {
+	Country       string    `json:"country"`
+	Rate          float64   `json:"rate"`
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+}
+
+type VariantInput struct {
+	ID      *string               `json:"id,omitempty"`
+	Sku     string                `json:"sku"`
+	Price   *float64              `json:"price,omitempty"`
+	Options []*VariantOptionInput `json:"options,omitempty"`
+	Stock   *int                  `json:"stock,omitempty"`
+}
+
+type VariantOption struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type VariantOptionInput struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type CancelReason string
+
+const (
+	CancelReasonCustomerRequest CancelReason = "CUSTOMER_REQUEST"
+	CancelReasonOutOfStock      CancelReason = "OUT_OF_STOCK"
+	CancelReasonPaymentFailed   CancelReason = "PAYMENT_FAILED"
+	CancelReasonFraud           CancelReason = "FRAUD"
+	CancelReasonOther           CancelReason = "OTHER"
+	CancelReasonReturn          CancelReason = "RETURN"
+)
+
+var AllCancelReason = []CancelReason{
+	CancelReasonCustomerRequest,
+	CancelReasonOutOfStock,
+	CancelReasonPaymentFailed,
+	CancelReasonFraud,
+	CancelReasonOther,
+	CancelReasonReturn,
+}
+
+func (e CancelReason) IsValid() bool {
+	switch e {
+	case CancelReasonCustomerRequest, CancelReasonOutOfStock, CancelReasonPaymentFailed, CancelReasonFraud, CancelReasonOther, CancelReasonReturn:
+		return true
+	}
+	return false
+}
+
+func (e CancelReason) String() string {
+	return string(e)
+}
+
+func (e *CancelReason) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = CancelReason(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid CancelReason", str)
+	}
+	return nil
+}
+
+func (e CancelReason) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type OrderSort string
+
+const (
+	OrderSortOldest OrderSort = "OLDEST"
+	OrderSortNewest OrderSort = "NEWEST"
+)
+
+var AllOrderSort = []OrderSort{
+	OrderSortOldest,
+	OrderSortNewest,
+}
+
+func (e OrderSort) IsValid() bool {
+	switch e {
+	case OrderSortOldest, OrderSortNewest:
+		return true
+	}
+	return false
+}
+
+func (e OrderSort) String() string {
+	return string(e)
+}
+
+func (e *OrderSort) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OrderSort(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OrderSort", str)
+	}
+	return nil
+}
+
+func (e OrderSort) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type OrderStatus string
+
+const (
+	OrderStatusPlaced           OrderStatus = "PLACED"
+	OrderStatusCancelled        OrderStatus = "CANCELLED"
+	OrderStatusPartiallyShipped OrderStatus = "PARTIALLY_SHIPPED"
+	OrderStatusShipped          OrderStatus = "SHIPPED"
+	OrderStatusDelivered        OrderStatus = "DELIVERED"
+)
+
+var AllOrderStatus = []OrderStatus{
+	OrderStatusPlaced,
+	OrderStatusCancelled,
+	OrderStatusPartiallyShipped,
+	OrderStatusShipped,
+	OrderStatusDelivered,
+}
+
+func (e OrderStatus) IsValid() bool {
+	switch e {
+	case OrderStatusPlaced, OrderStatusCancelled, OrderStatusPartiallyShipped, OrderStatusShipped, OrderStatusDelivered:
+		return true
+	}
+	return false
+}
+
+func (e OrderStatus) String() string {
+	return string(e)
+}
+
+func (e *OrderStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OrderStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OrderStatus", str)
+	}
+	return nil
+}
+
+func (e OrderStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ProductSort string
+
+const (
+	ProductSortRelevance  ProductSort = "RELEVANCE"
+	ProductSortPriceAsc   ProductSort = "PRICE_ASC"
+	ProductSortPriceDesc  ProductSort = "PRICE_DESC"
+	ProductSortNameAsc    ProductSort = "NAME_ASC"
+	ProductSortNameDesc   ProductSort = "NAME_DESC"
+	ProductSortNewest     ProductSort = "NEWEST"
+	ProductSortRatingDesc ProductSort = "RATING_DESC"
+)
+
+var AllProductSort = []ProductSort{
+	ProductSortRelevance,
+	ProductSortPriceAsc,
+	ProductSortPriceDesc,
+	ProductSortNameAsc,
+	ProductSortNameDesc,
+	ProductSortNewest,
+	ProductSortRatingDesc,
+}
+
+func (e ProductSort) IsValid() bool {
+	switch e {
+	case ProductSortRelevance, ProductSortPriceAsc, ProductSortPriceDesc, ProductSortNameAsc, ProductSortNameDesc, ProductSortNewest, ProductSortRatingDesc:
+		return true
+	}
+	return false
+}
+
+func (e ProductSort) String() string {
+	return string(e)
+}
+
+func (e *ProductSort) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ProductSort(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ProductSort", str)
+	}
+	return nil
+}
+
+func (e ProductSort) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ReturnStatus string
+
+const (
+	ReturnStatusRequested ReturnStatus = "REQUESTED"
+	ReturnStatusApproved  ReturnStatus = "APPROVED"
+	ReturnStatusRejected  ReturnStatus = "REJECTED"
+	ReturnStatusReceived  ReturnStatus = "RECEIVED"
+	ReturnStatusInspected ReturnStatus = "INSPECTED"
+)
+
+var AllReturnStatus = []ReturnStatus{
+	ReturnStatusRequested,
+	ReturnStatusApproved,
+	ReturnStatusRejected,
+	ReturnStatusReceived,
+	ReturnStatusInspected,
+}
+
+func (e ReturnStatus) IsValid() bool {
+	switch e {
+	case ReturnStatusRequested, ReturnStatusApproved, ReturnStatusRejected, ReturnStatusReceived, ReturnStatusInspected:
+		return true
+	}
+	return false
+}
+
+func (e ReturnStatus) String() string {
+	return string(e)
+}
+
+func (e *ReturnStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReturnStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReturnStatus", str)
+	}
+	return nil
+}
+
+func (e ReturnStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ReviewStatus string
+
+const (
+	ReviewStatusPublished ReviewStatus = "PUBLISHED"
+	ReviewStatusRejected  ReviewStatus = "REJECTED"
+)
+
+var AllReviewStatus = []ReviewStatus{
+	ReviewStatusPublished,
+	ReviewStatusRejected,
+}
+
+func (e ReviewStatus) IsValid() bool {
+	switch e {
+	case ReviewStatusPublished, ReviewStatusRejected:
+		return true
+	}
+	return false
+}
+
+func (e ReviewStatus) String() string {
+	return string(e)
+}
+
+func (e *ReviewStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReviewStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReviewStatus", str)
+	}
+	return nil
+}
+
+func (e ReviewStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type Role string
+
+const (
+	RoleAdmin Role = "ADMIN"
+)
+
+var AllRole = []Role{
+	RoleAdmin,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SearchFeedbackKind string
+
+const (
+	SearchFeedbackKindClick     SearchFeedbackKind = "CLICK"
+	SearchFeedbackKindAddToCart SearchFeedbackKind = "ADD_TO_CART"
+)
+
+var AllSearchFeedbackKind = []SearchFeedbackKind{
+	SearchFeedbackKindClick,
+	SearchFeedbackKindAddToCart,
+}
+
+func (e SearchFeedbackKind) IsValid() bool {
+	switch e {
+	case SearchFeedbackKindClick, SearchFeedbackKindAddToCart:
+		return true
+	}
+	return false
+}
+
+func (e SearchFeedbackKind) String() string {
+	return string(e)
+}
+
+func (e *SearchFeedbackKind) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SearchFeedbackKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SearchFeedbackKind", str)
+	}
+	return nil
+}
+
+func (e SearchFeedbackKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ShipmentStatus string
+
+const (
+	ShipmentStatusPending        ShipmentStatus = "PENDING"
+	ShipmentStatusInTransit      ShipmentStatus = "IN_TRANSIT"
+	ShipmentStatusOutForDelivery ShipmentStatus = "OUT_FOR_DELIVERY"
+	ShipmentStatusDelivered      ShipmentStatus = "DELIVERED"
+	ShipmentStatusException      ShipmentStatus = "EXCEPTION"
+)
+
+var AllShipmentStatus = []ShipmentStatus{
+	ShipmentStatusPending,
+	ShipmentStatusInTransit,
+	ShipmentStatusOutForDelivery,
+	ShipmentStatusDelivered,
+	ShipmentStatusException,
+}
+
+func (e ShipmentStatus) IsValid() bool {
+	switch e {
+	case ShipmentStatusPending, ShipmentStatusInTransit, ShipmentStatusOutForDelivery, ShipmentStatusDelivered, ShipmentStatusException:
+		return true
+	}
+	return false
+}
+
+func (e ShipmentStatus) String() string {
+	return string(e)
+}
+
+func (e *ShipmentStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ShipmentStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ShipmentStatus", str)
+	}
+	return nil
+}
+
+func (e ShipmentStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
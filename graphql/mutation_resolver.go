@@ -6,7 +6,10 @@ import (
 	"log"
 	"time"
 
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/account"
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/catalog"
 	"github.com/pirateunclejack/go-grpc-graphql-microservice/order"
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/review"
 )
 
 var (
@@ -35,14 +38,104 @@ func (r *mutationResolver) CreateAccount(
     }, nil
 }
 
+func (r *mutationResolver) CreateAddress(
+    ctx context.Context, accountID string, in AddressInput,
+) (*Address, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    a, err := r.server.accountClient.PostAddress(ctx, accountID, addressFromInput(in))
+    if err != nil {
+        log.Println("failed to post address from graphql: ", err)
+        return nil, err
+    }
+    return newAddress(*a), nil
+}
+
+func (r *mutationResolver) UpdateAddress(
+    ctx context.Context, accountID string, id string, in AddressInput,
+) (*Address, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    address := addressFromInput(in)
+    address.ID = id
+    a, err := r.server.accountClient.UpdateAddress(ctx, accountID, address)
+    if err != nil {
+        log.Println("failed to update address from graphql: ", err)
+        return nil, err
+    }
+    return newAddress(*a), nil
+}
+
+func (r *mutationResolver) DeleteAddress(
+    ctx context.Context, accountID string, id string,
+) (bool, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    if err := r.server.accountClient.DeleteAddress(ctx, accountID, id); err != nil {
+        log.Println("failed to delete address from graphql: ", err)
+        return false, err
+    }
+    return true, nil
+}
+
+func addressFromInput(in AddressInput) account.Address {
+    a := account.Address{
+        Name: in.Name,
+        Company: stringValue(in.Company),
+        Line1: in.Line1,
+        Line2: stringValue(in.Line2),
+        City: in.City,
+        Region: stringValue(in.Region),
+        PostalCode: stringValue(in.PostalCode),
+        Country: in.Country,
+        Phone: stringValue(in.Phone),
+    }
+    if in.IsDefault != nil {
+        a.IsDefault = *in.IsDefault
+    }
+    return a
+}
+
+// orderAddressChoice returns nil for a nil in, leaving the order service
+// to pick the default. Exactly one of addressId and address must be set.
+func orderAddressChoice(in *OrderAddressInput) (*order.AddressChoice, error) {
+    switch {
+    case in == nil:
+        return nil, nil
+    case (in.AddressID == nil) == (in.Address == nil):
+        return nil, ErrInvalidParameter
+    case in.AddressID != nil:
+        return &order.AddressChoice{AddressID: *in.AddressID}, nil
+    }
+    a := addressFromInput(*in.Address)
+    return &order.AddressChoice{Address: &order.Address{
+        Name: a.Name,
+        Company: a.Company,
+        Line1: a.Line1,
+        Line2: a.Line2,
+        City: a.City,
+        Region: a.Region,
+        PostalCode: a.PostalCode,
+        Country: a.Country,
+        Phone: a.Phone,
+    }}, nil
+}
+
 func (r *mutationResolver) CreateProduct(
     ctx context.Context, in *ProductInput,
 ) (*Product, error) {
     ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
     defer cancel()
 
+    variants, err := variantsFromInput(in.Variants)
+    if err != nil {
+        return nil, err
+    }
     p, err := r.server.catalogClient.PostProduct(
-        ctx, in.Name, in.Description, in.Price,
+        ctx, in.Name, in.Description, in.Price, in.Tags, in.CategoryIds, variants,
     )
 
     if err != nil {
@@ -50,12 +143,7 @@ func (r *mutationResolver) CreateProduct(
         return nil, err
     }
 
-    return &Product {
-        ID: p.ID,
-        Name: p.Name,
-        Description: p.Description,
-        Price: p.Price,
-    }, nil
+    return newProduct(*p), nil
 
 }
 
@@ -72,14 +160,27 @@ func (r *mutationResolver) CreateOrder(
             return nil, ErrInvalidParameter
         }
 
-        products = append(products, order.OrderedProduct {
+        product := order.OrderedProduct {
             ID: p.ID,
             Quantity: uint32(p.Quantity),
-        })
+        }
+        if p.VariantID != nil {
+            product.VariantID = *p.VariantID
+        }
+        products = append(products, product)
+    }
+
+    shipping, err := orderAddressChoice(in.ShippingAddress)
+    if err != nil {
+        return nil, err
+    }
+    billing, err := orderAddressChoice(in.BillingAddress)
+    if err != nil {
+        return nil, err
     }
 
     o, err := r.server.orderClient.PostOrder(
-        ctx, in.AccountID, products,
+        ctx, in.AccountID, products, shipping, billing,
     )
 
     if err != nil {
@@ -87,9 +188,433 @@ func (r *mutationResolver) CreateOrder(
         return nil, err
     }
 
-    return &Order{
-        ID: o.ID,
-        CreatedAt: o.CreatedAt,
-        TotalPrice: o.TotalPrice,
+    return newOrder(*o), nil
+}
+
+func (r *mutationResolver) CancelOrder(
+    ctx context.Context, id string, reason CancelReason, lines []*CancelOrderLineInput,
+) (*Order, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    cancelled := []order.OrderedProduct{}
+    for _, l := range lines {
+        if l.Quantity <= 0 {
+            return nil, ErrInvalidParameter
+        }
+        line := order.OrderedProduct{
+            ID: l.ProductID,
+            Quantity: uint32(l.Quantity),
+        }
+        if l.VariantID != nil {
+            line.VariantID = *l.VariantID
+        }
+        cancelled = append(cancelled, line)
+    }
+
+    var orderReason order.CancelReason
+    for r, gr := range cancelReasons {
+        if gr == reason {
+            orderReason = r
+        }
+    }
+
+    o, err := r.server.orderClient.CancelOrder(ctx, id, orderReason, cancelled)
+    if err != nil {
+        log.Println("failed to cancel order from graphql: ", err)
+        return nil, err
+    }
+    return newOrder(*o), nil
+}
+
+func (r *mutationResolver) CreateShipment(
+    ctx context.Context, orderID, carrier string, trackingNumber *string, lines []*ShipmentLineInput,
+) (*Shipment, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    shipped := []order.ShipmentLine{}
+    for _, l := range lines {
+        if l.Quantity <= 0 {
+            return nil, ErrInvalidParameter
+        }
+        shipped = append(shipped, order.ShipmentLine{
+            ProductID: l.ProductID,
+            VariantID: stringValue(l.VariantID),
+            Quantity: uint32(l.Quantity),
+        })
+    }
+
+    s, err := r.server.orderClient.CreateShipment(ctx, orderID, carrier, stringValue(trackingNumber), shipped)
+    if err != nil {
+        log.Println("failed to create shipment from graphql: ", err)
+        return nil, err
+    }
+    return newShipment(*s), nil
+}
+
+func (r *mutationResolver) UpdateShipment(
+    ctx context.Context, id string, status ShipmentStatus, carrier, trackingNumber *string,
+) (*Shipment, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    var orderStatus order.ShipmentStatus
+    for s, gs := range shipmentStatuses {
+        if gs == status {
+            orderStatus = s
+        }
+    }
+
+    s, err := r.server.orderClient.UpdateShipment(
+        ctx, id, orderStatus, stringValue(carrier), stringValue(trackingNumber),
+    )
+    if err != nil {
+        log.Println("failed to update shipment from graphql: ", err)
+        return nil, err
+    }
+    return newShipment(*s), nil
+}
+
+func (r *mutationResolver) RequestReturn(ctx context.Context, in ReturnInput) (*Return, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    if in.Quantity <= 0 {
+        return nil, ErrInvalidParameter
+    }
+    ret, err := r.server.orderClient.RequestReturn(
+        ctx, in.AccountID, in.OrderID, in.ProductID, stringValue(in.VariantID),
+        uint32(in.Quantity), stringValue(in.Reason),
+    )
+    if err != nil {
+        log.Println("failed to request return from graphql: ", err)
+        return nil, err
+    }
+    return newReturn(*ret), nil
+}
+
+func (r *mutationResolver) ReviewReturn(
+    ctx context.Context, id string, approve bool, note *string,
+) (*Return, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    ret, err := r.server.orderClient.ReviewReturn(ctx, id, approve, stringValue(note))
+    if err != nil {
+        log.Println("failed to review return from graphql: ", err)
+        return nil, err
+    }
+    return newReturn(*ret), nil
+}
+
+func (r *mutationResolver) ReceiveReturn(ctx context.Context, id string, note *string) (*Return, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    ret, err := r.server.orderClient.ReceiveReturn(ctx, id, stringValue(note))
+    if err != nil {
+        log.Println("failed to receive return from graphql: ", err)
+        return nil, err
+    }
+    return newReturn(*ret), nil
+}
+
+func (r *mutationResolver) InspectReturn(
+    ctx context.Context, id string, restockQuantity int, refundAmount *float64, note *string,
+) (*Return, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    if restockQuantity < 0 {
+        return nil, ErrInvalidParameter
+    }
+    ret, err := r.server.orderClient.InspectReturn(
+        ctx, id, uint32(restockQuantity), refundAmount, stringValue(note),
+    )
+    if err != nil {
+        log.Println("failed to inspect return from graphql: ", err)
+        return nil, err
+    }
+    return newReturn(*ret), nil
+}
+
+func (r *mutationResolver) SetTaxRate(
+    ctx context.Context, country string, rate float64, effectiveFrom *time.Time,
+) (*TaxRate, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    t := order.TaxRate{Country: country, Rate: rate}
+    if effectiveFrom != nil {
+        t.EffectiveFrom = *effectiveFrom
+    }
+    stored, err := r.server.orderClient.SetTaxRate(ctx, t)
+    if err != nil {
+        log.Println("failed to set tax rate from graphql: ", err)
+        return nil, err
+    }
+    return &TaxRate{
+        Country: stored.Country,
+        Rate: stored.Rate,
+        EffectiveFrom: stored.EffectiveFrom,
     }, nil
 }
+
+func (r *mutationResolver) CreateCategory(
+    ctx context.Context, in *CategoryInput,
+) (*Category, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    if in == nil {
+        return nil, ErrInvalidParameter
+    }
+    slug, parentID := in.fields()
+    c, err := r.server.catalogClient.PostCategory(ctx, in.Name, slug, parentID)
+    if err != nil {
+        log.Println("failed to post category from graphql: ", err)
+        return nil, err
+    }
+
+    return newCategory(*c), nil
+}
+
+func (r *mutationResolver) UpdateCategory(
+    ctx context.Context, id string, in *CategoryInput,
+) (*Category, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    if in == nil {
+        return nil, ErrInvalidParameter
+    }
+    slug, parentID := in.fields()
+    c, err := r.server.catalogClient.UpdateCategory(ctx, id, in.Name, slug, parentID)
+    if err != nil {
+        log.Println("failed to update category from graphql: ", err)
+        return nil, err
+    }
+
+    return newCategory(*c), nil
+}
+
+func (r *mutationResolver) DeleteCategory(
+    ctx context.Context, id string,
+) (bool, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    if err := r.server.catalogClient.DeleteCategory(ctx, id); err != nil {
+        log.Println("failed to delete category from graphql: ", err)
+        return false, err
+    }
+    return true, nil
+}
+
+func (r *mutationResolver) SetProductTaxonomy(
+    ctx context.Context, productID string, categoryIDs []string, tags []string,
+) (*Product, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    p, err := r.server.catalogClient.SetProductTaxonomy(ctx, productID, categoryIDs, tags)
+    if err != nil {
+        log.Println("failed to set product taxonomy from graphql: ", err)
+        return nil, err
+    }
+
+    return newProduct(*p), nil
+}
+
+func (r *mutationResolver) SetProductVariants(
+    ctx context.Context, productID string, variants []*VariantInput,
+) (*Product, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    in, err := variantsFromInput(variants)
+    if err != nil {
+        return nil, err
+    }
+    p, err := r.server.catalogClient.SetProductVariants(ctx, productID, in)
+    if err != nil {
+        log.Println("failed to set product variants from graphql: ", err)
+        return nil, err
+    }
+
+    return newProduct(*p), nil
+}
+
+func variantsFromInput(in []*VariantInput) ([]catalog.Variant, error) {
+    variants := []catalog.Variant{}
+    for _, v := range in {
+        if v.Stock != nil && *v.Stock < 0 {
+            return nil, ErrInvalidParameter
+        }
+        variant := catalog.Variant{
+            SKU: v.Sku,
+            Price: v.Price,
+            Options: []catalog.Option{},
+        }
+        if v.ID != nil {
+            variant.ID = *v.ID
+        }
+        if v.Stock != nil {
+            variant.Stock = uint32(*v.Stock)
+        }
+        for _, o := range v.Options {
+            variant.Options = append(variant.Options, catalog.Option{
+                Name: o.Name,
+                Value: o.Value,
+            })
+        }
+        variants = append(variants, variant)
+    }
+    return variants, nil
+}
+
+func (in CategoryInput) fields() (slug string, parentID string) {
+    if in.Slug != nil {
+        slug = *in.Slug
+    }
+    if in.ParentID != nil {
+        parentID = *in.ParentID
+    }
+    return slug, parentID
+}
+
+// PutSynonymRule takes effect on the next search, without a reindex.
+func (r *mutationResolver) PutSynonymRule(
+    ctx context.Context, id *string, terms []string,
+) (*SynonymRule, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    ruleID := ""
+    if id != nil {
+        ruleID = *id
+    }
+    rule, err := r.server.catalogClient.PutSynonymRule(ctx, ruleID, terms)
+    if err != nil {
+        log.Println("failed to put synonym rule from graphql: ", err)
+        return nil, err
+    }
+
+    return &SynonymRule{ID: rule.ID, Terms: rule.Terms}, nil
+}
+
+func (r *mutationResolver) DeleteSynonymRule(
+    ctx context.Context, id string,
+) (bool, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    if err := r.server.catalogClient.DeleteSynonymRule(ctx, id); err != nil {
+        log.Println("failed to delete synonym rule from graphql: ", err)
+        return false, err
+    }
+    return true, nil
+}
+
+func (r *mutationResolver) SetStopwords(
+    ctx context.Context, words []string,
+) ([]string, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    stopwords, err := r.server.catalogClient.SetStopwords(ctx, words)
+    if err != nil {
+        log.Println("failed to set stopwords from graphql: ", err)
+        return nil, err
+    }
+    return stopwords, nil
+}
+
+var searchFeedbackKinds = map[SearchFeedbackKind]catalog.FeedbackKind{
+    SearchFeedbackKindClick:     catalog.FeedbackClick,
+    SearchFeedbackKindAddToCart: catalog.FeedbackAddToCart,
+}
+
+// RecordSearchFeedback is sent by storefronts when a customer clicks or
+// adds to cart a product listed by the search with searchID.
+func (r *mutationResolver) RecordSearchFeedback(
+    ctx context.Context, searchID string, productID string, kind SearchFeedbackKind,
+) (bool, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    err := r.server.catalogClient.RecordSearchFeedback(
+        ctx, searchID, productID, searchFeedbackKinds[kind],
+    )
+    if err != nil {
+        log.Println("failed to record search feedback from graphql: ", err)
+        return false, err
+    }
+    return true, nil
+}
+
+// CreateReview is rejected unless the account has ordered the product.
+func (r *mutationResolver) CreateReview(
+    ctx context.Context, in ReviewInput,
+) (*Review, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    if in.Rating < 1 || in.Rating > 5 {
+        return nil, ErrInvalidParameter
+    }
+    title := ""
+    if in.Title != nil {
+        title = *in.Title
+    }
+
+    rv, err := r.server.reviewClient.PostReview(
+        ctx, in.ProductID, in.AccountID, uint32(in.Rating), title, in.Text,
+    )
+    if err != nil {
+        log.Println("failed to create review from graphql: ", err)
+        return nil, err
+    }
+    return newReview(*rv), nil
+}
+
+func (r *mutationResolver) ModerateReview(
+    ctx context.Context, id string, status ReviewStatus,
+) (*Review, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    s := review.StatusPublished
+    if status == ReviewStatusRejected {
+        s = review.StatusRejected
+    }
+    rv, err := r.server.reviewClient.ModerateReview(ctx, id, s)
+    if err != nil {
+        log.Println("failed to moderate review from graphql: ", err)
+        return nil, err
+    }
+    return newReview(*rv), nil
+}
+
+func (r *mutationResolver) VoteReviewHelpful(
+    ctx context.Context, id string, accountID string,
+) (*Review, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    rv, err := r.server.reviewClient.VoteReviewHelpful(ctx, id, accountID)
+    if err != nil {
+        log.Println("failed to vote review helpful from graphql: ", err)
+        return nil, err
+    }
+    return newReview(*rv), nil
+}
+
+// stringValue returns *s, or "" for nil.
+func stringValue(s *string) string {
+    if s == nil {
+        return ""
+    }
+    return *s
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/catalog"
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/review"
+)
+
+type productResolver struct {
+    server *Server
+}
+
+func (r *productResolver) Categories(
+    ctx context.Context, obj *Product,
+) ([]*Category, error) {
+    categories := []*Category{}
+    if len(obj.CategoryIDs) == 0 {
+        return categories, nil
+    }
+
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    categoryList, err := r.server.catalogClient.GetCategories(ctx, obj.CategoryIDs, nil)
+    if err != nil {
+        log.Println(err)
+        return nil, err
+    }
+
+    for _, c := range categoryList {
+        categories = append(categories, newCategory(c))
+    }
+    return categories, nil
+}
+
+// Reviews lists the product's reviews, most helpful first; moderators pass
+// includeRejected to see rejected ones too.
+func (r *productResolver) Reviews(
+    ctx context.Context, obj *Product, pagination *PaginationInput, includeRejected *bool,
+) ([]*Review, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    skip, take := uint64(0), uint64(0)
+    if pagination != nil {
+        skip, take = pagination.bounds()
+    }
+    reviewList, err := r.server.reviewClient.GetReviews(
+        ctx, obj.ID, includeRejected != nil && *includeRejected, skip, take,
+    )
+    if err != nil {
+        log.Println(err)
+        return nil, err
+    }
+
+    reviews := []*Review{}
+    for _, rv := range reviewList {
+        reviews = append(reviews, newReview(rv))
+    }
+    return reviews, nil
+}
+
+// RelatedProducts lists the products frequently bought together with the
+// product, filled up with similar ones.
+func (r *productResolver) RelatedProducts(
+    ctx context.Context, obj *Product, take *int,
+) ([]*Product, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    n := 0
+    if take != nil {
+        if *take < 0 {
+            return nil, ErrInvalidParameter
+        }
+        n = *take
+    }
+    related, err := r.server.orderClient.GetRelatedProducts(ctx, obj.ID, n)
+    if err != nil {
+        log.Println(err)
+        return nil, err
+    }
+
+    ids := []string{}
+    for _, p := range related {
+        ids = append(ids, p.ID)
+    }
+    // Products removed from the catalog since they were ordered are left
+    // out.
+    productList, _, err := r.server.catalogClient.GetProductsByIDs(ctx, ids)
+    if err != nil {
+        log.Println(err)
+        return nil, err
+    }
+
+    products := []*Product{}
+    for _, p := range productList {
+        products = append(products, newProduct(p))
+    }
+    return products, nil
+}
+
+func newProduct(p catalog.Product) *Product {
+    product := &Product{
+        ID: p.ID,
+        Name: p.Name,
+        Description: p.Description,
+        Price: p.Price,
+        Tags: p.Tags,
+        CategoryIDs: p.CategoryIDs,
+        Variants: []*ProductVariant{},
+        ReviewCount: int(p.ReviewCount),
+    }
+    if p.ReviewCount != 0 {
+        product.Rating = &p.Rating
+    }
+    for _, v := range p.Variants {
+        variant := &ProductVariant{
+            ID: v.ID,
+            Sku: v.SKU,
+            Price: p.VariantPrice(v),
+            Options: []*VariantOption{},
+            Stock: int(v.Stock),
+        }
+        for _, o := range v.Options {
+            variant.Options = append(variant.Options, &VariantOption{
+                Name: o.Name,
+                Value: o.Value,
+            })
+        }
+        product.Variants = append(product.Variants, variant)
+    }
+    return product
+}
+
+var reviewStatuses = map[review.Status]ReviewStatus{
+    review.StatusPublished: ReviewStatusPublished,
+    review.StatusRejected:  ReviewStatusRejected,
+}
+
+func newReview(r review.Review) *Review {
+    return &Review{
+        ID: r.ID,
+        ProductID: r.ProductID,
+        AccountID: r.AccountID,
+        Rating: int(r.Rating),
+        Title: r.Title,
+        Text: r.Text,
+        Status: reviewStatuses[r.Status],
+        HelpfulVotes: int(r.HelpfulVotes),
+        CreatedAt: r.CreatedAt,
+    }
+}
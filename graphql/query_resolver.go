@@ -4,6 +4,9 @@ import (
 	"context"
 	"log"
 	"time"
+
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/catalog"
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/order"
 )
 
 type queryResolver struct {
@@ -70,14 +73,7 @@ func (r *queryResolver) Products(
             return nil, err
         }
 
-        return []*Product {
-            {
-                ID: r.ID,
-                Name: r.Name,
-                Description: r.Description,
-                Price: r.Price,
-            },
-        }, nil
+        return []*Product{newProduct(*r)}, nil
     }
 
     skip, take := uint64(0), uint64(0)
@@ -100,18 +96,167 @@ func (r *queryResolver) Products(
 
     var products []*Product
     for _, a := range *productList {
-        products = append(products, &Product{
-            ID: a.ID,
-            Name: a.Name,
-            Description: a.Description,
-            Price: a.Price,
-        })
+        products = append(products, newProduct(a))
     }
 
     return products, nil
 
 }
 
+var productSorts = map[ProductSort]catalog.SearchSort{
+    ProductSortRelevance:  catalog.SortRelevance,
+    ProductSortPriceAsc:   catalog.SortPriceAsc,
+    ProductSortPriceDesc:  catalog.SortPriceDesc,
+    ProductSortNameAsc:    catalog.SortNameAsc,
+    ProductSortNameDesc:   catalog.SortNameDesc,
+    ProductSortNewest:     catalog.SortNewest,
+    ProductSortRatingDesc: catalog.SortRatingDesc,
+}
+
+func (r *queryResolver) SearchProducts(
+    ctx context.Context, search *ProductSearchInput, pagination *PaginationInput,
+) (*ProductSearchResult, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    req := catalog.SearchRequest{}
+    if pagination != nil {
+        req.Skip, req.Take = pagination.bounds()
+    }
+    if search != nil {
+        if search.Query != nil {
+            req.Query = *search.Query
+        }
+        if search.Sort != nil {
+            req.Sort = productSorts[*search.Sort]
+        }
+        req.MinPrice = search.MinPrice
+        req.MaxPrice = search.MaxPrice
+        req.Tags = search.Tags
+        req.CategoryIDs = search.CategoryIds
+        if search.Fuzziness != nil && (*search.Fuzziness < 0 || *search.Fuzziness > 2) {
+            return nil, ErrInvalidParameter
+        }
+        req.Fuzziness = search.Fuzziness
+        req.MinRating = search.MinRating
+    }
+
+    res, err := r.server.catalogClient.SearchProducts(ctx, req)
+    if err != nil {
+        log.Println(err)
+        return nil, err
+    }
+
+    result := &ProductSearchResult{
+        Products: []*Product{},
+        Total: int(res.Total),
+        Facets: &ProductFacets{Prices: []*PriceBucket{}, Tags: []*TagFacet{}},
+        Highlights: []*ProductHighlight{},
+        SearchID: res.SearchID,
+    }
+    if res.DidYouMean != "" {
+        result.DidYouMean = &res.DidYouMean
+    }
+    for _, p := range res.Products {
+        result.Products = append(result.Products, newProduct(p))
+        for _, h := range res.Highlights[p.ID] {
+            result.Highlights = append(result.Highlights, &ProductHighlight{
+                ProductID: p.ID,
+                Field: h.Field,
+                Fragments: h.Fragments,
+            })
+        }
+    }
+    for _, b := range res.Facets.Prices {
+        result.Facets.Prices = append(result.Facets.Prices, &PriceBucket{
+            From: b.From,
+            To: b.To,
+            Count: int(b.Count),
+        })
+    }
+    for _, t := range res.Facets.Tags {
+        result.Facets.Tags = append(result.Facets.Tags, &TagFacet{
+            Tag: t.Tag,
+            Count: int(t.Count),
+        })
+    }
+
+    return result, nil
+}
+
+// ProductSuggestions backs the search box autocomplete, so it gets a
+// tighter deadline than other queries.
+func (r *queryResolver) ProductSuggestions(
+    ctx context.Context, prefix string, limit *int,
+) ([]*ProductSuggestion, error) {
+    ctx, cancel := context.WithTimeout(ctx, 500 * time.Millisecond)
+    defer cancel()
+
+    n := 0
+    if limit != nil {
+        if *limit < 0 {
+            return nil, ErrInvalidParameter
+        }
+        n = *limit
+    }
+    suggestionList, err := r.server.catalogClient.SuggestProducts(ctx, prefix, n)
+    if err != nil {
+        log.Println(err)
+        return nil, err
+    }
+
+    suggestions := []*ProductSuggestion{}
+    for _, s := range suggestionList {
+        suggestions = append(suggestions, &ProductSuggestion{
+            ProductID: s.ProductID,
+            Name: s.Name,
+            Highlighted: s.Highlighted,
+        })
+    }
+    return suggestions, nil
+}
+
+// Categories returns the category with id or path when either is given,
+// otherwise the children of parentId, or the root categories without it.
+func (r *queryResolver) Categories(
+    ctx context.Context, id *string, path *string, parentID *string,
+) ([]*Category, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    if id != nil || path != nil {
+        categoryID, categoryPath := "", ""
+        if id != nil {
+            categoryID = *id
+        }
+        if path != nil {
+            categoryPath = *path
+        }
+        c, err := r.server.catalogClient.GetCategory(ctx, categoryID, categoryPath)
+        if err != nil {
+            log.Println(err)
+            return nil, err
+        }
+        return []*Category{newCategory(*c)}, nil
+    }
+
+    parent := ""
+    if parentID != nil {
+        parent = *parentID
+    }
+    categoryList, err := r.server.catalogClient.GetCategories(ctx, nil, &parent)
+    if err != nil {
+        log.Println(err)
+        return nil, err
+    }
+
+    categories := []*Category{}
+    for _, c := range categoryList {
+        categories = append(categories, newCategory(c))
+    }
+    return categories, nil
+}
+
 func (p PaginationInput) bounds() (skip uint64, take uint64){
     skipValue := uint64(0)
     takeValue := uint64(0)
@@ -124,3 +269,150 @@ func (p PaginationInput) bounds() (skip uint64, take uint64){
 
     return skipValue, takeValue
 }
+
+func (r *queryResolver) SearchSynonyms(ctx context.Context) ([]*SynonymRule, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    ruleList, err := r.server.catalogClient.GetSynonyms(ctx)
+    if err != nil {
+        log.Println("failed to get synonyms from graphql: ", err)
+        return nil, err
+    }
+
+    rules := []*SynonymRule{}
+    for _, rule := range ruleList {
+        rules = append(rules, &SynonymRule{ID: rule.ID, Terms: rule.Terms})
+    }
+    return rules, nil
+}
+
+func (r *queryResolver) SearchStopwords(ctx context.Context) ([]string, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    words, err := r.server.catalogClient.GetStopwords(ctx)
+    if err != nil {
+        log.Println("failed to get stopwords from graphql: ", err)
+        return nil, err
+    }
+    return words, nil
+}
+
+func (r *queryResolver) TopSearchQueries(
+    ctx context.Context, days *int, limit *int,
+) ([]*SearchQueryCount, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    n, err := reportParameter(limit)
+    if err != nil {
+        return nil, err
+    }
+    d, err := reportParameter(days)
+    if err != nil {
+        return nil, err
+    }
+    queries, err := r.server.catalogClient.GetTopSearchQueries(ctx, d, n)
+    if err != nil {
+        log.Println("failed to get top search queries from graphql: ", err)
+        return nil, err
+    }
+    return newSearchQueryCounts(queries), nil
+}
+
+func (r *queryResolver) ZeroResultSearchQueries(
+    ctx context.Context, days *int, limit *int,
+) ([]*SearchQueryCount, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    n, err := reportParameter(limit)
+    if err != nil {
+        return nil, err
+    }
+    d, err := reportParameter(days)
+    if err != nil {
+        return nil, err
+    }
+    queries, err := r.server.catalogClient.GetZeroResultQueries(ctx, d, n)
+    if err != nil {
+        log.Println("failed to get zero result queries from graphql: ", err)
+        return nil, err
+    }
+    return newSearchQueryCounts(queries), nil
+}
+
+func (r *queryResolver) SearchClickThrough(
+    ctx context.Context, days *int,
+) (*SearchClickThrough, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    d, err := reportParameter(days)
+    if err != nil {
+        return nil, err
+    }
+    ct, err := r.server.catalogClient.GetSearchClickThrough(ctx, d)
+    if err != nil {
+        log.Println("failed to get search click through from graphql: ", err)
+        return nil, err
+    }
+
+    return &SearchClickThrough{
+        Searches: int(ct.Searches),
+        ClickedSearches: int(ct.ClickedSearches),
+        AddedToCartSearches: int(ct.AddedToCartSearches),
+        Rate: ct.Rate,
+    }, nil
+}
+
+// reportParameter reads an optional report window or limit; 0 picks the
+// catalog service default.
+func reportParameter(v *int) (int, error) {
+    if v == nil {
+        return 0, nil
+    }
+    if *v < 0 {
+        return 0, ErrInvalidParameter
+    }
+    return *v, nil
+}
+
+func newSearchQueryCounts(queries []catalog.QueryCount) []*SearchQueryCount {
+    res := []*SearchQueryCount{}
+    for _, q := range queries {
+        res = append(res, &SearchQueryCount{Query: q.Query, Searches: int(q.Searches)})
+    }
+    return res
+}
+
+// Orders searches the orders of all accounts; the schema restricts it to
+// admins.
+func (r *queryResolver) Orders(
+    ctx context.Context, filter *OrderSearchInput, sort *OrderSort, first *int, after *string,
+) (*OrderConnection, error) {
+    ctx, cancel := context.WithTimeout(ctx, 3 * time.Second)
+    defer cancel()
+
+    q := orderSearch(filter)
+    if sort != nil {
+        q.Descending = *sort == OrderSortNewest
+    }
+    if first != nil {
+        if *first < 0 || *first > order.MaxOrdersPage {
+            return nil, ErrInvalidParameter
+        }
+        q.First = *first
+    }
+    if after != nil {
+        q.After = *after
+    }
+    page, err := r.server.orderClient.SearchOrders(ctx, q)
+    if err != nil {
+        log.Println("failed to search orders from graphql: ", err)
+        return nil, err
+    }
+
+    return newOrderConnection(page), nil
+}
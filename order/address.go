@@ -0,0 +1,60 @@
+package order
+
+import (
+	"encoding/json"
+
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/order/pb"
+)
+
+// Address is a copy of a shipping or billing address taken when the order
+// is placed, so later edits to the account's address book don't change
+// it.
+type Address struct {
+    Name       string `json:"name"`
+    Company    string `json:"company"`
+    Line1      string `json:"line1"`
+    Line2      string `json:"line2"`
+    City       string `json:"city"`
+    Region     string `json:"region"`
+    PostalCode string `json:"postal_code"`
+    Country    string `json:"country"`
+    Phone      string `json:"phone"`
+}
+
+// AddressChoice picks the account's address book entry with AddressID, or
+// gives Address inline.
+type AddressChoice struct {
+    AddressID string
+    Address   *Address
+}
+
+func (c *AddressChoice) toProto() *pb.AddressChoice {
+    switch {
+    case c == nil:
+        return nil
+    case c.Address != nil:
+        return &pb.AddressChoice{Source: &pb.AddressChoice_Address{Address: addressToProto(c.Address)}}
+    }
+    return &pb.AddressChoice{Source: &pb.AddressChoice_AddressId{AddressId: c.AddressID}}
+}
+
+// encodeAddress returns the JSON stored for a, or nil, stored as NULL, for
+// a nil a.
+func encodeAddress(a *Address) (any, error) {
+    if a == nil {
+        return nil, nil
+    }
+    return json.Marshal(a)
+}
+
+// decodeAddress is the reverse of encodeAddress.
+func decodeAddress(data []byte) (*Address, error) {
+    if data == nil {
+        return nil, nil
+    }
+    a := &Address{}
+    if err := json.Unmarshal(data, a); err != nil {
+        return nil, err
+    }
+    return a, nil
+}
@@ -0,0 +1,50 @@
+package order
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPostOrderBillsShippingAddress(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+    home := &Address{Name: "Ann", Line1: "1 Main St", City: "Springfield", Region: "IL", PostalCode: "62701", Country: "US"}
+    if _, err := s.PostOrder(ctx, "a1", testOrder().Products, home, nil); err != nil {
+        t.Fatal(err)
+    }
+    // The stored copy doesn't follow later changes to the caller's address.
+    home.City = "Shelbyville"
+
+    page, err := s.GetOrdersForAccount(ctx, "a1", OrderQuery{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    o := page.Orders[0]
+    if o.ShippingAddress == nil || o.ShippingAddress.City != "Springfield" {
+        t.Fatalf("unexpected shipping address %+v", o.ShippingAddress)
+    }
+    if o.BillingAddress == nil || *o.BillingAddress != *o.ShippingAddress {
+        t.Fatalf("expected the order to be billed to its shipping address, got %+v", o.BillingAddress)
+    }
+}
+
+func TestEncodeAddress(t *testing.T) {
+    if data, err := encodeAddress(nil); data != nil || err != nil {
+        t.Fatalf("expected a nil address to be stored as NULL, got %v %v", data, err)
+    }
+    a := &Address{Name: "Ann", Line1: "1 Main St", City: "Paris", PostalCode: "75001", Country: "FR"}
+    data, err := encodeAddress(a)
+    if err != nil {
+        t.Fatal(err)
+    }
+    decoded, err := decodeAddress(data.([]byte))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if decoded == nil || *decoded != *a {
+        t.Fatalf("expected %+v back, got %+v", a, decoded)
+    }
+    if decoded, err := decodeAddress(nil); decoded != nil || err != nil {
+        t.Fatalf("expected NULL to decode to nil, got %v %v", decoded, err)
+    }
+}
@@ -0,0 +1,201 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+var (
+    ErrOrderNotFound       = errors.New("order not found")
+    ErrNotCancellable      = errors.New("order can only be cancelled before it is fulfilled")
+    ErrInvalidCancellation = errors.New("cancelled quantities must be positive and not exceed the ordered ones")
+    ErrUnpricedLine        = errors.New("orders placed before line prices were stored can only be cancelled in full")
+    ErrOrderChanged        = errors.New("order changed while it was being cancelled")
+)
+
+type CancelReason int
+
+const (
+    ReasonCustomerRequest CancelReason = iota
+    ReasonOutOfStock
+    ReasonPaymentFailed
+    ReasonFraud
+    ReasonOther
+    // ReasonReturn refunds inspected returns; orders can't be cancelled
+    // with it.
+    ReasonReturn
+)
+
+// Refund is money paid back for cancelled or returned order lines.
+type Refund struct {
+    ID        string
+    OrderID   string
+    // ReturnID is set for refunds of returns.
+    ReturnID  string
+    Reason    CancelReason
+    Amount    float64
+    Lines     []RefundLine
+    CreatedAt time.Time
+}
+
+type RefundLine struct {
+    ProductID string
+    VariantID string
+    Quantity  uint32
+    Amount    float64
+}
+
+// remaining is the quantity of the line that isn't cancelled.
+func (p OrderedProduct) remaining() uint32 {
+    return p.Quantity - p.CancelledQuantity
+}
+
+// Cancellation is a checked and priced cancellation of an order's lines,
+// stored by CommitCancellation unless the order changed in between.
+type Cancellation struct {
+    Refund      Refund
+    // Status is the order's status once the cancellation is stored.
+    Status      Status
+    lastUpdated time.Time
+    // products are the order's co-purchased products before c.
+    products    []string
+}
+
+// CancelOrder cancels the given quantities of the order's lines, or all of
+// them if lines is empty, and refunds them. Lines are refunded at the price
+// they were ordered at; cancelling what's left of the order refunds the
+// rest of its total, so partial refunds never add up to more. The order is
+// cancelled once all its lines are. Orders with shipments or returns are
+// being fulfilled and can't be cancelled.
+func (s *orderService) CancelOrder(
+    ctx context.Context, id string, reason CancelReason, lines []OrderedProduct,
+) (*Order, error) {
+    c, err := s.PlanCancellation(ctx, id, reason, lines)
+    if err != nil {
+        return nil, err
+    }
+    return s.CommitCancellation(ctx, c)
+}
+
+// PlanCancellation checks and prices a cancellation like CancelOrder
+// without storing it.
+func (s *orderService) PlanCancellation(
+    ctx context.Context, id string, reason CancelReason, lines []OrderedProduct,
+) (*Cancellation, error) {
+    o, err := s.repository.GetOrderByID(ctx, id)
+    if err != nil {
+        log.Println("failed to get order from order service: ", err)
+        return nil, err
+    }
+    if o.Status != StatusPlaced || len(o.Shipments) != 0 || len(o.Returns) != 0 {
+        return nil, ErrNotCancellable
+    }
+    if reason == ReasonReturn {
+        return nil, ErrInvalidCancellation
+    }
+
+    refund, all, err := newRefund(*o, reason, lines)
+    if err != nil {
+        return nil, err
+    }
+    c := &Cancellation{
+        Refund: *refund,
+        Status: StatusPlaced,
+        lastUpdated: o.UpdatedAt,
+        products: coPurchasedProducts(*o),
+    }
+    if all {
+        c.Status = StatusCancelled
+    }
+    return c, nil
+}
+
+// CommitCancellation stores c. It returns ErrOrderChanged if the order
+// changed since c was planned.
+func (s *orderService) CommitCancellation(ctx context.Context, c *Cancellation) (*Order, error) {
+    if err := s.repository.CancelOrder(ctx, c.Refund, c.Status, c.lastUpdated); err != nil {
+        log.Println("failed to cancel order from order service: ", err)
+        return nil, err
+    }
+    o, err := s.repository.GetOrderByID(ctx, c.Refund.OrderID)
+    if err != nil {
+        log.Println("failed to get order from order service: ", err)
+        return nil, err
+    }
+    s.forgetCoPurchases(ctx, c.products, *o)
+
+    return o, nil
+}
+
+// newRefund checks lines against what's left of o and prices them, and
+// reports whether they are all that's left. Lines for the same product and
+// variant are merged.
+func newRefund(o Order, reason CancelReason, lines []OrderedProduct) (*Refund, bool, error) {
+    cancelled := map[int]uint32{}
+    if len(lines) == 0 {
+        for i, p := range o.Products {
+            if p.remaining() != 0 {
+                cancelled[i] = p.remaining()
+            }
+        }
+    }
+    for _, l := range lines {
+        i := -1
+        for j, p := range o.Products {
+            if p.ID == l.ID && p.VariantID == l.VariantID {
+                i = j
+            }
+        }
+        if i < 0 || l.Quantity == 0 || cancelled[i] + l.Quantity > o.Products[i].remaining() {
+            return nil, false, ErrInvalidCancellation
+        }
+        cancelled[i] += l.Quantity
+    }
+    if len(cancelled) == 0 {
+        return nil, false, ErrInvalidCancellation
+    }
+
+    r := &Refund{
+        ID: ksuid.New().String(),
+        OrderID: o.ID,
+        Reason: reason,
+        CreatedAt: time.Now().UTC(),
+    }
+    all, priced := true, true
+    for i, p := range o.Products {
+        if cancelled[i] != p.remaining() {
+            all = false
+        }
+        if cancelled[i] == 0 {
+            continue
+        }
+        if p.Price == 0 {
+            priced = false
+        }
+        line := RefundLine{
+            ProductID: p.ID,
+            VariantID: p.VariantID,
+            Quantity: cancelled[i],
+            Amount: roundCents(float64(cancelled[i]) * p.Price),
+        }
+        r.Lines = append(r.Lines, line)
+        r.Amount += line.Amount
+    }
+
+    if all {
+        r.Amount = o.TotalPrice - o.RefundedTotal
+    } else if !priced {
+        return nil, false, ErrUnpricedLine
+    }
+    r.Amount = roundCents(r.Amount)
+    return r, all, nil
+}
+
+func roundCents(amount float64) float64 {
+    return math.Round(amount * 100) / 100
+}
@@ -0,0 +1,189 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func testOrder() Order {
+    return Order{
+        ID: "o1",
+        TotalPrice: 39,
+        Products: []OrderedProduct{
+            {ID: "mug", Quantity: 2, Price: 9.5},
+            {ID: "beans", Quantity: 1, Price: 20},
+        },
+    }
+}
+
+func TestNewRefundPartial(t *testing.T) {
+    r, all, err := newRefund(testOrder(), ReasonOutOfStock, []OrderedProduct{{ID: "mug", Quantity: 1}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if all || r.Amount != 9.5 || len(r.Lines) != 1 || r.Lines[0].Quantity != 1 || r.Reason != ReasonOutOfStock {
+        t.Fatalf("unexpected refund %+v, all %v", r, all)
+    }
+}
+
+func TestNewRefundRestRefundsRemainingTotal(t *testing.T) {
+    o := testOrder()
+    // A discount left the total below the sum of the lines.
+    o.TotalPrice = 35
+    o.RefundedTotal = 9.5
+    o.Products[0].CancelledQuantity = 1
+
+    r, all, err := newRefund(o, ReasonCustomerRequest, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !all || r.Amount != 25.5 || len(r.Lines) != 2 || r.Lines[0].Quantity != 1 {
+        t.Fatalf("unexpected refund %+v, all %v", r, all)
+    }
+}
+
+func TestNewRefundRejectsInvalidLines(t *testing.T) {
+    for _, lines := range [][]OrderedProduct{
+        {{ID: "mug", Quantity: 3}},
+        {{ID: "mug", Quantity: 2}, {ID: "mug", Quantity: 1}},
+        {{ID: "mug", Quantity: 0}},
+        {{ID: "tea", Quantity: 1}},
+        {{ID: "mug", VariantID: "v1", Quantity: 1}},
+    } {
+        if _, _, err := newRefund(testOrder(), ReasonOther, lines); !errors.Is(err, ErrInvalidCancellation) {
+            t.Fatalf("expected %v to be rejected, got %v", lines, err)
+        }
+    }
+
+    o := testOrder()
+    o.Products[0].Price = 0
+    if _, _, err := newRefund(o, ReasonOther, []OrderedProduct{{ID: "mug", Quantity: 1}}); !errors.Is(err, ErrUnpricedLine) {
+        t.Fatalf("expected unpriced lines to be cancelled in full only, got %v", err)
+    }
+    if _, _, err := newRefund(o, ReasonOther, nil); err != nil {
+        t.Fatalf("expected a full cancellation of unpriced lines, got %v", err)
+    }
+}
+
+func TestCancelOrderMemory(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+    o, err := s.PostOrder(ctx, "a1", testOrder().Products, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    o, err = s.CancelOrder(ctx, o.ID, ReasonCustomerRequest, []OrderedProduct{{ID: "beans", Quantity: 1}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if o.Status != StatusPlaced || o.RefundedTotal != 20 || o.Products[1].CancelledQuantity != 1 {
+        t.Fatalf("unexpected partially cancelled order %+v", o)
+    }
+
+    o, err = s.CancelOrder(ctx, o.ID, ReasonCustomerRequest, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if o.Status != StatusCancelled || o.RefundedTotal != 39 || len(o.Refunds) != 2 {
+        t.Fatalf("unexpected cancelled order %+v", o)
+    }
+    if !o.UpdatedAt.Equal(o.Refunds[1].CreatedAt) {
+        t.Fatalf("expected the order to be updated by the last refund, got %v", o.UpdatedAt)
+    }
+
+    if _, err := s.CancelOrder(ctx, o.ID, ReasonOther, nil); !errors.Is(err, ErrNotCancellable) {
+        t.Fatalf("expected a cancelled order not to be cancellable, got %v", err)
+    }
+    if _, err := s.CancelOrder(ctx, "unknown", ReasonOther, nil); !errors.Is(err, ErrOrderNotFound) {
+        t.Fatalf("expected an unknown order to be reported, got %v", err)
+    }
+}
+
+func TestCancelOrderMemoryDetectsChanges(t *testing.T) {
+    ctx := context.Background()
+    r := NewMemoryRepository()
+    s := NewService(r)
+    o, err := s.PostOrder(ctx, "a1", testOrder().Products, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    refund, _, err := newRefund(*o, ReasonOther, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := r.CancelOrder(ctx, *refund, StatusCancelled, o.UpdatedAt.Add(-1)); !errors.Is(err, ErrOrderChanged) {
+        t.Fatalf("expected a stale order to be reported, got %v", err)
+    }
+}
+
+func TestCommitCancellationDetectsChanges(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+    o, err := s.PostOrder(ctx, "a1", testOrder().Products, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    c, err := s.PlanCancellation(ctx, o.ID, ReasonOther, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if c.Status != StatusCancelled || len(c.Refund.Lines) != 2 {
+        t.Fatalf("expected a full cancellation, got %+v", c)
+    }
+    if _, err := s.CancelOrder(ctx, o.ID, ReasonOther, []OrderedProduct{{ID: "mug", Quantity: 1}}); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.CommitCancellation(ctx, c); !errors.Is(err, ErrOrderChanged) {
+        t.Fatalf("expected a cancellation planned before a change to fail, got %v", err)
+    }
+}
+
+func TestCancelOrderForgetsCoPurchases(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+    products := append(testOrder().Products, OrderedProduct{ID: "filter", Quantity: 1, Price: 5})
+    o, err := s.PostOrder(ctx, "a1", products, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.PostOrder(ctx, "a2", testOrder().Products, nil, nil); err != nil {
+        t.Fatal(err)
+    }
+
+    scores := func(productID string) map[string]uint64 {
+        related, err := s.GetRelatedProducts(ctx, productID, 0)
+        if err != nil {
+            t.Fatal(err)
+        }
+        res := map[string]uint64{}
+        for _, r := range related {
+            res[r.ID] = r.Score
+        }
+        return res
+    }
+
+    if _, err := s.CancelOrder(ctx, o.ID, ReasonOther, []OrderedProduct{{ID: "filter", Quantity: 1}}); err != nil {
+        t.Fatal(err)
+    }
+    if got := scores("mug"); got["beans"] != 2 || got["filter"] != 0 {
+        t.Fatalf("expected the cancelled line to stop counting, got %v", got)
+    }
+
+    if _, err := s.CancelOrder(ctx, o.ID, ReasonOther, nil); err != nil {
+        t.Fatal(err)
+    }
+    if got := scores("mug"); got["beans"] != 1 || len(got) != 1 {
+        t.Fatalf("expected the cancelled order to stop counting, got %v", got)
+    }
+
+    if err := s.RebuildRecommendations(ctx); err != nil {
+        t.Fatal(err)
+    }
+    if got := scores("mug"); got["beans"] != 1 || len(got) != 1 {
+        t.Fatalf("expected the rebuild to leave out cancellations, got %v", got)
+    }
+}
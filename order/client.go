@@ -2,8 +2,8 @@ package order
 
 import (
 	"context"
+	"io"
 	"log"
-	"time"
 
 	"github.com/pirateunclejack/go-grpc-graphql-microservice/order/pb"
 	"google.golang.org/grpc"
@@ -15,9 +15,14 @@ type Client struct {
     service pb.OrderServiceClient
 }
 
-func NewClient(url string) (*Client, error){
-    opts := grpc.WithTransportCredentials(insecure.NewCredentials())
-    conn, err := grpc.NewClient(url, opts)
+// NewClient dials url with insecure credentials; opts are appended, e.g.
+// a custom dialer.
+func NewClient(url string, opts ...grpc.DialOption) (*Client, error) {
+    opts = append(
+        []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+        opts...,
+    )
+    conn, err := grpc.NewClient(url, opts...)
     if err != nil {
         log.Println("failed to create new grpc client from order client: ", err)
         return nil, err
@@ -32,13 +37,16 @@ func (c *Client) Close() {
 }
 
 
+// PostOrder places an order; nil shipping and billing choices default to
+// the account's default address and the shipping address.
 func (c *Client) PostOrder(
-    ctx context.Context, accountID string, products []OrderedProduct,
+    ctx context.Context, accountID string, products []OrderedProduct, shipping, billing *AddressChoice,
 ) (*Order, error){
     protoProducts := []*pb.PostOrderRequest_OrderProduct{}
     for _, p := range products {
         protoProducts = append(protoProducts, &pb.PostOrderRequest_OrderProduct{
             ProductId: p.ID,
+            VariantId: p.VariantID,
             Quantity: p.Quantity,
         })
     }
@@ -48,6 +56,8 @@ func (c *Client) PostOrder(
         &pb.PostOrderRequest{
             AccountId: accountID,
             Products: protoProducts,
+            ShippingAddress: shipping.toProto(),
+            BillingAddress: billing.toProto(),
         },
     )
     if err != nil {
@@ -55,54 +65,526 @@ func (c *Client) PostOrder(
         return nil, err
     }
 
-    newOrder := r.Order
-    newOrderCreatedAt := time.Time{}
-    newOrderCreatedAt.UnmarshalBinary(newOrder.CreatedAt)
-    return &Order{
-        ID: newOrder.Id,
-        CreatedAt: newOrderCreatedAt,
-        TotalPrice: newOrder.TotalPrice,
-        Products: products,
-    }, nil
-
+    newOrder, err := orderFromProto(r.Order)
+    if err != nil {
+        log.Println("failed to decode order from order client: ", err)
+        return nil, err
+    }
+    return newOrder, nil
 }
 
+// GetOrdersForAccount returns a page of the account's orders matching
+// q.Filter; pass the id of the page's last order as q.After to get the next
+// one.
 func (c *Client) GetOrdersForAccount(
-    ctx context.Context, accountID string,
-) ([]Order, error){
+    ctx context.Context, accountID string, q OrderQuery,
+) (*OrderPage, error){
+    // The proto field is unsigned, so negative sizes are rejected here.
+    if q.First < 0 || q.First > MaxOrdersPage {
+        return nil, ErrInvalidPage
+    }
+
     r, err := c.service.GetOrdersForAccount(
         ctx,
         &pb.GetOrdersForAccountRequest{
             AccountId: accountID,
+            Filter: orderFilterToProto(q.Filter),
+            Descending: q.Descending,
+            First: uint32(q.First),
+            After: q.After,
         },
     )
     if err != nil {
         log.Println("failed to get orders for account from order client: ", err)
         return nil, err
     }
+    return orderPageFromProto(r.Orders, r.HasMore)
+}
+
+// StreamOrdersForAccount calls fn with every order of the account matching
+// q.Filter after q.After as it arrives, ignoring q.First. It stops at the
+// first error of fn and returns it.
+func (c *Client) StreamOrdersForAccount(
+    ctx context.Context, accountID string, q OrderQuery, fn func(Order) error,
+) error {
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    stream, err := c.service.StreamOrdersForAccount(
+        ctx,
+        &pb.StreamOrdersForAccountRequest{
+            AccountId: accountID,
+            Filter: orderFilterToProto(q.Filter),
+            Descending: q.Descending,
+            After: q.After,
+        },
+    )
+    if err != nil {
+        log.Println("failed to stream orders for account from order client: ", err)
+        return err
+    }
+    for {
+        p, err := stream.Recv()
+        if err == io.EOF {
+            return nil
+        }
+        if err != nil {
+            log.Println("failed to receive order from order client: ", err)
+            return err
+        }
+        o, err := orderFromProto(p)
+        if err != nil {
+            log.Println("failed to decode order from order client: ", err)
+            return err
+        }
+        if err := fn(*o); err != nil {
+            return err
+        }
+    }
+}
+
+// SearchOrders returns a page of the orders of all accounts matching q;
+// pass the id of the page's last order as q.After to get the next one.
+func (c *Client) SearchOrders(ctx context.Context, q OrderSearch) (*OrderPage, error) {
+    if q.First < 0 || q.First > MaxOrdersPage {
+        return nil, ErrInvalidPage
+    }
+
+    r, err := c.service.SearchOrders(
+        ctx,
+        &pb.SearchOrdersRequest{
+            Filter: orderFilterToProto(q.Filter),
+            AccountId: q.AccountID,
+            AccountName: q.AccountName,
+            Descending: q.Descending,
+            First: uint32(q.First),
+            After: q.After,
+        },
+    )
+    if err != nil {
+        log.Println("failed to search orders from order client: ", err)
+        return nil, err
+    }
+    return orderPageFromProto(r.Orders, r.HasMore)
+}
+
+// CancelOrder cancels the given quantities of the order's lines, or all of
+// them if lines is empty, and returns the updated order.
+func (c *Client) CancelOrder(
+    ctx context.Context, id string, reason CancelReason, lines []OrderedProduct,
+) (*Order, error) {
+    req := &pb.CancelOrderRequest{
+        Id: id,
+        Reason: pb.CancelReason(reason),
+    }
+    for _, l := range lines {
+        req.Lines = append(req.Lines, &pb.CancelOrderRequest_Line{
+            ProductId: l.ID,
+            VariantId: l.VariantID,
+            Quantity: l.Quantity,
+        })
+    }
+
+    r, err := c.service.CancelOrder(ctx, req)
+    if err != nil {
+        log.Println("failed to cancel order from order client: ", err)
+        return nil, err
+    }
+    o, err := orderFromProto(r.Order)
+    if err != nil {
+        log.Println("failed to decode order from order client: ", err)
+        return nil, err
+    }
+    return o, nil
+}
+
+// RequestReturn opens a return of quantity units of a line of the
+// account's order.
+func (c *Client) RequestReturn(
+    ctx context.Context, accountID, orderID, productID, variantID string, quantity uint32, reason string,
+) (*Return, error) {
+    r, err := c.service.RequestReturn(ctx, &pb.RequestReturnRequest{
+        AccountId: accountID,
+        OrderId: orderID,
+        ProductId: productID,
+        VariantId: variantID,
+        Quantity: quantity,
+        Reason: reason,
+    })
+    if err != nil {
+        log.Println("failed to request return from order client: ", err)
+        return nil, err
+    }
+    return returnFromProto(r.Return)
+}
+
+func (c *Client) ReviewReturn(ctx context.Context, id string, approve bool, note string) (*Return, error) {
+    r, err := c.service.ReviewReturn(ctx, &pb.ReviewReturnRequest{Id: id, Approve: approve, Note: note})
+    if err != nil {
+        log.Println("failed to review return from order client: ", err)
+        return nil, err
+    }
+    return returnFromProto(r.Return)
+}
+
+func (c *Client) ReceiveReturn(ctx context.Context, id, note string) (*Return, error) {
+    r, err := c.service.ReceiveReturn(ctx, &pb.ReceiveReturnRequest{Id: id, Note: note})
+    if err != nil {
+        log.Println("failed to receive return from order client: ", err)
+        return nil, err
+    }
+    return returnFromProto(r.Return)
+}
+
+// InspectReturn closes a received return; a nil refundAmount refunds the
+// returned units at the price they were ordered at.
+func (c *Client) InspectReturn(
+    ctx context.Context, id string, restockQuantity uint32, refundAmount *float64, note string,
+) (*Return, error) {
+    r, err := c.service.InspectReturn(ctx, &pb.InspectReturnRequest{
+        Id: id,
+        RestockQuantity: restockQuantity,
+        RefundAmount: refundAmount,
+        Note: note,
+    })
+    if err != nil {
+        log.Println("failed to inspect return from order client: ", err)
+        return nil, err
+    }
+    return returnFromProto(r.Return)
+}
+
+// CreateShipment packs the given quantities of the order's lines, or
+// everything not yet shipped if lines is empty, into a pending shipment.
+func (c *Client) CreateShipment(
+    ctx context.Context, orderID, carrier, trackingNumber string, lines []ShipmentLine,
+) (*Shipment, error) {
+    req := &pb.CreateShipmentRequest{
+        OrderId: orderID,
+        Carrier: carrier,
+        TrackingNumber: trackingNumber,
+    }
+    for _, l := range lines {
+        req.Lines = append(req.Lines, &pb.Shipment_Line{
+            ProductId: l.ProductID,
+            VariantId: l.VariantID,
+            Quantity: l.Quantity,
+        })
+    }
+
+    r, err := c.service.CreateShipment(ctx, req)
+    if err != nil {
+        log.Println("failed to create shipment from order client: ", err)
+        return nil, err
+    }
+    return shipmentFromProto(r.Shipment)
+}
+
+// UpdateShipment moves a shipment to status; empty carrier and
+// trackingNumber keep the current ones.
+func (c *Client) UpdateShipment(
+    ctx context.Context, id string, status ShipmentStatus, carrier, trackingNumber string,
+) (*Shipment, error) {
+    r, err := c.service.UpdateShipment(ctx, &pb.UpdateShipmentRequest{
+        Id: id,
+        Status: pb.ShipmentStatus(status),
+        Carrier: carrier,
+        TrackingNumber: trackingNumber,
+    })
+    if err != nil {
+        log.Println("failed to update shipment from order client: ", err)
+        return nil, err
+    }
+    return shipmentFromProto(r.Shipment)
+}
+
+// GetInvoice returns the order's invoice rendered in format.
+func (c *Client) GetInvoice(
+    ctx context.Context, orderID string, format InvoiceFormat,
+) (*InvoiceDocument, error) {
+    r, err := c.service.GetInvoice(ctx, &pb.GetInvoiceRequest{
+        OrderId: orderID,
+        Format: pb.InvoiceFormat(format),
+    })
+    if err != nil {
+        log.Println("failed to get invoice from order client: ", err)
+        return nil, err
+    }
+    return &InvoiceDocument{
+        Number: r.InvoiceNumber,
+        ContentType: r.ContentType,
+        Document: r.Document,
+    }, nil
+}
+
+// SetTaxRate sets the rate of r.Country for orders placed from
+// r.EffectiveFrom on, or from now if it is zero.
+func (c *Client) SetTaxRate(ctx context.Context, r TaxRate) (*TaxRate, error) {
+    req := &pb.SetTaxRateRequest{Country: r.Country, Rate: r.Rate}
+    if !r.EffectiveFrom.IsZero() {
+        req.EffectiveFrom = timeToProto(&r.EffectiveFrom)
+    }
+    res, err := c.service.SetTaxRate(ctx, req)
+    if err != nil {
+        log.Println("failed to set tax rate from order client: ", err)
+        return nil, err
+    }
+    return &TaxRate{
+        Country: res.Country,
+        Rate: res.Rate,
+        EffectiveFrom: res.EffectiveFrom.AsTime(),
+    }, nil
+}
+
+// orderFilterToProto sends the created bounds in both the Timestamp and
+// the deprecated fields, for servers that predate the Timestamps.
+func orderFilterToProto(f OrderFilter) *pb.OrderFilter {
+    filter := &pb.OrderFilter{
+        CreatedFrom: timeToProto(f.CreatedFrom),
+        CreatedTo: timeToProto(f.CreatedTo),
+        LegacyCreatedFrom: legacyTimeToProto(f.CreatedFrom),
+        LegacyCreatedTo: legacyTimeToProto(f.CreatedTo),
+        MinTotal: f.MinTotal,
+        MaxTotal: f.MaxTotal,
+        ProductId: f.ProductID,
+    }
+    for _, status := range f.Statuses {
+        filter.Statuses = append(filter.Statuses, pb.OrderStatus(status))
+    }
+    return filter
+}
+
+func orderPageFromProto(protoOrders []*pb.Order, hasMore bool) (*OrderPage, error) {
     orders := []Order{}
-    for _, orderProto := range r.Orders {
-        newOrder := Order{
-            ID: orderProto.Id,
-            TotalPrice: orderProto.TotalPrice,
-            AccountID: orderProto.AccountId,
+    for _, orderProto := range protoOrders {
+        newOrder, err := orderFromProto(orderProto)
+        if err != nil {
+            log.Println("failed to decode order from order client: ", err)
+            return nil, err
         }
-        newOrder.CreatedAt = time.Time{}
-        newOrder.CreatedAt.UnmarshalBinary(orderProto.CreatedAt)
-        products := []OrderedProduct{}
-        for _, p := range orderProto.Products {
-            products = append(products, OrderedProduct{
-                ID: p.Id,
-                Quantity: p.Quantity,
-                Name: p.Name,
-                Description: p.Description,
-                Price: p.Price,
+        orders = append(orders, *newOrder)
+    }
+    return &OrderPage{Orders: orders, HasMore: hasMore}, nil
+}
+
+// orderFromProto reads createdAt from the deprecated bytes field when the
+// server doesn't send the Timestamp yet; updatedAt then defaults to it.
+func orderFromProto(p *pb.Order) (*Order, error) {
+    o := &Order{
+        ID: p.Id,
+        AccountID: p.AccountId,
+        TotalPrice: p.TotalPrice,
+        Status: Status(p.Status),
+        RefundedTotal: p.RefundedTotal,
+        Products: []OrderedProduct{},
+        Refunds: []Refund{},
+        Returns: []Return{},
+        Shipments: []Shipment{},
+    }
+
+    o.ShippingAddress = addressFromProto(p.ShippingAddress)
+    o.BillingAddress = addressFromProto(p.BillingAddress)
+    o.TaxRate = p.TaxRate
+    invoice, err := parseInvoiceNumber(p.InvoiceNumber)
+    if err != nil {
+        return nil, err
+    }
+    o.Invoice = invoice
+
+    createdAt, err := timeFromProto(p.CreatedAt, p.LegacyCreatedAt)
+    if err != nil {
+        return nil, err
+    }
+    if createdAt != nil {
+        o.CreatedAt = *createdAt
+    }
+    o.UpdatedAt = o.CreatedAt
+    updatedAt, err := timeFromProto(p.UpdatedAt, nil)
+    if err != nil {
+        return nil, err
+    }
+    if updatedAt != nil {
+        o.UpdatedAt = *updatedAt
+    }
+
+    for _, product := range p.Products {
+        o.Products = append(o.Products, OrderedProduct{
+            ID: product.Id,
+            VariantID: product.VariantId,
+            SKU: product.Sku,
+            Name: product.Name,
+            Description: product.Description,
+            Price: product.Price,
+            Quantity: product.Quantity,
+            CancelledQuantity: product.CancelledQuantity,
+        })
+    }
+
+    for _, refund := range p.Refunds {
+        r := Refund{
+            ID: refund.Id,
+            OrderID: p.Id,
+            ReturnID: refund.ReturnId,
+            Reason: CancelReason(refund.Reason),
+            Amount: refund.Amount,
+            Lines: []RefundLine{},
+        }
+        createdAt, err := timeFromProto(refund.CreatedAt, nil)
+        if err != nil {
+            return nil, err
+        }
+        if createdAt != nil {
+            r.CreatedAt = *createdAt
+        }
+        for _, l := range refund.Lines {
+            r.Lines = append(r.Lines, RefundLine{
+                ProductID: l.ProductId,
+                VariantID: l.VariantId,
+                Quantity: l.Quantity,
+                Amount: l.Amount,
             })
         }
-        newOrder.Products = products
-        orders = append(orders, newOrder)
-        log.Println("orders from client: ", orders)
+        o.Refunds = append(o.Refunds, r)
+    }
+
+    for _, ret := range p.Returns {
+        r, err := returnFromProto(ret)
+        if err != nil {
+            return nil, err
+        }
+        o.Returns = append(o.Returns, *r)
     }
 
-    return orders, nil
+    for _, shipment := range p.Shipments {
+        s, err := shipmentFromProto(shipment)
+        if err != nil {
+            return nil, err
+        }
+        o.Shipments = append(o.Shipments, *s)
+    }
+    return o, nil
+}
+
+func shipmentFromProto(p *pb.Shipment) (*Shipment, error) {
+    s := &Shipment{
+        ID: p.Id,
+        OrderID: p.OrderId,
+        Carrier: p.Carrier,
+        TrackingNumber: p.TrackingNumber,
+        Status: ShipmentStatus(p.Status),
+        Lines: []ShipmentLine{},
+    }
+    createdAt, err := timeFromProto(p.CreatedAt, nil)
+    if err != nil {
+        return nil, err
+    }
+    if createdAt != nil {
+        s.CreatedAt = *createdAt
+    }
+    updatedAt, err := timeFromProto(p.UpdatedAt, nil)
+    if err != nil {
+        return nil, err
+    }
+    if updatedAt != nil {
+        s.UpdatedAt = *updatedAt
+    }
+    if s.ShippedAt, err = timeFromProto(p.ShippedAt, nil); err != nil {
+        return nil, err
+    }
+    if s.DeliveredAt, err = timeFromProto(p.DeliveredAt, nil); err != nil {
+        return nil, err
+    }
+
+    for _, l := range p.Lines {
+        s.Lines = append(s.Lines, ShipmentLine{
+            ProductID: l.ProductId,
+            VariantID: l.VariantId,
+            Quantity: l.Quantity,
+        })
+    }
+    return s, nil
+}
+
+func returnFromProto(p *pb.Return) (*Return, error) {
+    r := &Return{
+        ID: p.Id,
+        OrderID: p.OrderId,
+        ProductID: p.ProductId,
+        VariantID: p.VariantId,
+        Quantity: p.Quantity,
+        Reason: p.Reason,
+        Status: ReturnStatus(p.Status),
+        RestockedQuantity: p.RestockedQuantity,
+        RefundAmount: p.RefundAmount,
+        History: []ReturnEvent{},
+    }
+    createdAt, err := timeFromProto(p.CreatedAt, nil)
+    if err != nil {
+        return nil, err
+    }
+    if createdAt != nil {
+        r.CreatedAt = *createdAt
+    }
+    updatedAt, err := timeFromProto(p.UpdatedAt, nil)
+    if err != nil {
+        return nil, err
+    }
+    if updatedAt != nil {
+        r.UpdatedAt = *updatedAt
+    }
+
+    for _, e := range p.History {
+        event := ReturnEvent{Status: ReturnStatus(e.Status), Note: e.Note}
+        createdAt, err := timeFromProto(e.CreatedAt, nil)
+        if err != nil {
+            return nil, err
+        }
+        if createdAt != nil {
+            event.CreatedAt = *createdAt
+        }
+        r.History = append(r.History, event)
+    }
+    return r, nil
+}
+
+// GetRelatedProducts returns up to take products to recommend with the one
+// with productID; a zero take uses the server default.
+func (c *Client) GetRelatedProducts(
+    ctx context.Context, productID string, take int,
+) ([]RelatedProduct, error) {
+    r, err := c.service.GetRelatedProducts(ctx, &pb.GetRelatedProductsRequest{
+        ProductId: productID,
+        Take: uint32(take),
+    })
+    if err != nil {
+        log.Println("failed to get related products from order client: ", err)
+        return nil, err
+    }
+
+    related := []RelatedProduct{}
+    for _, p := range r.Products {
+        related = append(related, RelatedProduct{
+            ID: p.ProductId,
+            Score: p.Score,
+            Source: RelatedSource(p.Source),
+        })
+    }
+    return related, nil
+}
+
+func addressFromProto(p *pb.OrderAddress) *Address {
+    if p == nil {
+        return nil
+    }
+    return &Address{
+        Name: p.Name,
+        Company: p.Company,
+        Line1: p.Line1,
+        Line2: p.Line2,
+        City: p.City,
+        Region: p.Region,
+        PostalCode: p.PostalCode,
+        Country: p.Country,
+        Phone: p.Phone,
+    }
 }
@@ -0,0 +1,69 @@
+package order
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/order/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestOrderProtoRoundTrip(t *testing.T) {
+    created := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+    updated := created.Add(time.Hour)
+    p := orderToProto(&Order{
+        ID: "o1",
+        AccountID: "a1",
+        CreatedAt: created,
+        UpdatedAt: updated,
+        TotalPrice: 12.5,
+        Products: []OrderedProduct{{ID: "p1", Quantity: 2}},
+    })
+    if !p.CreatedAt.AsTime().Equal(created) || !p.UpdatedAt.AsTime().Equal(updated) {
+        t.Fatalf("unexpected timestamps %v %v", p.CreatedAt, p.UpdatedAt)
+    }
+    if len(p.LegacyCreatedAt) == 0 {
+        t.Fatal("expected the deprecated createdAt to still be filled in")
+    }
+
+    o, err := orderFromProto(p)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if o.ID != "o1" || !o.CreatedAt.Equal(created) || !o.UpdatedAt.Equal(updated) || len(o.Products) != 1 {
+        t.Fatalf("unexpected order %+v", o)
+    }
+}
+
+func TestOrderFromProtoLegacyCreatedAt(t *testing.T) {
+    created := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+    legacy, _ := created.MarshalBinary()
+
+    o, err := orderFromProto(&pb.Order{Id: "o1", LegacyCreatedAt: legacy})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !o.CreatedAt.Equal(created) || !o.UpdatedAt.Equal(created) {
+        t.Fatalf("expected both times from the legacy field, got %v %v", o.CreatedAt, o.UpdatedAt)
+    }
+
+    if _, err := orderFromProto(&pb.Order{Id: "o1", LegacyCreatedAt: []byte("garbage")}); err == nil {
+        t.Fatal("expected an error for an undecodable createdAt")
+    }
+}
+
+func TestTimeFromProtoPrefersTimestamp(t *testing.T) {
+    ts := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+    legacy, _ := ts.Add(time.Hour).MarshalBinary()
+
+    got, err := timeFromProto(timestamppb.New(ts), legacy)
+    if err != nil || got == nil || !got.Equal(ts) {
+        t.Fatalf("got %v %v, want %v", got, err, ts)
+    }
+    if got, err := timeFromProto(nil, nil); got != nil || err != nil {
+        t.Fatalf("expected no time, got %v %v", got, err)
+    }
+    if _, err := timeFromProto(&timestamppb.Timestamp{Nanos: -1}, nil); err == nil {
+        t.Fatal("expected an error for an invalid timestamp")
+    }
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"log"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
@@ -24,10 +26,23 @@ func main() {
         log.Fatal(err)
     }
 
+    if len(os.Args) > 1 {
+        switch os.Args[1] {
+        case "rebuild-recommendations":
+            runRebuildRecommendations(cfg, os.Args[2:])
+        default:
+            log.Fatal("unknown order command: ", os.Args[1])
+        }
+        return
+    }
+
     var r order.Repository
 
     ctx := context.Background()
-    if err := retry.Constant(ctx, time.Second * 1 , func(ctx context.Context) error {
+    if strings.HasPrefix(cfg.DatabaseURL, "memory://") {
+        log.Println("using in-memory order repository")
+        r = order.NewMemoryRepository()
+    } else if err := retry.Constant(ctx, time.Second * 1 , func(ctx context.Context) error {
         r, err = order.NewPostgresRepository(cfg.DatabaseURL)
         if err != nil {
             log.Println("failed to create order postgres repository: ", err)
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"strings"
+
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/order"
+)
+
+// runRebuildRecommendations recomputes the frequently bought together
+// counts from the order history, e.g. after importing orders or to repair
+// counts missed while the service was failing to record them:
+//
+//	order rebuild-recommendations
+func runRebuildRecommendations(cfg Config, args []string) {
+    flags := flag.NewFlagSet("rebuild-recommendations", flag.ExitOnError)
+    flags.Parse(args)
+    if strings.HasPrefix(cfg.DatabaseURL, "memory://") {
+        log.Fatal("rebuild-recommendations only applies to the postgres order repository")
+    }
+
+    r, err := order.NewPostgresRepository(cfg.DatabaseURL)
+    if err != nil {
+        log.Fatal("failed to create order postgres repository: ", err)
+    }
+    defer r.Close()
+
+    if err := order.NewService(r).RebuildRecommendations(context.Background()); err != nil {
+        log.Fatal("failed to rebuild recommendations: ", err)
+    }
+    log.Println("recommendations rebuilt")
+}
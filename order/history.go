@@ -0,0 +1,101 @@
+package order
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+var (
+    ErrInvalidFilter   = errors.New("invalid order filter")
+    ErrInvalidPage     = errors.New("page size must be between 0 and 200")
+    ErrTooManyAccounts = errors.New("account name matches too many accounts; use a longer one")
+)
+
+const (
+    defaultOrdersPage = 50
+    // MaxOrdersPage is the largest page size; larger ones are rejected
+    // with ErrInvalidPage.
+    MaxOrdersPage     = 200
+)
+
+// OrderFilter narrows an account's orders; zero fields don't filter.
+type OrderFilter struct {
+    // CreatedFrom is inclusive and CreatedTo exclusive.
+    CreatedFrom *time.Time
+    CreatedTo   *time.Time
+    // Statuses matches orders with any of the statuses.
+    Statuses []Status
+    MinTotal *float64
+    MaxTotal *float64
+    // ProductID matches orders with a line for the product.
+    ProductID string
+}
+
+// OrderQuery selects a page of an account's orders in creation order,
+// oldest first unless Descending is set.
+type OrderQuery struct {
+    Filter     OrderFilter
+    Descending bool
+    // First is the page size, defaulting to 50 and at most 200.
+    First int
+    // After is the cursor of the previous page: the id of its last order.
+    After string
+}
+
+// OrderSearch is an OrderQuery across accounts. AccountID and AccountName
+// are optional; AccountName matches accounts whose name contains it,
+// ignoring case.
+type OrderSearch struct {
+    OrderQuery
+    AccountID   string
+    AccountName string
+}
+
+type OrderPage struct {
+    Orders  []Order
+    HasMore bool
+}
+
+func (q OrderQuery) normalize() (OrderQuery, error) {
+    if q.First < 0 || q.First > MaxOrdersPage {
+        return q, ErrInvalidPage
+    }
+    if q.First == 0 {
+        q.First = defaultOrdersPage
+    }
+
+    f := q.Filter
+    if f.CreatedFrom != nil && f.CreatedTo != nil && !f.CreatedFrom.Before(*f.CreatedTo) {
+        return q, ErrInvalidFilter
+    }
+    if f.MinTotal != nil && f.MaxTotal != nil && *f.MinTotal > *f.MaxTotal {
+        return q, ErrInvalidFilter
+    }
+    return q, nil
+}
+
+// matches reports whether o passes f, for the memory repository.
+func (f OrderFilter) matches(o Order) bool {
+    if f.CreatedFrom != nil && o.CreatedAt.Before(*f.CreatedFrom) {
+        return false
+    }
+    if f.CreatedTo != nil && !o.CreatedAt.Before(*f.CreatedTo) {
+        return false
+    }
+    if len(f.Statuses) != 0 && !slices.Contains(f.Statuses, o.Status) {
+        return false
+    }
+    if f.MinTotal != nil && o.TotalPrice < *f.MinTotal {
+        return false
+    }
+    if f.MaxTotal != nil && o.TotalPrice > *f.MaxTotal {
+        return false
+    }
+    if f.ProductID != "" && !slices.ContainsFunc(o.Products, func(p OrderedProduct) bool {
+        return p.ID == f.ProductID
+    }) {
+        return false
+    }
+    return true
+}
@@ -0,0 +1,216 @@
+package order
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+var (
+    ErrInvoiceNotFound = errors.New("invoice not found")
+    ErrInvalidTaxRate  = errors.New("tax rate must be at least 0 and below 1, for a two-letter country code")
+)
+
+// InvoiceNumber numbers an order's invoice. Sequences start at 1 every
+// year and have no gaps: one is only used up if its order is stored.
+type InvoiceNumber struct {
+    Year     int
+    Sequence int
+}
+
+func (n InvoiceNumber) String() string {
+    return fmt.Sprintf("%d-%06d", n.Year, n.Sequence)
+}
+
+// parseInvoiceNumber is the reverse of InvoiceNumber.String. It returns
+// nil for "", the number of orders placed before invoices.
+func parseInvoiceNumber(s string) (*InvoiceNumber, error) {
+    if s == "" {
+        return nil, nil
+    }
+    year, sequence, ok := strings.Cut(s, "-")
+    if !ok {
+        return nil, fmt.Errorf("invalid invoice number %q", s)
+    }
+    n := &InvoiceNumber{}
+    var err error
+    if n.Year, err = strconv.Atoi(year); err != nil {
+        return nil, fmt.Errorf("invalid invoice number %q", s)
+    }
+    if n.Sequence, err = strconv.Atoi(sequence); err != nil {
+        return nil, fmt.Errorf("invalid invoice number %q", s)
+    }
+    return n, nil
+}
+
+// TaxRate is the part of prices that is tax for orders billed to Country
+// and placed from EffectiveFrom until the country's next rate takes effect.
+// Orders keep the rate they were placed with, so later changes don't alter
+// their invoices.
+type TaxRate struct {
+    Country       string
+    Rate          float64
+    EffectiveFrom time.Time
+}
+
+// SetTaxRate stores the rate of r.Country from r.EffectiveFrom, replacing
+// one from the same time. Orders billed to countries without a rate are
+// invoiced without tax.
+func (s *orderService) SetTaxRate(ctx context.Context, r TaxRate) (*TaxRate, error) {
+    r.Country = strings.ToUpper(strings.TrimSpace(r.Country))
+    if len(r.Country) != 2 || r.Rate < 0 || r.Rate >= 1 {
+        return nil, ErrInvalidTaxRate
+    }
+    r.EffectiveFrom = r.EffectiveFrom.UTC()
+    if err := s.repository.PutTaxRate(ctx, r); err != nil {
+        log.Println("failed to put tax rate from order service: ", err)
+        return nil, err
+    }
+    return &r, nil
+}
+
+// taxRate is the rate of the billing country at the time, or 0 without a
+// billing address.
+func (s *orderService) taxRate(ctx context.Context, billing *Address, at time.Time) (float64, error) {
+    if billing == nil {
+        return 0, nil
+    }
+    rate, err := s.repository.GetTaxRate(ctx, billing.Country, at)
+    if err != nil {
+        log.Println("failed to get tax rate from order service: ", err)
+        return 0, err
+    }
+    return rate, nil
+}
+
+type InvoiceFormat int
+
+const (
+    InvoiceHTML InvoiceFormat = iota
+    InvoiceText
+)
+
+// ContentType is the MIME type of invoices rendered in f.
+func (f InvoiceFormat) ContentType() string {
+    if f == InvoiceText {
+        return "text/plain; charset=utf-8"
+    }
+    return "text/html; charset=utf-8"
+}
+
+// Invoice is the invoice of an order, issued when the order was placed.
+// Its lines are the order's lines as ordered; cancellations and returns
+// show up as refunds.
+type Invoice struct {
+    Number   InvoiceNumber
+    IssuedAt time.Time
+    Order    Order
+}
+
+type InvoiceLine struct {
+    Description string
+    SKU         string
+    Quantity    uint32
+    UnitPrice   float64
+    Amount      float64
+}
+
+// Lines describes the order's lines by product name, or id for lines
+// without one.
+func (inv *Invoice) Lines() []InvoiceLine {
+    lines := []InvoiceLine{}
+    for _, p := range inv.Order.Products {
+        l := InvoiceLine{
+            Description: p.Name,
+            SKU: p.SKU,
+            Quantity: p.Quantity,
+            UnitPrice: p.Price,
+            Amount: roundCents(float64(p.Quantity) * p.Price),
+        }
+        if l.Description == "" {
+            l.Description = p.ID
+        }
+        lines = append(lines, l)
+    }
+    return lines
+}
+
+func (inv *Invoice) TaxRate() float64 {
+    return inv.Order.TaxRate
+}
+
+func (inv *Invoice) Total() float64 {
+    return roundCents(inv.Order.TotalPrice)
+}
+
+// Tax is the part of the total that is tax.
+func (inv *Invoice) Tax() float64 {
+    return roundCents(inv.Order.TotalPrice * inv.Order.TaxRate / (1 + inv.Order.TaxRate))
+}
+
+func (inv *Invoice) Net() float64 {
+    return roundCents(inv.Total() - inv.Tax())
+}
+
+func (inv *Invoice) Refunded() float64 {
+    return roundCents(inv.Order.RefundedTotal)
+}
+
+//go:embed invoice.html
+var invoiceHTML string
+
+//go:embed invoice.txt
+var invoiceText string
+
+var invoiceFuncs = map[string]any{
+    "money": func(amount float64) string { return strconv.FormatFloat(amount, 'f', 2, 64) },
+    "percent": func(rate float64) string { return strconv.FormatFloat(math.Round(rate * 10000) / 100, 'f', -1, 64) + "%" },
+    "date": func(t time.Time) string { return t.Format("2006-01-02") },
+}
+
+var (
+    invoiceHTMLTemplate = htmltemplate.Must(
+        htmltemplate.New("invoice").Funcs(invoiceFuncs).Parse(invoiceHTML),
+    )
+    invoiceTextTemplate = texttemplate.Must(
+        texttemplate.New("invoice").Funcs(invoiceFuncs).Parse(invoiceText),
+    )
+)
+
+// Render writes inv to w as a document in format.
+func (inv *Invoice) Render(w io.Writer, format InvoiceFormat) error {
+    if format == InvoiceText {
+        return invoiceTextTemplate.Execute(w, inv)
+    }
+    return invoiceHTMLTemplate.Execute(w, inv)
+}
+
+// InvoiceDocument is a rendered invoice.
+type InvoiceDocument struct {
+    Number      string
+    ContentType string
+    Document    []byte
+}
+
+// GetInvoice returns the invoice of the order with orderID. Orders placed
+// before invoices have none and return ErrInvoiceNotFound.
+func (s *orderService) GetInvoice(ctx context.Context, orderID string) (*Invoice, error) {
+    o, err := s.repository.GetOrderByID(ctx, orderID)
+    if err != nil {
+        log.Println("failed to get order from order service: ", err)
+        return nil, err
+    }
+    if o.Invoice == nil {
+        return nil, ErrInvoiceNotFound
+    }
+    return &Invoice{Number: *o.Invoice, IssuedAt: o.CreatedAt, Order: *o}, nil
+}
@@ -0,0 +1,138 @@
+package order
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInvoiceNumbersAreSequentialPerYear(t *testing.T) {
+    ctx := context.Background()
+    r := NewMemoryRepository()
+
+    numbers := []string{}
+    for i, created := range []time.Time{
+        time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC),
+        time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+        time.Date(2024, 12, 31, 23, 30, 0, 0, time.UTC),
+        time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+    } {
+        o := &Order{ID: string(rune('a' + i)), CreatedAt: created}
+        if err := r.PutOrder(ctx, o); err != nil {
+            t.Fatal(err)
+        }
+        numbers = append(numbers, o.Invoice.String())
+    }
+    // A duplicate order doesn't use up a number.
+    if err := r.PutOrder(ctx, &Order{ID: "a", CreatedAt: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)}); err == nil {
+        t.Fatal("expected a duplicate order to be rejected")
+    }
+    o := &Order{ID: "e", CreatedAt: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)}
+    if err := r.PutOrder(ctx, o); err != nil {
+        t.Fatal(err)
+    }
+    numbers = append(numbers, o.Invoice.String())
+
+    want := []string{"2024-000001", "2025-000001", "2024-000002", "2025-000002", "2025-000003"}
+    if strings.Join(numbers, " ") != strings.Join(want, " ") {
+        t.Fatalf("expected invoice numbers %v, got %v", want, numbers)
+    }
+    for _, n := range numbers {
+        parsed, err := parseInvoiceNumber(n)
+        if err != nil || parsed.String() != n {
+            t.Fatalf("expected %s to parse back, got %v %v", n, parsed, err)
+        }
+    }
+}
+
+func TestRenderInvoice(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+    if _, err := s.SetTaxRate(ctx, TaxRate{Country: "de", Rate: 0.19}); err != nil {
+        t.Fatal(err)
+    }
+    billing := &Address{Name: "Ann <Co>", Line1: "1 Hauptstr.", City: "Berlin", PostalCode: "10115", Country: "DE"}
+    o, err := s.PostOrder(ctx, "a1", testOrder().Products, nil, billing)
+    if err != nil {
+        t.Fatal(err)
+    }
+    // The invoice keeps the rate the order was placed with.
+    if _, err := s.SetTaxRate(ctx, TaxRate{Country: "DE", Rate: 0.16, EffectiveFrom: time.Now()}); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.CancelOrder(ctx, o.ID, ReasonOutOfStock, []OrderedProduct{{ID: "beans", Quantity: 1}}); err != nil {
+        t.Fatal(err)
+    }
+
+    invoice, err := s.GetInvoice(ctx, o.ID)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if invoice.Number != *o.Invoice || invoice.TaxRate() != 0.19 {
+        t.Fatalf("unexpected invoice %+v", invoice)
+    }
+    if invoice.Total() != 39 || invoice.Tax() != 6.23 || invoice.Net() != 32.77 || invoice.Refunded() != 20 {
+        t.Fatalf("unexpected totals %v %v %v %v", invoice.Total(), invoice.Tax(), invoice.Net(), invoice.Refunded())
+    }
+    invoice.Order.Products[0].Name = "Mug"
+
+    var text bytes.Buffer
+    if err := invoice.Render(&text, InvoiceText); err != nil {
+        t.Fatal(err)
+    }
+    for _, want := range []string{"INVOICE " + o.Invoice.String(), "Bill to:", "Mug", "beans", "Tax (19%)", "6.23", "39.00", "-20.00"} {
+        if !strings.Contains(text.String(), want) {
+            t.Fatalf("expected %q in the text invoice:\n%s", want, text.String())
+        }
+    }
+    if strings.Contains(text.String(), "Ship to:") {
+        t.Fatalf("expected no shipping address in the text invoice:\n%s", text.String())
+    }
+
+    var html bytes.Buffer
+    if err := invoice.Render(&html, InvoiceHTML); err != nil {
+        t.Fatal(err)
+    }
+    if !strings.Contains(html.String(), "Ann &lt;Co&gt;") || !strings.Contains(html.String(), "<td>Mug</td>") {
+        t.Fatalf("expected an escaped HTML invoice:\n%s", html.String())
+    }
+
+    if _, err := s.GetInvoice(ctx, "unknown"); !errors.Is(err, ErrOrderNotFound) {
+        t.Fatalf("expected an unknown order to be reported, got %v", err)
+    }
+}
+
+func TestTaxRatesTakeEffect(t *testing.T) {
+    ctx := context.Background()
+    r := NewMemoryRepository()
+    s := NewService(r)
+    for _, rate := range []TaxRate{
+        {Country: "FI", Rate: 0.255, EffectiveFrom: time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)},
+        {Country: "FI", Rate: 0.24},
+    } {
+        if _, err := s.SetTaxRate(ctx, rate); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    for at, want := range map[time.Time]float64{
+        time.Date(2024, 8, 31, 0, 0, 0, 0, time.UTC): 0.24,
+        time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC):  0.255,
+    } {
+        if rate, err := r.GetTaxRate(ctx, "FI", at); err != nil || rate != want {
+            t.Fatalf("got rate %v %v at %v, want %v", rate, err, at, want)
+        }
+    }
+    if rate, err := r.GetTaxRate(ctx, "US", time.Now()); err != nil || rate != 0 {
+        t.Fatalf("expected no tax without a rate, got %v %v", rate, err)
+    }
+
+    for _, rate := range []TaxRate{{Country: "FIN", Rate: 0.2}, {Country: "FI", Rate: -0.1}, {Country: "FI", Rate: 1}} {
+        if _, err := s.SetTaxRate(ctx, rate); !errors.Is(err, ErrInvalidTaxRate) {
+            t.Fatalf("expected %+v to be rejected, got %v", rate, err)
+        }
+    }
+}
@@ -0,0 +1,434 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"cmp"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+    ErrDuplicateID = errors.New("duplicate id")
+)
+
+type memoryRepository struct {
+    mu     sync.RWMutex
+    orders map[string]Order
+    // coPurchases counts, by product and related product, the orders
+    // containing both.
+    coPurchases map[string]map[string]uint64
+    // invoiceCounters holds the last invoice sequence of each year.
+    invoiceCounters map[int]int
+    // taxRates holds the rates of each country, oldest first.
+    taxRates map[string][]TaxRate
+}
+
+func NewMemoryRepository() Repository {
+    return &memoryRepository{
+        orders: map[string]Order{},
+        coPurchases: map[string]map[string]uint64{},
+        invoiceCounters: map[int]int{},
+        taxRates: map[string][]TaxRate{},
+    }
+}
+
+func (r *memoryRepository) Close() {}
+
+// PutOrder keeps only what the postgres schema stores for each line: the
+// product and variant ids, quantity and price.
+func (r *memoryRepository) PutOrder(ctx context.Context, o *Order) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if _, ok := r.orders[o.ID]; ok {
+        log.Println("failed to put order from order memory repository: duplicate id ", o.ID)
+        return ErrDuplicateID
+    }
+
+    products := make([]OrderedProduct, 0, len(o.Products))
+    for _, p := range o.Products {
+        products = append(products, OrderedProduct{
+            ID:        p.ID,
+            VariantID: p.VariantID,
+            Quantity:  p.Quantity,
+            Price:     p.Price,
+        })
+    }
+    year := o.CreatedAt.Year()
+    r.invoiceCounters[year]++
+    o.Invoice = &InvoiceNumber{Year: year, Sequence: r.invoiceCounters[year]}
+
+    stored := o.clone()
+    stored.Products = products
+    r.orders[o.ID] = stored
+    return nil
+}
+
+func (r *memoryRepository) GetOrdersForAccount(
+    ctx context.Context, accountID string, q OrderQuery,
+) ([]Order, error) {
+    return r.SearchOrders(ctx, []string{accountID}, q)
+}
+
+func (r *memoryRepository) SearchOrders(
+    ctx context.Context, accountIDs []string, q OrderQuery,
+) ([]Order, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    orders := r.matchingOrders(accountIDs, q)
+    return orders[:min(q.First, len(orders))], nil
+}
+
+func (r *memoryRepository) StreamOrdersForAccount(
+    ctx context.Context, accountID string, q OrderQuery, fn func(Order) error,
+) error {
+    r.mu.RLock()
+    orders := r.matchingOrders([]string{accountID}, q)
+    r.mu.RUnlock()
+
+    for _, o := range orders {
+        if err := fn(o); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// matchingOrders returns copies of the orders of accountIDs, or of every
+// account if there are none, matching q, sorted like q asks.
+func (r *memoryRepository) matchingOrders(accountIDs []string, q OrderQuery) []Order {
+    orders := []Order{}
+    for _, o := range r.orders {
+        if len(accountIDs) != 0 && !slices.Contains(accountIDs, o.AccountID) || !q.Filter.matches(o) {
+            continue
+        }
+        if q.After != "" && (q.Descending && o.ID >= q.After || !q.Descending && o.ID <= q.After) {
+            continue
+        }
+        orders = append(orders, o.clone())
+    }
+    sort.Slice(orders, func(i, j int) bool {
+        return (orders[i].ID < orders[j].ID) != q.Descending
+    })
+    return orders
+}
+
+func (r *memoryRepository) GetTaxRate(
+    ctx context.Context, country string, at time.Time,
+) (float64, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    rate := 0.0
+    for _, t := range r.taxRates[country] {
+        if t.EffectiveFrom.After(at) {
+            break
+        }
+        rate = t.Rate
+    }
+    return rate, nil
+}
+
+func (r *memoryRepository) PutTaxRate(ctx context.Context, t TaxRate) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    rates := slices.DeleteFunc(slices.Clone(r.taxRates[t.Country]), func(old TaxRate) bool {
+        return old.EffectiveFrom.Equal(t.EffectiveFrom)
+    })
+    rates = append(rates, t)
+    slices.SortFunc(rates, func(a, b TaxRate) int {
+        return a.EffectiveFrom.Compare(b.EffectiveFrom)
+    })
+    r.taxRates[t.Country] = rates
+    return nil
+}
+
+func (r *memoryRepository) AddCoPurchases(ctx context.Context, productIDs []string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.addCoPurchases(productIDs)
+    return nil
+}
+
+func (r *memoryRepository) addCoPurchases(productIDs []string) {
+    for _, a := range productIDs {
+        for _, b := range productIDs {
+            if a == b {
+                continue
+            }
+            if r.coPurchases[a] == nil {
+                r.coPurchases[a] = map[string]uint64{}
+            }
+            r.coPurchases[a][b]++
+        }
+    }
+}
+
+func (r *memoryRepository) RemoveCoPurchases(ctx context.Context, productIDs, removedIDs []string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    for _, a := range productIDs {
+        for _, b := range productIDs {
+            if a == b || !slices.Contains(removedIDs, a) && !slices.Contains(removedIDs, b) {
+                continue
+            }
+            if r.coPurchases[a][b] > 0 {
+                r.coPurchases[a][b]--
+            }
+            if r.coPurchases[a][b] == 0 {
+                delete(r.coPurchases[a], b)
+            }
+        }
+    }
+    return nil
+}
+
+func (r *memoryRepository) RebuildCoPurchases(ctx context.Context) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.coPurchases = map[string]map[string]uint64{}
+    for _, o := range r.orders {
+        r.addCoPurchases(coPurchasedProducts(o))
+    }
+    return nil
+}
+
+func (r *memoryRepository) GetRelatedProducts(
+    ctx context.Context, productID string, take int,
+) ([]RelatedProduct, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    related := []RelatedProduct{}
+    for id, orders := range r.coPurchases[productID] {
+        related = append(related, RelatedProduct{ID: id, Score: orders})
+    }
+    slices.SortFunc(related, func(a, b RelatedProduct) int {
+        return cmp.Or(cmp.Compare(b.Score, a.Score), strings.Compare(a.ID, b.ID))
+    })
+    return related[:min(take, len(related))], nil
+}
+
+func (r *memoryRepository) GetOrderByID(ctx context.Context, id string) (*Order, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    o, ok := r.orders[id]
+    if !ok {
+        return nil, ErrOrderNotFound
+    }
+    o = o.clone()
+    return &o, nil
+}
+
+func (r *memoryRepository) CancelOrder(
+    ctx context.Context, refund Refund, status Status, lastUpdated time.Time,
+) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    o, ok := r.orders[refund.OrderID]
+    if !ok {
+        return ErrOrderNotFound
+    }
+    if !o.UpdatedAt.Equal(lastUpdated) {
+        return ErrOrderChanged
+    }
+
+    o = o.clone()
+    for _, l := range refund.Lines {
+        i := slices.IndexFunc(o.Products, func(p OrderedProduct) bool {
+            return p.ID == l.ProductID && p.VariantID == l.VariantID
+        })
+        if i < 0 || o.Products[i].remaining() < l.Quantity {
+            return ErrInvalidCancellation
+        }
+        o.Products[i].CancelledQuantity += l.Quantity
+    }
+    o.Status = status
+    o.RefundedTotal = roundCents(o.RefundedTotal + refund.Amount)
+    o.Refunds = append(o.Refunds, refund)
+    o.UpdatedAt = refund.CreatedAt
+    r.orders[o.ID] = o
+    return nil
+}
+
+func (r *memoryRepository) GetOrderByReturnID(ctx context.Context, returnID string) (*Order, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    for _, o := range r.orders {
+        if slices.ContainsFunc(o.Returns, func(ret Return) bool { return ret.ID == returnID }) {
+            o = o.clone()
+            return &o, nil
+        }
+    }
+    return nil, ErrReturnNotFound
+}
+
+func (r *memoryRepository) PutReturn(ctx context.Context, ret Return, lastUpdated time.Time) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    o, ok := r.orders[ret.OrderID]
+    if !ok {
+        return ErrOrderNotFound
+    }
+    if !o.UpdatedAt.Equal(lastUpdated) {
+        return ErrOrderChanged
+    }
+    if o.returnable(ret.ProductID, ret.VariantID) < ret.Quantity {
+        return ErrInvalidReturn
+    }
+
+    o = o.clone()
+    ret.History = append([]ReturnEvent{}, ret.History...)
+    o.Returns = append(o.Returns, ret)
+    o.UpdatedAt = ret.CreatedAt
+    r.orders[o.ID] = o
+    return nil
+}
+
+func (r *memoryRepository) UpdateReturn(
+    ctx context.Context, ret Return, refund *Refund, lastUpdated time.Time,
+) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    o, ok := r.orders[ret.OrderID]
+    if !ok {
+        return ErrOrderNotFound
+    }
+    if !o.UpdatedAt.Equal(lastUpdated) {
+        return ErrOrderChanged
+    }
+    i := slices.IndexFunc(o.Returns, func(stored Return) bool { return stored.ID == ret.ID })
+    if i < 0 {
+        return ErrReturnNotFound
+    }
+
+    o = o.clone()
+    ret.History = append([]ReturnEvent{}, ret.History...)
+    o.Returns[i] = ret
+    if refund != nil {
+        o.RefundedTotal = roundCents(o.RefundedTotal + refund.Amount)
+        o.Refunds = append(o.Refunds, *refund)
+    }
+    o.UpdatedAt = ret.UpdatedAt
+    r.orders[o.ID] = o
+    return nil
+}
+
+func (r *memoryRepository) GetOrderByShipmentID(ctx context.Context, shipmentID string) (*Order, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    for _, o := range r.orders {
+        if slices.ContainsFunc(o.Shipments, func(s Shipment) bool { return s.ID == shipmentID }) {
+            o = o.clone()
+            return &o, nil
+        }
+    }
+    return nil, ErrShipmentNotFound
+}
+
+func (r *memoryRepository) PutShipment(ctx context.Context, s Shipment, lastUpdated time.Time) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    o, ok := r.orders[s.OrderID]
+    if !ok {
+        return ErrOrderNotFound
+    }
+    if !o.UpdatedAt.Equal(lastUpdated) {
+        return ErrOrderChanged
+    }
+    for _, l := range s.Lines {
+        i := slices.IndexFunc(o.Products, func(p OrderedProduct) bool {
+            return p.ID == l.ProductID && p.VariantID == l.VariantID
+        })
+        if i < 0 || l.Quantity > o.shippable(o.Products[i]) {
+            return ErrInvalidShipment
+        }
+    }
+
+    o = o.clone()
+    s.Lines = append([]ShipmentLine{}, s.Lines...)
+    o.Shipments = append(o.Shipments, s)
+    o.UpdatedAt = s.CreatedAt
+    r.orders[o.ID] = o
+    return nil
+}
+
+func (r *memoryRepository) UpdateShipment(
+    ctx context.Context, s Shipment, status Status, lastUpdated time.Time,
+) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    o, ok := r.orders[s.OrderID]
+    if !ok {
+        return ErrOrderNotFound
+    }
+    if !o.UpdatedAt.Equal(lastUpdated) {
+        return ErrOrderChanged
+    }
+    i := slices.IndexFunc(o.Shipments, func(stored Shipment) bool { return stored.ID == s.ID })
+    if i < 0 {
+        return ErrShipmentNotFound
+    }
+
+    o = o.clone()
+    s.Lines = o.Shipments[i].Lines
+    o.Shipments[i] = s
+    o.Status = status
+    o.UpdatedAt = s.UpdatedAt
+    r.orders[o.ID] = o
+    return nil
+}
+
+// clone copies the slices of o, so callers can't change stored orders.
+func (o Order) clone() Order {
+    o.Products = append([]OrderedProduct{}, o.Products...)
+    refunds := []Refund{}
+    for _, refund := range o.Refunds {
+        refund.Lines = append([]RefundLine{}, refund.Lines...)
+        refunds = append(refunds, refund)
+    }
+    o.Refunds = refunds
+    returns := []Return{}
+    for _, ret := range o.Returns {
+        ret.History = append([]ReturnEvent{}, ret.History...)
+        returns = append(returns, ret)
+    }
+    o.Returns = returns
+    shipments := []Shipment{}
+    for _, s := range o.Shipments {
+        s.Lines = append([]ShipmentLine{}, s.Lines...)
+        shipments = append(shipments, s)
+    }
+    o.Shipments = shipments
+    if o.ShippingAddress != nil {
+        a := *o.ShippingAddress
+        o.ShippingAddress = &a
+    }
+    if o.BillingAddress != nil {
+        a := *o.BillingAddress
+        o.BillingAddress = &a
+    }
+    if o.Invoice != nil {
+        n := *o.Invoice
+        o.Invoice = &n
+    }
+    return o
+}
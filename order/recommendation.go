@@ -0,0 +1,111 @@
+package order
+
+import (
+	"context"
+	"log"
+	"slices"
+)
+
+const (
+    defaultRelatedProducts = 5
+    maxRelatedProducts     = 20
+)
+
+type RelatedSource int
+
+const (
+    SourceBoughtTogether RelatedSource = iota
+    // SourceSimilar marks catalog products resembling the product, used
+    // when too few were bought together with it.
+    SourceSimilar
+)
+
+// RelatedProduct is a product recommended along with another one. For
+// products bought together, Score is the number of orders containing both.
+type RelatedProduct struct {
+    ID     string
+    Score  uint64
+    Source RelatedSource
+}
+
+// coPurchasedProducts returns the distinct products of o that count as
+// bought together: none for cancelled orders, and only the lines that
+// aren't fully cancelled otherwise.
+func coPurchasedProducts(o Order) []string {
+    productIDs := []string{}
+    if o.Status == StatusCancelled {
+        return productIDs
+    }
+    for _, p := range o.Products {
+        if p.remaining() > 0 && !slices.Contains(productIDs, p.ID) {
+            productIDs = append(productIDs, p.ID)
+        }
+    }
+    return productIDs
+}
+
+// recordCoPurchases counts each pair of distinct products of o as bought
+// together once. The order is already stored, so failures are only logged;
+// RebuildRecommendations recovers the missed pairs.
+func (s *orderService) recordCoPurchases(ctx context.Context, o Order) {
+    productIDs := coPurchasedProducts(o)
+    if len(productIDs) < 2 {
+        return
+    }
+
+    if err := s.repository.AddCoPurchases(ctx, productIDs); err != nil {
+        log.Println("failed to record co-purchases from order service: ", err)
+    }
+}
+
+// forgetCoPurchases uncounts the pairs recordCoPurchases counted for the
+// products of productIDs a cancellation removed from their order. Like
+// recordCoPurchases it only logs failures.
+func (s *orderService) forgetCoPurchases(ctx context.Context, productIDs []string, o Order) {
+    left := coPurchasedProducts(o)
+    removed := []string{}
+    for _, id := range productIDs {
+        if !slices.Contains(left, id) {
+            removed = append(removed, id)
+        }
+    }
+    if len(productIDs) < 2 || len(removed) == 0 {
+        return
+    }
+
+    if err := s.repository.RemoveCoPurchases(ctx, productIDs, removed); err != nil {
+        log.Println("failed to remove co-purchases from order service: ", err)
+    }
+}
+
+// GetRelatedProducts returns up to take products most often ordered
+// together with productID, most frequent first.
+func (s *orderService) GetRelatedProducts(
+    ctx context.Context, productID string, take int,
+) ([]RelatedProduct, error) {
+    related, err := s.repository.GetRelatedProducts(ctx, productID, relatedTake(take))
+    if err != nil {
+        log.Println("failed to get related products from order service: ", err)
+        return nil, err
+    }
+    return related, nil
+}
+
+// RebuildRecommendations recomputes every co-purchase count from the
+// stored orders.
+func (s *orderService) RebuildRecommendations(ctx context.Context) error {
+    if err := s.repository.RebuildCoPurchases(ctx); err != nil {
+        log.Println("failed to rebuild co-purchases from order service: ", err)
+        return err
+    }
+    return nil
+}
+
+// relatedTake defaults take to defaultRelatedProducts and caps it at
+// maxRelatedProducts.
+func relatedTake(take int) int {
+    if take <= 0 {
+        return defaultRelatedProducts
+    }
+    return min(take, maxRelatedProducts)
+}
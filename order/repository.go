@@ -3,16 +3,80 @@ package order
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/lib/pq"
 )
 
 type Repository interface {
     Close()
-    PutOrder(ctx context.Context, o Order) error
-    GetOrdersForAccount(ctx context.Context, accountID string) ([]Order, error)
+    // PutOrder stores a new order and numbers its invoice with the next
+    // sequence of the year it was created in, setting o.Invoice.
+    PutOrder(ctx context.Context, o *Order) error
+    // GetOrdersForAccount returns up to q.First orders of the account
+    // matching q.Filter, after the q.After cursor.
+    GetOrdersForAccount(ctx context.Context, accountID string, q OrderQuery) ([]Order, error)
+    // SearchOrders is GetOrdersForAccount for the orders of any of
+    // accountIDs, or of every account if accountIDs is empty.
+    SearchOrders(ctx context.Context, accountIDs []string, q OrderQuery) ([]Order, error)
+    // StreamOrdersForAccount calls fn with each of the account's orders
+    // matching q.Filter after the q.After cursor, in order, ignoring
+    // q.First. It stops at the first error of fn and returns it.
+    StreamOrdersForAccount(
+        ctx context.Context, accountID string, q OrderQuery, fn func(Order) error,
+    ) error
+    // AddCoPurchases counts every pair of the distinct productIDs as
+    // ordered together once more.
+    AddCoPurchases(ctx context.Context, productIDs []string) error
+    // RemoveCoPurchases counts every pair of the distinct productIDs with
+    // at least one of removedIDs as ordered together once less.
+    RemoveCoPurchases(ctx context.Context, productIDs, removedIDs []string) error
+    // RebuildCoPurchases replaces the co-purchase counts with ones computed
+    // from all stored orders, leaving out cancelled orders and lines.
+    RebuildCoPurchases(ctx context.Context) error
+    GetRelatedProducts(ctx context.Context, productID string, take int) ([]RelatedProduct, error)
+    // GetTaxRate returns the rate of country in effect at, or 0 if it had
+    // none then.
+    GetTaxRate(ctx context.Context, country string, at time.Time) (float64, error)
+    // PutTaxRate stores r, replacing the rate of its country from the same
+    // time.
+    PutTaxRate(ctx context.Context, r TaxRate) error
+    // GetOrderByID returns ErrOrderNotFound for unknown ids.
+    GetOrderByID(ctx context.Context, id string) (*Order, error)
+    // CancelOrder stores the refund, counts its lines as cancelled, adds it
+    // to the order's refunded total and sets the order's status. It returns
+    // ErrOrderChanged, changing nothing, if the order was updated since
+    // lastUpdated.
+    CancelOrder(ctx context.Context, refund Refund, status Status, lastUpdated time.Time) error
+    // GetOrderByReturnID returns the order of a return, or
+    // ErrReturnNotFound.
+    GetOrderByReturnID(ctx context.Context, returnID string) (*Order, error)
+    // PutReturn stores a new return with its history. It returns
+    // ErrInvalidReturn if the order's line doesn't have the returned
+    // quantity left, and ErrOrderChanged, like CancelOrder, if the order was
+    // updated since lastUpdated.
+    PutReturn(ctx context.Context, r Return, lastUpdated time.Time) error
+    // UpdateReturn stores the return's status, restocked quantity, refund
+    // amount and the last event of its history, and the refund, if any,
+    // like CancelOrder without cancelling lines.
+    UpdateReturn(ctx context.Context, r Return, refund *Refund, lastUpdated time.Time) error
+    // GetOrderByShipmentID returns the order of a shipment, or
+    // ErrShipmentNotFound.
+    GetOrderByShipmentID(ctx context.Context, shipmentID string) (*Order, error)
+    // PutShipment stores a new shipment. It returns ErrInvalidShipment if
+    // the order's lines don't have its quantities left, and
+    // ErrOrderChanged, like CancelOrder, if the order was updated since
+    // lastUpdated.
+    PutShipment(ctx context.Context, s Shipment, lastUpdated time.Time) error
+    // UpdateShipment stores the shipment's status, carrier, tracking number
+    // and timestamps and sets the order's status.
+    UpdateShipment(ctx context.Context, s Shipment, status Status, lastUpdated time.Time) error
 }
 
 type postgresRepository struct {
@@ -39,9 +103,9 @@ func (r *postgresRepository) Close(){
     r.db.Close()
 }
 
-func (r *postgresRepository) PutOrder(ctx context.Context, o Order) error {
-
-    log.Println("order: repository: order: ", o)
+// PutOrder sets o.Invoice only once the order is committed, so a number
+// handed out is always stored.
+func (r *postgresRepository) PutOrder(ctx context.Context, o *Order) error {
     tx, err := r.db.BeginTx(ctx, nil)
     if err != nil {
         return fmt.Errorf(
@@ -49,216 +113,1059 @@ func (r *postgresRepository) PutOrder(ctx context.Context, o Order) error {
             err,
         )
     }
+    defer tx.Rollback()
 
-    defer func() {
-        if err != nil {
-            log.Println("failed to put order, rollback, : ", err)
-            tx.Rollback()
-            return
-        }
-        err = tx.Commit()
-    }()
+    shipping, err := encodeAddress(o.ShippingAddress)
+    if err != nil {
+        return err
+    }
+    billing, err := encodeAddress(o.BillingAddress)
+    if err != nil {
+        return err
+    }
+    // The counter's row stays locked until the transaction ends, so orders
+    // of the same year are numbered one at a time and a rolled back order
+    // gives its number back.
+    invoice := InvoiceNumber{Year: o.CreatedAt.Year()}
+    err = tx.QueryRowContext(
+        ctx,
+        `INSERT INTO invoice_counters (year, last_sequence) VALUES ($1, 1)
+        ON CONFLICT (year) DO UPDATE SET last_sequence = invoice_counters.last_sequence + 1
+        RETURNING last_sequence`,
+        invoice.Year,
+    ).Scan(&invoice.Sequence)
+    if err != nil {
+        log.Println("failed to allocate invoice number from order repository: ", err)
+        return fmt.Errorf("failed to allocate invoice number from order repository: %w", err)
+    }
 
     _, err = tx.ExecContext(
         ctx,
-        "INSERT INTO orders (id, created_at, account_id, total_price) VALUES ($1,$2,$3,$4)",
+        `INSERT INTO orders (
+            id, created_at, updated_at, account_id, total_price, status, shipping_address, billing_address,
+            invoice_year, invoice_sequence, tax_rate
+        )
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`,
         o.ID,
         o.CreatedAt,
+        o.UpdatedAt,
         o.AccountID,
         o.TotalPrice,
+        o.Status,
+        shipping,
+        billing,
+        invoice.Year,
+        invoice.Sequence,
+        o.TaxRate,
     )
     if err != nil {
         log.Println("failed to insert order from order repository: ", err)
         return fmt.Errorf("failed to insert order from order repository: %w", err)
     }
 
-    stmt, _ := tx.PrepareContext(ctx, pq.CopyIn(
+    stmt, err := tx.PrepareContext(ctx, pq.CopyIn(
         "order_products",
         "order_id",
         "product_id",
+        "variant_id",
         "quantity",
+        "price",
     ))
+    if err != nil {
+        log.Println("failed to prepare order products from order repository: ", err)
+        return fmt.Errorf("failed to prepare order products from order repository: %w", err)
+    }
+    defer stmt.Close()
     for _, p := range o.Products{
-        _, err = stmt.ExecContext(ctx, o.ID, p.ID, p.Quantity)
+        _, err = stmt.ExecContext(ctx, o.ID, p.ID, p.VariantID, p.Quantity, p.Price)
         if err != nil {
-        log.Println("failed to insert order product from order repository: ", err)
-        return fmt.Errorf("failed to insert order product from order repository: %w", err)
+            log.Println("failed to insert order product from order repository: ", err)
+            return fmt.Errorf("failed to insert order product from order repository: %w", err)
         }
     }
-
-    _, err = stmt.ExecContext(ctx)
-    if err != nil {
+    if _, err = stmt.ExecContext(ctx); err != nil {
         log.Println("failed to commit order products from order repository: ", err)
         return fmt.Errorf("failed to commit order products from order repository: %w", err)
     }
+    if err = stmt.Close(); err != nil {
+        log.Println("failed to close order products from order repository: ", err)
+        return fmt.Errorf("failed to close order products from order repository: %w", err)
+    }
 
-    stmt.Close()
-    return err
+    if err = tx.Commit(); err != nil {
+        log.Println("failed to commit order from order repository: ", err)
+        return fmt.Errorf("failed to commit order from order repository: %w", err)
+    }
+    o.Invoice = &invoice
+    return nil
 }
 
-func (r *postgresRepository) GetOrdersForAccount(
-    ctx context.Context, accountID string,
-) ([]Order, error){
-    rows, err := r.db.QueryContext(
+// orderFilter returns the WHERE clause selecting the orders of accountIDs,
+// or of every account if there are none, matching q, with its arguments.
+func orderFilter(accountIDs []string, q OrderQuery) (string, []any) {
+    args := []any{}
+    conditions := []string{}
+    add := func(condition string, arg any) {
+        args = append(args, arg)
+        conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+    }
+
+    switch len(accountIDs) {
+    case 0:
+    case 1:
+        add("account_id = $%d", accountIDs[0])
+    default:
+        add("account_id = ANY($%d)", pq.Array(accountIDs))
+    }
+    f := q.Filter
+    if f.CreatedFrom != nil {
+        add("created_at >= $%d", *f.CreatedFrom)
+    }
+    if f.CreatedTo != nil {
+        add("created_at < $%d", *f.CreatedTo)
+    }
+    if len(f.Statuses) != 0 {
+        statuses := []int64{}
+        for _, status := range f.Statuses {
+            statuses = append(statuses, int64(status))
+        }
+        add("status = ANY($%d)", pq.Array(statuses))
+    }
+    if f.MinTotal != nil {
+        add("total_price::numeric >= $%d", *f.MinTotal)
+    }
+    if f.MaxTotal != nil {
+        add("total_price::numeric <= $%d", *f.MaxTotal)
+    }
+    if f.ProductID != "" {
+        add("EXISTS (SELECT 1 FROM order_products WHERE order_id = orders.id AND product_id = $%d)", f.ProductID)
+    }
+    if q.After != "" {
+        if q.Descending {
+            add("id < $%d", q.After)
+        } else {
+            add("id > $%d", q.After)
+        }
+    }
+    if len(conditions) == 0 {
+        return "TRUE", args
+    }
+    return strings.Join(conditions, " AND "), args
+}
+
+func (r *postgresRepository) GetTaxRate(
+    ctx context.Context, country string, at time.Time,
+) (float64, error) {
+    var rate float64
+    err := r.db.QueryRowContext(
+        ctx,
+        `SELECT rate FROM tax_rates WHERE country = $1 AND effective_from <= $2
+        ORDER BY effective_from DESC LIMIT 1`,
+        country,
+        at,
+    ).Scan(&rate)
+    if errors.Is(err, sql.ErrNoRows) {
+        return 0, nil
+    }
+    if err != nil {
+        log.Println("failed to get tax rate from order repository: ", err)
+        return 0, fmt.Errorf("failed to get tax rate from order repository: %w", err)
+    }
+    return rate, nil
+}
+
+func (r *postgresRepository) PutTaxRate(ctx context.Context, t TaxRate) error {
+    _, err := r.db.ExecContext(
         ctx,
-        `SELECT
-        o.id,
-        o.created_at,
-        o.account_id,
-        o.total_price::money::numeric::float8,
-        op.product_id,
-        op.quantity
-        FROM orders o JOIN order_products op ON (o.id = op.order_id)
-        WHERE o.account_id=$1
-        ORDER BY o.id`,
-        accountID,
+        `INSERT INTO tax_rates (country, effective_from, rate) VALUES ($1, $2, $3)
+        ON CONFLICT (country, effective_from) DO UPDATE SET rate = EXCLUDED.rate`,
+        t.Country,
+        t.EffectiveFrom,
+        t.Rate,
     )
     if err != nil {
-        log.Println("failed to get orders from order repository: ", err)
-        return nil, fmt.Errorf("failed to get orders from order repository: %w", err)
+        log.Println("failed to put tax rate from order repository: ", err)
+        return fmt.Errorf("failed to put tax rate from order repository: %w", err)
     }
-    defer rows.Close()
+    return nil
+}
 
+func (r *postgresRepository) GetOrdersForAccount(
+    ctx context.Context, accountID string, q OrderQuery,
+) ([]Order, error){
+    return r.SearchOrders(ctx, []string{accountID}, q)
+}
+
+// orderColumns selects an order for scanOrder. Lines, refunds, returns and
+// shipments are aggregated into JSON arrays, so each row is a whole order.
+const orderColumns = `id,
+        created_at,
+        updated_at,
+        account_id,
+        total_price::money::numeric::float8,
+        status,
+        refunded_total::numeric::float8,
+        shipping_address,
+        billing_address,
+        invoice_year,
+        invoice_sequence,
+        tax_rate,
+        (
+            SELECT COALESCE(json_agg(json_build_object(
+                'product_id', product_id,
+                'variant_id', variant_id,
+                'quantity', quantity,
+                'price', price::numeric,
+                'cancelled_quantity', cancelled_quantity
+            ) ORDER BY product_id, variant_id), '[]')
+            FROM order_products WHERE order_id = orders.id
+        ),
+        (
+            SELECT COALESCE(json_agg(json_build_object(
+                'id', id,
+                'return_id', return_id,
+                'reason', reason,
+                'amount', amount::numeric,
+                'created_at', created_at,
+                'lines', (
+                    SELECT COALESCE(json_agg(json_build_object(
+                        'product_id', product_id,
+                        'variant_id', variant_id,
+                        'quantity', quantity,
+                        'amount', amount::numeric
+                    ) ORDER BY product_id, variant_id), '[]')
+                    FROM refund_lines WHERE refund_id = refunds.id
+                )
+            ) ORDER BY id), '[]')
+            FROM refunds WHERE order_id = orders.id
+        ),
+        (
+            SELECT COALESCE(json_agg(json_build_object(
+                'id', id,
+                'product_id', product_id,
+                'variant_id', variant_id,
+                'quantity', quantity,
+                'reason', reason,
+                'status', status,
+                'restocked_quantity', restocked_quantity,
+                'refund_amount', refund_amount::numeric,
+                'created_at', created_at,
+                'updated_at', updated_at,
+                'history', (
+                    SELECT COALESCE(json_agg(json_build_object(
+                        'status', status,
+                        'note', note,
+                        'created_at', created_at
+                    ) ORDER BY created_at, status), '[]')
+                    FROM return_events WHERE return_id = returns.id
+                )
+            ) ORDER BY created_at, id), '[]')
+            FROM returns WHERE order_id = orders.id
+        ),
+        (
+            SELECT COALESCE(json_agg(json_build_object(
+                'id', id,
+                'carrier', carrier,
+                'tracking_number', tracking_number,
+                'status', status,
+                'created_at', created_at,
+                'updated_at', updated_at,
+                'shipped_at', shipped_at,
+                'delivered_at', delivered_at,
+                'lines', (
+                    SELECT COALESCE(json_agg(json_build_object(
+                        'product_id', product_id,
+                        'variant_id', variant_id,
+                        'quantity', quantity
+                    ) ORDER BY product_id, variant_id), '[]')
+                    FROM shipment_lines WHERE shipment_id = shipments.id
+                )
+            ) ORDER BY created_at, id), '[]')
+            FROM shipments WHERE order_id = orders.id
+        )`
+
+// SearchOrders returns a page of at most q.First orders. Postgres
+// aggregates the lines, so each row is a whole order and nothing is
+// regrouped here.
+func (r *postgresRepository) SearchOrders(
+    ctx context.Context, accountIDs []string, q OrderQuery,
+) ([]Order, error) {
     orders := []Order{}
-    order := &Order{}
-    lastOrder := &Order{}
-    orderedProduct := &OrderedProduct{}
-    products := []OrderedProduct{}
-    newOrder := Order{}
+    err := r.scanOrders(ctx, accountIDs, q, true, func(o Order) error {
+        orders = append(orders, o)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return orders, nil
+}
+
+// StreamOrdersForAccount reads the orders one row at a time, so only the
+// order passed to fn is held in memory.
+func (r *postgresRepository) StreamOrdersForAccount(
+    ctx context.Context, accountID string, q OrderQuery, fn func(Order) error,
+) error {
+    return r.scanOrders(ctx, []string{accountID}, q, false, fn)
+}
+
+// scanOrders calls fn with the orders matching q as their rows arrive,
+// stopping at the first q.First of them if limit is set.
+func (r *postgresRepository) scanOrders(
+    ctx context.Context, accountIDs []string, q OrderQuery, limit bool, fn func(Order) error,
+) error {
+    where, args := orderFilter(accountIDs, q)
+    direction := "ASC"
+    if q.Descending {
+        direction = "DESC"
+    }
+    query := `SELECT `+orderColumns+`
+        FROM orders
+        WHERE `+where+`
+        ORDER BY id `+direction
+    if limit {
+        args = append(args, q.First)
+        query += ` LIMIT $`+strconv.Itoa(len(args))
+    }
+    rows, err := r.db.QueryContext(ctx, query, args...)
+    if err != nil {
+        log.Println("failed to get orders from order repository: ", err)
+        return fmt.Errorf("failed to get orders from order repository: %w", err)
+    }
+    defer rows.Close()
 
-    // Scan rows into Order structs
     for rows.Next() {
-        if err = rows.Scan(
-            &order.ID,
-            &order.CreatedAt,
-            &order.AccountID,
-            &order.TotalPrice,
-            &orderedProduct.ID,
-            &orderedProduct.Quantity,
-        ); err != nil {
-            return nil, err
+        o, err := scanOrder(rows)
+        if err != nil {
+            log.Println("failed to scan order from order repository: ", err)
+            return fmt.Errorf("failed to scan order from order repository: %w", err)
         }
+        if err := fn(*o); err != nil {
+            return err
+        }
+    }
+    if err := rows.Err(); err != nil {
+        log.Println("failed to get orders from order repository: ", err)
+        return fmt.Errorf("failed to get orders from order repository: %w", err)
+    }
+    return nil
+}
 
-        log.Println("row: ", order.ID, order.CreatedAt, order.AccountID, order.TotalPrice, orderedProduct.ID, orderedProduct.Quantity)
-        log.Println("lastorder: ", lastOrder)
-        // Scan order
-
-        if lastOrder.ID == "" {
-            products = append(products, OrderedProduct{
-                ID:       orderedProduct.ID,
-                Quantity: orderedProduct.Quantity,
-            })
-            newOrder = Order{
-                ID:         order.ID,
-                AccountID:  order.AccountID,
-                CreatedAt:  order.CreatedAt,
-                TotalPrice: order.TotalPrice,
-                Products:   products,
-            }
-        } else {
-            if lastOrder.ID == order.ID {
-                // newOrder.Products = append(newOrder.Products, OrderedProduct{
-                //     ID:       orderedProduct.ID,
-                //     Quantity: orderedProduct.Quantity,
-                // })
-                products = append(products, OrderedProduct{
-                    ID:       orderedProduct.ID,
-                    Quantity: orderedProduct.Quantity,
-                })
-                newOrder = Order{
-                    ID:         order.ID,
-                    AccountID:  order.AccountID,
-                    CreatedAt:  order.CreatedAt,
-                    TotalPrice: order.TotalPrice,
-                    Products:   products,
-                }
-            } else {
-                orders = append(orders, newOrder)
-                products = []OrderedProduct{}
-                products = append(products, OrderedProduct{
-                    ID:       orderedProduct.ID,
-                    Quantity: orderedProduct.Quantity,
-                })
-                newOrder = Order{
-                    ID:         order.ID,
-                    AccountID:  order.AccountID,
-                    CreatedAt:  order.CreatedAt,
-                    TotalPrice: order.TotalPrice,
-                    Products:   products,
-                }
-            }
+// orderLine is an element of the lines array of orderColumns. Price is
+// null for lines of orders placed before prices were stored.
+type orderLine struct {
+    ProductID         string   `json:"product_id"`
+    VariantID         string   `json:"variant_id"`
+    Quantity          uint32   `json:"quantity"`
+    Price             *float64 `json:"price"`
+    CancelledQuantity uint32   `json:"cancelled_quantity"`
+}
+
+// refundRow is an element of the refunds array of orderColumns.
+type refundRow struct {
+    ID        string       `json:"id"`
+    ReturnID  *string      `json:"return_id"`
+    Reason    CancelReason `json:"reason"`
+    Amount    float64      `json:"amount"`
+    CreatedAt time.Time    `json:"created_at"`
+    Lines     []struct {
+        ProductID string  `json:"product_id"`
+        VariantID string  `json:"variant_id"`
+        Quantity  uint32  `json:"quantity"`
+        Amount    float64 `json:"amount"`
+    } `json:"lines"`
+}
+
+// returnRow is an element of the returns array of orderColumns.
+type returnRow struct {
+    ID                string       `json:"id"`
+    ProductID         string       `json:"product_id"`
+    VariantID         string       `json:"variant_id"`
+    Quantity          uint32       `json:"quantity"`
+    Reason            string       `json:"reason"`
+    Status            ReturnStatus `json:"status"`
+    RestockedQuantity uint32       `json:"restocked_quantity"`
+    RefundAmount      float64      `json:"refund_amount"`
+    CreatedAt         time.Time    `json:"created_at"`
+    UpdatedAt         time.Time    `json:"updated_at"`
+    History           []struct {
+        Status    ReturnStatus `json:"status"`
+        Note      string       `json:"note"`
+        CreatedAt time.Time    `json:"created_at"`
+    } `json:"history"`
+}
+
+// shipmentRow is an element of the shipments array of orderColumns.
+type shipmentRow struct {
+    ID             string         `json:"id"`
+    Carrier        string         `json:"carrier"`
+    TrackingNumber string         `json:"tracking_number"`
+    Status         ShipmentStatus `json:"status"`
+    CreatedAt      time.Time      `json:"created_at"`
+    UpdatedAt      time.Time      `json:"updated_at"`
+    ShippedAt      *time.Time     `json:"shipped_at"`
+    DeliveredAt    *time.Time     `json:"delivered_at"`
+    Lines          []struct {
+        ProductID string `json:"product_id"`
+        VariantID string `json:"variant_id"`
+        Quantity  uint32 `json:"quantity"`
+    } `json:"lines"`
+}
+
+type rowScanner interface {
+    Scan(dest ...any) error
+}
+
+func scanOrder(row rowScanner) (*Order, error) {
+    o := &Order{}
+    lines, refunds, returns, shipments := []byte{}, []byte{}, []byte{}, []byte{}
+    var shipping, billing []byte
+    var invoiceYear, invoiceSequence sql.NullInt32
+    err := row.Scan(
+        &o.ID,
+        &o.CreatedAt,
+        &o.UpdatedAt,
+        &o.AccountID,
+        &o.TotalPrice,
+        &o.Status,
+        &o.RefundedTotal,
+        &shipping,
+        &billing,
+        &invoiceYear,
+        &invoiceSequence,
+        &o.TaxRate,
+        &lines,
+        &refunds,
+        &returns,
+        &shipments,
+    )
+    if err != nil {
+        return nil, err
+    }
+    if invoiceYear.Valid && invoiceSequence.Valid {
+        o.Invoice = &InvoiceNumber{Year: int(invoiceYear.Int32), Sequence: int(invoiceSequence.Int32)}
+    }
+    if o.ShippingAddress, err = decodeAddress(shipping); err != nil {
+        return nil, err
+    }
+    if o.BillingAddress, err = decodeAddress(billing); err != nil {
+        return nil, err
+    }
+    if o.Products, err = decodeOrderLines(lines); err != nil {
+        return nil, err
+    }
+    if o.Refunds, err = decodeRefunds(o.ID, refunds); err != nil {
+        return nil, err
+    }
+    if o.Returns, err = decodeReturns(o.ID, returns); err != nil {
+        return nil, err
+    }
+    if o.Shipments, err = decodeShipments(o.ID, shipments); err != nil {
+        return nil, err
+    }
+    return o, nil
+}
+
+func decodeOrderLines(data []byte) ([]OrderedProduct, error) {
+    lines := []orderLine{}
+    if err := json.Unmarshal(data, &lines); err != nil {
+        return nil, err
+    }
+    products := make([]OrderedProduct, 0, len(lines))
+    for _, l := range lines {
+        p := OrderedProduct{
+            ID:                l.ProductID,
+            VariantID:         l.VariantID,
+            Quantity:          l.Quantity,
+            CancelledQuantity: l.CancelledQuantity,
         }
+        if l.Price != nil {
+            p.Price = *l.Price
+        }
+        products = append(products, p)
+    }
+    return products, nil
+}
 
-        *lastOrder = *order
-
-        log.Println("orders: ", orders)
-
-    }
-    orders = append(orders, newOrder)
-
-    log.Println("final orders: ", orders)
-
-    // orders := []Order{}
-	// order := &Order{}
-	// lastOrder := &Order{}
-	// orderedProduct := &OrderedProduct{}
-	// products := []OrderedProduct{}
-
-	// // Scan rows into Order structs
-	// for rows.Next() {
-	// 	if err = rows.Scan(
-	// 		&order.ID,
-	// 		&order.CreatedAt,
-	// 		&order.AccountID,
-	// 		&order.TotalPrice,
-	// 		&orderedProduct.ID,
-	// 		&orderedProduct.Quantity,
-	// 	); err != nil {
-	// 		return nil, err
-	// 	}
-
-    //     log.Println("row: ", order.ID, order.CreatedAt, order.AccountID, order.TotalPrice, orderedProduct.ID, orderedProduct.Quantity)
-    //     log.Println("lastorder: ", lastOrder)
-
-	// 	// Scan order
-	// 	if lastOrder.ID != "" && lastOrder.ID != order.ID {
-	// 		newOrder := Order{
-	// 			ID:         lastOrder.ID,
-	// 			AccountID:  lastOrder.AccountID,
-	// 			CreatedAt:  lastOrder.CreatedAt,
-	// 			TotalPrice: lastOrder.TotalPrice,
-	// 			Products:   products,
-	// 		}
-	// 		orders = append(orders, newOrder)
-	// 		products = []OrderedProduct{}
-	// 	}
-	// 	// Scan products
-	// 	products = append(products, OrderedProduct{
-	// 		ID:       orderedProduct.ID,
-	// 		Quantity: orderedProduct.Quantity,
-	// 	})
-
-	// 	*lastOrder = *order
-    //     log.Println("orders: ", orders)
-	// }
-
-	// // Add last order (or first :D)
-	// if lastOrder != nil {
-	// 	newOrder := Order{
-	// 		ID:         lastOrder.ID,
-	// 		AccountID:  lastOrder.AccountID,
-	// 		CreatedAt:  lastOrder.CreatedAt,
-	// 		TotalPrice: lastOrder.TotalPrice,
-	// 		Products:   products,
-	// 	}
-	// 	orders = append(orders, newOrder)
-	// }
+func decodeRefunds(orderID string, data []byte) ([]Refund, error) {
+    rows := []refundRow{}
+    if err := json.Unmarshal(data, &rows); err != nil {
+        return nil, err
+    }
+    refunds := make([]Refund, 0, len(rows))
+    for _, row := range rows {
+        r := Refund{
+            ID: row.ID,
+            OrderID: orderID,
+            Reason: row.Reason,
+            Amount: row.Amount,
+            CreatedAt: row.CreatedAt,
+            Lines: []RefundLine{},
+        }
+        if row.ReturnID != nil {
+            r.ReturnID = *row.ReturnID
+        }
+        for _, l := range row.Lines {
+            r.Lines = append(r.Lines, RefundLine(l))
+        }
+        refunds = append(refunds, r)
+    }
+    return refunds, nil
+}
 
-    if err := rows.Err(); err != nil {
-        log.Println("failed to get orders from order repository: ", err)
-        return nil, fmt.Errorf("failed to get orders from order repository: %w", err)
+func decodeReturns(orderID string, data []byte) ([]Return, error) {
+    rows := []returnRow{}
+    if err := json.Unmarshal(data, &rows); err != nil {
+        return nil, err
+    }
+    returns := make([]Return, 0, len(rows))
+    for _, row := range rows {
+        r := Return{
+            ID: row.ID,
+            OrderID: orderID,
+            ProductID: row.ProductID,
+            VariantID: row.VariantID,
+            Quantity: row.Quantity,
+            Reason: row.Reason,
+            Status: row.Status,
+            RestockedQuantity: row.RestockedQuantity,
+            RefundAmount: row.RefundAmount,
+            CreatedAt: row.CreatedAt,
+            UpdatedAt: row.UpdatedAt,
+            History: []ReturnEvent{},
+        }
+        for _, e := range row.History {
+            r.History = append(r.History, ReturnEvent(e))
+        }
+        returns = append(returns, r)
+    }
+    return returns, nil
+}
+
+func decodeShipments(orderID string, data []byte) ([]Shipment, error) {
+    rows := []shipmentRow{}
+    if err := json.Unmarshal(data, &rows); err != nil {
+        return nil, err
+    }
+    shipments := make([]Shipment, 0, len(rows))
+    for _, row := range rows {
+        s := Shipment{
+            ID: row.ID,
+            OrderID: orderID,
+            Carrier: row.Carrier,
+            TrackingNumber: row.TrackingNumber,
+            Status: row.Status,
+            CreatedAt: row.CreatedAt,
+            UpdatedAt: row.UpdatedAt,
+            ShippedAt: row.ShippedAt,
+            DeliveredAt: row.DeliveredAt,
+            Lines: []ShipmentLine{},
+        }
+        for _, l := range row.Lines {
+            s.Lines = append(s.Lines, ShipmentLine(l))
+        }
+        shipments = append(shipments, s)
+    }
+    return shipments, nil
+}
+
+func (r *postgresRepository) GetOrderByID(ctx context.Context, id string) (*Order, error) {
+    o, err := scanOrder(r.db.QueryRowContext(
+        ctx, "SELECT "+orderColumns+" FROM orders WHERE id = $1", id,
+    ))
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, ErrOrderNotFound
+    }
+    if err != nil {
+        log.Println("failed to get order from order repository: ", err)
+        return nil, fmt.Errorf("failed to get order from order repository: %w", err)
+    }
+    return o, nil
+}
+
+// CancelOrder updates the order first, so a concurrent cancellation of the
+// same order waits for this transaction and then finds it changed.
+func (r *postgresRepository) CancelOrder(
+    ctx context.Context, refund Refund, status Status, lastUpdated time.Time,
+) error {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        log.Println("failed to begin cancel transaction from order repository: ", err)
+        return err
+    }
+    defer tx.Rollback()
+
+    res, err := tx.ExecContext(
+        ctx,
+        `UPDATE orders
+        SET status = $2, refunded_total = refunded_total + $3::numeric::money, updated_at = $4
+        WHERE id = $1 AND updated_at = $5`,
+        refund.OrderID,
+        status,
+        refund.Amount,
+        refund.CreatedAt,
+        lastUpdated,
+    )
+    if err != nil {
+        log.Println("failed to update cancelled order from order repository: ", err)
+        return fmt.Errorf("failed to update cancelled order from order repository: %w", err)
+    }
+    if n, err := res.RowsAffected(); err == nil && n == 0 {
+        return ErrOrderChanged
+    }
+
+    for _, l := range refund.Lines {
+        res, err := tx.ExecContext(
+            ctx,
+            `UPDATE order_products SET cancelled_quantity = cancelled_quantity + $4
+            WHERE order_id = $1 AND product_id = $2 AND variant_id = $3
+            AND quantity - cancelled_quantity >= $4`,
+            refund.OrderID,
+            l.ProductID,
+            l.VariantID,
+            l.Quantity,
+        )
+        if err != nil {
+            log.Println("failed to cancel order line from order repository: ", err)
+            return fmt.Errorf("failed to cancel order line from order repository: %w", err)
+        }
+        if n, err := res.RowsAffected(); err == nil && n == 0 {
+            return ErrInvalidCancellation
+        }
+    }
+
+    if err := insertRefund(ctx, tx, refund); err != nil {
+        return err
+    }
+    return tx.Commit()
+}
+
+// insertRefund stores the refund and its lines in tx.
+func insertRefund(ctx context.Context, tx *sql.Tx, refund Refund) error {
+    _, err := tx.ExecContext(
+        ctx,
+        `INSERT INTO refunds (id, order_id, return_id, reason, amount, created_at)
+        VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)`,
+        refund.ID,
+        refund.OrderID,
+        refund.ReturnID,
+        refund.Reason,
+        refund.Amount,
+        refund.CreatedAt,
+    )
+    if err != nil {
+        log.Println("failed to insert refund from order repository: ", err)
+        return fmt.Errorf("failed to insert refund from order repository: %w", err)
+    }
+
+    for _, l := range refund.Lines {
+        _, err = tx.ExecContext(
+            ctx,
+            `INSERT INTO refund_lines (refund_id, product_id, variant_id, quantity, amount)
+            VALUES ($1, $2, $3, $4, $5)`,
+            refund.ID,
+            l.ProductID,
+            l.VariantID,
+            l.Quantity,
+            l.Amount,
+        )
+        if err != nil {
+            log.Println("failed to insert refund line from order repository: ", err)
+            return fmt.Errorf("failed to insert refund line from order repository: %w", err)
+        }
+    }
+    return nil
+}
+
+func (r *postgresRepository) GetOrderByReturnID(ctx context.Context, returnID string) (*Order, error) {
+    o, err := scanOrder(r.db.QueryRowContext(
+        ctx,
+        "SELECT "+orderColumns+" FROM orders WHERE id = (SELECT order_id FROM returns WHERE id = $1)",
+        returnID,
+    ))
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, ErrReturnNotFound
+    }
+    if err != nil {
+        log.Println("failed to get order from order repository: ", err)
+        return nil, fmt.Errorf("failed to get order from order repository: %w", err)
+    }
+    return o, nil
+}
+
+// touchOrder sets the order's updated_at to updated and adds refunded to
+// its refunded total, if it wasn't updated since lastUpdated. Like in
+// CancelOrder, this locks the order for the rest of tx.
+func touchOrder(
+    ctx context.Context, tx *sql.Tx, id string, refunded float64, updated, lastUpdated time.Time,
+) error {
+    res, err := tx.ExecContext(
+        ctx,
+        `UPDATE orders SET refunded_total = refunded_total + $2::numeric::money, updated_at = $3
+        WHERE id = $1 AND updated_at = $4`,
+        id,
+        refunded,
+        updated,
+        lastUpdated,
+    )
+    if err != nil {
+        log.Println("failed to update order from order repository: ", err)
+        return fmt.Errorf("failed to update order from order repository: %w", err)
+    }
+    if n, err := res.RowsAffected(); err == nil && n == 0 {
+        return ErrOrderChanged
+    }
+    return nil
+}
+
+func insertReturnEvent(ctx context.Context, tx *sql.Tx, returnID string, e ReturnEvent) error {
+    _, err := tx.ExecContext(
+        ctx,
+        "INSERT INTO return_events (return_id, status, note, created_at) VALUES ($1, $2, $3, $4)",
+        returnID,
+        e.Status,
+        e.Note,
+        e.CreatedAt,
+    )
+    if err != nil {
+        log.Println("failed to insert return event from order repository: ", err)
+        return fmt.Errorf("failed to insert return event from order repository: %w", err)
+    }
+    return nil
+}
+
+// PutReturn only inserts the return if the order line has its quantity
+// shipped and left once cancellations and returns that weren't rejected
+// are counted.
+func (r *postgresRepository) PutReturn(ctx context.Context, ret Return, lastUpdated time.Time) error {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        log.Println("failed to begin return transaction from order repository: ", err)
+        return err
+    }
+    defer tx.Rollback()
+
+    if err := touchOrder(ctx, tx, ret.OrderID, 0, ret.CreatedAt, lastUpdated); err != nil {
+        return err
     }
 
-    log.Println("final orders: ", orders)
-    return orders, err
+    res, err := tx.ExecContext(
+        ctx,
+        `INSERT INTO returns (id, order_id, product_id, variant_id, quantity, reason, status, created_at, updated_at)
+        SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9 FROM order_products
+        WHERE order_id = $2 AND product_id = $3 AND variant_id = $4
+        AND LEAST(quantity - cancelled_quantity, (
+            SELECT COALESCE(SUM(sl.quantity), 0)
+            FROM shipment_lines sl JOIN shipments ON shipments.id = sl.shipment_id
+            WHERE shipments.order_id = $2 AND sl.product_id = $3 AND sl.variant_id = $4
+            AND shipments.status <> $11
+        )) - (
+            SELECT COALESCE(SUM(quantity), 0) FROM returns
+            WHERE order_id = $2 AND product_id = $3 AND variant_id = $4 AND status <> $10
+        ) >= $5`,
+        ret.ID,
+        ret.OrderID,
+        ret.ProductID,
+        ret.VariantID,
+        ret.Quantity,
+        ret.Reason,
+        ret.Status,
+        ret.CreatedAt,
+        ret.UpdatedAt,
+        ReturnRejected,
+        ShipmentPending,
+    )
+    if err != nil {
+        log.Println("failed to insert return from order repository: ", err)
+        return fmt.Errorf("failed to insert return from order repository: %w", err)
+    }
+    if n, err := res.RowsAffected(); err == nil && n == 0 {
+        return ErrInvalidReturn
+    }
+
+    for _, e := range ret.History {
+        if err := insertReturnEvent(ctx, tx, ret.ID, e); err != nil {
+            return err
+        }
+    }
+    return tx.Commit()
+}
+
+func (r *postgresRepository) UpdateReturn(
+    ctx context.Context, ret Return, refund *Refund, lastUpdated time.Time,
+) error {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        log.Println("failed to begin return transaction from order repository: ", err)
+        return err
+    }
+    defer tx.Rollback()
+
+    refunded := 0.0
+    if refund != nil {
+        refunded = refund.Amount
+    }
+    if err := touchOrder(ctx, tx, ret.OrderID, refunded, ret.UpdatedAt, lastUpdated); err != nil {
+        return err
+    }
+
+    _, err = tx.ExecContext(
+        ctx,
+        `UPDATE returns SET status = $2, restocked_quantity = $3, refund_amount = $4, updated_at = $5
+        WHERE id = $1`,
+        ret.ID,
+        ret.Status,
+        ret.RestockedQuantity,
+        ret.RefundAmount,
+        ret.UpdatedAt,
+    )
+    if err != nil {
+        log.Println("failed to update return from order repository: ", err)
+        return fmt.Errorf("failed to update return from order repository: %w", err)
+    }
+    if len(ret.History) != 0 {
+        if err := insertReturnEvent(ctx, tx, ret.ID, ret.History[len(ret.History)-1]); err != nil {
+            return err
+        }
+    }
+
+    if refund != nil {
+        if err := insertRefund(ctx, tx, *refund); err != nil {
+            return err
+        }
+    }
+    return tx.Commit()
+}
+
+func (r *postgresRepository) GetOrderByShipmentID(ctx context.Context, shipmentID string) (*Order, error) {
+    o, err := scanOrder(r.db.QueryRowContext(
+        ctx,
+        "SELECT "+orderColumns+" FROM orders WHERE id = (SELECT order_id FROM shipments WHERE id = $1)",
+        shipmentID,
+    ))
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, ErrShipmentNotFound
+    }
+    if err != nil {
+        log.Println("failed to get order from order repository: ", err)
+        return nil, fmt.Errorf("failed to get order from order repository: %w", err)
+    }
+    return o, nil
+}
+
+// PutShipment only inserts a line of the shipment if the order line has
+// its quantity left once cancellations and other shipments, or returns that
+// weren't rejected if they hold more, are counted.
+func (r *postgresRepository) PutShipment(ctx context.Context, s Shipment, lastUpdated time.Time) error {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        log.Println("failed to begin shipment transaction from order repository: ", err)
+        return err
+    }
+    defer tx.Rollback()
+
+    if err := touchOrder(ctx, tx, s.OrderID, 0, s.CreatedAt, lastUpdated); err != nil {
+        return err
+    }
+
+    _, err = tx.ExecContext(
+        ctx,
+        `INSERT INTO shipments (id, order_id, carrier, tracking_number, status, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+        s.ID,
+        s.OrderID,
+        s.Carrier,
+        s.TrackingNumber,
+        s.Status,
+        s.CreatedAt,
+        s.UpdatedAt,
+    )
+    if err != nil {
+        log.Println("failed to insert shipment from order repository: ", err)
+        return fmt.Errorf("failed to insert shipment from order repository: %w", err)
+    }
+
+    for _, l := range s.Lines {
+        res, err := tx.ExecContext(
+            ctx,
+            `INSERT INTO shipment_lines (shipment_id, product_id, variant_id, quantity)
+            SELECT $1, $3, $4, $5 FROM order_products
+            WHERE order_id = $2 AND product_id = $3 AND variant_id = $4
+            AND quantity - cancelled_quantity - GREATEST((
+                SELECT COALESCE(SUM(sl.quantity), 0)
+                FROM shipment_lines sl JOIN shipments ON shipments.id = sl.shipment_id
+                WHERE shipments.order_id = $2 AND sl.product_id = $3 AND sl.variant_id = $4
+            ), (
+                SELECT COALESCE(SUM(quantity), 0) FROM returns
+                WHERE order_id = $2 AND product_id = $3 AND variant_id = $4 AND status <> $6
+            )) >= $5`,
+            s.ID,
+            s.OrderID,
+            l.ProductID,
+            l.VariantID,
+            l.Quantity,
+            ReturnRejected,
+        )
+        if err != nil {
+            log.Println("failed to insert shipment line from order repository: ", err)
+            return fmt.Errorf("failed to insert shipment line from order repository: %w", err)
+        }
+        if n, err := res.RowsAffected(); err == nil && n == 0 {
+            return ErrInvalidShipment
+        }
+    }
+    return tx.Commit()
+}
+
+func (r *postgresRepository) UpdateShipment(
+    ctx context.Context, s Shipment, status Status, lastUpdated time.Time,
+) error {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        log.Println("failed to begin shipment transaction from order repository: ", err)
+        return err
+    }
+    defer tx.Rollback()
+
+    res, err := tx.ExecContext(
+        ctx,
+        "UPDATE orders SET status = $2, updated_at = $3 WHERE id = $1 AND updated_at = $4",
+        s.OrderID,
+        status,
+        s.UpdatedAt,
+        lastUpdated,
+    )
+    if err != nil {
+        log.Println("failed to update order from order repository: ", err)
+        return fmt.Errorf("failed to update order from order repository: %w", err)
+    }
+    if n, err := res.RowsAffected(); err == nil && n == 0 {
+        return ErrOrderChanged
+    }
+
+    _, err = tx.ExecContext(
+        ctx,
+        `UPDATE shipments
+        SET carrier = $2, tracking_number = $3, status = $4, updated_at = $5, shipped_at = $6, delivered_at = $7
+        WHERE id = $1`,
+        s.ID,
+        s.Carrier,
+        s.TrackingNumber,
+        s.Status,
+        s.UpdatedAt,
+        s.ShippedAt,
+        s.DeliveredAt,
+    )
+    if err != nil {
+        log.Println("failed to update shipment from order repository: ", err)
+        return fmt.Errorf("failed to update shipment from order repository: %w", err)
+    }
+    return tx.Commit()
+}
+
+func (r *postgresRepository) AddCoPurchases(ctx context.Context, productIDs []string) error {
+    _, err := r.db.ExecContext(
+        ctx,
+        `INSERT INTO product_co_purchases (product_id, related_id, orders)
+        SELECT a, b, 1 FROM unnest($1::TEXT[]) a, unnest($1::TEXT[]) b WHERE a <> b
+        ON CONFLICT (product_id, related_id)
+        DO UPDATE SET orders = product_co_purchases.orders + 1`,
+        pq.Array(productIDs),
+    )
+    if err != nil {
+        log.Println("failed to add co-purchases from order repository: ", err)
+        return fmt.Errorf("failed to add co-purchases from order repository: %w", err)
+    }
+    return nil
+}
+
+// RemoveCoPurchases drops the pairs whose count reaches zero, so they stop
+// being recommended.
+func (r *postgresRepository) RemoveCoPurchases(ctx context.Context, productIDs, removedIDs []string) error {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        log.Println("failed to begin co-purchases transaction from order repository: ", err)
+        return err
+    }
+    defer tx.Rollback()
+
+    _, err = tx.ExecContext(
+        ctx,
+        `UPDATE product_co_purchases SET orders = orders - 1
+        FROM unnest($1::TEXT[]) a, unnest($1::TEXT[]) b
+        WHERE a <> b AND (a = ANY($2) OR b = ANY($2))
+            AND product_id = a AND related_id = b AND orders > 0`,
+        pq.Array(productIDs), pq.Array(removedIDs),
+    )
+    if err != nil {
+        log.Println("failed to remove co-purchases from order repository: ", err)
+        return fmt.Errorf("failed to remove co-purchases from order repository: %w", err)
+    }
+    if _, err := tx.ExecContext(ctx, "DELETE FROM product_co_purchases WHERE orders = 0"); err != nil {
+        log.Println("failed to remove co-purchases from order repository: ", err)
+        return fmt.Errorf("failed to remove co-purchases from order repository: %w", err)
+    }
+    return tx.Commit()
+}
+
+// RebuildCoPurchases recomputes the counts in one transaction, so readers
+// see either the old or the new counts.
+func (r *postgresRepository) RebuildCoPurchases(ctx context.Context) error {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        log.Println("failed to begin co-purchases transaction from order repository: ", err)
+        return err
+    }
+    defer tx.Rollback()
+
+    if _, err := tx.ExecContext(ctx, "DELETE FROM product_co_purchases"); err != nil {
+        log.Println("failed to clear co-purchases from order repository: ", err)
+        return fmt.Errorf("failed to clear co-purchases from order repository: %w", err)
+    }
+    _, err = tx.ExecContext(
+        ctx,
+        `INSERT INTO product_co_purchases (product_id, related_id, orders)
+        SELECT a.product_id, b.product_id, COUNT(DISTINCT a.order_id)
+        FROM order_products a JOIN order_products b
+            ON a.order_id = b.order_id AND a.product_id <> b.product_id
+        JOIN orders o ON o.id = a.order_id
+        WHERE o.status <> $1
+            AND a.quantity > a.cancelled_quantity AND b.quantity > b.cancelled_quantity
+        GROUP BY a.product_id, b.product_id`,
+        StatusCancelled,
+    )
+    if err != nil {
+        log.Println("failed to rebuild co-purchases from order repository: ", err)
+        return fmt.Errorf("failed to rebuild co-purchases from order repository: %w", err)
+    }
+    return tx.Commit()
+}
+
+func (r *postgresRepository) GetRelatedProducts(
+    ctx context.Context, productID string, take int,
+) ([]RelatedProduct, error) {
+    rows, err := r.db.QueryContext(
+        ctx,
+        `SELECT related_id, orders FROM product_co_purchases
+        WHERE product_id=$1
+        ORDER BY orders DESC, related_id
+        LIMIT $2`,
+        productID,
+        take,
+    )
+    if err != nil {
+        log.Println("failed to get related products from order repository: ", err)
+        return nil, fmt.Errorf("failed to get related products from order repository: %w", err)
+    }
+    defer rows.Close()
+
+    related := []RelatedProduct{}
+    for rows.Next() {
+        p := RelatedProduct{}
+        if err := rows.Scan(&p.ID, &p.Score); err != nil {
+            return nil, err
+        }
+        related = append(related, p)
+    }
+    return related, rows.Err()
 }
@@ -0,0 +1,481 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/segmentio/ksuid"
+)
+
+func TestOrderFilter(t *testing.T) {
+    from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    min := 10.0
+    where, args := orderFilter([]string{"a1"}, OrderQuery{
+        Filter: OrderFilter{
+            CreatedFrom: &from,
+            Statuses: []Status{StatusPlaced},
+            MinTotal: &min,
+            ProductID: "p1",
+        },
+        Descending: true,
+        After: "o1",
+    })
+
+    want := "account_id = $1 AND created_at >= $2 AND status = ANY($3) AND total_price::numeric >= $4" +
+        " AND EXISTS (SELECT 1 FROM order_products WHERE order_id = orders.id AND product_id = $5)" +
+        " AND id < $6"
+    if where != want {
+        t.Fatalf("got %q, want %q", where, want)
+    }
+    wantArgs := []any{"a1", from, pq.Array([]int64{0}), 10.0, "p1", "o1"}
+    if !reflect.DeepEqual(args, wantArgs) {
+        t.Fatalf("got args %#v, want %#v", args, wantArgs)
+    }
+}
+
+func TestOrderFilterAccounts(t *testing.T) {
+    where, args := orderFilter(nil, OrderQuery{})
+    if where != "TRUE" || len(args) != 0 {
+        t.Fatalf("expected no accounts to match every order, got %q %v", where, args)
+    }
+
+    where, args = orderFilter([]string{"a1", "a2"}, OrderQuery{Filter: OrderFilter{ProductID: "p1"}})
+    want := "account_id = ANY($1)" +
+        " AND EXISTS (SELECT 1 FROM order_products WHERE order_id = orders.id AND product_id = $2)"
+    if where != want {
+        t.Fatalf("got %q, want %q", where, want)
+    }
+    if !reflect.DeepEqual(args, []any{pq.Array([]string{"a1", "a2"}), "p1"}) {
+        t.Fatalf("unexpected args %#v", args)
+    }
+}
+
+func TestOrderQueryNormalize(t *testing.T) {
+    for first, want := range map[int]int{0: defaultOrdersPage, 1: 1, MaxOrdersPage: MaxOrdersPage} {
+        q, err := OrderQuery{First: first}.normalize()
+        if err != nil || q.First != want {
+            t.Fatalf("normalize(%d) = %d, %v, want %d", first, q.First, err, want)
+        }
+    }
+    for _, first := range []int{-1, MaxOrdersPage + 1} {
+        if _, err := (OrderQuery{First: first}).normalize(); err != ErrInvalidPage {
+            t.Fatalf("expected page size %d to be rejected, got %v", first, err)
+        }
+    }
+}
+
+func TestStreamOrdersForAccount(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+    for i := 0; i < MaxOrdersPage + 1; i++ {
+        if _, err := s.PostOrder(ctx, "a1", testOrder().Products, nil, nil); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if _, err := s.PostOrder(ctx, "a2", testOrder().Products, nil, nil); err != nil {
+        t.Fatal(err)
+    }
+
+    // Unlike a page, a stream isn't capped.
+    ids := []string{}
+    err := s.StreamOrdersForAccount(ctx, "a1", OrderQuery{First: 1}, func(o Order) error {
+        ids = append(ids, o.ID)
+        return nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(ids) != MaxOrdersPage + 1 || !sort.StringsAreSorted(ids) {
+        t.Fatalf("got %d orders, sorted %v", len(ids), sort.StringsAreSorted(ids))
+    }
+
+    stop := errors.New("stop")
+    n := 0
+    err = s.StreamOrdersForAccount(ctx, "a1", OrderQuery{}, func(o Order) error {
+        n++
+        return stop
+    })
+    if err != stop || n != 1 {
+        t.Fatalf("expected the stream to stop at the first error, got %v after %d orders", err, n)
+    }
+}
+
+func TestDecodeOrderLines(t *testing.T) {
+    products, err := decodeOrderLines([]byte(
+        `[{"product_id" : "p1", "variant_id" : "", "quantity" : 2, "price" : 9.5, "cancelled_quantity" : 1},` +
+        ` {"product_id" : "p2", "variant_id" : "v1", "quantity" : 1, "price" : null, "cancelled_quantity" : 0}]`,
+    ))
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := []OrderedProduct{
+        {ID: "p1", Quantity: 2, Price: 9.5, CancelledQuantity: 1},
+        {ID: "p2", VariantID: "v1", Quantity: 1},
+    }
+    if !reflect.DeepEqual(products, want) {
+        t.Fatalf("got %+v, want %+v", products, want)
+    }
+
+    products, err = decodeOrderLines([]byte("[]"))
+    if err != nil || products == nil || len(products) != 0 {
+        t.Fatalf("expected an empty slice for an order without lines, got %#v %v", products, err)
+    }
+}
+
+func TestDecodeReturns(t *testing.T) {
+    returns, err := decodeReturns("o1", []byte(
+        `[{"id" : "r1", "product_id" : "p1", "variant_id" : "", "quantity" : 2, "reason" : "broken",` +
+        ` "status" : 4, "restocked_quantity" : 1, "refund_amount" : 19, "created_at" : "2024-05-01T12:00:00+00:00",` +
+        ` "updated_at" : "2024-05-03T12:00:00+00:00", "history" : [{"status" : 0, "note" : "",` +
+        ` "created_at" : "2024-05-01T12:00:00+00:00"}, {"status" : 4, "note" : "chipped",` +
+        ` "created_at" : "2024-05-03T12:00:00+00:00"}]}]`,
+    ))
+    if err != nil {
+        t.Fatal(err)
+    }
+    created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+    if len(returns) != 1 || returns[0].OrderID != "o1" || returns[0].Status != ReturnInspected ||
+        returns[0].RefundAmount != 19 || !returns[0].CreatedAt.Equal(created) {
+        t.Fatalf("unexpected returns %+v", returns)
+    }
+    if h := returns[0].History; len(h) != 2 || h[1].Status != ReturnInspected || h[1].Note != "chipped" {
+        t.Fatalf("unexpected history %+v", h)
+    }
+}
+
+func TestDecodeShipments(t *testing.T) {
+    shipments, err := decodeShipments("o1", []byte(
+        `[{"id" : "s1", "carrier" : "ups", "tracking_number" : "1Z1", "status" : 1,` +
+        ` "created_at" : "2024-05-01T12:00:00+00:00", "updated_at" : "2024-05-02T12:00:00+00:00",` +
+        ` "shipped_at" : "2024-05-02T12:00:00+00:00", "delivered_at" : null,` +
+        ` "lines" : [{"product_id" : "p1", "variant_id" : "", "quantity" : 2}]}]`,
+    ))
+    if err != nil {
+        t.Fatal(err)
+    }
+    shipped := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+    s := shipments[0]
+    if len(shipments) != 1 || s.OrderID != "o1" || s.Status != ShipmentInTransit || s.TrackingNumber != "1Z1" {
+        t.Fatalf("unexpected shipments %+v", shipments)
+    }
+    if s.ShippedAt == nil || !s.ShippedAt.Equal(shipped) || s.DeliveredAt != nil {
+        t.Fatalf("unexpected timestamps %v %v", s.ShippedAt, s.DeliveredAt)
+    }
+    if !reflect.DeepEqual(s.Lines, []ShipmentLine{{ProductID: "p1", Quantity: 2}}) {
+        t.Fatalf("unexpected lines %+v", s.Lines)
+    }
+}
+
+const benchmarkOrders = 10000
+
+// seedBenchmarkAccount stores benchmarkOrders orders of three lines each
+// for a new account in the database at ORDER_TEST_DATABASE_URL, and skips
+// the benchmark if it isn't set.
+func seedBenchmarkAccount(b *testing.B) (*postgresRepository, string) {
+    url := os.Getenv("ORDER_TEST_DATABASE_URL")
+    if url == "" {
+        b.Skip("ORDER_TEST_DATABASE_URL is not set")
+    }
+    r, err := NewPostgresRepository(url)
+    if err != nil {
+        b.Fatal(err)
+    }
+    b.Cleanup(r.Close)
+
+    schema, err := os.ReadFile("up.sql")
+    if err != nil {
+        b.Fatal(err)
+    }
+    if _, err := r.db.Exec(string(schema)); err != nil {
+        b.Fatal(err)
+    }
+
+    accountID := ksuid.New().String()
+    tx, err := r.db.Begin()
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer tx.Rollback()
+
+    orderIDs := []string{}
+    stmt, err := tx.Prepare(pq.CopyIn("orders", "id", "created_at", "updated_at", "account_id", "total_price", "status"))
+    if err != nil {
+        b.Fatal(err)
+    }
+    for i := 0; i < benchmarkOrders; i++ {
+        id := ksuid.New().String()
+        orderIDs = append(orderIDs, id)
+        now := time.Now().UTC()
+        if _, err := stmt.Exec(id, now, now, accountID, "30.00", StatusPlaced); err != nil {
+            b.Fatal(err)
+        }
+    }
+    if _, err := stmt.Exec(); err != nil {
+        b.Fatal(err)
+    }
+    stmt.Close()
+
+    stmt, err = tx.Prepare(pq.CopyIn("order_products", "order_id", "product_id", "variant_id", "quantity"))
+    if err != nil {
+        b.Fatal(err)
+    }
+    for _, id := range orderIDs {
+        for j := 0; j < 3; j++ {
+            if _, err := stmt.Exec(id, ksuid.New().String(), "", j+1); err != nil {
+                b.Fatal(err)
+            }
+        }
+    }
+    if _, err := stmt.Exec(); err != nil {
+        b.Fatal(err)
+    }
+    stmt.Close()
+    if err := tx.Commit(); err != nil {
+        b.Fatal(err)
+    }
+
+    b.Cleanup(func() {
+        r.db.Exec("DELETE FROM orders WHERE account_id = $1", accountID)
+    })
+    return r, accountID
+}
+
+// readAccount pages through the benchmark account with fetch, a page of
+// MaxOrdersPage orders at a time, like a client of GetOrdersForAccount.
+func readAccount(b *testing.B, fetch func(q OrderQuery) ([]Order, error)) {
+    q := OrderQuery{First: MaxOrdersPage}
+    n := 0
+    for {
+        orders, err := fetch(q)
+        if err != nil {
+            b.Fatal(err)
+        }
+        n += len(orders)
+        if len(orders) < MaxOrdersPage {
+            break
+        }
+        q.After = orders[len(orders) - 1].ID
+    }
+    if n != benchmarkOrders {
+        b.Fatalf("got %d orders, want %d", n, benchmarkOrders)
+    }
+}
+
+// BenchmarkGetOrdersForAccountBaseline measures the query and regrouping
+// GetOrdersForAccount used before lines were aggregated in SQL.
+func BenchmarkGetOrdersForAccountBaseline(b *testing.B) {
+    r, accountID := seedBenchmarkAccount(b)
+    ctx := context.Background()
+    // The baseline logs every row; only the formatting is measured.
+    log.SetOutput(io.Discard)
+    b.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        readAccount(b, func(q OrderQuery) ([]Order, error) {
+            return baselineGetOrdersForAccount(ctx, r, accountID, q)
+        })
+    }
+}
+
+// BenchmarkGetOrdersForAccountAggregated measures the current query shape,
+// one row per order with its lines aggregated, for the columns of the
+// baseline.
+func BenchmarkGetOrdersForAccountAggregated(b *testing.B) {
+    r, accountID := seedBenchmarkAccount(b)
+    ctx := context.Background()
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        readAccount(b, func(q OrderQuery) ([]Order, error) {
+            return aggregatedGetOrdersForAccount(ctx, r, accountID, q)
+        })
+    }
+}
+
+// BenchmarkGetOrdersForAccount measures the repository method with all the
+// columns of an order.
+func BenchmarkGetOrdersForAccount(b *testing.B) {
+    r, accountID := seedBenchmarkAccount(b)
+    ctx := context.Background()
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        readAccount(b, func(q OrderQuery) ([]Order, error) {
+            return r.GetOrdersForAccount(ctx, accountID, q)
+        })
+    }
+}
+
+// BenchmarkStreamOrdersForAccount reads the whole account in one streamed
+// query.
+func BenchmarkStreamOrdersForAccount(b *testing.B) {
+    r, accountID := seedBenchmarkAccount(b)
+    ctx := context.Background()
+
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        n := 0
+        err := r.StreamOrdersForAccount(ctx, accountID, OrderQuery{}, func(o Order) error {
+            n++
+            return nil
+        })
+        if err != nil {
+            b.Fatal(err)
+        }
+        if n != benchmarkOrders {
+            b.Fatalf("got %d orders, want %d", n, benchmarkOrders)
+        }
+    }
+}
+
+// aggregatedGetOrdersForAccount selects what baselineGetOrdersForAccount
+// does, with the lines aggregated like orderColumns.
+func aggregatedGetOrdersForAccount(
+    ctx context.Context, r *postgresRepository, accountID string, q OrderQuery,
+) ([]Order, error) {
+    where, args := orderFilter([]string{accountID}, q)
+    args = append(args, q.First)
+    rows, err := r.db.QueryContext(
+        ctx,
+        `SELECT id, created_at, account_id, total_price::money::numeric::float8, status,
+        (
+            SELECT COALESCE(json_agg(json_build_object(
+                'product_id', product_id,
+                'variant_id', variant_id,
+                'quantity', quantity
+            ) ORDER BY product_id, variant_id), '[]')
+            FROM order_products WHERE order_id = orders.id
+        )
+        FROM orders
+        WHERE `+where+`
+        ORDER BY id
+        LIMIT $`+strconv.Itoa(len(args)),
+        args...,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    orders := []Order{}
+    for rows.Next() {
+        o := Order{}
+        var data []byte
+        if err := rows.Scan(&o.ID, &o.CreatedAt, &o.AccountID, &o.TotalPrice, &o.Status, &data); err != nil {
+            return nil, err
+        }
+        lines := []orderLine{}
+        if err := json.Unmarshal(data, &lines); err != nil {
+            return nil, err
+        }
+        for _, l := range lines {
+            o.Products = append(o.Products, OrderedProduct{ID: l.ProductID, VariantID: l.VariantID, Quantity: l.Quantity})
+        }
+        orders = append(orders, o)
+    }
+    return orders, rows.Err()
+}
+
+// baselineGetOrdersForAccount is GetOrdersForAccount as it was before lines
+// were aggregated in SQL: one row per line, regrouped here.
+func baselineGetOrdersForAccount(
+    ctx context.Context, r *postgresRepository, accountID string, q OrderQuery,
+) ([]Order, error) {
+    where, args := orderFilter([]string{accountID}, q)
+    direction := "ASC"
+    if q.Descending {
+        direction = "DESC"
+    }
+    args = append(args, q.First)
+    rows, err := r.db.QueryContext(
+        ctx,
+        `WITH page AS (
+            SELECT id, created_at, account_id, total_price, status FROM orders
+            WHERE `+where+`
+            ORDER BY id `+direction+`
+            LIMIT $`+strconv.Itoa(len(args))+`
+        )
+        SELECT
+        o.id,
+        o.created_at,
+        o.account_id,
+        o.total_price::money::numeric::float8,
+        o.status,
+        op.product_id,
+        op.variant_id,
+        op.quantity
+        FROM page o JOIN order_products op ON (o.id = op.order_id)
+        ORDER BY o.id `+direction,
+        args...,
+    )
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    orders := []Order{}
+    order := &Order{}
+    lastOrder := &Order{}
+    orderedProduct := &OrderedProduct{}
+    products := []OrderedProduct{}
+    newOrder := Order{}
+
+    for rows.Next() {
+        if err = rows.Scan(
+            &order.ID,
+            &order.CreatedAt,
+            &order.AccountID,
+            &order.TotalPrice,
+            &order.Status,
+            &orderedProduct.ID,
+            &orderedProduct.VariantID,
+            &orderedProduct.Quantity,
+        ); err != nil {
+            return nil, err
+        }
+
+        log.Println("row: ", order.ID, order.CreatedAt, order.AccountID, order.TotalPrice, orderedProduct.ID, orderedProduct.Quantity)
+        log.Println("lastorder: ", lastOrder)
+
+        if lastOrder.ID == "" || lastOrder.ID == order.ID {
+            products = append(products, OrderedProduct{
+                ID:        orderedProduct.ID,
+                VariantID: orderedProduct.VariantID,
+                Quantity:  orderedProduct.Quantity,
+            })
+        } else {
+            orders = append(orders, newOrder)
+            products = []OrderedProduct{{
+                ID:        orderedProduct.ID,
+                VariantID: orderedProduct.VariantID,
+                Quantity:  orderedProduct.Quantity,
+            }}
+        }
+        newOrder = Order{
+            ID:         order.ID,
+            AccountID:  order.AccountID,
+            CreatedAt:  order.CreatedAt,
+            TotalPrice: order.TotalPrice,
+            Status:     order.Status,
+            Products:   products,
+        }
+        *lastOrder = *order
+
+        log.Println("orders: ", orders)
+    }
+    orders = append(orders, newOrder)
+
+    log.Println("final orders: ", orders)
+    return orders, rows.Err()
+}
@@ -0,0 +1,262 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+var (
+    ErrReturnNotFound       = errors.New("return not found")
+    ErrInvalidReturn        = errors.New("returned quantities must be positive and not exceed the shipped ones left after cancellations and other returns")
+    ErrReturnStatus         = errors.New("return can't move to that status from its current one")
+    ErrInvalidRestock       = errors.New("restocked quantity can't exceed the returned one")
+    ErrInvalidRefund        = errors.New("refund amount must not be negative or exceed what's left of the order total")
+    ErrRefundAmountRequired = errors.New("returns of lines stored without a price need a refund amount")
+)
+
+type ReturnStatus int
+
+const (
+    ReturnRequested ReturnStatus = iota
+    ReturnApproved
+    ReturnRejected
+    ReturnReceived
+    // ReturnInspected is final; the return's refund is issued on
+    // inspection.
+    ReturnInspected
+)
+
+// returnTransitions lists the statuses each status can move to.
+var returnTransitions = map[ReturnStatus][]ReturnStatus{
+    ReturnRequested: {ReturnApproved, ReturnRejected},
+    ReturnApproved:  {ReturnReceived},
+    ReturnReceived:  {ReturnInspected},
+}
+
+// Return is a customer's request to send back some units of an order
+// line, tracked until the items are inspected.
+type Return struct {
+    ID        string
+    OrderID   string
+    ProductID string
+    VariantID string
+    Quantity  uint32
+    Reason    string
+    Status    ReturnStatus
+    // RestockedQuantity is how many of the returned units went back into
+    // stock after inspection.
+    RestockedQuantity uint32
+    RefundAmount      float64
+    CreatedAt         time.Time
+    UpdatedAt         time.Time
+    // History holds an event for every status the return reached, oldest
+    // first.
+    History []ReturnEvent
+}
+
+type ReturnEvent struct {
+    Status    ReturnStatus
+    Note      string
+    CreatedAt time.Time
+}
+
+// returnable is the quantity of the order's line for productID and
+// variantID that left the warehouse, isn't cancelled and isn't in a return
+// that wasn't rejected.
+func (o Order) returnable(productID, variantID string) uint32 {
+    i := slices.IndexFunc(o.Products, func(p OrderedProduct) bool {
+        return p.ID == productID && p.VariantID == variantID
+    })
+    if i < 0 {
+        return 0
+    }
+    left := min(o.Products[i].remaining(), o.allocated(productID, variantID, true))
+    return left - min(left, o.returned(productID, variantID))
+}
+
+// returned is the quantity of the order's line for productID and variantID
+// in returns that weren't rejected.
+func (o Order) returned(productID, variantID string) uint32 {
+    returned := uint32(0)
+    for _, r := range o.Returns {
+        if r.ProductID == productID && r.VariantID == variantID && r.Status != ReturnRejected {
+            returned += r.Quantity
+        }
+    }
+    return returned
+}
+
+// RequestReturn opens a return of quantity units of an order line of the
+// account's order. Only units of shipments that left the warehouse can be
+// returned.
+func (s *orderService) RequestReturn(
+    ctx context.Context, accountID, orderID, productID, variantID string, quantity uint32, reason string,
+) (*Return, error) {
+    o, err := s.repository.GetOrderByID(ctx, orderID)
+    if err != nil {
+        log.Println("failed to get order from order service: ", err)
+        return nil, err
+    }
+    if o.AccountID != accountID {
+        return nil, ErrOrderNotFound
+    }
+    if quantity == 0 || quantity > o.returnable(productID, variantID) {
+        return nil, ErrInvalidReturn
+    }
+
+    now := time.Now().UTC()
+    r := Return{
+        ID: ksuid.New().String(),
+        OrderID: o.ID,
+        ProductID: productID,
+        VariantID: variantID,
+        Quantity: quantity,
+        Reason: strings.TrimSpace(reason),
+        Status: ReturnRequested,
+        CreatedAt: now,
+        UpdatedAt: now,
+        History: []ReturnEvent{{Status: ReturnRequested, CreatedAt: now}},
+    }
+    if err := s.repository.PutReturn(ctx, r, o.UpdatedAt); err != nil {
+        log.Println("failed to put return from order service: ", err)
+        return nil, err
+    }
+    return &r, nil
+}
+
+// ReviewReturn approves or rejects a requested return.
+func (s *orderService) ReviewReturn(
+    ctx context.Context, id string, approve bool, note string,
+) (*Return, error) {
+    status := ReturnRejected
+    if approve {
+        status = ReturnApproved
+    }
+    return s.updateReturn(ctx, id, status, note, nil)
+}
+
+func (s *orderService) GetReturn(ctx context.Context, id string) (*Return, error) {
+    o, err := s.repository.GetOrderByReturnID(ctx, id)
+    if err != nil {
+        log.Println("failed to get order from order service: ", err)
+        return nil, err
+    }
+    i := slices.IndexFunc(o.Returns, func(r Return) bool { return r.ID == id })
+    if i < 0 {
+        return nil, ErrReturnNotFound
+    }
+    return &o.Returns[i], nil
+}
+
+// checkInspection reports why r can't be inspected with restockQuantity
+// units restocked, if it can't, before anything is restocked for it.
+func (r *Return) checkInspection(restockQuantity uint32) error {
+    if !slices.Contains(returnTransitions[r.Status], ReturnInspected) {
+        return ErrReturnStatus
+    }
+    if restockQuantity > r.Quantity {
+        return ErrInvalidRestock
+    }
+    return nil
+}
+
+// ReceiveReturn records that the items of an approved return arrived.
+func (s *orderService) ReceiveReturn(ctx context.Context, id, note string) (*Return, error) {
+    return s.updateReturn(ctx, id, ReturnReceived, note, nil)
+}
+
+// InspectReturn closes a received return, recording how many of its units
+// are restocked, and refunds it. Without a refundAmount the returned units
+// are refunded at the price they were ordered at, up to what's left of the
+// order total.
+func (s *orderService) InspectReturn(
+    ctx context.Context, id string, restockQuantity uint32, refundAmount *float64, note string,
+) (*Return, error) {
+    return s.updateReturn(ctx, id, ReturnInspected, note, func(o Order, r *Return) (*Refund, error) {
+        if restockQuantity > r.Quantity {
+            return nil, ErrInvalidRestock
+        }
+        r.RestockedQuantity = restockQuantity
+
+        left := roundCents(o.TotalPrice - o.RefundedTotal)
+        if refundAmount != nil {
+            r.RefundAmount = roundCents(*refundAmount)
+        } else {
+            i := slices.IndexFunc(o.Products, func(p OrderedProduct) bool {
+                return p.ID == r.ProductID && p.VariantID == r.VariantID
+            })
+            if i < 0 || o.Products[i].Price == 0 {
+                return nil, ErrRefundAmountRequired
+            }
+            r.RefundAmount = min(roundCents(float64(r.Quantity) * o.Products[i].Price), left)
+        }
+        if r.RefundAmount < 0 || r.RefundAmount > left {
+            return nil, ErrInvalidRefund
+        }
+        if r.RefundAmount == 0 {
+            return nil, nil
+        }
+
+        return &Refund{
+            ID: ksuid.New().String(),
+            OrderID: o.ID,
+            ReturnID: r.ID,
+            Reason: ReasonReturn,
+            Amount: r.RefundAmount,
+            Lines: []RefundLine{{
+                ProductID: r.ProductID,
+                VariantID: r.VariantID,
+                Quantity: r.Quantity,
+                Amount: r.RefundAmount,
+            }},
+            CreatedAt: r.UpdatedAt,
+        }, nil
+    })
+}
+
+// updateReturn moves the return with id to status, after apply, if set,
+// filled in the rest of the change and priced its refund.
+func (s *orderService) updateReturn(
+    ctx context.Context, id string, status ReturnStatus, note string,
+    apply func(o Order, r *Return) (*Refund, error),
+) (*Return, error) {
+    o, err := s.repository.GetOrderByReturnID(ctx, id)
+    if err != nil {
+        log.Println("failed to get order from order service: ", err)
+        return nil, err
+    }
+    i := slices.IndexFunc(o.Returns, func(r Return) bool { return r.ID == id })
+    if i < 0 {
+        return nil, ErrReturnNotFound
+    }
+    r := o.Returns[i]
+    if !slices.Contains(returnTransitions[r.Status], status) {
+        return nil, ErrReturnStatus
+    }
+
+    r.Status = status
+    r.UpdatedAt = time.Now().UTC()
+    r.History = append(r.History, ReturnEvent{
+        Status: status,
+        Note: strings.TrimSpace(note),
+        CreatedAt: r.UpdatedAt,
+    })
+    var refund *Refund
+    if apply != nil {
+        if refund, err = apply(*o, &r); err != nil {
+            return nil, err
+        }
+    }
+
+    if err := s.repository.UpdateReturn(ctx, r, refund, o.UpdatedAt); err != nil {
+        log.Println("failed to update return from order service: ", err)
+        return nil, err
+    }
+    return &r, nil
+}
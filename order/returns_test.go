@@ -0,0 +1,191 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestReturnWorkflowMemory(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+    o, err := s.PostOrder(ctx, "a1", testOrder().Products, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := s.RequestReturn(ctx, "a1", o.ID, "mug", "", 1, "broken"); !errors.Is(err, ErrInvalidReturn) {
+        t.Fatalf("expected mugs that weren't shipped not to be returnable, got %v", err)
+    }
+    ship(t, s, o.ID)
+
+    if _, err := s.RequestReturn(ctx, "a2", o.ID, "mug", "", 1, "broken"); !errors.Is(err, ErrOrderNotFound) {
+        t.Fatalf("expected another account's order to be reported missing, got %v", err)
+    }
+    for _, quantity := range []uint32{0, 3} {
+        if _, err := s.RequestReturn(ctx, "a1", o.ID, "mug", "", quantity, "broken"); !errors.Is(err, ErrInvalidReturn) {
+            t.Fatalf("expected returning %d mugs to fail, got %v", quantity, err)
+        }
+    }
+
+    r, err := s.RequestReturn(ctx, "a1", o.ID, "mug", "", 2, " broken ")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if r.Status != ReturnRequested || r.Reason != "broken" || len(r.History) != 1 {
+        t.Fatalf("unexpected return %+v", r)
+    }
+    if _, err := s.RequestReturn(ctx, "a1", o.ID, "mug", "", 1, ""); !errors.Is(err, ErrInvalidReturn) {
+        t.Fatalf("expected mugs in an open return not to be returnable again, got %v", err)
+    }
+    if _, err := s.ReceiveReturn(ctx, r.ID, ""); !errors.Is(err, ErrReturnStatus) {
+        t.Fatalf("expected a requested return not to be receivable, got %v", err)
+    }
+
+    if _, err = s.ReviewReturn(ctx, r.ID, true, "ok"); err != nil {
+        t.Fatal(err)
+    }
+    if _, err = s.ReceiveReturn(ctx, r.ID, ""); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.InspectReturn(ctx, r.ID, 3, nil, ""); !errors.Is(err, ErrInvalidRestock) {
+        t.Fatalf("expected restocking more than was returned to fail, got %v", err)
+    }
+    if _, err := s.InspectReturn(ctx, r.ID, 1, floatPtr(40), ""); !errors.Is(err, ErrInvalidRefund) {
+        t.Fatalf("expected refunding more than the order total to fail, got %v", err)
+    }
+    r, err = s.InspectReturn(ctx, r.ID, 1, nil, "one chipped")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if r.Status != ReturnInspected || r.RestockedQuantity != 1 || r.RefundAmount != 19 || len(r.History) != 4 {
+        t.Fatalf("unexpected inspected return %+v", r)
+    }
+    if r.History[3].Status != ReturnInspected || r.History[3].Note != "one chipped" {
+        t.Fatalf("unexpected history %+v", r.History)
+    }
+
+    page, err := s.GetOrdersForAccount(ctx, "a1", OrderQuery{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    o = &page.Orders[0]
+    if o.RefundedTotal != 19 || len(o.Refunds) != 1 || o.Refunds[0].ReturnID != r.ID || o.Refunds[0].Reason != ReasonReturn {
+        t.Fatalf("expected the return's refund on the order, got %+v", o)
+    }
+    if len(o.Returns) != 1 || o.Returns[0].Status != ReturnInspected {
+        t.Fatalf("expected the return on the order, got %+v", o.Returns)
+    }
+    if _, err := s.CancelOrder(ctx, o.ID, ReasonOther, nil); !errors.Is(err, ErrNotCancellable) {
+        t.Fatalf("expected an order with returns not to be cancellable, got %v", err)
+    }
+}
+
+func TestRejectedReturnFreesQuantity(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+    o, err := s.PostOrder(ctx, "a1", testOrder().Products, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.CancelOrder(ctx, o.ID, ReasonOutOfStock, []OrderedProduct{{ID: "mug", Quantity: 1}}); err != nil {
+        t.Fatal(err)
+    }
+    ship(t, s, o.ID)
+
+    r, err := s.RequestReturn(ctx, "a1", o.ID, "mug", "", 1, "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.RequestReturn(ctx, "a1", o.ID, "mug", "", 1, ""); !errors.Is(err, ErrInvalidReturn) {
+        t.Fatalf("expected cancelled mugs not to be returnable, got %v", err)
+    }
+    if _, err := s.ReviewReturn(ctx, r.ID, false, "used"); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.ReviewReturn(ctx, r.ID, true, ""); !errors.Is(err, ErrReturnStatus) {
+        t.Fatalf("expected a rejected return to be final, got %v", err)
+    }
+    if _, err := s.RequestReturn(ctx, "a1", o.ID, "mug", "", 1, ""); err != nil {
+        t.Fatalf("expected the rejected mug to be returnable again, got %v", err)
+    }
+    if _, err := s.ReviewReturn(ctx, "unknown", true, ""); !errors.Is(err, ErrReturnNotFound) {
+        t.Fatalf("expected an unknown return to be reported, got %v", err)
+    }
+}
+
+func TestInspectReturnCapsDefaultRefund(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+    o, err := s.PostOrder(ctx, "a1", []OrderedProduct{{ID: "mug", Quantity: 1, Price: 9.5}, {ID: "old", Quantity: 1}}, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    ship(t, s, o.ID)
+
+    old, err := s.RequestReturn(ctx, "a1", o.ID, "old", "", 1, "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    s.ReviewReturn(ctx, old.ID, true, "")
+    s.ReceiveReturn(ctx, old.ID, "")
+    if _, err := s.InspectReturn(ctx, old.ID, 0, nil, ""); !errors.Is(err, ErrRefundAmountRequired) {
+        t.Fatalf("expected an unpriced line to need a refund amount, got %v", err)
+    }
+    old, err = s.InspectReturn(ctx, old.ID, 0, floatPtr(2), "")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    mug, err := s.RequestReturn(ctx, "a1", o.ID, "mug", "", 1, "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    s.ReviewReturn(ctx, mug.ID, true, "")
+    s.ReceiveReturn(ctx, mug.ID, "")
+    mug, err = s.InspectReturn(ctx, mug.ID, 1, nil, "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if old.RefundAmount != 2 || mug.RefundAmount != 7.5 {
+        t.Fatalf("expected refunds of 2 and what's left of the total, got %v and %v", old.RefundAmount, mug.RefundAmount)
+    }
+}
+
+func TestShippableLeavesOutReturns(t *testing.T) {
+    o := Order{Products: []OrderedProduct{{ID: "mug", Quantity: 3}}}
+    o.Returns = []Return{{ProductID: "mug", Quantity: 2, Status: ReturnRequested}}
+    if n := o.shippable(o.Products[0]); n != 1 {
+        t.Fatalf("expected returned mugs not to be shippable, got %d", n)
+    }
+    o.Returns[0].Status = ReturnRejected
+    if n := o.shippable(o.Products[0]); n != 3 {
+        t.Fatalf("expected rejected returns to be shippable, got %d", n)
+    }
+
+    // Returned units that came in a shipment aren't counted twice.
+    o.Shipments = []Shipment{{Status: ShipmentDelivered, Lines: []ShipmentLine{{ProductID: "mug", Quantity: 2}}}}
+    o.Returns[0].Status = ReturnReceived
+    if n := o.shippable(o.Products[0]); n != 1 {
+        t.Fatalf("expected one mug left to ship, got %d", n)
+    }
+    if n := o.returnable("mug", ""); n != 0 {
+        t.Fatalf("expected no mugs left to return, got %d", n)
+    }
+}
+
+// ship sends everything left of the order in one shipment.
+func ship(t *testing.T, s Service, orderID string) {
+    ctx := context.Background()
+    shipment, err := s.CreateShipment(ctx, orderID, "UPS", "1Z1", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.UpdateShipment(ctx, shipment.ID, ShipmentInTransit, "", ""); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func floatPtr(f float64) *float64 {
+    return &f
+}
@@ -1,17 +1,24 @@
 package order
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"log"
 	"net"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/pirateunclejack/go-grpc-graphql-microservice/account"
 	"github.com/pirateunclejack/go-grpc-graphql-microservice/catalog"
 	"github.com/pirateunclejack/go-grpc-graphql-microservice/order/pb"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type grpcServer struct {
@@ -43,6 +50,14 @@ func ListenGRPC(s Service, accountURL, catalogURL string, port int) error {
         return err
     }
 
+    return NewGRPCServer(s, accountClient, catalogClient).Serve(lis)
+}
+
+// NewGRPCServer returns a grpc server with the order service registered,
+// using the given clients to look up accounts and products.
+func NewGRPCServer(
+    s Service, accountClient *account.Client, catalogClient *catalog.Client,
+) *grpc.Server {
     serv := grpc.NewServer()
     pb.RegisterOrderServiceServer(
         serv,
@@ -55,7 +70,7 @@ func ListenGRPC(s Service, accountURL, catalogURL string, port int) error {
     )
 
     reflection.Register(serv)
-    return serv.Serve(lis)
+    return serv
 }
 
 func (s grpcServer) PostOrder(
@@ -72,78 +87,593 @@ func (s grpcServer) PostOrder(
     for _, p := range r.Products {
 		productIDs = append(productIDs, p.ProductId)
 	}
-    orderedProducts, err := s.catalogClient.GetProducts(
-        ctx, 0, 0 , productIDs, "",
-    )
+    orderedProducts, missing, err := s.catalogClient.GetProductsByIDs(ctx, productIDs)
     if err != nil {
         log.Println("failed to get products from order server: ", err)
         return nil, errors.New("products not found")
     }
+    if len(missing) != 0 {
+        return nil, fmt.Errorf("products not found: %s", strings.Join(missing, ", "))
+    }
 
+    catalogProducts := map[string]catalog.Product{}
+    for _, p := range orderedProducts {
+        catalogProducts[p.ID] = p
+    }
+
+    // Lines for the same product and variant are merged.
     products := []OrderedProduct{}
-    for _, p := range *orderedProducts {
+    for _, rp := range r.Products {
+        p := catalogProducts[rp.ProductId]
+        if rp.Quantity == 0 {
+            continue
+        }
+        if rp.VariantId == "" && len(p.Variants) != 0 {
+            return nil, errors.New("variant required")
+        }
+
+        i := slices.IndexFunc(products, func(op OrderedProduct) bool {
+            return op.ID == rp.ProductId && op.VariantID == rp.VariantId
+        })
+        if i >= 0 {
+            products[i].Quantity += rp.Quantity
+            continue
+        }
+
         product := OrderedProduct{
             ID: p.ID,
-            Quantity: 0,
-            Price: p.Price,
-            Name: p.Name,
-            Description: p.Description,
+            VariantID: rp.VariantId,
+            Quantity: rp.Quantity,
         }
-
-        for _, rp := range r.Products {
-            if rp.ProductId == p.ID {
-                product.Quantity = rp.Quantity
-                break
-            }
+        if !describeLine(&product, p) {
+            return nil, errors.New("variant not found")
         }
+        products = append(products, product)
+    }
 
-        if product.Quantity != 0 {
-            products = append(products, product)
+    shipping, err := s.resolveAddress(ctx, r.AccountId, r.ShippingAddress)
+    if err != nil {
+        return nil, err
+    }
+    if r.ShippingAddress == nil {
+        if shipping, err = s.defaultAddress(ctx, r.AccountId); err != nil {
+            return nil, err
         }
     }
+    billing, err := s.resolveAddress(ctx, r.AccountId, r.BillingAddress)
+    if err != nil {
+        return nil, err
+    }
 
-    order, err := s.service.PostOrder(ctx, r.AccountId, products)
+    reserved, err := s.reserveStock(ctx, products)
+    if err != nil {
+        return nil, err
+    }
+    order, err := s.service.PostOrder(ctx, r.AccountId, products, shipping, billing)
     if err != nil {
         log.Println("failed to post order from order server: ", err)
+        s.releaseStock(ctx, reserved)
         return nil, errors.New("could not post order")
     }
 
-    orderProto := &pb.Order {
-        Id: order.ID,
-        AccountId: order.AccountID,
-        TotalPrice: order.TotalPrice,
-        Products: []*pb.Order_OrderProduct{},
+    return &pb.PostOrderResponse{
+        Order: orderToProto(order),
+    }, nil
+}
+
+// reserveStock takes the ordered units of variants out of the catalog's
+// stock and returns the lines it took them for; products without variants
+// don't track stock. If one can't be reserved, those already taken are put
+// back.
+func (s grpcServer) reserveStock(
+    ctx context.Context, products []OrderedProduct,
+) ([]OrderedProduct, error) {
+    reserved := []OrderedProduct{}
+    for _, p := range products {
+        if p.VariantID == "" {
+            continue
+        }
+        _, err := s.catalogClient.ReserveVariantStock(ctx, p.ID, p.VariantID, p.Quantity)
+        if err != nil {
+            log.Println("failed to reserve variant stock from order server: ", err)
+            s.releaseStock(ctx, reserved)
+            if status.Code(err) == codes.FailedPrecondition {
+                return nil, fmt.Errorf("out of stock: %s", p.SKU)
+            }
+            return nil, errors.New("could not reserve stock")
+        }
+        reserved = append(reserved, p)
+    }
+    return reserved, nil
+}
+
+// releaseStock puts the units of products back into the catalog's stock.
+// It keeps going past failures and returns the first one.
+func (s grpcServer) releaseStock(ctx context.Context, products []OrderedProduct) error {
+    var first error
+    for _, p := range products {
+        _, err := s.catalogClient.RestockVariant(ctx, p.ID, p.VariantID, p.Quantity)
+        if err != nil {
+            log.Println("failed to release variant stock from order server: ", err)
+            if first == nil {
+                first = err
+            }
+        }
+    }
+    return first
+}
+
+// restockVariants puts the units of variant lines back into the catalog's
+// stock. If one can't be put back, those already put back are taken out
+// again.
+func (s grpcServer) restockVariants(ctx context.Context, products []OrderedProduct) error {
+    for i, p := range products {
+        _, err := s.catalogClient.RestockVariant(ctx, p.ID, p.VariantID, p.Quantity)
+        if err != nil {
+            log.Println("failed to restock variant from order server: ", err)
+            s.takeStock(ctx, products[:i])
+            return err
+        }
+    }
+    return nil
+}
+
+// takeStock takes the units of variant lines out of the catalog's stock
+// again, undoing restockVariants. Failures are only logged.
+func (s grpcServer) takeStock(ctx context.Context, products []OrderedProduct) {
+    for _, p := range products {
+        _, err := s.catalogClient.ReserveVariantStock(ctx, p.ID, p.VariantID, p.Quantity)
+        if err != nil {
+            log.Println("failed to take back restocked units from order server: ", err)
+        }
     }
+}
 
-    orderProto.CreatedAt, _ = order.CreatedAt.MarshalBinary()
-    for _, p := range order.Products {
-        orderProto.Products = append(orderProto.Products, &pb.Order_OrderProduct{
-            Id: p.ID,
+// resolveAddress looks up the account's address book entry c picks, or
+// validates the address c gives. It returns nil for a nil c.
+func (s grpcServer) resolveAddress(
+    ctx context.Context, accountID string, c *pb.AddressChoice,
+) (*Address, error) {
+    switch source := c.GetSource().(type) {
+    case *pb.AddressChoice_AddressId:
+        a, err := s.accountClient.GetAddress(ctx, accountID, source.AddressId)
+        if err != nil {
+            log.Println("failed to get address from order server: ", err)
+            return nil, account.ErrAddressNotFound
+        }
+        return addressFromAccount(*a), nil
+    case *pb.AddressChoice_Address:
+        p := source.Address
+        a := account.Address{
             Name: p.Name,
-            Description: p.Description,
-            Price: p.Price,
-            Quantity: p.Quantity,
-        })
+            Company: p.Company,
+            Line1: p.Line1,
+            Line2: p.Line2,
+            City: p.City,
+            Region: p.Region,
+            PostalCode: p.PostalCode,
+            Country: p.Country,
+            Phone: p.Phone,
+        }.Normalize()
+        if err := a.Validate(); err != nil {
+            return nil, err
+        }
+        return addressFromAccount(a), nil
     }
+    return nil, nil
+}
 
-    return &pb.PostOrderResponse{
-        Order: orderProto,
-    }, nil
+// defaultAddress returns the account's default address, or nil if its
+// address book is empty.
+func (s grpcServer) defaultAddress(ctx context.Context, accountID string) (*Address, error) {
+    addresses, err := s.accountClient.GetAddresses(ctx, accountID)
+    if err != nil {
+        log.Println("failed to get addresses from order server: ", err)
+        return nil, errors.New("could not get addresses")
+    }
+    for _, a := range addresses {
+        if a.IsDefault {
+            return addressFromAccount(a), nil
+        }
+    }
+    return nil, nil
+}
+
+func addressFromAccount(a account.Address) *Address {
+    return &Address{
+        Name: a.Name,
+        Company: a.Company,
+        Line1: a.Line1,
+        Line2: a.Line2,
+        City: a.City,
+        Region: a.Region,
+        PostalCode: a.PostalCode,
+        Country: a.Country,
+        Phone: a.Phone,
+    }
+}
+
+func addressToProto(a *Address) *pb.OrderAddress {
+    if a == nil {
+        return nil
+    }
+    return &pb.OrderAddress{
+        Name: a.Name,
+        Company: a.Company,
+        Line1: a.Line1,
+        Line2: a.Line2,
+        City: a.City,
+        Region: a.Region,
+        PostalCode: a.PostalCode,
+        Country: a.Country,
+        Phone: a.Phone,
+    }
 }
 
 func (s grpcServer) GetOrdersForAccount(
     ctx context.Context, r *pb.GetOrdersForAccountRequest,
 ) (*pb.GetOrdersForAccountResponse, error) {
-    accountOrders, err := s.service.GetOrdersForAccount(
-        ctx, r.AccountId,
-)
+    q, err := orderQueryFromProto(r.Filter, r.Descending, r.First, r.After)
+    if err != nil {
+        return nil, err
+    }
+    page, err := s.service.GetOrdersForAccount(ctx, r.AccountId, q)
     if err != nil {
         log.Println("failed to get orders for account from order server: ", err)
         return nil, err
     }
 
+    orders, err := s.describeOrders(ctx, page.Orders)
+    if err != nil {
+        return nil, err
+    }
+    return &pb.GetOrdersForAccountResponse{
+        Orders: orders,
+        HasMore: page.HasMore,
+    }, nil
+}
+
+// streamBatch is how many streamed orders are described with one catalog
+// request before they are sent.
+const streamBatch = 100
+
+// StreamOrdersForAccount sends the account's orders as they are read, so
+// neither side holds all of them.
+func (s grpcServer) StreamOrdersForAccount(
+    r *pb.StreamOrdersForAccountRequest, stream pb.OrderService_StreamOrdersForAccountServer,
+) error {
+    ctx := stream.Context()
+    q, err := orderQueryFromProto(r.Filter, r.Descending, 0, r.After)
+    if err != nil {
+        return err
+    }
+
+    batch := []Order{}
+    send := func() error {
+        orders, err := s.describeOrders(ctx, batch)
+        if err != nil {
+            return err
+        }
+        for _, o := range orders {
+            if err := stream.Send(o); err != nil {
+                log.Println("failed to send order from order server: ", err)
+                return err
+            }
+        }
+        batch = batch[:0]
+        return nil
+    }
+    err = s.service.StreamOrdersForAccount(ctx, r.AccountId, q, func(o Order) error {
+        batch = append(batch, o)
+        if len(batch) < streamBatch {
+            return nil
+        }
+        return send()
+    })
+    if err != nil {
+        log.Println("failed to stream orders for account from order server: ", err)
+        return err
+    }
+    if len(batch) == 0 {
+        return nil
+    }
+    return send()
+}
+
+// CancelOrder puts the cancelled units of variants back into the catalog's
+// stock before cancelling them, so a failed restock leaves the order as it
+// was and the cancellation can be retried. If the cancellation fails, e.g.
+// because the order changed meanwhile, the units are taken out of the
+// stock again.
+func (s grpcServer) CancelOrder(
+    ctx context.Context, r *pb.CancelOrderRequest,
+) (*pb.CancelOrderResponse, error) {
+    lines := []OrderedProduct{}
+    for _, l := range r.Lines {
+        lines = append(lines, OrderedProduct{
+            ID: l.ProductId,
+            VariantID: l.VariantId,
+            Quantity: l.Quantity,
+        })
+    }
+
+    c, err := s.service.PlanCancellation(ctx, r.Id, CancelReason(r.Reason), lines)
+    if err != nil {
+        log.Println("failed to cancel order from order server: ", err)
+        return nil, err
+    }
+    restocked := refundedVariants(&c.Refund)
+    if err := s.restockVariants(ctx, restocked); err != nil {
+        return nil, err
+    }
+
+    o, err := s.service.CommitCancellation(ctx, c)
+    if err != nil {
+        log.Println("failed to cancel order from order server: ", err)
+        s.takeStock(ctx, restocked)
+        return nil, err
+    }
+
+    orders, err := s.describeOrders(ctx, []Order{*o})
+    if err != nil {
+        return nil, err
+    }
+    return &pb.CancelOrderResponse{Order: orders[0]}, nil
+}
+
+// refundedVariants returns the lines of r for variants, which track stock.
+func refundedVariants(r *Refund) []OrderedProduct {
+    products := []OrderedProduct{}
+    for _, l := range r.Lines {
+        if l.VariantID != "" {
+            products = append(products, OrderedProduct{
+                ID: l.ProductID,
+                VariantID: l.VariantID,
+                Quantity: l.Quantity,
+            })
+        }
+    }
+    return products
+}
+
+func (s grpcServer) RequestReturn(
+    ctx context.Context, r *pb.RequestReturnRequest,
+) (*pb.ReturnResponse, error) {
+    ret, err := s.service.RequestReturn(
+        ctx, r.AccountId, r.OrderId, r.ProductId, r.VariantId, r.Quantity, r.Reason,
+    )
+    if err != nil {
+        log.Println("failed to request return from order server: ", err)
+        return nil, err
+    }
+    return &pb.ReturnResponse{Return: returnToProto(ret)}, nil
+}
+
+func (s grpcServer) ReviewReturn(
+    ctx context.Context, r *pb.ReviewReturnRequest,
+) (*pb.ReturnResponse, error) {
+    ret, err := s.service.ReviewReturn(ctx, r.Id, r.Approve, r.Note)
+    if err != nil {
+        log.Println("failed to review return from order server: ", err)
+        return nil, err
+    }
+    return &pb.ReturnResponse{Return: returnToProto(ret)}, nil
+}
+
+func (s grpcServer) ReceiveReturn(
+    ctx context.Context, r *pb.ReceiveReturnRequest,
+) (*pb.ReturnResponse, error) {
+    ret, err := s.service.ReceiveReturn(ctx, r.Id, r.Note)
+    if err != nil {
+        log.Println("failed to receive return from order server: ", err)
+        return nil, err
+    }
+    return &pb.ReturnResponse{Return: returnToProto(ret)}, nil
+}
+
+// InspectReturn puts the restocked units of variants back into the
+// catalog's stock before inspecting the return; products without variants
+// don't track stock. If restocking fails the return stays received, so the
+// inspection can be retried, and if the inspection fails the units are
+// taken out of the stock again.
+func (s grpcServer) InspectReturn(
+    ctx context.Context, r *pb.InspectReturnRequest,
+) (*pb.ReturnResponse, error) {
+    ret, err := s.service.GetReturn(ctx, r.Id)
+    if err != nil {
+        log.Println("failed to get return from order server: ", err)
+        return nil, err
+    }
+
+    restock := r.RestockQuantity != 0 && ret.VariantID != ""
+    if restock {
+        if err := ret.checkInspection(r.RestockQuantity); err != nil {
+            return nil, err
+        }
+        _, err := s.catalogClient.RestockVariant(ctx, ret.ProductID, ret.VariantID, r.RestockQuantity)
+        if err != nil {
+            log.Println("failed to restock variant from order server: ", err)
+            return nil, err
+        }
+    }
+
+    inspected, err := s.service.InspectReturn(ctx, r.Id, r.RestockQuantity, r.RefundAmount, r.Note)
+    if err != nil {
+        log.Println("failed to inspect return from order server: ", err)
+        if restock {
+            _, err := s.catalogClient.ReserveVariantStock(ctx, ret.ProductID, ret.VariantID, r.RestockQuantity)
+            if err != nil {
+                log.Println("failed to take back restocked units from order server: ", err)
+            }
+        }
+        return nil, err
+    }
+    return &pb.ReturnResponse{Return: returnToProto(inspected)}, nil
+}
+
+func (s grpcServer) CreateShipment(
+    ctx context.Context, r *pb.CreateShipmentRequest,
+) (*pb.ShipmentResponse, error) {
+    lines := []ShipmentLine{}
+    for _, l := range r.Lines {
+        lines = append(lines, ShipmentLine{
+            ProductID: l.ProductId,
+            VariantID: l.VariantId,
+            Quantity: l.Quantity,
+        })
+    }
+
+    shipment, err := s.service.CreateShipment(ctx, r.OrderId, r.Carrier, r.TrackingNumber, lines)
+    if err != nil {
+        log.Println("failed to create shipment from order server: ", err)
+        return nil, err
+    }
+    return &pb.ShipmentResponse{Shipment: shipmentToProto(shipment)}, nil
+}
+
+func (s grpcServer) UpdateShipment(
+    ctx context.Context, r *pb.UpdateShipmentRequest,
+) (*pb.ShipmentResponse, error) {
+    shipment, err := s.service.UpdateShipment(
+        ctx, r.Id, ShipmentStatus(r.Status), r.Carrier, r.TrackingNumber,
+    )
+    if err != nil {
+        log.Println("failed to update shipment from order server: ", err)
+        return nil, err
+    }
+    return &pb.ShipmentResponse{Shipment: shipmentToProto(shipment)}, nil
+}
+
+// GetInvoice renders the order's invoice, describing its lines from the
+// catalog like the order history does.
+func (s grpcServer) GetInvoice(
+    ctx context.Context, r *pb.GetInvoiceRequest,
+) (*pb.GetInvoiceResponse, error) {
+    invoice, err := s.service.GetInvoice(ctx, r.OrderId)
+    if err != nil {
+        log.Println("failed to get invoice from order server: ", err)
+        return nil, err
+    }
+    if err := s.describeLines(ctx, []Order{invoice.Order}); err != nil {
+        return nil, err
+    }
+
+    format := InvoiceFormat(r.Format)
+    var document bytes.Buffer
+    if err := invoice.Render(&document, format); err != nil {
+        log.Println("failed to render invoice from order server: ", err)
+        return nil, err
+    }
+    return &pb.GetInvoiceResponse{
+        InvoiceNumber: invoice.Number.String(),
+        ContentType: format.ContentType(),
+        Document: document.Bytes(),
+    }, nil
+}
+
+func (s grpcServer) SetTaxRate(
+    ctx context.Context, r *pb.SetTaxRateRequest,
+) (*pb.SetTaxRateResponse, error) {
+    effectiveFrom := time.Now().UTC()
+    if r.EffectiveFrom != nil {
+        if err := r.EffectiveFrom.CheckValid(); err != nil {
+            return nil, err
+        }
+        effectiveFrom = r.EffectiveFrom.AsTime()
+    }
+    rate, err := s.service.SetTaxRate(ctx, TaxRate{
+        Country: r.Country,
+        Rate: r.Rate,
+        EffectiveFrom: effectiveFrom,
+    })
+    if err != nil {
+        log.Println("failed to set tax rate from order server: ", err)
+        return nil, err
+    }
+    return &pb.SetTaxRateResponse{
+        Country: rate.Country,
+        Rate: rate.Rate,
+        EffectiveFrom: timestamppb.New(rate.EffectiveFrom),
+    }, nil
+}
+
+// maxSearchAccounts is the most accounts an account name search may match;
+// it stays below the account service's own cap so one more can be asked
+// for to tell when there are too many.
+const maxSearchAccounts = 500
+
+// SearchOrders looks up the accounts matching accountName first, then
+// searches their orders. It returns ErrTooManyAccounts rather than search
+// the orders of only some of them.
+func (s grpcServer) SearchOrders(
+    ctx context.Context, r *pb.SearchOrdersRequest,
+) (*pb.SearchOrdersResponse, error) {
+    q, err := orderQueryFromProto(r.Filter, r.Descending, r.First, r.After)
+    if err != nil {
+        return nil, err
+    }
+
+    accountIDs := []string{}
+    if r.AccountId != "" {
+        accountIDs = append(accountIDs, r.AccountId)
+    }
+    if name := strings.TrimSpace(r.AccountName); name != "" {
+        accounts, err := s.accountClient.SearchAccounts(ctx, name, maxSearchAccounts + 1)
+        if err != nil {
+            log.Println("failed to search accounts from order server: ", err)
+            return nil, err
+        }
+        if len(accounts) > maxSearchAccounts {
+            return nil, ErrTooManyAccounts
+        }
+        matched := []string{}
+        for _, a := range accounts {
+            if r.AccountId == "" || a.ID == r.AccountId {
+                matched = append(matched, a.ID)
+            }
+        }
+        if len(matched) == 0 {
+            return &pb.SearchOrdersResponse{Orders: []*pb.Order{}}, nil
+        }
+        accountIDs = matched
+    }
+
+    page, err := s.service.SearchOrders(ctx, accountIDs, q)
+    if err != nil {
+        log.Println("failed to search orders from order server: ", err)
+        return nil, err
+    }
+
+    orders, err := s.describeOrders(ctx, page.Orders)
+    if err != nil {
+        return nil, err
+    }
+    return &pb.SearchOrdersResponse{
+        Orders: orders,
+        HasMore: page.HasMore,
+    }, nil
+}
+
+// describeOrders fills in the lines of orders from the catalog, see
+// describeLines, and converts them.
+func (s grpcServer) describeOrders(ctx context.Context, orders []Order) ([]*pb.Order, error) {
+    if err := s.describeLines(ctx, orders); err != nil {
+        return nil, err
+    }
+
+    protoOrders := []*pb.Order{}
+    for _, o := range orders {
+        protoOrders = append(protoOrders, orderToProto(&o))
+    }
+    return protoOrders, nil
+}
+
+// describeLines fills in the lines of orders from the catalog. Products
+// removed from the catalog since are reported missing; their lines are
+// left without a description.
+func (s grpcServer) describeLines(ctx context.Context, orders []Order) error {
     productIDMap := map[string]bool{}
-    for _, o := range accountOrders {
+    for _, o := range orders {
         for _, p := range o.Products {
             productIDMap[p.ID] = true
         }
@@ -152,46 +682,272 @@ func (s grpcServer) GetOrdersForAccount(
     for id := range productIDMap {
         productIDs = append(productIDs, id)
     }
-    products, err := s.catalogClient.GetProducts(
-        ctx, 0, 0, productIDs, "",
-    )
+    productList, _, err := s.catalogClient.GetProductsByIDs(ctx, productIDs)
     if err != nil {
         log.Println("failed to get products from order server: ", err)
-        return nil, err
+        return err
+    }
+    products := map[string]catalog.Product{}
+    for _, p := range productList {
+        products[p.ID] = p
     }
 
-    orders := []*pb.Order{}
-    for _, o := range accountOrders {
-        fmt.Println("accountOrders: ", accountOrders)
-        op := &pb.Order{
-            AccountId: o.AccountID,
-            Id: o.ID,
-            TotalPrice: o.TotalPrice,
-            Products: []*pb.Order_OrderProduct{},
+    for _, o := range orders {
+        for i := range o.Products {
+            // Lines keep the price they were ordered at; only the ones
+            // stored without it show the catalog price.
+            price := o.Products[i].Price
+            if p, ok := products[o.Products[i].ID]; ok {
+                describeLine(&o.Products[i], p)
+            }
+            if price != 0 {
+                o.Products[i].Price = price
+            }
         }
-        op.CreatedAt, _ = o.CreatedAt.MarshalBinary()
+    }
+    return nil
+}
 
-        for _, product := range o.Products {
-            for _, p := range *products {
-                if p.ID == product.ID {
-                    product.Name = p.Name
-                    product.Description = p.Description
-                    product.Price = p.Price
-                    break
-                }
-            }
-            op.Products = append(op.Products, &pb.Order_OrderProduct{
-                Id: product.ID,
-                Name: product.Name,
-                Description: product.Description,
-                Quantity: product.Quantity,
-                Price: product.Price,
+func orderQueryFromProto(
+    f *pb.OrderFilter, descending bool, first uint32, after string,
+) (OrderQuery, error) {
+    q := OrderQuery{
+        Descending: descending,
+        First: int(first),
+        After: after,
+    }
+    if f == nil {
+        return q, nil
+    }
+
+    createdFrom, err := timeFromProto(f.CreatedFrom, f.LegacyCreatedFrom)
+    if err != nil {
+        return q, ErrInvalidFilter
+    }
+    createdTo, err := timeFromProto(f.CreatedTo, f.LegacyCreatedTo)
+    if err != nil {
+        return q, ErrInvalidFilter
+    }
+    q.Filter = OrderFilter{
+        CreatedFrom: createdFrom,
+        CreatedTo: createdTo,
+        MinTotal: f.MinTotal,
+        MaxTotal: f.MaxTotal,
+        ProductID: f.ProductId,
+    }
+    for _, status := range f.Statuses {
+        q.Filter.Statuses = append(q.Filter.Statuses, Status(status))
+    }
+    return q, nil
+}
+
+func orderToProto(o *Order) *pb.Order {
+    p := &pb.Order{
+        Id: o.ID,
+        AccountId: o.AccountID,
+        TotalPrice: o.TotalPrice,
+        Status: pb.OrderStatus(o.Status),
+        CreatedAt: timestamppb.New(o.CreatedAt),
+        UpdatedAt: timestamppb.New(o.UpdatedAt),
+        RefundedTotal: o.RefundedTotal,
+        Products: []*pb.Order_OrderProduct{},
+        Refunds: []*pb.Refund{},
+        Returns: []*pb.Return{},
+        Shipments: []*pb.Shipment{},
+    }
+    p.LegacyCreatedAt = legacyTimeToProto(&o.CreatedAt)
+
+    for _, refund := range o.Refunds {
+        r := &pb.Refund{
+            Id: refund.ID,
+            ReturnId: refund.ReturnID,
+            Reason: pb.CancelReason(refund.Reason),
+            Amount: refund.Amount,
+            CreatedAt: timestamppb.New(refund.CreatedAt),
+            Lines: []*pb.Refund_Line{},
+        }
+        for _, l := range refund.Lines {
+            r.Lines = append(r.Lines, &pb.Refund_Line{
+                ProductId: l.ProductID,
+                VariantId: l.VariantID,
+                Quantity: l.Quantity,
+                Amount: l.Amount,
             })
         }
-        orders = append(orders, op)
+        p.Refunds = append(p.Refunds, r)
     }
-    fmt.Println("orders: ", orders)
-    return &pb.GetOrdersForAccountResponse{
-        Orders: orders,
-    }, nil
+    for i := range o.Returns {
+        p.Returns = append(p.Returns, returnToProto(&o.Returns[i]))
+    }
+    for i := range o.Shipments {
+        p.Shipments = append(p.Shipments, shipmentToProto(&o.Shipments[i]))
+    }
+    p.ShippingAddress = addressToProto(o.ShippingAddress)
+    p.BillingAddress = addressToProto(o.BillingAddress)
+    p.TaxRate = o.TaxRate
+    if o.Invoice != nil {
+        p.InvoiceNumber = o.Invoice.String()
+    }
+
+    for _, product := range o.Products {
+        p.Products = append(p.Products, &pb.Order_OrderProduct{
+            Id: product.ID,
+            VariantId: product.VariantID,
+            Sku: product.SKU,
+            Name: product.Name,
+            Description: product.Description,
+            Price: product.Price,
+            Quantity: product.Quantity,
+            CancelledQuantity: product.CancelledQuantity,
+        })
+    }
+    return p
+}
+
+func returnToProto(r *Return) *pb.Return {
+    p := &pb.Return{
+        Id: r.ID,
+        OrderId: r.OrderID,
+        ProductId: r.ProductID,
+        VariantId: r.VariantID,
+        Quantity: r.Quantity,
+        Reason: r.Reason,
+        Status: pb.ReturnStatus(r.Status),
+        RestockedQuantity: r.RestockedQuantity,
+        RefundAmount: r.RefundAmount,
+        CreatedAt: timestamppb.New(r.CreatedAt),
+        UpdatedAt: timestamppb.New(r.UpdatedAt),
+        History: []*pb.Return_Event{},
+    }
+    for _, e := range r.History {
+        p.History = append(p.History, &pb.Return_Event{
+            Status: pb.ReturnStatus(e.Status),
+            Note: e.Note,
+            CreatedAt: timestamppb.New(e.CreatedAt),
+        })
+    }
+    return p
+}
+
+func shipmentToProto(s *Shipment) *pb.Shipment {
+    p := &pb.Shipment{
+        Id: s.ID,
+        OrderId: s.OrderID,
+        Carrier: s.Carrier,
+        TrackingNumber: s.TrackingNumber,
+        Status: pb.ShipmentStatus(s.Status),
+        CreatedAt: timestamppb.New(s.CreatedAt),
+        UpdatedAt: timestamppb.New(s.UpdatedAt),
+        ShippedAt: timeToProto(s.ShippedAt),
+        DeliveredAt: timeToProto(s.DeliveredAt),
+        Lines: []*pb.Shipment_Line{},
+    }
+    for _, l := range s.Lines {
+        p.Lines = append(p.Lines, &pb.Shipment_Line{
+            ProductId: l.ProductID,
+            VariantId: l.VariantID,
+            Quantity: l.Quantity,
+        })
+    }
+    return p
+}
+
+// timeFromProto returns ts, or, for peers that only send the deprecated
+// field, legacy decoded with time.Time.UnmarshalBinary. Neither set is no
+// time.
+func timeFromProto(ts *timestamppb.Timestamp, legacy []byte) (*time.Time, error) {
+    if ts != nil {
+        if err := ts.CheckValid(); err != nil {
+            return nil, err
+        }
+        t := ts.AsTime()
+        return &t, nil
+    }
+    if len(legacy) == 0 {
+        return nil, nil
+    }
+    t := time.Time{}
+    if err := t.UnmarshalBinary(legacy); err != nil {
+        return nil, err
+    }
+    return &t, nil
+}
+
+// timeToProto returns t as a Timestamp; nil is unset.
+func timeToProto(t *time.Time) *timestamppb.Timestamp {
+    if t == nil {
+        return nil
+    }
+    return timestamppb.New(*t)
+}
+
+// legacyTimeToProto encodes t for the deprecated bytes fields; nil is
+// empty.
+func legacyTimeToProto(t *time.Time) []byte {
+    if t == nil {
+        return nil
+    }
+    b, _ := t.MarshalBinary()
+    return b
+}
+
+// GetRelatedProducts returns the products bought together with the
+// product, then, if there are fewer than requested, fills up with similar
+// catalog products so products nobody ordered with others yet still get
+// recommendations.
+func (s grpcServer) GetRelatedProducts(
+    ctx context.Context, r *pb.GetRelatedProductsRequest,
+) (*pb.GetRelatedProductsResponse, error) {
+    take := relatedTake(int(r.Take))
+    related, err := s.service.GetRelatedProducts(ctx, r.ProductId, take)
+    if err != nil {
+        log.Println("failed to get related products from order server: ", err)
+        return nil, err
+    }
+
+    if len(related) < take {
+        similar, err := s.catalogClient.GetSimilarProducts(ctx, r.ProductId, take)
+        if err != nil {
+            log.Println("failed to get similar products from order server: ", err)
+        }
+        for _, p := range similar {
+            if len(related) == take {
+                break
+            }
+            if slices.ContainsFunc(related, func(rp RelatedProduct) bool { return rp.ID == p.ID }) {
+                continue
+            }
+            related = append(related, RelatedProduct{ID: p.ID, Source: SourceSimilar})
+        }
+    }
+
+    res := &pb.GetRelatedProductsResponse{Products: []*pb.RelatedProduct{}}
+    for _, p := range related {
+        res.Products = append(res.Products, &pb.RelatedProduct{
+            ProductId: p.ID,
+            Score: p.Score,
+            Source: pb.RelatedProductSource(p.Source),
+        })
+    }
+    return res, nil
+}
+
+// describeLine copies name, description, price and, for variant lines, the
+// sku from the catalog product. It returns false if the line's variant no
+// longer exists; the line then keeps the product price.
+func describeLine(line *OrderedProduct, p catalog.Product) bool {
+    line.Name = p.Name
+    line.Description = p.Description
+    line.Price = p.Price
+    if line.VariantID == "" {
+        return true
+    }
+
+    v, ok := p.Variant(line.VariantID)
+    if !ok {
+        return false
+    }
+    line.SKU = v.SKU
+    line.Price = p.VariantPrice(*v)
+    return true
 }
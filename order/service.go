@@ -10,27 +10,88 @@ import (
 
 type Service interface {
     PostOrder(
-        ctx context.Context, accountID string, products []OrderedProduct,
+        ctx context.Context, accountID string, products []OrderedProduct, shipping, billing *Address,
         ) (*Order, error)
     GetOrdersForAccount(
-        ctx context.Context, accountID string,
-        ) ([]Order, error)
+        ctx context.Context, accountID string, q OrderQuery,
+        ) (*OrderPage, error)
+    SearchOrders(
+        ctx context.Context, accountIDs []string, q OrderQuery,
+        ) (*OrderPage, error)
+    StreamOrdersForAccount(
+        ctx context.Context, accountID string, q OrderQuery, fn func(Order) error,
+        ) error
+    GetRelatedProducts(ctx context.Context, productID string, take int) ([]RelatedProduct, error)
+    RebuildRecommendations(ctx context.Context) error
+    CancelOrder(
+        ctx context.Context, id string, reason CancelReason, lines []OrderedProduct,
+        ) (*Order, error)
+    PlanCancellation(
+        ctx context.Context, id string, reason CancelReason, lines []OrderedProduct,
+        ) (*Cancellation, error)
+    CommitCancellation(ctx context.Context, c *Cancellation) (*Order, error)
+    RequestReturn(
+        ctx context.Context, accountID, orderID, productID, variantID string, quantity uint32, reason string,
+        ) (*Return, error)
+    GetReturn(ctx context.Context, id string) (*Return, error)
+    ReviewReturn(ctx context.Context, id string, approve bool, note string) (*Return, error)
+    ReceiveReturn(ctx context.Context, id, note string) (*Return, error)
+    InspectReturn(
+        ctx context.Context, id string, restockQuantity uint32, refundAmount *float64, note string,
+        ) (*Return, error)
+    CreateShipment(
+        ctx context.Context, orderID, carrier, trackingNumber string, lines []ShipmentLine,
+        ) (*Shipment, error)
+    UpdateShipment(
+        ctx context.Context, id string, status ShipmentStatus, carrier, trackingNumber string,
+        ) (*Shipment, error)
+    GetInvoice(ctx context.Context, orderID string) (*Invoice, error)
+    SetTaxRate(ctx context.Context, r TaxRate) (*TaxRate, error)
 }
 
+type Status int
+
+const (
+    StatusPlaced Status = iota
+    StatusCancelled
+    // The shipping statuses follow from the order's shipments.
+    StatusPartiallyShipped
+    StatusShipped
+    StatusDelivered
+)
+
 type Order struct {
     ID          string
     CreatedAt   time.Time
+    UpdatedAt   time.Time
     TotalPrice  float64
     AccountID   string
+    Status      Status
     Products    []OrderedProduct
+    // RefundedTotal is the part of TotalPrice paid back by Refunds.
+    RefundedTotal float64
+    Refunds       []Refund
+    Returns       []Return
+    Shipments     []Shipment
+    // The addresses are nil on orders placed before they were recorded.
+    ShippingAddress *Address
+    BillingAddress  *Address
+    // Invoice is numbered when the order is stored; it's nil on orders
+    // placed before invoices. TaxRate is the part of the prices that is
+    // tax, the rate of the billing country when the order was placed.
+    Invoice *InvoiceNumber
+    TaxRate float64
 }
 
 type OrderedProduct struct {
     ID          string
+    VariantID   string
+    SKU         string
     Name        string
     Description string
     Price       float64
     Quantity    uint32
+    CancelledQuantity uint32
 }
 
 type orderService struct {
@@ -41,14 +102,38 @@ func NewService(r Repository) Service {
     return &orderService{r}
 }
 
+// PostOrder places an order shipped to shipping and billed to billing, or
+// to shipping if billing is nil.
 func (s *orderService) PostOrder(
-    ctx context.Context, accountID string, products []OrderedProduct,
+    ctx context.Context, accountID string, products []OrderedProduct, shipping, billing *Address,
 ) (*Order, error) {
+    if billing == nil {
+        billing = shipping
+    }
+    // The order keeps copies of the addresses.
+    if shipping != nil {
+        a := *shipping
+        shipping = &a
+    }
+    if billing != nil {
+        a := *billing
+        billing = &a
+    }
+    now := time.Now().UTC()
+    rate, err := s.taxRate(ctx, billing, now)
+    if err != nil {
+        return nil, err
+    }
     o := &Order{
         ID: ksuid.New().String(),
-        CreatedAt: time.Now().UTC(),
+        CreatedAt: now,
+        UpdatedAt: now,
         AccountID: accountID,
+        Status: StatusPlaced,
         Products:   products,
+        ShippingAddress: shipping,
+        BillingAddress: billing,
+        TaxRate: rate,
     }
 
     o.TotalPrice = 0.0
@@ -56,17 +141,80 @@ func (s *orderService) PostOrder(
         o.TotalPrice += float64(p.Quantity) * p.Price
     }
 
-    err := s.repository.PutOrder(ctx, *o)
+    err = s.repository.PutOrder(ctx, o)
     if err != nil {
         log.Println("failed to put order from order service: ", err)
         return nil, err
     }
+    s.recordCoPurchases(ctx, *o)
 
     return o, nil
 }
 
+// GetOrdersForAccount returns a page of the account's orders matching
+// q.Filter.
 func (s *orderService) GetOrdersForAccount(
-    ctx context.Context, accountID string,
-) ([]Order, error) {
-    return s.repository.GetOrdersForAccount(ctx, accountID)
+    ctx context.Context, accountID string, q OrderQuery,
+) (*OrderPage, error) {
+    return fetchPage(q, func(q OrderQuery) ([]Order, error) {
+        orders, err := s.repository.GetOrdersForAccount(ctx, accountID, q)
+        if err != nil {
+            log.Println("failed to get orders for account from order service: ", err)
+        }
+        return orders, err
+    })
+}
+
+// StreamOrdersForAccount calls fn with every order of the account matching
+// q.Filter as it is read, for accounts with too many orders to page through.
+// q.First is ignored.
+func (s *orderService) StreamOrdersForAccount(
+    ctx context.Context, accountID string, q OrderQuery, fn func(Order) error,
+) error {
+    q.First = 0
+    q, err := q.normalize()
+    if err != nil {
+        return err
+    }
+    err = s.repository.StreamOrdersForAccount(ctx, accountID, q, fn)
+    if err != nil {
+        log.Println("failed to stream orders for account from order service: ", err)
+    }
+    return err
+}
+
+// SearchOrders pages through the orders of accountIDs, or of every account
+// if there are none, like GetOrdersForAccount.
+func (s *orderService) SearchOrders(
+    ctx context.Context, accountIDs []string, q OrderQuery,
+) (*OrderPage, error) {
+    return fetchPage(q, func(q OrderQuery) ([]Order, error) {
+        orders, err := s.repository.SearchOrders(ctx, accountIDs, q)
+        if err != nil {
+            log.Println("failed to search orders from order service: ", err)
+        }
+        return orders, err
+    })
+}
+
+// fetchPage normalizes q and asks fetch for one order more than the page
+// holds to tell whether there are more.
+func fetchPage(q OrderQuery, fetch func(OrderQuery) ([]Order, error)) (*OrderPage, error) {
+    q, err := q.normalize()
+    if err != nil {
+        return nil, err
+    }
+
+    first := q.First
+    q.First++
+    orders, err := fetch(q)
+    if err != nil {
+        return nil, err
+    }
+
+    page := &OrderPage{Orders: orders}
+    if len(orders) > first {
+        page.Orders, page.HasMore = orders[:first], true
+    }
+    return page, nil
 }
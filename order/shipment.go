@@ -0,0 +1,220 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+var (
+    ErrShipmentNotFound = errors.New("shipment not found")
+    ErrInvalidShipment  = errors.New("shipped quantities must be positive and not exceed the ordered ones left after cancellations and other shipments")
+    ErrShipmentStatus   = errors.New("shipment can't move to that status from its current one")
+)
+
+type ShipmentStatus int
+
+const (
+    // ShipmentPending is packed but not yet handed to the carrier.
+    ShipmentPending ShipmentStatus = iota
+    ShipmentInTransit
+    ShipmentOutForDelivery
+    ShipmentDelivered
+    // ShipmentException is a delivery problem reported by the carrier,
+    // e.g. a damaged parcel or a failed delivery attempt.
+    ShipmentException
+)
+
+// shipmentTransitions lists the statuses each status can move to.
+var shipmentTransitions = map[ShipmentStatus][]ShipmentStatus{
+    ShipmentPending:        {ShipmentInTransit, ShipmentOutForDelivery, ShipmentDelivered},
+    ShipmentInTransit:      {ShipmentOutForDelivery, ShipmentDelivered, ShipmentException},
+    ShipmentOutForDelivery: {ShipmentInTransit, ShipmentDelivered, ShipmentException},
+    ShipmentException:      {ShipmentInTransit, ShipmentOutForDelivery, ShipmentDelivered},
+}
+
+// Shipment is a parcel covering some quantities of an order's lines.
+type Shipment struct {
+    ID             string
+    OrderID        string
+    Carrier        string
+    TrackingNumber string
+    Status         ShipmentStatus
+    Lines          []ShipmentLine
+    CreatedAt      time.Time
+    UpdatedAt      time.Time
+    // ShippedAt is set once the shipment leaves ShipmentPending and
+    // DeliveredAt once it's delivered.
+    ShippedAt   *time.Time
+    DeliveredAt *time.Time
+}
+
+type ShipmentLine struct {
+    ProductID string
+    VariantID string
+    Quantity  uint32
+}
+
+// allocated is the quantity of the order's line for productID and
+// variantID covered by shipments, and, if shippedOnly is set, by the ones
+// that left the warehouse.
+func (o Order) allocated(productID, variantID string, shippedOnly bool) uint32 {
+    allocated := uint32(0)
+    for _, s := range o.Shipments {
+        if shippedOnly && s.Status == ShipmentPending {
+            continue
+        }
+        for _, l := range s.Lines {
+            if l.ProductID == productID && l.VariantID == variantID {
+                allocated += l.Quantity
+            }
+        }
+    }
+    return allocated
+}
+
+// shippable is the quantity of the order's line that can still go into a
+// shipment: what isn't cancelled, less what is in shipments or, if they
+// hold more, in returns that weren't rejected, so no returned unit is
+// shipped again.
+func (o Order) shippable(p OrderedProduct) uint32 {
+    taken := max(o.allocated(p.ID, p.VariantID, false), o.returned(p.ID, p.VariantID))
+    return p.remaining() - min(p.remaining(), taken)
+}
+
+// shippingStatus is the status of o following from its shipments: placed
+// until one leaves the warehouse, then partially shipped until all that
+// isn't cancelled did, and delivered once every shipment is.
+func (o Order) shippingStatus() Status {
+    shipped, partly := true, false
+    for _, p := range o.Products {
+        n := o.allocated(p.ID, p.VariantID, true)
+        if n < p.remaining() {
+            shipped = false
+        }
+        if n != 0 {
+            partly = true
+        }
+    }
+    switch {
+    case !partly:
+        return o.Status
+    case !shipped:
+        return StatusPartiallyShipped
+    }
+    for _, s := range o.Shipments {
+        if s.Status != ShipmentDelivered {
+            return StatusShipped
+        }
+    }
+    return StatusDelivered
+}
+
+// CreateShipment packs the given quantities of the order's lines, or
+// everything not yet in a shipment or return if lines is empty, into a
+// pending shipment.
+func (s *orderService) CreateShipment(
+    ctx context.Context, orderID, carrier, trackingNumber string, lines []ShipmentLine,
+) (*Shipment, error) {
+    o, err := s.repository.GetOrderByID(ctx, orderID)
+    if err != nil {
+        log.Println("failed to get order from order service: ", err)
+        return nil, err
+    }
+    if o.Status == StatusCancelled {
+        return nil, ErrInvalidShipment
+    }
+
+    shipment := Shipment{
+        ID: ksuid.New().String(),
+        OrderID: o.ID,
+        Carrier: strings.TrimSpace(carrier),
+        TrackingNumber: strings.TrimSpace(trackingNumber),
+        Status: ShipmentPending,
+        Lines: []ShipmentLine{},
+        CreatedAt: time.Now().UTC(),
+    }
+    shipment.UpdatedAt = shipment.CreatedAt
+    if len(lines) == 0 {
+        for _, p := range o.Products {
+            if left := o.shippable(p); left != 0 {
+                lines = append(lines, ShipmentLine{ProductID: p.ID, VariantID: p.VariantID, Quantity: left})
+            }
+        }
+    }
+    for _, l := range lines {
+        i := slices.IndexFunc(shipment.Lines, func(sl ShipmentLine) bool {
+            return sl.ProductID == l.ProductID && sl.VariantID == l.VariantID
+        })
+        if i < 0 {
+            shipment.Lines = append(shipment.Lines, ShipmentLine{ProductID: l.ProductID, VariantID: l.VariantID})
+            i = len(shipment.Lines) - 1
+        }
+        shipment.Lines[i].Quantity += l.Quantity
+    }
+    if len(shipment.Lines) == 0 {
+        return nil, ErrInvalidShipment
+    }
+    for _, l := range shipment.Lines {
+        p := slices.IndexFunc(o.Products, func(p OrderedProduct) bool {
+            return p.ID == l.ProductID && p.VariantID == l.VariantID
+        })
+        if p < 0 || l.Quantity == 0 || l.Quantity > o.shippable(o.Products[p]) {
+            return nil, ErrInvalidShipment
+        }
+    }
+
+    if err := s.repository.PutShipment(ctx, shipment, o.UpdatedAt); err != nil {
+        log.Println("failed to put shipment from order service: ", err)
+        return nil, err
+    }
+    return &shipment, nil
+}
+
+// UpdateShipment moves a shipment to status and updates the order's
+// status to follow. Empty carrier and trackingNumber keep the current
+// ones.
+func (s *orderService) UpdateShipment(
+    ctx context.Context, id string, status ShipmentStatus, carrier, trackingNumber string,
+) (*Shipment, error) {
+    o, err := s.repository.GetOrderByShipmentID(ctx, id)
+    if err != nil {
+        log.Println("failed to get order from order service: ", err)
+        return nil, err
+    }
+    i := slices.IndexFunc(o.Shipments, func(s Shipment) bool { return s.ID == id })
+    if i < 0 {
+        return nil, ErrShipmentNotFound
+    }
+    shipment := &o.Shipments[i]
+    if status != shipment.Status && !slices.Contains(shipmentTransitions[shipment.Status], status) {
+        return nil, ErrShipmentStatus
+    }
+
+    now := time.Now().UTC()
+    shipment.Status = status
+    shipment.UpdatedAt = now
+    if carrier = strings.TrimSpace(carrier); carrier != "" {
+        shipment.Carrier = carrier
+    }
+    if trackingNumber = strings.TrimSpace(trackingNumber); trackingNumber != "" {
+        shipment.TrackingNumber = trackingNumber
+    }
+    if status != ShipmentPending && shipment.ShippedAt == nil {
+        shipment.ShippedAt = &now
+    }
+    if status == ShipmentDelivered && shipment.DeliveredAt == nil {
+        shipment.DeliveredAt = &now
+    }
+
+    if err := s.repository.UpdateShipment(ctx, *shipment, o.shippingStatus(), o.UpdatedAt); err != nil {
+        log.Println("failed to update shipment from order service: ", err)
+        return nil, err
+    }
+    return shipment, nil
+}
@@ -0,0 +1,129 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestShipmentsDriveOrderStatus(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+    o, err := s.PostOrder(ctx, "a1", testOrder().Products, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for _, lines := range [][]ShipmentLine{
+        {{ProductID: "mug", Quantity: 3}},
+        {{ProductID: "mug", Quantity: 0}},
+        {{ProductID: "tea", Quantity: 1}},
+        {{ProductID: "mug", Quantity: 1}, {ProductID: "mug", Quantity: 2}},
+    } {
+        if _, err := s.CreateShipment(ctx, o.ID, "ups", "", lines); !errors.Is(err, ErrInvalidShipment) {
+            t.Fatalf("expected shipping %v to fail, got %v", lines, err)
+        }
+    }
+
+    first, err := s.CreateShipment(ctx, o.ID, " ups ", "1Z1", []ShipmentLine{{ProductID: "mug", Quantity: 1}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if first.Status != ShipmentPending || first.Carrier != "ups" || first.ShippedAt != nil {
+        t.Fatalf("unexpected shipment %+v", first)
+    }
+    // Everything that's left goes into the second parcel.
+    second, err := s.CreateShipment(ctx, o.ID, "dhl", "", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(second.Lines) != 2 || second.Lines[0].Quantity != 1 || second.Lines[1].Quantity != 1 {
+        t.Fatalf("expected the rest of the order in the second shipment, got %+v", second.Lines)
+    }
+    if _, err := s.CreateShipment(ctx, o.ID, "dhl", "", nil); !errors.Is(err, ErrInvalidShipment) {
+        t.Fatalf("expected nothing to be left to ship, got %v", err)
+    }
+
+    status := func() Status {
+        page, err := s.GetOrdersForAccount(ctx, "a1", OrderQuery{})
+        if err != nil {
+            t.Fatal(err)
+        }
+        return page.Orders[0].Status
+    }
+    if got := status(); got != StatusPlaced {
+        t.Fatalf("expected pending shipments to leave the order placed, got %v", got)
+    }
+
+    first, err = s.UpdateShipment(ctx, first.ID, ShipmentInTransit, "", "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if first.ShippedAt == nil || first.TrackingNumber != "1Z1" {
+        t.Fatalf("unexpected shipped shipment %+v", first)
+    }
+    if got := status(); got != StatusPartiallyShipped {
+        t.Fatalf("expected a partially shipped order, got %v", got)
+    }
+    if _, err := s.CancelOrder(ctx, o.ID, ReasonOther, nil); !errors.Is(err, ErrNotCancellable) {
+        t.Fatalf("expected an order with shipments not to be cancellable, got %v", err)
+    }
+
+    if _, err := s.UpdateShipment(ctx, second.ID, ShipmentInTransit, "", "JD2"); err != nil {
+        t.Fatal(err)
+    }
+    if got := status(); got != StatusShipped {
+        t.Fatalf("expected a shipped order, got %v", got)
+    }
+
+    if _, err := s.UpdateShipment(ctx, first.ID, ShipmentDelivered, "", ""); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.UpdateShipment(ctx, first.ID, ShipmentPending, "", ""); !errors.Is(err, ErrShipmentStatus) {
+        t.Fatalf("expected a delivered shipment to be final, got %v", err)
+    }
+    second, err = s.UpdateShipment(ctx, second.ID, ShipmentDelivered, "", "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if second.DeliveredAt == nil || second.TrackingNumber != "JD2" {
+        t.Fatalf("unexpected delivered shipment %+v", second)
+    }
+    if got := status(); got != StatusDelivered {
+        t.Fatalf("expected a delivered order, got %v", got)
+    }
+
+    if _, err := s.UpdateShipment(ctx, "unknown", ShipmentDelivered, "", ""); !errors.Is(err, ErrShipmentNotFound) {
+        t.Fatalf("expected an unknown shipment to be reported, got %v", err)
+    }
+}
+
+func TestShipmentsSkipCancelledQuantities(t *testing.T) {
+    ctx := context.Background()
+    s := NewService(NewMemoryRepository())
+    o, err := s.PostOrder(ctx, "a1", testOrder().Products, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.CancelOrder(ctx, o.ID, ReasonOutOfStock, []OrderedProduct{{ID: "beans", Quantity: 1}}); err != nil {
+        t.Fatal(err)
+    }
+
+    shipment, err := s.CreateShipment(ctx, o.ID, "ups", "", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(shipment.Lines) != 1 || shipment.Lines[0].ProductID != "mug" || shipment.Lines[0].Quantity != 2 {
+        t.Fatalf("expected only the mugs to be shipped, got %+v", shipment.Lines)
+    }
+    if _, err := s.UpdateShipment(ctx, shipment.ID, ShipmentDelivered, "", ""); err != nil {
+        t.Fatal(err)
+    }
+    page, err := s.GetOrdersForAccount(ctx, "a1", OrderQuery{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := page.Orders[0].Status; got != StatusDelivered {
+        t.Fatalf("expected the order to be delivered without its cancelled line, got %v", got)
+    }
+}
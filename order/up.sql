@@ -1,15 +1,181 @@
--- Create a table for orders with an ID, creation time, account ID, and total price.
+-- Create a table for orders with an ID, creation and update times, account ID, total price and status.
+-- status 0 is placed, 1 cancelled, 2 partially shipped, 3 shipped and 4 delivered; refunded_total is the part of total_price paid back by refunds.
 CREATE TABLE IF NOT EXISTS orders (
     id CHAR(27) PRIMARY KEY,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
     account_id CHAR(27) NOT NULL,
-    total_price MONEY NOT NULL
+    total_price MONEY NOT NULL,
+    status SMALLINT NOT NULL DEFAULT 0,
+    refunded_total MONEY NOT NULL DEFAULT 0
 );
 
--- Create a table for order products with an order ID, product ID, quantity, and primary key on the combination of product ID and order ID.
+-- Bring orders created before statuses up to date.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS status SMALLINT NOT NULL DEFAULT 0;
+
+-- Orders created before updated_at were last updated when they were created.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE;
+UPDATE orders SET updated_at = created_at WHERE updated_at IS NULL;
+ALTER TABLE orders ALTER COLUMN updated_at SET NOT NULL;
+
+-- Bring orders created before refunds up to date.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS refunded_total MONEY NOT NULL DEFAULT 0;
+
+-- Orders keep copies of their addresses; orders placed before have none.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_address JSONB;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS billing_address JSONB;
+
+-- Invoices are numbered from 1 each year without gaps; invoice_counters
+-- holds the last number issued. Orders placed before invoices have none.
+CREATE TABLE IF NOT EXISTS invoice_counters (
+    year SMALLINT PRIMARY KEY,
+    last_sequence INTEGER NOT NULL
+);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS invoice_year SMALLINT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS invoice_sequence INTEGER;
+CREATE UNIQUE INDEX IF NOT EXISTS orders_invoice_idx ON orders (invoice_year, invoice_sequence);
+-- tax_rate is the part of the prices that is tax, e.g. 0.19, copied from tax_rates when the order is placed.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_rate DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- Create a table for the VAT rates of billing countries; a rate applies from effective_from until the country's next one.
+-- Countries without a rate are invoiced without tax. Rates are changed by adding one with a later effective_from.
+CREATE TABLE IF NOT EXISTS tax_rates (
+    country CHAR(2) NOT NULL,
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    rate DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (country, effective_from)
+);
+INSERT INTO tax_rates (country, effective_from, rate) VALUES
+    ('AT', '1970-01-01', 0.20),
+    ('BE', '1970-01-01', 0.21),
+    ('DE', '1970-01-01', 0.19),
+    ('DK', '1970-01-01', 0.25),
+    ('ES', '1970-01-01', 0.21),
+    ('FI', '1970-01-01', 0.24),
+    ('FI', '2024-09-01', 0.255),
+    ('FR', '1970-01-01', 0.20),
+    ('GB', '1970-01-01', 0.20),
+    ('IE', '1970-01-01', 0.23),
+    ('IT', '1970-01-01', 0.22),
+    ('NL', '1970-01-01', 0.21),
+    ('PL', '1970-01-01', 0.23),
+    ('PT', '1970-01-01', 0.23),
+    ('SE', '1970-01-01', 0.25)
+ON CONFLICT DO NOTHING;
+
+-- Order history pages through an account's orders by id.
+CREATE INDEX IF NOT EXISTS orders_account_id_idx ON orders (account_id, id);
+
+-- Admin order search pages through all orders by id, filtering on these.
+CREATE INDEX IF NOT EXISTS orders_created_at_idx ON orders (created_at);
+CREATE INDEX IF NOT EXISTS orders_status_idx ON orders (status, id);
+
+-- Create a table for order products with an order ID, product ID, variant ID, quantity, and primary key on the combination of product ID, variant ID and order ID.
+-- Lines for products without variants have an empty variant ID.
+-- price is the unit price paid, null for lines of orders placed before it was stored; cancelled_quantity counts the refunded units.
 CREATE TABLE IF NOT EXISTS order_products (
     order_id CHAR(27) REFERENCES orders (id) ON DELETE CASCADE,
     product_id CHAR(27),
+    variant_id VARCHAR(27) NOT NULL DEFAULT '',
+    quantity INT NOT NULL,
+    price MONEY,
+    cancelled_quantity INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (product_id, variant_id, order_id)
+);
+
+-- Bring order_products created before variants up to date.
+ALTER TABLE order_products ADD COLUMN IF NOT EXISTS variant_id VARCHAR(27) NOT NULL DEFAULT '';
+ALTER TABLE order_products DROP CONSTRAINT IF EXISTS order_products_pkey;
+ALTER TABLE order_products ADD CONSTRAINT order_products_pkey PRIMARY KEY (product_id, variant_id, order_id);
+
+-- Bring order_products created before refunds up to date.
+ALTER TABLE order_products ADD COLUMN IF NOT EXISTS price MONEY;
+ALTER TABLE order_products ADD COLUMN IF NOT EXISTS cancelled_quantity INT NOT NULL DEFAULT 0;
+
+-- Order history aggregates each order's lines; the primary key leads with product_id, so it can't find them.
+CREATE INDEX IF NOT EXISTS order_products_order_id_idx ON order_products (order_id);
+
+-- Create a table counting how many orders contained both products; each pair is stored in both directions.
+-- It's kept up to date as orders are placed and can be recomputed with `order rebuild-recommendations`.
+CREATE TABLE IF NOT EXISTS product_co_purchases (
+    product_id CHAR(27) NOT NULL,
+    related_id CHAR(27) NOT NULL,
+    orders INT NOT NULL,
+    PRIMARY KEY (product_id, related_id)
+);
+
+-- Create a table for refunds of cancelled order lines with an ID, order ID, reason code, amount and creation time.
+CREATE TABLE IF NOT EXISTS refunds (
+    id CHAR(27) PRIMARY KEY,
+    order_id CHAR(27) NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    reason SMALLINT NOT NULL,
+    amount MONEY NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS refunds_order_id_idx ON refunds (order_id);
+
+-- Create a table for the lines a refund covers, with the quantity refunded and its amount.
+CREATE TABLE IF NOT EXISTS refund_lines (
+    refund_id CHAR(27) REFERENCES refunds (id) ON DELETE CASCADE,
+    product_id CHAR(27),
+    variant_id VARCHAR(27) NOT NULL DEFAULT '',
+    quantity INT NOT NULL,
+    amount MONEY NOT NULL,
+    PRIMARY KEY (refund_id, product_id, variant_id)
+);
+
+-- Create a table for returns of order lines with an ID, order ID, the returned product, variant and quantity, the customer's reason and a status.
+-- status 0 is requested, 1 approved, 2 rejected, 3 received and 4 inspected; restocked_quantity and refund_amount are set on inspection.
+CREATE TABLE IF NOT EXISTS returns (
+    id CHAR(27) PRIMARY KEY,
+    order_id CHAR(27) NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    product_id CHAR(27) NOT NULL,
+    variant_id VARCHAR(27) NOT NULL DEFAULT '',
+    quantity INT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    status SMALLINT NOT NULL DEFAULT 0,
+    restocked_quantity INT NOT NULL DEFAULT 0,
+    refund_amount MONEY NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS returns_order_id_idx ON returns (order_id);
+
+-- Create a table for the history of returns, one row for every status a return reached with the note left with it.
+CREATE TABLE IF NOT EXISTS return_events (
+    return_id CHAR(27) REFERENCES returns (id) ON DELETE CASCADE,
+    status SMALLINT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (return_id, status)
+);
+
+-- Refunds of inspected returns reference their return.
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS return_id CHAR(27) REFERENCES returns (id) ON DELETE SET NULL;
+
+-- Create a table for shipments of orders with an ID, order ID, carrier, tracking number, package status and timestamps.
+-- status 0 is pending, 1 in transit, 2 out for delivery, 3 delivered and 4 a carrier exception; shipped_at is set once it leaves pending.
+CREATE TABLE IF NOT EXISTS shipments (
+    id CHAR(27) PRIMARY KEY,
+    order_id CHAR(27) NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    carrier TEXT NOT NULL DEFAULT '',
+    tracking_number TEXT NOT NULL DEFAULT '',
+    status SMALLINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    shipped_at TIMESTAMP WITH TIME ZONE,
+    delivered_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS shipments_order_id_idx ON shipments (order_id);
+
+-- Create a table for the order line quantities a shipment covers.
+CREATE TABLE IF NOT EXISTS shipment_lines (
+    shipment_id CHAR(27) REFERENCES shipments (id) ON DELETE CASCADE,
+    product_id CHAR(27),
+    variant_id VARCHAR(27) NOT NULL DEFAULT '',
     quantity INT NOT NULL,
-    PRIMARY KEY (product_id, order_id)
+    PRIMARY KEY (shipment_id, product_id, variant_id)
 );
@@ -0,0 +1,118 @@
+package review
+
+import (
+	"context"
+	"log"
+
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/review/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type Client struct {
+    conn *grpc.ClientConn
+    service pb.ReviewServiceClient
+}
+
+// NewClient dials url with insecure credentials; opts are appended, e.g.
+// a custom dialer.
+func NewClient(url string, opts ...grpc.DialOption) (*Client, error) {
+    opts = append(
+        []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+        opts...,
+    )
+    conn, err := grpc.NewClient(url, opts...)
+    if err != nil {
+        log.Println("failed to create new grpc client from review client: ", err)
+        return nil, err
+    }
+
+    c := pb.NewReviewServiceClient(conn)
+    return &Client{conn: conn, service: c}, nil
+}
+
+func (c *Client) Close() {
+    c.conn.Close()
+}
+
+func (c *Client) PostReview(
+    ctx context.Context, productID, accountID string, rating uint32, title, text string,
+) (*Review, error) {
+    r, err := c.service.PostReview(ctx, &pb.PostReviewRequest{
+        ProductId: productID,
+        AccountId: accountID,
+        Rating: rating,
+        Title: title,
+        Text: text,
+    })
+    if err != nil {
+        log.Println("failed to post review from review client: ", err)
+        return nil, err
+    }
+    review, err := reviewFromProto(r.Review)
+    if err != nil {
+        log.Println("failed to decode review from review client: ", err)
+        return nil, err
+    }
+    return &review, nil
+}
+
+func (c *Client) GetReviews(
+    ctx context.Context, productID string, includeRejected bool, skip, take uint64,
+) ([]Review, error) {
+    r, err := c.service.GetReviews(ctx, &pb.GetReviewsRequest{
+        ProductId: productID,
+        Skip: skip,
+        Take: take,
+        IncludeRejected: includeRejected,
+    })
+    if err != nil {
+        log.Println("failed to get reviews from review client: ", err)
+        return nil, err
+    }
+
+    reviews := []Review{}
+    for _, p := range r.Reviews {
+        review, err := reviewFromProto(p)
+        if err != nil {
+            log.Println("failed to decode review from review client: ", err)
+            return nil, err
+        }
+        reviews = append(reviews, review)
+    }
+    return reviews, nil
+}
+
+func (c *Client) ModerateReview(ctx context.Context, id string, status Status) (*Review, error) {
+    r, err := c.service.ModerateReview(ctx, &pb.ModerateReviewRequest{
+        Id: id,
+        Status: pb.ReviewStatus(status),
+    })
+    if err != nil {
+        log.Println("failed to moderate review from review client: ", err)
+        return nil, err
+    }
+    review, err := reviewFromProto(r.Review)
+    if err != nil {
+        log.Println("failed to decode review from review client: ", err)
+        return nil, err
+    }
+    return &review, nil
+}
+
+func (c *Client) VoteReviewHelpful(ctx context.Context, id, accountID string) (*Review, error) {
+    r, err := c.service.VoteReviewHelpful(ctx, &pb.VoteReviewHelpfulRequest{
+        Id: id,
+        AccountId: accountID,
+    })
+    if err != nil {
+        log.Println("failed to vote review helpful from review client: ", err)
+        return nil, err
+    }
+    review, err := reviewFromProto(r.Review)
+    if err != nil {
+        log.Println("failed to decode review from review client: ", err)
+        return nil, err
+    }
+    return &review, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/review"
+	"github.com/sethvargo/go-retry"
+)
+
+type Config struct {
+    DatabaseURL string `envconfig:"DATABASE_URL"`
+    OrderURL    string `envconfig:"ORDER_SERVICE_URL"`
+    CatalogURL  string `envconfig:"CATALOG_SERVICE_URL"`
+}
+
+func main() {
+    var cfg Config
+    err := envconfig.Process("", &cfg)
+    if err != nil {
+        log.Println("failed to get review config with envconfig: ", err)
+        log.Fatal(err)
+    }
+
+    var r review.Repository
+
+    ctx := context.Background()
+    if strings.HasPrefix(cfg.DatabaseURL, "memory://") {
+        log.Println("using in-memory review repository")
+        r = review.NewMemoryRepository()
+    } else if err := retry.Constant(ctx, time.Second * 1 , func(ctx context.Context) error {
+        r, err = review.NewPostgresRepository(cfg.DatabaseURL)
+        if err != nil {
+            log.Println("failed to create review postgres repository: ", err)
+            return retry.RetryableError(err)
+        }
+        return nil
+    }); err != nil {
+        log.Fatal("failed to retry create review postgres repository: ", err)
+    }
+
+    defer r.Close()
+    log.Println("Listening on port 8080...")
+
+    s := review.NewService(r)
+    log.Fatal(review.ListenGRPC(s, cfg.OrderURL, cfg.CatalogURL, 8080))
+}
@@ -0,0 +1,129 @@
+package review
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type memoryRepository struct {
+    mu      sync.RWMutex
+    reviews map[string]Review
+    // votes holds the ids of the accounts that voted for each review.
+    votes map[string]map[string]bool
+    // ratingVersions holds the version of the last rating of each product.
+    ratingVersions map[string]uint64
+}
+
+func NewMemoryRepository() Repository {
+    return &memoryRepository{
+        reviews: map[string]Review{},
+        votes: map[string]map[string]bool{},
+        ratingVersions: map[string]uint64{},
+    }
+}
+
+func (r *memoryRepository) Close() {}
+
+func (r *memoryRepository) PutReview(ctx context.Context, review Review) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    for _, existing := range r.reviews {
+        if existing.ProductID == review.ProductID && existing.AccountID == review.AccountID {
+            return ErrAlreadyReviewed
+        }
+    }
+    r.reviews[review.ID] = review
+    return nil
+}
+
+func (r *memoryRepository) GetReviewByID(ctx context.Context, id string) (*Review, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    review, ok := r.reviews[id]
+    if !ok {
+        return nil, ErrNotFound
+    }
+    return &review, nil
+}
+
+func (r *memoryRepository) ListReviewsForProduct(
+    ctx context.Context, productID string, includeRejected bool, skip, take uint64,
+) ([]Review, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    reviews := []Review{}
+    for _, review := range r.reviews {
+        if review.ProductID == productID && (includeRejected || review.Status != StatusRejected) {
+            reviews = append(reviews, review)
+        }
+    }
+    slices.SortFunc(reviews, func(a, b Review) int {
+        return cmp.Or(
+            cmp.Compare(b.HelpfulVotes, a.HelpfulVotes),
+            b.CreatedAt.Compare(a.CreatedAt),
+            strings.Compare(b.ID, a.ID),
+        )
+    })
+
+    if skip >= uint64(len(reviews)) {
+        return []Review{}, nil
+    }
+    return reviews[skip:min(skip+take, uint64(len(reviews)))], nil
+}
+
+func (r *memoryRepository) SetReviewStatus(ctx context.Context, id string, status Status) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    review, ok := r.reviews[id]
+    if !ok {
+        return ErrNotFound
+    }
+    review.Status = status
+    r.reviews[id] = review
+    return nil
+}
+
+func (r *memoryRepository) AddHelpfulVote(ctx context.Context, id, accountID string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    review, ok := r.reviews[id]
+    if !ok {
+        return ErrNotFound
+    }
+    if r.votes[id] == nil {
+        r.votes[id] = map[string]bool{}
+    }
+    if r.votes[id][accountID] {
+        return ErrAlreadyVoted
+    }
+    r.votes[id][accountID] = true
+    review.HelpfulVotes++
+    r.reviews[id] = review
+    return nil
+}
+
+func (r *memoryRepository) GetProductRating(ctx context.Context, productID string) (*Rating, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.ratingVersions[productID]++
+    rating, total := &Rating{Version: r.ratingVersions[productID]}, uint64(0)
+    for _, review := range r.reviews {
+        if review.ProductID == productID && review.Status == StatusPublished {
+            rating.Count++
+            total += uint64(review.Rating)
+        }
+    }
+    if rating.Count != 0 {
+        rating.Average = float64(total) / float64(rating.Count)
+    }
+    return rating, nil
+}
@@ -0,0 +1,242 @@
+package review
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	_ "github.com/lib/pq"
+)
+
+type Repository interface {
+    Close()
+    // PutReview returns ErrAlreadyReviewed if the account already reviewed
+    // the product.
+    PutReview(ctx context.Context, r Review) error
+    GetReviewByID(ctx context.Context, id string) (*Review, error)
+    ListReviewsForProduct(
+        ctx context.Context, productID string, includeRejected bool, skip, take uint64,
+    ) ([]Review, error)
+    SetReviewStatus(ctx context.Context, id string, status Status) error
+    // AddHelpfulVote returns ErrAlreadyVoted for a second vote of an account.
+    AddHelpfulVote(ctx context.Context, id, accountID string) error
+    // GetProductRating returns the product's rating with a version higher
+    // than that of any rating returned for it before, so that of a rating
+    // read later is higher and never reflects fewer review changes.
+    GetProductRating(ctx context.Context, productID string) (*Rating, error)
+}
+
+type postgresRepository struct {
+    db *sql.DB
+}
+
+func NewPostgresRepository(url string) (*postgresRepository, error) {
+    db, err := sql.Open("postgres", url)
+    if err != nil {
+        log.Println("failed to create postgres review repository from review repository: ", err)
+        return nil, err
+    }
+
+    err = db.Ping()
+    if err != nil {
+        log.Println("failed to connect to postgres review repository: ", err)
+        return nil, err
+    }
+
+    return &postgresRepository{db: db}, nil
+}
+
+func (r *postgresRepository) Close() {
+    r.db.Close()
+}
+
+const reviewColumns = "id, product_id, account_id, rating, title, text, status, helpful_votes, created_at"
+
+func (r *postgresRepository) PutReview(ctx context.Context, review Review) error {
+    res, err := r.db.ExecContext(
+        ctx,
+        `INSERT INTO reviews (`+reviewColumns+`)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        ON CONFLICT (product_id, account_id) DO NOTHING`,
+        review.ID,
+        review.ProductID,
+        review.AccountID,
+        review.Rating,
+        review.Title,
+        review.Text,
+        review.Status,
+        review.HelpfulVotes,
+        review.CreatedAt,
+    )
+    if err != nil {
+        log.Println("failed to insert review from review repository: ", err)
+        return err
+    }
+    if n, err := res.RowsAffected(); err == nil && n == 0 {
+        return ErrAlreadyReviewed
+    }
+    return nil
+}
+
+func (r *postgresRepository) GetReviewByID(ctx context.Context, id string) (*Review, error) {
+    review, err := scanReview(r.db.QueryRowContext(
+        ctx, "SELECT "+reviewColumns+" FROM reviews WHERE id=$1", id,
+    ))
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, ErrNotFound
+    }
+    if err != nil {
+        log.Println("failed to get review by id from review repository: ", err)
+        return nil, err
+    }
+    return review, nil
+}
+
+func (r *postgresRepository) ListReviewsForProduct(
+    ctx context.Context, productID string, includeRejected bool, skip, take uint64,
+) ([]Review, error) {
+    rows, err := r.db.QueryContext(
+        ctx,
+        `SELECT `+reviewColumns+` FROM reviews
+        WHERE product_id=$1 AND ($2 OR status=$3)
+        ORDER BY helpful_votes DESC, created_at DESC, id DESC
+        OFFSET $4 LIMIT $5`,
+        productID,
+        includeRejected,
+        StatusPublished,
+        skip,
+        take,
+    )
+    if err != nil {
+        log.Println("failed to list reviews from review repository: ", err)
+        return nil, err
+    }
+    defer rows.Close()
+
+    reviews := []Review{}
+    for rows.Next() {
+        review, err := scanReview(rows)
+        if err != nil {
+            log.Println("failed to scan review from review repository: ", err)
+            return nil, err
+        }
+        reviews = append(reviews, *review)
+    }
+    return reviews, rows.Err()
+}
+
+func (r *postgresRepository) SetReviewStatus(ctx context.Context, id string, status Status) error {
+    res, err := r.db.ExecContext(ctx, "UPDATE reviews SET status=$2 WHERE id=$1", id, status)
+    if err != nil {
+        log.Println("failed to update review status from review repository: ", err)
+        return err
+    }
+    if n, err := res.RowsAffected(); err == nil && n == 0 {
+        return ErrNotFound
+    }
+    return nil
+}
+
+// AddHelpfulVote records the vote and bumps the counter in one
+// transaction, so helpful_votes always matches review_votes.
+func (r *postgresRepository) AddHelpfulVote(ctx context.Context, id, accountID string) error {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        log.Println("failed to begin vote transaction from review repository: ", err)
+        return err
+    }
+    defer tx.Rollback()
+
+    res, err := tx.ExecContext(
+        ctx,
+        "UPDATE reviews SET helpful_votes = helpful_votes + 1 WHERE id=$1",
+        id,
+    )
+    if err != nil {
+        log.Println("failed to count helpful vote from review repository: ", err)
+        return err
+    }
+    if n, err := res.RowsAffected(); err == nil && n == 0 {
+        return ErrNotFound
+    }
+
+    res, err = tx.ExecContext(
+        ctx,
+        "INSERT INTO review_votes (review_id, account_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+        id,
+        accountID,
+    )
+    if err != nil {
+        log.Println("failed to insert helpful vote from review repository: ", err)
+        return err
+    }
+    if n, err := res.RowsAffected(); err == nil && n == 0 {
+        return ErrAlreadyVoted
+    }
+    return tx.Commit()
+}
+
+// GetProductRating takes the next version with the product's row of
+// rating_versions locked, so a concurrent call waits and then aggregates
+// with a newer snapshot.
+func (r *postgresRepository) GetProductRating(ctx context.Context, productID string) (*Rating, error) {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        log.Println("failed to begin rating transaction from review repository: ", err)
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    rating := &Rating{}
+    err = tx.QueryRowContext(
+        ctx,
+        `INSERT INTO rating_versions (product_id, version) VALUES ($1, 1)
+        ON CONFLICT (product_id) DO UPDATE SET version = rating_versions.version + 1
+        RETURNING version`,
+        productID,
+    ).Scan(&rating.Version)
+    if err != nil {
+        log.Println("failed to get product rating version from review repository: ", err)
+        return nil, err
+    }
+    err = tx.QueryRowContext(
+        ctx,
+        `SELECT COALESCE(AVG(rating), 0)::float8, COUNT(*)
+        FROM reviews WHERE product_id=$1 AND status=$2`,
+        productID,
+        StatusPublished,
+    ).Scan(&rating.Average, &rating.Count)
+    if err != nil {
+        log.Println("failed to get product rating from review repository: ", err)
+        return nil, err
+    }
+    if err := tx.Commit(); err != nil {
+        log.Println("failed to commit rating transaction from review repository: ", err)
+        return nil, err
+    }
+    return rating, nil
+}
+
+type rowScanner interface {
+    Scan(dest ...any) error
+}
+
+func scanReview(row rowScanner) (*Review, error) {
+    review := &Review{}
+    err := row.Scan(
+        &review.ID,
+        &review.ProductID,
+        &review.AccountID,
+        &review.Rating,
+        &review.Title,
+        &review.Text,
+        &review.Status,
+        &review.HelpfulVotes,
+        &review.CreatedAt,
+    )
+    if err != nil {
+        return nil, err
+    }
+    return review, nil
+}
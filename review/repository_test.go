@@ -0,0 +1,74 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryRepository(t *testing.T) {
+    ctx := context.Background()
+    r := NewMemoryRepository()
+
+    now := time.Now().UTC()
+    for _, review := range []Review{
+        {ID: "r1", ProductID: "p1", AccountID: "a1", Rating: 5, CreatedAt: now},
+        {ID: "r2", ProductID: "p1", AccountID: "a2", Rating: 3, CreatedAt: now.Add(time.Second)},
+        {ID: "r3", ProductID: "p1", AccountID: "a3", Rating: 1, CreatedAt: now.Add(2 * time.Second)},
+        {ID: "r4", ProductID: "p2", AccountID: "a1", Rating: 2, CreatedAt: now},
+    } {
+        if err := r.PutReview(ctx, review); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := r.PutReview(ctx, Review{ID: "r5", ProductID: "p1", AccountID: "a1"}); !errors.Is(err, ErrAlreadyReviewed) {
+        t.Fatalf("expected ErrAlreadyReviewed, got %v", err)
+    }
+
+    rating, err := r.GetProductRating(ctx, "p1")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if rating.Count != 3 || rating.Average != 3 {
+        t.Fatalf("expected 3 reviews rated 3, got %+v", rating)
+    }
+
+    if err := r.SetReviewStatus(ctx, "r3", StatusRejected); err != nil {
+        t.Fatal(err)
+    }
+    if err := r.SetReviewStatus(ctx, "missing", StatusRejected); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("expected ErrNotFound, got %v", err)
+    }
+    if later, _ := r.GetProductRating(ctx, "p1"); later.Count != 2 || later.Average != 4 {
+        t.Fatalf("expected the rejected review to be left out, got %+v", later)
+    } else if later.Version <= rating.Version {
+        t.Fatalf("expected a later rating to have a higher version than %d, got %d", rating.Version, later.Version)
+    }
+    if rating, _ := r.GetProductRating(ctx, "p3"); rating.Count != 0 || rating.Average != 0 {
+        t.Fatalf("expected no rating for an unreviewed product, got %+v", rating)
+    }
+
+    if err := r.AddHelpfulVote(ctx, "r1", "a2"); err != nil {
+        t.Fatal(err)
+    }
+    if err := r.AddHelpfulVote(ctx, "r1", "a2"); !errors.Is(err, ErrAlreadyVoted) {
+        t.Fatalf("expected ErrAlreadyVoted, got %v", err)
+    }
+
+    // Most helpful first, then newest first; rejected reviews on request.
+    reviews, err := r.ListReviewsForProduct(ctx, "p1", false, 0, 10)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(reviews) != 2 || reviews[0].ID != "r1" || reviews[1].ID != "r2" {
+        t.Fatalf("unexpected reviews %+v", reviews)
+    }
+    reviews, err = r.ListReviewsForProduct(ctx, "p1", true, 1, 10)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(reviews) != 2 || reviews[0].ID != "r3" || reviews[1].ID != "r2" {
+        t.Fatalf("unexpected reviews with rejected ones %+v", reviews)
+    }
+}
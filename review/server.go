@@ -0,0 +1,208 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/catalog"
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/order"
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/review/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type grpcServer struct {
+    pb.UnimplementedReviewServiceServer
+    service Service
+    orderClient *order.Client
+    catalogClient *catalog.Client
+}
+
+func ListenGRPC(s Service, orderURL, catalogURL string, port int) error {
+    orderClient, err := order.NewClient(orderURL)
+    if err != nil {
+        log.Println("failed to create order client from review server: ", err)
+        return err
+    }
+
+    catalogClient, err := catalog.NewClient(catalogURL)
+    if err != nil {
+        orderClient.Close()
+        log.Println("failed to create catalog client from review server: ", err)
+        return err
+    }
+
+    lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+    if err != nil {
+        orderClient.Close()
+        catalogClient.Close()
+        log.Println("failed to listen tcp from review server: ", err)
+        return err
+    }
+
+    return NewGRPCServer(s, orderClient, catalogClient).Serve(lis)
+}
+
+// NewGRPCServer returns a grpc server with the review service registered,
+// using orderClient to check purchases and catalogClient to keep product
+// ratings up to date.
+func NewGRPCServer(
+    s Service, orderClient *order.Client, catalogClient *catalog.Client,
+) *grpc.Server {
+    serv := grpc.NewServer()
+    pb.RegisterReviewServiceServer(
+        serv,
+        grpcServer{
+            UnimplementedReviewServiceServer: pb.UnimplementedReviewServiceServer{},
+            service: s,
+            orderClient: orderClient,
+            catalogClient: catalogClient,
+        },
+    )
+
+    reflection.Register(serv)
+    return serv
+}
+
+func (s grpcServer) PostReview(
+    ctx context.Context, r *pb.PostReviewRequest,
+) (*pb.ReviewResponse, error) {
+    purchased, err := s.purchased(ctx, r.AccountId, r.ProductId)
+    if err != nil {
+        log.Println("failed to get orders from review server: ", err)
+        return nil, err
+    }
+    if !purchased {
+        return nil, ErrNotPurchased
+    }
+
+    review, err := s.service.PostReview(ctx, r.ProductId, r.AccountId, r.Rating, r.Title, r.Text)
+    if err != nil {
+        log.Println("failed to post review from review server: ", err)
+        return nil, err
+    }
+    s.syncRating(ctx, review.ProductID)
+    return &pb.ReviewResponse{Review: reviewToProto(review)}, nil
+}
+
+// purchasedStatuses are the statuses of orders that weren't cancelled.
+var purchasedStatuses = []order.Status{
+    order.StatusPlaced,
+    order.StatusPartiallyShipped,
+    order.StatusShipped,
+    order.StatusDelivered,
+}
+
+// errPurchased stops streaming orders once a purchase is found.
+var errPurchased = errors.New("purchased")
+
+// purchased reports whether the account ordered the product and didn't
+// cancel all of it. Orders cancelled only in part still count for their
+// other lines.
+func (s grpcServer) purchased(ctx context.Context, accountID, productID string) (bool, error) {
+    q := order.OrderQuery{
+        Filter: order.OrderFilter{ProductID: productID, Statuses: purchasedStatuses},
+    }
+    err := s.orderClient.StreamOrdersForAccount(ctx, accountID, q, func(o order.Order) error {
+        for _, p := range o.Products {
+            if p.ID == productID && p.CancelledQuantity < p.Quantity {
+                return errPurchased
+            }
+        }
+        return nil
+    })
+    if err == errPurchased {
+        return true, nil
+    }
+    return false, err
+}
+
+func (s grpcServer) GetReviews(
+    ctx context.Context, r *pb.GetReviewsRequest,
+) (*pb.GetReviewsResponse, error) {
+    reviews, err := s.service.GetReviews(ctx, r.ProductId, r.IncludeRejected, r.Skip, r.Take)
+    if err != nil {
+        log.Println("failed to get reviews from review server: ", err)
+        return nil, err
+    }
+
+    res := &pb.GetReviewsResponse{Reviews: []*pb.Review{}}
+    for _, review := range reviews {
+        res.Reviews = append(res.Reviews, reviewToProto(&review))
+    }
+    return res, nil
+}
+
+func (s grpcServer) ModerateReview(
+    ctx context.Context, r *pb.ModerateReviewRequest,
+) (*pb.ReviewResponse, error) {
+    review, err := s.service.ModerateReview(ctx, r.Id, Status(r.Status))
+    if err != nil {
+        log.Println("failed to moderate review from review server: ", err)
+        return nil, err
+    }
+    s.syncRating(ctx, review.ProductID)
+    return &pb.ReviewResponse{Review: reviewToProto(review)}, nil
+}
+
+func (s grpcServer) VoteReviewHelpful(
+    ctx context.Context, r *pb.VoteReviewHelpfulRequest,
+) (*pb.ReviewResponse, error) {
+    review, err := s.service.VoteHelpful(ctx, r.Id, r.AccountId)
+    if err != nil {
+        log.Println("failed to vote review helpful from review server: ", err)
+        return nil, err
+    }
+    return &pb.ReviewResponse{Review: reviewToProto(review)}, nil
+}
+
+// syncRating copies the product's rating to the catalog. The review is
+// already stored, so a failure is only logged; the next review or
+// moderation of the product syncs it again. The catalog ignores a rating
+// arriving after a newer one, so concurrent syncs can't leave a stale one.
+func (s grpcServer) syncRating(ctx context.Context, productID string) {
+    rating, err := s.service.GetProductRating(ctx, productID)
+    if err != nil {
+        log.Println("failed to get product rating from review server: ", err)
+        return
+    }
+    err = s.catalogClient.SetProductRating(ctx, productID, rating.Average, rating.Count, rating.Version)
+    if err != nil {
+        log.Println("failed to set product rating from review server: ", err)
+    }
+}
+
+func reviewToProto(r *Review) *pb.Review {
+    return &pb.Review{
+        Id: r.ID,
+        ProductId: r.ProductID,
+        AccountId: r.AccountID,
+        Rating: r.Rating,
+        Title: r.Title,
+        Text: r.Text,
+        Status: pb.ReviewStatus(r.Status),
+        HelpfulVotes: r.HelpfulVotes,
+        CreatedAt: timestamppb.New(r.CreatedAt),
+    }
+}
+
+func reviewFromProto(p *pb.Review) (Review, error) {
+    if err := p.CreatedAt.CheckValid(); err != nil {
+        return Review{}, err
+    }
+    return Review{
+        ID: p.Id,
+        ProductID: p.ProductId,
+        AccountID: p.AccountId,
+        Rating: p.Rating,
+        Title: p.Title,
+        Text: p.Text,
+        Status: Status(p.Status),
+        HelpfulVotes: p.HelpfulVotes,
+        CreatedAt: p.CreatedAt.AsTime(),
+    }, nil
+}
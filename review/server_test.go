@@ -0,0 +1,138 @@
+package review_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/order"
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/review"
+	"github.com/pirateunclejack/go-grpc-graphql-microservice/testharness"
+)
+
+func TestPostReviewNeedsPurchase(t *testing.T) {
+    ctx := context.Background()
+    h := testharness.Start(t)
+
+    a, err := h.AccountClient.PostAccount(ctx, "alice")
+    if err != nil {
+        t.Fatal(err)
+    }
+    kettle, err := h.CatalogClient.PostProduct(ctx, "Kettle", "1.7l", 30, nil, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    mug, err := h.CatalogClient.PostProduct(ctx, "Mug", "ceramic", 10, nil, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    post := func() error {
+        _, err := h.ReviewClient.PostReview(ctx, kettle.ID, a.ID, 4, "", "boils fast")
+        return err
+    }
+    notPurchased := func(err error) bool {
+        return err != nil && strings.Contains(err.Error(), review.ErrNotPurchased.Error())
+    }
+
+    if err := post(); !notPurchased(err) {
+        t.Fatalf("expected a review without an order to be rejected, got %v", err)
+    }
+
+    // Neither a cancelled order nor a cancelled line counts.
+    o, err := h.OrderClient.PostOrder(ctx, a.ID, []order.OrderedProduct{{ID: kettle.ID, Quantity: 1}}, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := h.OrderClient.CancelOrder(ctx, o.ID, order.ReasonCustomerRequest, nil); err != nil {
+        t.Fatal(err)
+    }
+    o, err = h.OrderClient.PostOrder(ctx, a.ID, []order.OrderedProduct{
+        {ID: kettle.ID, Quantity: 1},
+        {ID: mug.ID, Quantity: 1},
+    }, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    _, err = h.OrderClient.CancelOrder(
+        ctx, o.ID, order.ReasonOutOfStock, []order.OrderedProduct{{ID: kettle.ID, Quantity: 1}},
+    )
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := post(); !notPurchased(err) {
+        t.Fatalf("expected cancelled purchases not to count, got %v", err)
+    }
+
+    if _, err := h.OrderClient.PostOrder(ctx, a.ID, []order.OrderedProduct{{ID: kettle.ID, Quantity: 1}}, nil, nil); err != nil {
+        t.Fatal(err)
+    }
+    if err := post(); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestReviewRatingSync(t *testing.T) {
+    ctx := context.Background()
+    h := testharness.Start(t)
+
+    kettle, err := h.CatalogClient.PostProduct(ctx, "Kettle", "1.7l", 30, nil, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    reviews := []*review.Review{}
+    for i, rating := range []uint32{5, 2, 4} {
+        a, err := h.AccountClient.PostAccount(ctx, string(rune('a' + i)))
+        if err != nil {
+            t.Fatal(err)
+        }
+        _, err = h.OrderClient.PostOrder(ctx, a.ID, []order.OrderedProduct{{ID: kettle.ID, Quantity: 1}}, nil, nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        r, err := h.ReviewClient.PostReview(ctx, kettle.ID, a.ID, rating, "", "fine")
+        if err != nil {
+            t.Fatal(err)
+        }
+        if r.CreatedAt.IsZero() {
+            t.Fatalf("expected the review's creation time, got %+v", r)
+        }
+        reviews = append(reviews, r)
+    }
+
+    p, err := h.CatalogClient.GetProduct(ctx, kettle.ID)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if p.ReviewCount != 3 || p.Rating < 3.66 || p.Rating > 3.67 {
+        t.Fatalf("expected 3 reviews rated 3.67, got %v from %d", p.Rating, p.ReviewCount)
+    }
+
+    // Rejecting a review leaves it out of the rating; publishing it again
+    // brings it back.
+    if _, err := h.ReviewClient.ModerateReview(ctx, reviews[1].ID, review.StatusRejected); err != nil {
+        t.Fatal(err)
+    }
+    if p, _ := h.CatalogClient.GetProduct(ctx, kettle.ID); p.ReviewCount != 2 || p.Rating != 4.5 {
+        t.Fatalf("expected 2 reviews rated 4.5, got %v from %d", p.Rating, p.ReviewCount)
+    }
+    listed, err := h.ReviewClient.GetReviews(ctx, kettle.ID, false, 0, 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(listed) != 2 {
+        t.Fatalf("expected the rejected review to be hidden, got %+v", listed)
+    }
+    if listed, _ := h.ReviewClient.GetReviews(ctx, kettle.ID, true, 0, 0); len(listed) != 3 {
+        t.Fatalf("expected all reviews with includeRejected, got %+v", listed)
+    }
+
+    if _, err := h.ReviewClient.ModerateReview(ctx, reviews[1].ID, review.StatusPublished); err != nil {
+        t.Fatal(err)
+    }
+    if p, _ := h.CatalogClient.GetProduct(ctx, kettle.ID); p.ReviewCount != 3 {
+        t.Fatalf("expected the published review back in the rating, got %d", p.ReviewCount)
+    }
+    if _, err := h.ReviewClient.ModerateReview(ctx, "missing", review.StatusRejected); err == nil {
+        t.Fatal("expected moderating an unknown review to fail")
+    }
+}
@@ -0,0 +1,150 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+var (
+    ErrNotFound        = errors.New("review not found")
+    ErrInvalidRating   = errors.New("rating must be between 1 and 5")
+    ErrEmptyReview     = errors.New("review text is required")
+    ErrNotPurchased    = errors.New("only accounts that ordered a product can review it")
+    ErrAlreadyReviewed = errors.New("account already reviewed this product")
+    ErrOwnReview       = errors.New("accounts can't vote for their own reviews")
+    ErrAlreadyVoted    = errors.New("account already voted for this review")
+)
+
+const (
+    minRating = 1
+    maxRating = 5
+)
+
+type Status int
+
+const (
+    StatusPublished Status = iota
+    // StatusRejected hides a review and leaves it out of the product
+    // rating.
+    StatusRejected
+)
+
+type Service interface {
+    PostReview(
+        ctx context.Context, productID, accountID string, rating uint32, title, text string,
+        ) (*Review, error)
+    GetReviews(
+        ctx context.Context, productID string, includeRejected bool, skip, take uint64,
+        ) ([]Review, error)
+    ModerateReview(ctx context.Context, id string, status Status) (*Review, error)
+    VoteHelpful(ctx context.Context, id, accountID string) (*Review, error)
+    GetProductRating(ctx context.Context, productID string) (*Rating, error)
+}
+
+type Review struct {
+    ID           string
+    ProductID    string
+    AccountID    string
+    Rating       uint32
+    Title        string
+    Text         string
+    Status       Status
+    HelpfulVotes uint32
+    CreatedAt    time.Time
+}
+
+// Rating aggregates the published reviews of a product. Version orders
+// the ratings of a product by when they were aggregated.
+type Rating struct {
+    Average float64
+    Count   uint64
+    Version uint64
+}
+
+type reviewService struct {
+    repository Repository
+}
+
+func NewService(r Repository) Service {
+    return &reviewService{r}
+}
+
+// PostReview publishes a review right away; moderators reject reviews
+// after the fact. Checking that the account ordered the product is left to
+// the server, which has the order client.
+func (s *reviewService) PostReview(
+    ctx context.Context, productID, accountID string, rating uint32, title, text string,
+) (*Review, error) {
+    if rating < minRating || rating > maxRating {
+        return nil, ErrInvalidRating
+    }
+    text = strings.TrimSpace(text)
+    if text == "" {
+        return nil, ErrEmptyReview
+    }
+
+    r := &Review{
+        ID: ksuid.New().String(),
+        ProductID: productID,
+        AccountID: accountID,
+        Rating: rating,
+        Title: strings.TrimSpace(title),
+        Text: text,
+        Status: StatusPublished,
+        CreatedAt: time.Now().UTC(),
+    }
+    if err := s.repository.PutReview(ctx, *r); err != nil {
+        log.Println("failed to put review from review service: ", err)
+        return nil, err
+    }
+    return r, nil
+}
+
+func (s *reviewService) GetReviews(
+    ctx context.Context, productID string, includeRejected bool, skip, take uint64,
+) ([]Review, error) {
+    if take > 100 || (skip == 0 && take == 0) {
+        take = 100
+    }
+    return s.repository.ListReviewsForProduct(ctx, productID, includeRejected, skip, take)
+}
+
+func (s *reviewService) ModerateReview(
+    ctx context.Context, id string, status Status,
+) (*Review, error) {
+    if err := s.repository.SetReviewStatus(ctx, id, status); err != nil {
+        log.Println("failed to set review status from review service: ", err)
+        return nil, err
+    }
+    return s.repository.GetReviewByID(ctx, id)
+}
+
+// VoteHelpful counts one vote per account and review.
+func (s *reviewService) VoteHelpful(
+    ctx context.Context, id, accountID string,
+) (*Review, error) {
+    r, err := s.repository.GetReviewByID(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    if r.AccountID == accountID {
+        return nil, ErrOwnReview
+    }
+
+    if err := s.repository.AddHelpfulVote(ctx, id, accountID); err != nil {
+        log.Println("failed to add helpful vote from review service: ", err)
+        return nil, err
+    }
+    return s.repository.GetReviewByID(ctx, id)
+}
+
+func (s *reviewService) GetProductRating(
+    ctx context.Context, productID string,
+) (*Rating, error) {
+    return s.repository.GetProductRating(ctx, productID)
+}
@@ -0,0 +1,31 @@
+-- Create a table for reviews; each account may review a product once.
+-- status is 0 for published and 1 for rejected reviews.
+CREATE TABLE IF NOT EXISTS reviews (
+    id CHAR(27) PRIMARY KEY,
+    product_id CHAR(27) NOT NULL,
+    account_id CHAR(27) NOT NULL,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    title TEXT NOT NULL DEFAULT '',
+    text TEXT NOT NULL,
+    status SMALLINT NOT NULL DEFAULT 0,
+    helpful_votes INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (product_id, account_id)
+);
+
+CREATE INDEX IF NOT EXISTS reviews_product_listing_idx
+    ON reviews (product_id, helpful_votes DESC, created_at DESC);
+
+-- Create a table counting the ratings aggregated for each product; the
+-- catalog ignores ratings of lower versions than the one it stored.
+CREATE TABLE IF NOT EXISTS rating_versions (
+    product_id CHAR(27) PRIMARY KEY,
+    version BIGINT NOT NULL
+);
+
+-- Create a table for helpful votes, one per account and review.
+CREATE TABLE IF NOT EXISTS review_votes (
+    review_id CHAR(27) REFERENCES reviews (id) ON DELETE CASCADE,
+    account_id CHAR(27) NOT NULL,
+    PRIMARY KEY (review_id, account_id)
+);